@@ -8,6 +8,7 @@ const (
 	PostConstVideo
 	PostConstPhotoGallery
 	PostConstAlbum
+	PostConstRepost
 )
 
 func (pc PostConst) Parse() int {
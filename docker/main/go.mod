@@ -1,3 +1,5 @@
+// The entrypoint of the functions, the Dockerfile in the root copies main.go into the function which is built.
+// This module keeps the template out of the root module.
 module handler
 
-go 1.15
\ No newline at end of file
+go 1.16
@@ -1,3 +0,0 @@
-module github.com/red-gold/ts-serverless/micros/__micro_name
-
-go 1.15
@@ -2,4 +2,10 @@ module github.com/red-gold/ts-serverless
 
 go 1.16
 
-require github.com/red-gold/telar-core v0.1.10
+require (
+	github.com/alexellis/hmac v0.0.0-20180624211220-5c52ab81c0de
+	github.com/gofiber/fiber/v2 v2.10.0
+	github.com/gofrs/uuid v4.0.0+incompatible
+	github.com/red-gold/telar-core v0.1.16
+	go.mongodb.org/mongo-driver v1.5.1
+)
@@ -15,6 +15,8 @@ import (
 	micros "github.com/red-gold/ts-serverless/micros"
 	"github.com/red-gold/ts-serverless/micros/circles/database"
 	"github.com/red-gold/ts-serverless/micros/circles/router"
+	service "github.com/red-gold/ts-serverless/micros/circles/services"
+	"github.com/red-gold/ts-serverless/micros/internal/index"
 )
 
 // Cache state
@@ -53,9 +55,16 @@ func Handle(w http.ResponseWriter, r *http.Request) {
 			log.Error("Error startup: %s", startErr.Error())
 			w.WriteHeader(http.StatusInternalServerError)
 			w.Write([]byte(startErr.Error()))
+		} else {
+			go index.Reconcile(newIndexService)
 		}
 	}
 
 	adaptor.FiberApp(app)(w, r)
 
 }
+
+// newIndexService create the index service of the function for the reconciler
+func newIndexService() (index.Service, error) {
+	return service.NewIndexService(database.Db)
+}
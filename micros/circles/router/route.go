@@ -11,7 +11,10 @@ import (
 	"github.com/red-gold/telar-core/middleware/authcookie"
 	"github.com/red-gold/telar-core/middleware/authhmac"
 	"github.com/red-gold/telar-core/types"
+	"github.com/red-gold/ts-serverless/micros/circles/database"
 	"github.com/red-gold/ts-serverless/micros/circles/handlers"
+	service "github.com/red-gold/ts-serverless/micros/circles/services"
+	"github.com/red-gold/ts-serverless/micros/internal/index"
 )
 
 // SetupRoutes func
@@ -53,6 +56,8 @@ func SetupRoutes(app *fiber.App) {
 	hmacCookieHandlers := []func(*fiber.Ctx) error{authHMACMiddleware(true), authCookieMiddleware(true)}
 
 	// Routers
+	app.Post("/index", authHMACMiddleware(false), index.InitHandle(newIndexService))
+	app.Get("/index", authHMACMiddleware(false), index.MissingHandle(newIndexService))
 	app.Post("/following/:userId", authHMACMiddleware(false), handlers.CreateFollowingHandle)
 	app.Post("/", append(hmacCookieHandlers, handlers.CreateCircleHandle)...)
 	app.Put("/", append(hmacCookieHandlers, handlers.UpdateCircleHandle)...)
@@ -60,3 +65,8 @@ func SetupRoutes(app *fiber.App) {
 	app.Get("/my", append(hmacCookieHandlers, handlers.GetMyCircleHandle)...)
 	app.Get("/id/:circleId", append(hmacCookieHandlers, handlers.GetCircleHandle)...)
 }
+
+// newIndexService create the index service of the function
+func newIndexService() (index.Service, error) {
+	return service.NewIndexService(database.Db)
+}
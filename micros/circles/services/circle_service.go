@@ -11,6 +11,7 @@ import (
 	mongoRepo "github.com/red-gold/telar-core/data/mongodb"
 	"github.com/red-gold/telar-core/utils"
 	dto "github.com/red-gold/ts-serverless/micros/circles/dto"
+	"github.com/red-gold/ts-serverless/micros/internal/inmemory"
 )
 
 // CircleService handlers with injected dependencies
@@ -29,6 +30,10 @@ func NewCircleService(db interface{}) (CircleService, error) {
 		mongodb := db.(mongodb.MongoDatabase)
 		circleService.CircleRepo = mongoRepo.NewDataRepositoryMongo(mongodb)
 
+	case config.DB_INMEMORY:
+
+		circleService.CircleRepo = inmemory.NewDataRepositoryInMemory(db.(*inmemory.Database))
+
 	}
 
 	return circleService, nil
@@ -0,0 +1,15 @@
+package service
+
+import (
+	"github.com/red-gold/ts-serverless/micros/internal/index"
+)
+
+// requiredIndexes are the indexes which the circles function needs
+var requiredIndexes = []index.Model{
+	index.New(circleCollectionName, false, "ownerUserId", 1),
+}
+
+// NewIndexService create the index service on the required indexes of the function
+func NewIndexService(db interface{}) (index.Service, error) {
+	return index.NewService(db, requiredIndexes)
+}
@@ -5,8 +5,13 @@ type (
 		BaseRoute      string
 		QueryPrettyURL bool
 		Debug          bool // Debug enables verbose logging of claims / cookies
+
+		// TrashRetentionDays the days which the deleted items are kept in the trash before they are purged
+		TrashRetentionDays int64
 	}
 )
 
 // CommentConfig holds the configuration values from comment-config.yml file
-var CommentConfig Configuration
+var CommentConfig = Configuration{
+	TrashRetentionDays: 30,
+}
@@ -41,4 +41,15 @@ func loadConfigFromEnvironment() {
 		CommentConfig.Debug = parsedDebug
 		log.Printf("[INFO]: Debug information loaded from env.")
 	}
+
+	trashRetentionDays, ok := os.LookupEnv("trash_retention_days")
+	if ok {
+		parsedTrashRetentionDays, errParse := strconv.ParseInt(trashRetentionDays, 10, 64)
+		if errParse != nil || parsedTrashRetentionDays <= 0 {
+			log.Printf("[ERROR]: Trash retention days information loading error: %s", trashRetentionDays)
+		} else {
+			CommentConfig.TrashRetentionDays = parsedTrashRetentionDays
+			log.Printf("[INFO]: Trash retention days information loaded from env.")
+		}
+	}
 }
@@ -11,9 +11,15 @@ type Comment struct {
 	OwnerDisplayName string    `json:"ownerDisplayName" bson:"ownerDisplayName"`
 	OwnerAvatar      string    `json:"ownerAvatar" bson:"ownerAvatar"`
 	PostId           uuid.UUID `json:"postId" bson:"postId"`
+	PostOwnerUserId  uuid.UUID `json:"postOwnerUserId" bson:"postOwnerUserId"`
+	ParentCommentId  uuid.UUID `json:"parentCommentId" bson:"parentCommentId"`
+	ReplyCounter     int64     `json:"replyCounter" bson:"replyCounter"`
 	Text             string    `json:"text" bson:"text"`
+	Mentions         []Mention `json:"mentions" bson:"mentions"`
 	Deleted          bool      `json:"deleted" bson:"deleted"`
 	DeletedDate      int64     `json:"deletedDate" bson:"deletedDate"`
+	PostDeleted      bool      `json:"postDeleted" bson:"postDeleted"`
+	PostDeletedDate  int64     `json:"postDeletedDate" bson:"postDeletedDate"`
 	CreatedDate      int64     `json:"created_date" bson:"created_date"`
 	LastUpdated      int64     `json:"last_updated" bson:"last_updated"`
 }
@@ -1,18 +1,18 @@
 package handlers
 
 import (
-	"bytes"
-	"encoding/hex"
+	"context"
+	"encoding/json"
 	"fmt"
-	"io/ioutil"
 	"net/http"
 
-	"github.com/alexellis/hmac"
 	"github.com/gofiber/fiber/v2"
 	uuid "github.com/gofrs/uuid"
-	coreConfig "github.com/red-gold/telar-core/config"
+	"github.com/red-gold/telar-core/pkg/log"
 	"github.com/red-gold/telar-core/types"
-	"github.com/red-gold/telar-core/utils"
+	models "github.com/red-gold/ts-serverless/micros/comments/models"
+	"github.com/red-gold/ts-serverless/micros/internal/httperr"
+	"github.com/red-gold/ts-serverless/micros/internal/rpc"
 )
 
 type ResultAsync struct {
@@ -69,57 +69,64 @@ func getHeaderInfoReq(c *fiber.Ctx) map[string][]string {
 	return getHeadersFromUserInfoReq(getUserInfoReq(c))
 }
 
-// functionCall send request to another function/microservice using HMAC validation
-func functionCall(method string, bytesReq []byte, url string, header map[string][]string) ([]byte, error) {
-	prettyURL := utils.GetPrettyURLf(url)
-	bodyReader := bytes.NewBuffer(bytesReq)
-
-	httpReq, httpErr := http.NewRequest(method, *coreConfig.AppConfig.InternalGateway+prettyURL, bodyReader)
-	if httpErr != nil {
-		return nil, httpErr
-	}
-
-	digest := hmac.Sign(bytesReq, []byte(*coreConfig.AppConfig.PayloadSecret))
-	httpReq.Header.Set("Content-type", "application/json")
-	fmt.Printf("\ndigest: %s, header: %v \n", "sha1="+hex.EncodeToString(digest), types.HeaderHMACAuthenticate)
-	httpReq.Header.Add(types.HeaderHMACAuthenticate, "sha1="+hex.EncodeToString(digest))
-
-	if header != nil {
-		for k, v := range header {
-			httpReq.Header[k] = v
+// getProfileBySocialName Get user profile by social name
+func getProfileBySocialName(ctx context.Context, socialName string) (*models.UserProfileModel, error) {
+	profileURL := fmt.Sprintf("/profile/social/%s", socialName)
+	foundProfileData, err := rpc.Call(ctx, http.MethodGet, profileURL, []byte(""))
+	if err != nil {
+		if rpc.IsNotFound(err) {
+			return nil, nil
 		}
+		log.Error("rpc.Call (%s) -  %s", profileURL, err.Error())
+		return nil, fmt.Errorf("getProfileBySocialName/rpc")
+	}
+	var foundProfile models.UserProfileModel
+	err = json.Unmarshal(foundProfileData, &foundProfile)
+	if err != nil {
+		log.Error("Unmarshal foundProfile -  %s", err.Error())
+		return nil, fmt.Errorf("getProfileBySocialName/unmarshal")
 	}
+	return &foundProfile, nil
+}
 
-	c := http.Client{}
-	res, reqErr := c.Do(httpReq)
-	fmt.Printf("\nRes: %v\n", res)
-	if reqErr != nil {
-		return nil, fmt.Errorf("Error while sending admin check request!: %s", reqErr.Error())
+// getBlockedUserIds Get the users which the current user blocked or is blocked by.
+// If the restrictions are not available, the request fails, the comments of the blocked users are never shown.
+func getBlockedUserIds(c *fiber.Ctx) ([]uuid.UUID, error) {
+	if getUserInfoReq(c).UserId == uuid.Nil {
+		return nil, nil
 	}
-	if res.Body != nil {
-		defer res.Body.Close()
+	restrictionsURL := "/user-rels/restrictions"
+	restrictionsData, err := rpc.Call(rpc.Context(c), http.MethodGet, restrictionsURL, []byte(""))
+	if err != nil {
+		log.Error("rpc.Call (%s) -  %s", restrictionsURL, err.Error())
+		return nil, fmt.Errorf("getBlockedUserIds/rpc")
 	}
-
-	resData, readErr := ioutil.ReadAll(res.Body)
-	if resData == nil || readErr != nil {
-		return nil, fmt.Errorf("failed to read response from admin check request.")
+	var restrictions models.UserRestrictionsModel
+	if err := json.Unmarshal(restrictionsData, &restrictions); err != nil {
+		log.Error("Unmarshal restrictions -  %s", err.Error())
+		return nil, fmt.Errorf("getBlockedUserIds/unmarshal")
 	}
+	return restrictions.BlockedUserIds, nil
+}
 
-	if res.StatusCode != http.StatusAccepted && res.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to call admin check api, invalid status: %s", res.Status)
+// isBlockedUser check whether the user is in the blocked users
+func isBlockedUser(blockedUserIds []uuid.UUID, userId uuid.UUID) bool {
+	for _, blockedUserId := range blockedUserIds {
+		if blockedUserId == userId {
+			return true
+		}
 	}
-
-	return resData, nil
+	return false
 }
 
 // readPostAsync Read post async
-func readPostAsync(postId uuid.UUID, infoReq *UserInfoInReq) <-chan ResultAsync {
+func readPostAsync(ctx context.Context, postId uuid.UUID) <-chan ResultAsync {
 	r := make(chan ResultAsync)
 	go func() {
 		defer close(r)
 		postURL := fmt.Sprintf("/posts/%s", postId.String())
 
-		post, err := functionCall(http.MethodGet, []byte(""), postURL, getHeadersFromUserInfoReq(infoReq))
+		post, err := rpc.Call(ctx, http.MethodGet, postURL, []byte(""))
 		if err != nil {
 			r <- ResultAsync{Error: err}
 			return
@@ -129,3 +136,29 @@ func readPostAsync(postId uuid.UUID, infoReq *UserInfoInReq) <-chan ResultAsync
 	}()
 	return r
 }
+
+// readCommentPost read the post of the comments as the current user.
+// The error is not found if the user can not see the post.
+func readCommentPost(ctx context.Context, postId uuid.UUID) (*PostModelNotification, error) {
+
+	postResult := <-readPostAsync(ctx, postId)
+	if postResult.Error != nil {
+		if rpc.IsNotFound(postResult.Error) {
+			return nil, httperr.New(http.StatusNotFound, "postNotFound", "Post not found!")
+		}
+		log.Error("[readCommentPost] Cannot get the post! error: %s", postResult.Error.Error())
+		return nil, httperr.New(http.StatusInternalServerError, "internal/readPost", "Error happened while reading post!")
+	}
+
+	var post PostModelNotification
+	if err := json.Unmarshal(postResult.Result, &post); err != nil {
+		log.Error("[readCommentPost] Cannot unmarshal the post! error: %s", err.Error())
+		return nil, httperr.New(http.StatusInternalServerError, "internal/readPost", "Error happened while reading post!")
+	}
+	return &post, nil
+}
+
+// commentsHidden check whether the owner of the post hid the comments from the user after locking them
+func commentsHidden(post *PostModelNotification, userId uuid.UUID) bool {
+	return post.DisableComments && post.HideComments && post.OwnerUserId != userId
+}
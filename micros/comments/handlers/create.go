@@ -14,6 +14,9 @@ import (
 	domain "github.com/red-gold/ts-serverless/micros/comments/dto"
 	models "github.com/red-gold/ts-serverless/micros/comments/models"
 	service "github.com/red-gold/ts-serverless/micros/comments/services"
+	"github.com/red-gold/ts-serverless/micros/internal/httperr"
+	"github.com/red-gold/ts-serverless/micros/internal/outbox"
+	"github.com/red-gold/ts-serverless/micros/internal/rpc"
 )
 
 type PostModelNotification struct {
@@ -22,6 +25,8 @@ type PostModelNotification struct {
 	OwnerDisplayName string    `json:"ownerDisplayName"`
 	OwnerAvatar      string    `json:"ownerAvatar"`
 	URLKey           string    `json:"urlKey"`
+	DisableComments  bool      `json:"disableComments"`
+	HideComments     bool      `json:"hideComments"`
 }
 
 // CreateCommentHandle handle create a new comment
@@ -54,6 +59,12 @@ func CreateCommentHandle(c *fiber.Ctx) error {
 		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/commentService", "Error happened while creating commentService!"))
 	}
 
+	outboxService, serviceErr := service.NewOutboxService(database.Db)
+	if serviceErr != nil {
+		log.Error("NewOutboxService %s", serviceErr.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/outboxService", "Error happened while creating outboxService!"))
+	}
+
 	currentUser, ok := c.Locals(types.UserCtxName).(types.UserContext)
 	if !ok {
 		log.Error("[CreateCommentHandle] Can not get current user")
@@ -61,11 +72,46 @@ func CreateCommentHandle(c *fiber.Ctx) error {
 			"Can not get current user"))
 	}
 
+	// Comments are only allowed on the posts the user can see and which are not locked by the owner
+	post, err := readCommentPost(rpc.Context(c), model.PostId)
+	if err != nil {
+		return httperr.Respond(c, err)
+	}
+	if post.DisableComments {
+		return c.Status(http.StatusForbidden).JSON(utils.Error("commentsDisabled", "Comments are disabled on this post!"))
+	}
+
+	// A reply should belong to a comment of the same post
+	var parentComment *domain.Comment
+	if model.ParentCommentId != uuid.Nil {
+		foundComment, err := commentService.FindById(model.ParentCommentId)
+		if err != nil {
+			log.Error("[CreateCommentHandle.FindById] %s", err.Error())
+			return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/findComment", "Error happened while find comment!"))
+		}
+		if foundComment == nil || foundComment.Deleted {
+			return c.Status(http.StatusNotFound).JSON(utils.Error("parentCommentNotFound", "Parent comment not found!"))
+		}
+		if foundComment.PostId != model.PostId {
+			return c.Status(http.StatusBadRequest).JSON(utils.Error("parentCommentIsNotValid", "Parent comment does not belong to the post!"))
+		}
+		parentComment = foundComment
+	}
+
+	mentions, err := resolveMentions(rpc.Context(c), model.Text)
+	if err != nil {
+		log.Error("[CreateCommentHandle.resolveMentions] %s", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/resolveMentions", "Error happened while resolving mentions!"))
+	}
+
 	newComment := &domain.Comment{
 		OwnerUserId:      currentUser.UserID,
 		PostId:           model.PostId,
+		PostOwnerUserId:  post.OwnerUserId,
+		ParentCommentId:  model.ParentCommentId,
 		Score:            0,
 		Text:             model.Text,
+		Mentions:         mentions,
 		OwnerDisplayName: currentUser.DisplayName,
 		OwnerAvatar:      currentUser.Avatar,
 		Deleted:          false,
@@ -73,93 +119,113 @@ func CreateCommentHandle(c *fiber.Ctx) error {
 		CreatedDate:      utils.UTCNowUnix(),
 		LastUpdated:      0,
 	}
-	userInfoReq := getUserInfoReq(c)
 
-	saveCommentChannel := commentService.SaveComment(newComment)
-	readPostChannel := readPostAsync(model.PostId, userInfoReq)
+	// The side effects are created before the comment is saved, a comment is not saved without them
+	events, err := createCommentEvents(currentUser, post, parentComment, mentions)
+	if err != nil {
+		log.Error("[CreateCommentHandle.createCommentEvents] %s", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/saveComment", "Error happened while saving comment!"))
+	}
 
-	saveCommentResult, postResult := <-saveCommentChannel, <-readPostChannel
+	saveCommentResult := <-commentService.SaveComment(newComment)
 	if saveCommentResult.Error != nil {
 		errorMessage := fmt.Sprintf("Save Comment Error %s", saveCommentResult.Error.Error())
 		log.Error(errorMessage)
 		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/saveComment", "Error happened while saving comment!"))
 	}
 
-	if postResult.Error != nil {
-		messageError := fmt.Sprintf("Cannot get the post! error: %s", postResult.Error.Error())
-		fmt.Println(messageError)
+	// The comment is removed if its side effects can not be stored
+	rollbackComment := func() error {
+		filter := struct {
+			ObjectId uuid.UUID `json:"objectId" bson:"objectId"`
+		}{
+			ObjectId: newComment.ObjectId,
+		}
+		return commentService.DeleteComment(filter)
+	}
+	if err := outbox.SaveEvents(outboxService, events, rollbackComment); err != nil {
+		log.Error("[CreateCommentHandle.saveOutboxEvents] %s", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/saveComment", "Error happened while saving comment!"))
 	}
 
-	// Create user headers for http request
-	userHeaders := make(map[string][]string)
-	userHeaders["uid"] = []string{currentUser.UserID.String()}
-	userHeaders["email"] = []string{currentUser.Username}
-	userHeaders["avatar"] = []string{currentUser.Avatar}
-	userHeaders["displayName"] = []string{currentUser.DisplayName}
-	userHeaders["role"] = []string{currentUser.SystemRole}
-	// Create request to increase comment counter on post
-	go func() {
-
-		postCommentURL := "/posts/comment/count"
-		payload, err := json.Marshal(fiber.Map{
-			"postId": model.PostId,
-			"count":  1,
-		})
-		if err != nil {
-			messageError := fmt.Sprintf("Can not parse comment count payload: %s", err.Error())
-			log.Error(messageError)
+	if parentComment != nil {
+		if err := commentService.IncrementReplyCounter(parentComment.ObjectId, 1); err != nil {
+			log.Error("[CreateCommentHandle.IncrementReplyCounter] %s", err.Error())
 		}
-		_, postCommentErr := functionCall(http.MethodPut, payload, postCommentURL, userHeaders)
+	}
 
-		if postCommentErr != nil {
-			messageError := fmt.Sprintf("Cannot save comment count on post! error: %s", postCommentErr.Error())
-			fmt.Println(messageError)
-		}
-	}()
+	go outbox.Dispatch(outboxService, events)
 
-	// Create notification request
-	go func(currentUser types.UserContext) {
+	return c.JSON(fiber.Map{
+		"objectId": newComment.ObjectId.String(),
+	})
 
-		var post PostModelNotification
-		marshalErr := json.Unmarshal(postResult.Result, &post)
-		if marshalErr != nil {
-			messageError := fmt.Sprintf("Cannot unmarshal the post! error: %s", marshalErr.Error())
-			fmt.Println(messageError)
-		}
+}
 
-		if post.OwnerUserId == currentUser.UserID {
-			// Should not send notification if the owner of the comment is same as owner of post
-			return
-		}
-		URL := fmt.Sprintf("/posts/%s", post.URLKey)
-		notificationModel := &models.NotificationModel{
-			OwnerUserId:          currentUser.UserID,
-			OwnerDisplayName:     currentUser.DisplayName,
-			OwnerAvatar:          currentUser.Avatar,
-			Title:                currentUser.DisplayName,
-			Description:          "commented on your post.",
-			URL:                  URL,
-			NotifyRecieverUserId: post.OwnerUserId,
-			TargetId:             model.PostId,
-			IsSeen:               false,
-			Type:                 "comment",
-		}
-		notificationBytes, marshalErr := json.Marshal(notificationModel)
-		if marshalErr != nil {
-			fmt.Printf("Cannot marshal notification! error: %s", marshalErr.Error())
+// createCommentEvents create the requests which follow a new comment, the comment counter of the post and the notifications
+func createCommentEvents(currentUser types.UserContext, post *PostModelNotification, parentComment *domain.Comment, mentions []domain.Mention) ([]outbox.Event, error) {
+	userHeaders := rpc.UserHeaders(currentUser)
 
-		}
+	// Create request to increase comment counter on post
+	payload, err := json.Marshal(fiber.Map{
+		"postId": post.ObjectId,
+		"count":  1,
+	})
+	if err != nil {
+		log.Error("Marshal comment count payload -  %s", err.Error())
+		return nil, fmt.Errorf("createCommentEvents/marshalCount")
+	}
+	events := []outbox.Event{outbox.NewEvent(http.MethodPut, "/posts/comment/count", payload, userHeaders)}
 
-		notificationURL := "/notifications"
-		_, notificationIndexErr := functionCall(http.MethodPost, notificationBytes, notificationURL, userHeaders)
-		if notificationIndexErr != nil {
-			fmt.Printf("\nCannot save notification on follow user! error: %s\n", notificationIndexErr.Error())
+	// Should not send notification if the owner of the comment is same as owner of post
+	if post.OwnerUserId != currentUser.UserID {
+		event, err := commentNotificationEvent(currentUser, post.OwnerUserId, post, "commented on your post.", "comment", userHeaders)
+		if err != nil {
+			return nil, err
 		}
+		events = append(events, event)
+	}
 
-	}(currentUser)
+	// The owner of the parent comment is notified once, unless the post notification already reached them
+	if parentComment != nil && parentComment.OwnerUserId != currentUser.UserID &&
+		parentComment.OwnerUserId != post.OwnerUserId {
+		event, err := commentNotificationEvent(currentUser, parentComment.OwnerUserId, post, "replied to your comment.", "reply", userHeaders)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
 
-	return c.JSON(fiber.Map{
-		"objectId": newComment.ObjectId.String(),
-	})
+	// The mentioned users who are already notified as the owner of the post or the parent comment are skipped
+	notified := map[uuid.UUID]bool{post.OwnerUserId: true}
+	if parentComment != nil {
+		notified[parentComment.OwnerUserId] = true
+	}
+	mentionEvents, err := mentionNotificationEvents(currentUser, mentions, post, notified, userHeaders)
+	if err != nil {
+		return nil, err
+	}
+	return append(events, mentionEvents...), nil
+}
 
+// commentNotificationEvent create the notification request of a comment on the post for the receiver
+func commentNotificationEvent(currentUser types.UserContext, receiverUserId uuid.UUID, post *PostModelNotification, description string, notificationType string, userHeaders map[string][]string) (outbox.Event, error) {
+	notificationModel := &models.NotificationModel{
+		OwnerUserId:          currentUser.UserID,
+		OwnerDisplayName:     currentUser.DisplayName,
+		OwnerAvatar:          currentUser.Avatar,
+		Title:                currentUser.DisplayName,
+		Description:          description,
+		URL:                  fmt.Sprintf("/posts/%s", post.URLKey),
+		NotifyRecieverUserId: receiverUserId,
+		TargetId:             post.ObjectId,
+		IsSeen:               false,
+		Type:                 notificationType,
+	}
+	notificationBytes, err := json.Marshal(notificationModel)
+	if err != nil {
+		log.Error("Marshal notification -  %s", err.Error())
+		return outbox.Event{}, fmt.Errorf("commentNotificationEvent/marshal")
+	}
+	return outbox.NewEvent(http.MethodPost, "/notifications", notificationBytes, userHeaders), nil
 }
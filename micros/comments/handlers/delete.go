@@ -11,13 +11,15 @@ import (
 	"github.com/red-gold/telar-core/types"
 	"github.com/red-gold/telar-core/utils"
 	"github.com/red-gold/ts-serverless/micros/comments/database"
+	models "github.com/red-gold/ts-serverless/micros/comments/models"
 	service "github.com/red-gold/ts-serverless/micros/comments/services"
+	"github.com/red-gold/ts-serverless/micros/internal/outbox"
 )
 
 // DeleteCommentHandle handle delete a Comment
 func DeleteCommentHandle(c *fiber.Ctx) error {
 
-	// params from /comments/id/:commentId/post/:postId
+	// params from /comments/id/:commentId or /comments/id/:commentId/post/:postId
 	commentId := c.Params("commentId")
 	if commentId == "" {
 		errorMessage := fmt.Sprintf("Comment Id is required!")
@@ -32,13 +34,6 @@ func DeleteCommentHandle(c *fiber.Ctx) error {
 		return c.Status(http.StatusBadRequest).JSON(utils.Error("commentIdIsNotValid", "Comment id is not valid!"))
 	}
 
-	postId := c.Params("postId")
-	if postId == "" {
-		errorMessage := fmt.Sprintf("Post Id is required!")
-		log.Error(errorMessage)
-		return c.Status(http.StatusBadRequest).JSON(utils.Error("postIdRequired", errorMessage))
-	}
-
 	// Create service
 	commentService, serviceErr := service.NewCommentService(database.Db)
 	if serviceErr != nil {
@@ -46,6 +41,30 @@ func DeleteCommentHandle(c *fiber.Ctx) error {
 		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/commentService", "Error happened while creating commentService!"))
 	}
 
+	// The post of the comment is read from the comment, the post id of the route is only checked against it
+	if postId := c.Params("postId"); postId != "" {
+		postUUID, uuidErr := uuid.FromString(postId)
+		if uuidErr != nil {
+			errorMessage := fmt.Sprintf("UUID Error %s", uuidErr.Error())
+			log.Error(errorMessage)
+			return c.Status(http.StatusBadRequest).JSON(utils.Error("postIdIsNotValid", "Post id is not valid!"))
+		}
+		foundComment, err := commentService.FindById(commentUUID)
+		if err != nil {
+			log.Error("[DeleteCommentHandle.FindById] %s", err.Error())
+			return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/findComment", "Error happened while find comment!"))
+		}
+		if foundComment != nil && foundComment.PostId != postUUID {
+			return c.Status(http.StatusBadRequest).JSON(utils.Error("postIdIsNotValid", "Comment does not belong to the post!"))
+		}
+	}
+
+	outboxService, serviceErr := service.NewOutboxService(database.Db)
+	if serviceErr != nil {
+		log.Error("NewOutboxService %s", serviceErr.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/outboxService", "Error happened while creating outboxService!"))
+	}
+
 	currentUser, ok := c.Locals(types.UserCtxName).(types.UserContext)
 	if !ok {
 		log.Error("[DeleteCommentHandle] Can not get current user")
@@ -53,36 +72,47 @@ func DeleteCommentHandle(c *fiber.Ctx) error {
 			"Can not get current user"))
 	}
 
-	if err := commentService.DeleteCommentByOwner(currentUser.UserID, commentUUID); err != nil {
+	// The replies are tombstoned with the comment
+	deletedSince := utils.UTCNowUnix()
+	deletedComment, deletedCount, err := commentService.SoftDeleteCommentTree(currentUser.UserID, commentUUID)
+	if err != nil {
 		errorMessage := fmt.Sprintf("Delete Comment Error %s", err.Error())
 		log.Error(errorMessage)
 		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/deleteComment", "Error happened while delete comment!"))
 	}
 
-	// Create user headers for http request
-	userHeaders := make(map[string][]string)
-	userHeaders["uid"] = []string{currentUser.UserID.String()}
-	userHeaders["email"] = []string{currentUser.Username}
-	userHeaders["avatar"] = []string{currentUser.Avatar}
-	userHeaders["displayName"] = []string{currentUser.DisplayName}
-	userHeaders["role"] = []string{currentUser.SystemRole}
+	if deletedComment == nil {
+		return c.Status(http.StatusNotFound).JSON(utils.Error("commentNotFound", "Comment not found!"))
+	}
+
+	// The comment tree is restored if the counter change can not be stored
+	rollbackDelete := func() error {
+		_, _, err := commentService.RestoreCommentTree(currentUser.UserID, commentUUID, deletedSince)
+		return err
+	}
 
+	// Create request to decrease comment counter on post
 	postCommentURL := "/posts/comment/count"
 	payload, err := json.Marshal(fiber.Map{
-		"postId": postId,
-		"count":  -1,
+		"postId": deletedComment.PostId,
+		"count":  -deletedCount,
 	})
 	if err != nil {
-		messageError := fmt.Sprintf("Can not parse comment count payload: %s", err.Error())
-		log.Error(messageError)
+		log.Error("[DeleteCommentHandle] Marshal comment count payload -  %s", err.Error())
+		if rollbackErr := rollbackDelete(); rollbackErr != nil {
+			log.Error("[DeleteCommentHandle.rollbackDelete] %s", rollbackErr.Error())
+		}
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/deleteComment", "Error happened while delete comment!"))
 	}
-	_, commentDecreaseRes := functionCall(http.MethodPut, payload, postCommentURL, userHeaders)
+	events := []outbox.Event{outbox.NewEvent(http.MethodPut, postCommentURL, payload, getHeaderInfoReq(c))}
 
-	if commentDecreaseRes != nil {
-		log.Error("Cannot save vote on post! error: %s", commentDecreaseRes.Error())
-		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/decreasePostCommentCount", "Error happened while decrease post comment count!"))
+	if err := outbox.SaveEvents(outboxService, events, rollbackDelete); err != nil {
+		log.Error("[DeleteCommentHandle.saveOutboxEvents] %s", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/deleteComment", "Error happened while delete comment!"))
 	}
 
+	go outbox.Dispatch(outboxService, events)
+
 	return c.SendStatus(http.StatusOK)
 
 }
@@ -129,3 +159,48 @@ func DeleteCommentByPostIdHandle(c *fiber.Ctx) error {
 	return c.SendStatus(http.StatusOK)
 
 }
+
+// SetPostDeletedHandle handle hide the comments of a deleted post or show them when the post is restored
+func SetPostDeletedHandle(c *fiber.Ctx) error {
+
+	// params from /comments/post/:postId/deleted
+	postId := c.Params("postId")
+	if postId == "" {
+		errorMessage := fmt.Sprintf("Post Id is required!")
+		log.Error(errorMessage)
+		return c.Status(http.StatusBadRequest).JSON(utils.Error("postIdRequired", errorMessage))
+	}
+
+	postUUID, uuidErr := uuid.FromString(postId)
+	if uuidErr != nil {
+		errorMessage := fmt.Sprintf("UUID Error %s", uuidErr.Error())
+		log.Error(errorMessage)
+		return c.Status(http.StatusBadRequest).JSON(utils.Error("postIdIsNotValid", "Post id is not valid!"))
+	}
+
+	// Create the model object
+	model := new(models.PostDeletedModel)
+	if err := c.BodyParser(model); err != nil {
+		errorMessage := fmt.Sprintf("Parse PostDeletedModel Error %s", err.Error())
+		log.Error(errorMessage)
+		return c.Status(http.StatusBadRequest).JSON(utils.Error("parseModel", "Can not parse the model!"))
+	}
+
+	if model.Version <= 0 {
+		return c.Status(http.StatusBadRequest).JSON(utils.Error("versionIsRequired", "The version of the change is required!"))
+	}
+
+	// Create service
+	commentService, serviceErr := service.NewCommentService(database.Db)
+	if serviceErr != nil {
+		log.Error("NewCommentService %s", serviceErr.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/commentService", "Error happened while creating commentService!"))
+	}
+
+	if err := commentService.SetPostDeleted(postUUID, model.Deleted, model.Version); err != nil {
+		log.Error("[SetPostDeletedHandle.commentService.SetPostDeleted] %s", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/updateComment", "Error happened while updating comments!"))
+	}
+
+	return c.SendStatus(http.StatusOK)
+}
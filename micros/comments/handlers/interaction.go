@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+	uuid "github.com/gofrs/uuid"
+	"github.com/red-gold/telar-core/pkg/log"
+	"github.com/red-gold/telar-core/pkg/parser"
+	"github.com/red-gold/telar-core/utils"
+	"github.com/red-gold/ts-serverless/micros/comments/database"
+	service "github.com/red-gold/ts-serverless/micros/comments/services"
+)
+
+type InteractionQueryModel struct {
+	Since int64 `query:"since"`
+}
+
+// GetInteractionsHandle handle get the number of the comments between the user and each other user on their posts.
+// The comments created before the since date in the query are not counted.
+func GetInteractionsHandle(c *fiber.Ctx) error {
+
+	// params from /comments/interactions/:userId
+	userId := c.Params("userId")
+	if userId == "" {
+		return c.Status(http.StatusBadRequest).JSON(utils.Error("userIdRequired", "User Id is required!"))
+	}
+
+	userUUID, uuidErr := uuid.FromString(userId)
+	if uuidErr != nil {
+		log.Error("UUID Error %s", uuidErr.Error())
+		return c.Status(http.StatusBadRequest).JSON(utils.Error("userIdIsNotValid", "User id is not valid!"))
+	}
+
+	// Create service
+	commentService, serviceErr := service.NewCommentService(database.Db)
+	if serviceErr != nil {
+		log.Error("NewCommentService %s", serviceErr.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/commentService", "Error happened while creating commentService!"))
+	}
+
+	query := new(InteractionQueryModel)
+	if err := parser.QueryParser(c, query); err != nil {
+		log.Error("[GetInteractionsHandle] QueryParser %s", err.Error())
+		return c.Status(http.StatusBadRequest).JSON(utils.Error("queryParser", "Error happened while parsing query!"))
+	}
+
+	interactions, err := commentService.CountInteractions(userUUID, query.Since)
+	if err != nil {
+		log.Error("[GetInteractionsHandle.commentService.CountInteractions] %s", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/countInteractions", "Error happened while counting interactions!"))
+	}
+
+	return c.JSON(interactions)
+}
@@ -0,0 +1,188 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	uuid "github.com/gofrs/uuid"
+	"github.com/red-gold/telar-core/pkg/log"
+	"github.com/red-gold/telar-core/pkg/parser"
+	"github.com/red-gold/telar-core/types"
+	"github.com/red-gold/telar-core/utils"
+	"github.com/red-gold/ts-serverless/micros/comments/database"
+	domain "github.com/red-gold/ts-serverless/micros/comments/dto"
+	service "github.com/red-gold/ts-serverless/micros/comments/services"
+	"github.com/red-gold/ts-serverless/micros/internal/outbox"
+	"github.com/red-gold/ts-serverless/micros/internal/rpc"
+)
+
+// maxMentions the number of mentions which are resolved in a comment, the rest are kept as plain text
+const maxMentions = 20
+
+// mentionPattern matches @socialName which is not a part of a word or an email
+var mentionPattern = regexp.MustCompile(`(?:^|[^\w@.])@([\w.]+)`)
+
+type MentionQueryModel struct {
+	Page int64 `query:"page"`
+}
+
+// parseMentions get the distinct social names which are mentioned in the text
+func parseMentions(text string) []string {
+	socialNames := []string{}
+	seen := make(map[string]bool)
+	for _, match := range mentionPattern.FindAllStringSubmatch(text, -1) {
+		// The dots at the end belong to the sentence
+		socialName := strings.TrimRight(match[1], ".")
+		key := strings.ToLower(socialName)
+		if socialName == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		socialNames = append(socialNames, socialName)
+		if len(socialNames) == maxMentions {
+			break
+		}
+	}
+	return socialNames
+}
+
+// resolveMentions find the users who are mentioned in the text, the social names without a user are skipped
+func resolveMentions(ctx context.Context, text string) ([]domain.Mention, error) {
+	mentions := []domain.Mention{}
+	seen := make(map[uuid.UUID]bool)
+	for _, socialName := range parseMentions(text) {
+		profile, err := getProfileBySocialName(ctx, socialName)
+		if err != nil {
+			return nil, err
+		}
+		if profile == nil || seen[profile.ObjectId] {
+			continue
+		}
+		seen[profile.ObjectId] = true
+		mentions = append(mentions, domain.Mention{
+			UserId:     profile.ObjectId,
+			SocialName: profile.SocialName,
+		})
+	}
+	return mentions, nil
+}
+
+// addedMentions get the mentions which are not in the previous mentions
+func addedMentions(mentions []domain.Mention, previous []domain.Mention) []domain.Mention {
+	previousUsers := make(map[uuid.UUID]bool)
+	for _, mention := range previous {
+		previousUsers[mention.UserId] = true
+	}
+	var added []domain.Mention
+	for _, mention := range mentions {
+		if !previousUsers[mention.UserId] {
+			added = append(added, mention)
+		}
+	}
+	return added
+}
+
+// mentionNotificationEvents create the notification requests of the mentioned users.
+// The author and the users who are already notified about the comment are skipped.
+func mentionNotificationEvents(currentUser types.UserContext, mentions []domain.Mention, post *PostModelNotification, notified map[uuid.UUID]bool, userHeaders map[string][]string) ([]outbox.Event, error) {
+	var events []outbox.Event
+	for _, mention := range mentions {
+		if mention.UserId == currentUser.UserID || notified[mention.UserId] {
+			continue
+		}
+		event, err := commentNotificationEvent(currentUser, mention.UserId, post, "mentioned you in a comment.", "mention", userHeaders)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+// GetCommentsByMentionHandle handle get the comments which mention a user on the posts the current user can see
+func GetCommentsByMentionHandle(c *fiber.Ctx) error {
+
+	// params from /comments/mention/:userId
+	userId := c.Params("userId")
+	if userId == "" {
+		errorMessage := fmt.Sprintf("User Id is required!")
+		log.Error(errorMessage)
+		return c.Status(http.StatusBadRequest).JSON(utils.Error("userIdRequired", errorMessage))
+	}
+
+	userUUID, uuidErr := uuid.FromString(userId)
+	if uuidErr != nil {
+		errorMessage := fmt.Sprintf("UUID Error %s", uuidErr.Error())
+		log.Error(errorMessage)
+		return c.Status(http.StatusBadRequest).JSON(utils.Error("userIdIsNotValid", "User id is not valid!"))
+	}
+
+	// Create service
+	commentService, serviceErr := service.NewCommentService(database.Db)
+	if serviceErr != nil {
+		log.Error("NewCommentService %s", serviceErr.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/commentService", "Error happened while creating commentService!"))
+	}
+
+	query := new(MentionQueryModel)
+	if err := parser.QueryParser(c, query); err != nil {
+		log.Error("[GetCommentsByMentionHandle] QueryParser %s", err.Error())
+		return c.Status(http.StatusBadRequest).JSON(utils.Error("queryParser", "Error happened while parsing query!"))
+	}
+
+	currentUser, ok := c.Locals(types.UserCtxName).(types.UserContext)
+	if !ok {
+		log.Error("[GetCommentsByMentionHandle] Can not get current user")
+		return c.Status(http.StatusBadRequest).JSON(utils.Error("invalidCurrentUser", "Can not get current user"))
+	}
+
+	blockedUserIds, err := getBlockedUserIds(c)
+	if err != nil {
+		log.Error("[GetCommentsByMentionHandle.getBlockedUserIds] %s", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/getUserRestrictions", "Error while getting user restrictions"))
+	}
+	commentList, err := commentService.QueryCommentByMention(userUUID, query.Page, blockedUserIds)
+	if err != nil {
+		log.Error("[GetCommentsByMentionHandle.commentService.QueryCommentByMention] %s", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/queryComment", "Error happened while query comment!"))
+	}
+
+	// The posts are read as the current user, so the comments on the posts which they can not see are dropped
+	postResults := make(map[uuid.UUID]<-chan ResultAsync)
+	for _, comment := range commentList {
+		if _, ok := postResults[comment.PostId]; !ok {
+			postResults[comment.PostId] = readPostAsync(rpc.Context(c), comment.PostId)
+		}
+	}
+	visiblePosts := make(map[uuid.UUID]bool)
+	for postId, postResult := range postResults {
+		result := <-postResult
+		if result.Error != nil {
+			if rpc.IsNotFound(result.Error) {
+				continue
+			}
+			log.Error("[GetCommentsByMentionHandle] Cannot get the post! error: %s", result.Error.Error())
+			return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/readPost", "Error happened while reading post!"))
+		}
+		var post PostModelNotification
+		if err := json.Unmarshal(result.Result, &post); err != nil {
+			log.Error("[GetCommentsByMentionHandle] Cannot unmarshal the post! error: %s", err.Error())
+			return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/readPost", "Error happened while reading post!"))
+		}
+		visiblePosts[postId] = !commentsHidden(&post, currentUser.UserID)
+	}
+
+	visibleComments := []domain.Comment{}
+	for _, comment := range commentList {
+		if visiblePosts[comment.PostId] {
+			visibleComments = append(visibleComments, comment)
+		}
+	}
+
+	return c.JSON(visibleComments)
+}
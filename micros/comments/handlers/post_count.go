@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/red-gold/telar-core/pkg/log"
+	"github.com/red-gold/telar-core/utils"
+	"github.com/red-gold/ts-serverless/micros/comments/database"
+	models "github.com/red-gold/ts-serverless/micros/comments/models"
+	service "github.com/red-gold/ts-serverless/micros/comments/services"
+)
+
+// CountPostCommentsHandle handle get the number of the comments which are not deleted for each post.
+// The posts function reconciles the comment counters of the posts with these counts.
+func CountPostCommentsHandle(c *fiber.Ctx) error {
+
+	// Create the model object
+	model := new(models.PostIdsModel)
+	if err := c.BodyParser(model); err != nil {
+		errorMessage := fmt.Sprintf("Parse PostIdsModel Error %s", err.Error())
+		log.Error(errorMessage)
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/parseModel", "Error happened while parsing model!"))
+	}
+
+	if len(model.PostIds) == 0 {
+		return c.Status(http.StatusBadRequest).JSON(utils.Error("postIdsRequired", "Post ids are required!"))
+	}
+
+	// Create service
+	commentService, serviceErr := service.NewCommentService(database.Db)
+	if serviceErr != nil {
+		log.Error("NewCommentService %s", serviceErr.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/commentService", "Error happened while creating commentService!"))
+	}
+
+	counts, err := commentService.CountCommentsByPost(model.PostIds)
+	if err != nil {
+		log.Error("[CountPostCommentsHandle.commentService.CountCommentsByPost] %s", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/countPostComments", "Error happened while counting post comments!"))
+	}
+
+	return c.JSON(counts)
+}
@@ -10,8 +10,12 @@ import (
 	"github.com/red-gold/telar-core/pkg/parser"
 	utils "github.com/red-gold/telar-core/utils"
 	"github.com/red-gold/ts-serverless/micros/comments/database"
+	dto "github.com/red-gold/ts-serverless/micros/comments/dto"
 	models "github.com/red-gold/ts-serverless/micros/comments/models"
 	service "github.com/red-gold/ts-serverless/micros/comments/services"
+	"github.com/red-gold/ts-serverless/micros/internal/cursor"
+	"github.com/red-gold/ts-serverless/micros/internal/httperr"
+	"github.com/red-gold/ts-serverless/micros/internal/rpc"
 )
 
 type CommentQueryModel struct {
@@ -24,6 +28,16 @@ type CommentQueryModel struct {
 type CommentQueryByPostIdModel struct {
 	Page   int64     `query:"page"`
 	PostId uuid.UUID `query:"postId"`
+	Limit  int64     `query:"limit"`
+	After  string    `query:"after"`
+	Before string    `query:"before"`
+}
+
+type CommentRepliesQueryModel struct {
+	Page   int64  `query:"page"`
+	Limit  int64  `query:"limit"`
+	After  string `query:"after"`
+	Before string `query:"before"`
 }
 
 // QueryCommentHandle handle query on comment
@@ -53,6 +67,24 @@ func QueryCommentHandle(c *fiber.Ctx) error {
 
 }
 
+// newCommentPage create the page of comments with the cursors of the next and previous pages
+func newCommentPage(commentList []dto.Comment, limit int64, readBefore bool) models.CommentPageModel {
+	page := models.CommentPageModel{
+		Comments: []dto.Comment{},
+	}
+	if len(commentList) == 0 {
+		return page
+	}
+	page.Comments = commentList
+
+	positions := make([]cursor.Model, 0, len(commentList))
+	for _, item := range commentList {
+		positions = append(positions, cursor.Model{CreatedDate: item.CreatedDate, ObjectId: item.ObjectId})
+	}
+	page.NextCursor, page.PrevCursor = cursor.PageCursors(positions, limit, readBefore)
+	return page
+}
+
 // GetCommentsByPostIdHandle handle query on comment
 func GetCommentsByPostIdHandle(c *fiber.Ctx) error {
 
@@ -77,13 +109,160 @@ func GetCommentsByPostIdHandle(c *fiber.Ctx) error {
 
 	}
 
-	commentList, err := commentService.GetCommentByPostId(&query.PostId, "created_date", query.Page)
+	post, err := readCommentPost(rpc.Context(c), query.PostId)
+	if err != nil {
+		return httperr.Respond(c, err)
+	}
+
+	// Numbered pages are kept for the clients which do not use cursors
+	if query.Page > 0 || (query.Limit == 0 && query.After == "" && query.Before == "") {
+		if commentsHidden(post, getUserInfoReq(c).UserId) {
+			return c.JSON([]dto.Comment{})
+		}
+
+		blockedUserIds, err := getBlockedUserIds(c)
+		if err != nil {
+			log.Error("[GetCommentsByPostIdHandle.getBlockedUserIds] %s", err.Error())
+			return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/getUserRestrictions", "Error while getting user restrictions"))
+		}
+		commentList, err := commentService.GetCommentByPostId(&query.PostId, "created_date", query.Page, blockedUserIds)
+		if err != nil {
+			log.Error("[GetCommentsByPostIdHandle.commentService.GetCommentByPostId] %s ", err.Error())
+			return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/queryComment", "Error happened while query comment!"))
+		}
+
+		return c.JSON(commentList)
+	}
+
+	if query.Limit < 0 || query.Limit > cursor.MaxPageLimit {
+		return c.Status(http.StatusBadRequest).JSON(utils.Error("limitIsNotValid", fmt.Sprintf("Limit should be between 1 and %d!", cursor.MaxPageLimit)))
+	}
+
+	after, before, err := cursor.ReadPage(query.After, query.Before)
+	if err != nil {
+		log.Error("[GetCommentsByPostIdHandle.readPageCursors] %s", err.Error())
+		return c.Status(http.StatusBadRequest).JSON(utils.Error("cursorIsNotValid", "Cursor is not valid!"))
+	}
+
+	limit := query.Limit
+	if limit == 0 {
+		limit = cursor.DefaultPageLimit
+	}
+	if commentsHidden(post, getUserInfoReq(c).UserId) {
+		return c.JSON(newCommentPage([]dto.Comment{}, limit, before != nil))
+	}
+
+	blockedUserIds, err := getBlockedUserIds(c)
 	if err != nil {
-		log.Error("[GetCommentsByPostIdHandle.commentService.GetCommentByPostId] %s ", err.Error())
+		log.Error("[GetCommentsByPostIdHandle.getBlockedUserIds] %s", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/getUserRestrictions", "Error while getting user restrictions"))
+	}
+	commentList, err := commentService.GetCommentByPostIdByCursor(&query.PostId, after, before, limit, blockedUserIds)
+	if err != nil {
+		log.Error("[GetCommentsByPostIdHandle.commentService.GetCommentByPostIdByCursor] %s ", err.Error())
 		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/queryComment", "Error happened while query comment!"))
 	}
 
-	return c.JSON(commentList)
+	return c.JSON(newCommentPage(commentList, limit, before != nil))
+
+}
+
+// GetCommentRepliesHandle handle query on the replies of a comment
+func GetCommentRepliesHandle(c *fiber.Ctx) error {
+
+	// params from /comments/replies/:commentId
+	commentId := c.Params("commentId")
+	if commentId == "" {
+		errorMessage := fmt.Sprintf("Comment Id is required!")
+		log.Error(errorMessage)
+		return c.Status(http.StatusBadRequest).JSON(utils.Error("commentIdRequired", errorMessage))
+	}
+
+	commentUUID, uuidErr := uuid.FromString(commentId)
+	if uuidErr != nil {
+		errorMessage := fmt.Sprintf("UUID Error %s", uuidErr.Error())
+		log.Error(errorMessage)
+		return c.Status(http.StatusBadRequest).JSON(utils.Error("commentIdIsNotValid", "Comment id is not valid!"))
+	}
+
+	// Create service
+	commentService, serviceErr := service.NewCommentService(database.Db)
+	if serviceErr != nil {
+		log.Error("NewCommentService %s", serviceErr.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/commentService", "Error happened while creating commentService!"))
+	}
+
+	query := new(CommentRepliesQueryModel)
+
+	if err := parser.QueryParser(c, query); err != nil {
+		log.Error("[GetCommentRepliesHandle] QueryParser %s", err.Error())
+		return c.Status(http.StatusBadRequest).JSON(utils.Error("queryParser", "Error happened while parsing query!"))
+	}
+
+	parentComment, err := commentService.FindById(commentUUID)
+	if err != nil {
+		log.Error("[GetCommentRepliesHandle.commentService.FindById] %s", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/findComment", "Error happened while find comment!"))
+	}
+	if parentComment == nil {
+		return c.Status(http.StatusNotFound).JSON(utils.Error("commentNotFound", "Comment not found!"))
+	}
+
+	post, err := readCommentPost(rpc.Context(c), parentComment.PostId)
+	if err != nil {
+		return httperr.Respond(c, err)
+	}
+
+	// Numbered pages are kept for the clients which do not use cursors
+	if query.Page > 0 || (query.Limit == 0 && query.After == "" && query.Before == "") {
+		if commentsHidden(post, getUserInfoReq(c).UserId) {
+			return c.JSON([]dto.Comment{})
+		}
+
+		blockedUserIds, err := getBlockedUserIds(c)
+		if err != nil {
+			log.Error("[GetCommentRepliesHandle.getBlockedUserIds] %s", err.Error())
+			return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/getUserRestrictions", "Error while getting user restrictions"))
+		}
+		commentList, err := commentService.GetRepliesByCommentId(commentUUID, "created_date", query.Page, blockedUserIds)
+		if err != nil {
+			log.Error("[GetCommentRepliesHandle.commentService.GetRepliesByCommentId] %s ", err.Error())
+			return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/queryComment", "Error happened while query comment!"))
+		}
+
+		return c.JSON(commentList)
+	}
+
+	if query.Limit < 0 || query.Limit > cursor.MaxPageLimit {
+		return c.Status(http.StatusBadRequest).JSON(utils.Error("limitIsNotValid", fmt.Sprintf("Limit should be between 1 and %d!", cursor.MaxPageLimit)))
+	}
+
+	after, before, err := cursor.ReadPage(query.After, query.Before)
+	if err != nil {
+		log.Error("[GetCommentRepliesHandle.readPageCursors] %s", err.Error())
+		return c.Status(http.StatusBadRequest).JSON(utils.Error("cursorIsNotValid", "Cursor is not valid!"))
+	}
+
+	limit := query.Limit
+	if limit == 0 {
+		limit = cursor.DefaultPageLimit
+	}
+	if commentsHidden(post, getUserInfoReq(c).UserId) {
+		return c.JSON(newCommentPage([]dto.Comment{}, limit, before != nil))
+	}
+
+	blockedUserIds, err := getBlockedUserIds(c)
+	if err != nil {
+		log.Error("[GetCommentRepliesHandle.getBlockedUserIds] %s", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/getUserRestrictions", "Error while getting user restrictions"))
+	}
+	commentList, err := commentService.GetRepliesByCommentIdByCursor(commentUUID, after, before, limit, blockedUserIds)
+	if err != nil {
+		log.Error("[GetCommentRepliesHandle.commentService.GetRepliesByCommentIdByCursor] %s ", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/queryComment", "Error happened while query comment!"))
+	}
+
+	return c.JSON(newCommentPage(commentList, limit, before != nil))
 
 }
 
@@ -117,14 +296,32 @@ func GetCommentHandle(c *fiber.Ctx) error {
 		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/findComment", "Error happened while find comment!"))
 	}
 
-	// No comment found
+	// No comment found, the comments of the blocked users are not shown
 	if foundComment == nil {
 		return c.SendStatus(http.StatusOK)
 	}
+	blockedUserIds, err := getBlockedUserIds(c)
+	if err != nil {
+		log.Error("[GetCommentHandle.getBlockedUserIds] %s", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/getUserRestrictions", "Error while getting user restrictions"))
+	}
+	if isBlockedUser(blockedUserIds, foundComment.OwnerUserId) {
+		return c.SendStatus(http.StatusOK)
+	}
+
+	post, err := readCommentPost(rpc.Context(c), foundComment.PostId)
+	if err != nil {
+		return httperr.Respond(c, err)
+	}
+	if commentsHidden(post, getUserInfoReq(c).UserId) {
+		return c.SendStatus(http.StatusOK)
+	}
 	commentModel := models.CommentModel{
 		ObjectId:         foundComment.ObjectId,
 		OwnerUserId:      foundComment.OwnerUserId,
 		PostId:           foundComment.PostId,
+		ParentCommentId:  foundComment.ParentCommentId,
+		ReplyCounter:     foundComment.ReplyCounter,
 		Score:            foundComment.Score,
 		Text:             foundComment.Text,
 		OwnerDisplayName: foundComment.OwnerDisplayName,
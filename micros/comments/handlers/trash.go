@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+	uuid "github.com/gofrs/uuid"
+	"github.com/red-gold/telar-core/pkg/log"
+	"github.com/red-gold/telar-core/pkg/parser"
+	"github.com/red-gold/telar-core/types"
+	"github.com/red-gold/telar-core/utils"
+	commentConfig "github.com/red-gold/ts-serverless/micros/comments/config"
+	"github.com/red-gold/ts-serverless/micros/comments/database"
+	dto "github.com/red-gold/ts-serverless/micros/comments/dto"
+	service "github.com/red-gold/ts-serverless/micros/comments/services"
+	"github.com/red-gold/ts-serverless/micros/internal/outbox"
+)
+
+type TrashQueryModel struct {
+	Page int64 `query:"page"`
+}
+
+// trashCutoff the date which the comments deleted before it are out of the trash and can be purged
+func trashCutoff() int64 {
+	return utils.UTCNowUnix() - commentConfig.CommentConfig.TrashRetentionDays*24*60*60*1000
+}
+
+// GetTrashCommentsHandle handle get the deleted comments of the current user which can be restored
+func GetTrashCommentsHandle(c *fiber.Ctx) error {
+
+	// Create service
+	commentService, serviceErr := service.NewCommentService(database.Db)
+	if serviceErr != nil {
+		log.Error("NewCommentService %s", serviceErr.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/commentService", "Error happened while creating commentService!"))
+	}
+
+	query := new(TrashQueryModel)
+	if err := parser.QueryParser(c, query); err != nil {
+		log.Error("[GetTrashCommentsHandle] QueryParser %s", err.Error())
+		return c.Status(http.StatusBadRequest).JSON(utils.Error("queryParser", "Error happened while parsing query!"))
+	}
+
+	currentUser, ok := c.Locals(types.UserCtxName).(types.UserContext)
+	if !ok {
+		log.Error("[GetTrashCommentsHandle] Can not get current user")
+		return c.Status(http.StatusBadRequest).JSON(utils.Error("invalidCurrentUser", "Can not get current user"))
+	}
+
+	commentList, err := commentService.FindTrashByOwner(currentUser.UserID, trashCutoff(), query.Page)
+	if err != nil {
+		log.Error("[GetTrashCommentsHandle.commentService.FindTrashByOwner] %s", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/queryComment", "Error happened while query comment!"))
+	}
+	if commentList == nil {
+		commentList = []dto.Comment{}
+	}
+
+	return c.JSON(commentList)
+}
+
+// RestoreCommentHandle handle restore a comment which is in the trash with the replies which are deleted with it
+func RestoreCommentHandle(c *fiber.Ctx) error {
+
+	// params from /comments/restore/:commentId
+	commentId := c.Params("commentId")
+	if commentId == "" {
+		errorMessage := fmt.Sprintf("Comment Id is required!")
+		log.Error(errorMessage)
+		return c.Status(http.StatusBadRequest).JSON(utils.Error("commentIdRequired", errorMessage))
+	}
+
+	commentUUID, uuidErr := uuid.FromString(commentId)
+	if uuidErr != nil {
+		errorMessage := fmt.Sprintf("UUID Error %s", uuidErr.Error())
+		log.Error(errorMessage)
+		return c.Status(http.StatusBadRequest).JSON(utils.Error("commentIdIsNotValid", "Comment id is not valid!"))
+	}
+
+	// Create service
+	commentService, serviceErr := service.NewCommentService(database.Db)
+	if serviceErr != nil {
+		log.Error("NewCommentService %s", serviceErr.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/commentService", "Error happened while creating commentService!"))
+	}
+
+	outboxService, serviceErr := service.NewOutboxService(database.Db)
+	if serviceErr != nil {
+		log.Error("NewOutboxService %s", serviceErr.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/outboxService", "Error happened while creating outboxService!"))
+	}
+
+	currentUser, ok := c.Locals(types.UserCtxName).(types.UserContext)
+	if !ok {
+		log.Error("[RestoreCommentHandle] Can not get current user")
+		return c.Status(http.StatusBadRequest).JSON(utils.Error("invalidCurrentUser", "Can not get current user"))
+	}
+
+	restoredComment, restoredCount, err := commentService.RestoreCommentTree(currentUser.UserID, commentUUID, trashCutoff())
+	if err != nil {
+		log.Error("[RestoreCommentHandle.commentService.RestoreCommentTree] %s", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/restoreComment", "Error happened while restoring comment!"))
+	}
+
+	if restoredComment == nil {
+		return c.Status(http.StatusNotFound).JSON(utils.Error("commentNotFound", "Comment not found!"))
+	}
+
+	// The comment tree goes back to the trash if the counter change can not be stored
+	rollbackRestore := func() error {
+		_, _, err := commentService.SoftDeleteCommentTree(currentUser.UserID, commentUUID)
+		return err
+	}
+
+	payload, err := json.Marshal(fiber.Map{
+		"postId": restoredComment.PostId,
+		"count":  restoredCount,
+	})
+	if err != nil {
+		log.Error("[RestoreCommentHandle] Marshal comment count payload -  %s", err.Error())
+		if rollbackErr := rollbackRestore(); rollbackErr != nil {
+			log.Error("[RestoreCommentHandle.rollbackRestore] %s", rollbackErr.Error())
+		}
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/restoreComment", "Error happened while restoring comment!"))
+	}
+	events := []outbox.Event{outbox.NewEvent(http.MethodPut, "/posts/comment/count", payload, getHeaderInfoReq(c))}
+
+	if err := outbox.SaveEvents(outboxService, events, rollbackRestore); err != nil {
+		log.Error("[RestoreCommentHandle.saveOutboxEvents] %s", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/restoreComment", "Error happened while restoring comment!"))
+	}
+
+	go outbox.Dispatch(outboxService, events)
+
+	return c.SendStatus(http.StatusOK)
+}
+
+// PurgeTrashHandle handle remove the comments which are deleted before the trash retention
+func PurgeTrashHandle(c *fiber.Ctx) error {
+
+	purged, err := PurgeTrash()
+	if err != nil {
+		log.Error("[PurgeTrashHandle] %s", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/purgeComment", "Error happened while purging comments!"))
+	}
+
+	return c.JSON(fiber.Map{
+		"purged": purged,
+	})
+}
+
+// PurgeTrash remove the comments which are deleted before the trash retention and return their number
+func PurgeTrash() (int64, error) {
+
+	// Create service
+	commentService, err := service.NewCommentService(database.Db)
+	if err != nil {
+		return 0, fmt.Errorf("PurgeTrash/commentService %s", err.Error())
+	}
+
+	purged, err := commentService.PurgeDeletedComments(trashCutoff())
+	if err != nil {
+		return 0, fmt.Errorf("PurgeTrash/purgeComments %s", err.Error())
+	}
+	return purged, nil
+}
+
+// PurgePostCommentsHandle handle remove all the comments of a post which is purged
+func PurgePostCommentsHandle(c *fiber.Ctx) error {
+
+	// params from /comments/post/:postId/deleted
+	postId := c.Params("postId")
+	if postId == "" {
+		errorMessage := fmt.Sprintf("Post Id is required!")
+		log.Error(errorMessage)
+		return c.Status(http.StatusBadRequest).JSON(utils.Error("postIdRequired", errorMessage))
+	}
+
+	postUUID, uuidErr := uuid.FromString(postId)
+	if uuidErr != nil {
+		errorMessage := fmt.Sprintf("UUID Error %s", uuidErr.Error())
+		log.Error(errorMessage)
+		return c.Status(http.StatusBadRequest).JSON(utils.Error("postIdIsNotValid", "Post id is not valid!"))
+	}
+
+	// Create service
+	commentService, serviceErr := service.NewCommentService(database.Db)
+	if serviceErr != nil {
+		log.Error("NewCommentService %s", serviceErr.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/commentService", "Error happened while creating commentService!"))
+	}
+
+	if err := commentService.PurgeCommentsByPostId(postUUID); err != nil {
+		log.Error("[PurgePostCommentsHandle.commentService.PurgeCommentsByPostId] %s", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/purgeComment", "Error happened while purging comments!"))
+	}
+
+	return c.SendStatus(http.StatusOK)
+}
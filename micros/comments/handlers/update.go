@@ -12,6 +12,9 @@ import (
 	domain "github.com/red-gold/ts-serverless/micros/comments/dto"
 	models "github.com/red-gold/ts-serverless/micros/comments/models"
 	service "github.com/red-gold/ts-serverless/micros/comments/services"
+	"github.com/red-gold/ts-serverless/micros/internal/httperr"
+	"github.com/red-gold/ts-serverless/micros/internal/outbox"
+	"github.com/red-gold/ts-serverless/micros/internal/rpc"
 )
 
 // UpdateCommentHandle handle create a new comment
@@ -32,6 +35,12 @@ func UpdateCommentHandle(c *fiber.Ctx) error {
 		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/commentService", "Error happened while creating commentService!"))
 	}
 
+	outboxService, serviceErr := service.NewOutboxService(database.Db)
+	if serviceErr != nil {
+		log.Error("NewOutboxService %s", serviceErr.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/outboxService", "Error happened while creating outboxService!"))
+	}
+
 	currentUser, ok := c.Locals(types.UserCtxName).(types.UserContext)
 	if !ok {
 		log.Error("[UpdateCommentHandle] Can not get current user")
@@ -39,12 +48,33 @@ func UpdateCommentHandle(c *fiber.Ctx) error {
 			"Can not get current user"))
 	}
 
+	foundComment, err := commentService.FindById(model.ObjectId)
+	if err != nil {
+		log.Error("[UpdateCommentHandle.commentService.FindById] %s", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/updateComment", "Error happened while update comment!"))
+	}
+	if foundComment == nil || foundComment.OwnerUserId != currentUser.UserID || foundComment.Deleted {
+		return c.Status(http.StatusNotFound).JSON(utils.Error("commentNotFound", "Comment not found!"))
+	}
+
+	post, err := readCommentPost(rpc.Context(c), foundComment.PostId)
+	if err != nil {
+		return httperr.Respond(c, err)
+	}
+
+	mentions, err := resolveMentions(rpc.Context(c), model.Text)
+	if err != nil {
+		log.Error("[UpdateCommentHandle.resolveMentions] %s", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/resolveMentions", "Error happened while resolving mentions!"))
+	}
+
 	updatedComment := &domain.Comment{
 		ObjectId:         model.ObjectId,
 		OwnerUserId:      currentUser.UserID,
 		PostId:           model.PostId,
 		Score:            model.Score,
 		Text:             model.Text,
+		Mentions:         mentions,
 		OwnerDisplayName: currentUser.DisplayName,
 		OwnerAvatar:      currentUser.Avatar,
 		Deleted:          model.Deleted,
@@ -53,12 +83,27 @@ func UpdateCommentHandle(c *fiber.Ctx) error {
 		LastUpdated:      model.LastUpdated,
 	}
 
+	// Only the users who are mentioned by this update are notified
+	events, err := mentionNotificationEvents(currentUser, addedMentions(mentions, foundComment.Mentions), post, nil, rpc.UserHeaders(currentUser))
+	if err != nil {
+		log.Error("[UpdateCommentHandle.mentionNotificationEvents] %s", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/updateComment", "Error happened while update comment!"))
+	}
+
 	if err := commentService.UpdateCommentById(updatedComment); err != nil {
 		errorMessage := fmt.Sprintf("Update Comment Error %s", err.Error())
 		log.Error(errorMessage)
 		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/updateComment", "Error happened while update comment!"))
 	}
 
+	// The comment is already updated, so the events are stored without a rollback
+	if err := outboxService.SaveEvents(events); err != nil {
+		log.Error("[UpdateCommentHandle.outboxService.SaveEvents] %s", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/updateComment", "Error happened while update comment!"))
+	}
+
+	go outbox.Dispatch(outboxService, events)
+
 	return c.SendStatus(http.StatusOK)
 
 }
@@ -11,6 +11,8 @@ type CommentModel struct {
 	OwnerDisplayName string    `json:"ownerDisplayName"`
 	OwnerAvatar      string    `json:"ownerAvatar"`
 	PostId           uuid.UUID `json:"postId"`
+	ParentCommentId  uuid.UUID `json:"parentCommentId"`
+	ReplyCounter     int64     `json:"replyCounter"`
 	Text             string    `json:"text"`
 	Deleted          bool      `json:"deleted"`
 	DeletedDate      int64     `json:"deletedDate"`
@@ -0,0 +1,9 @@
+package models
+
+import dto "github.com/red-gold/ts-serverless/micros/comments/dto"
+
+type CommentPageModel struct {
+	Comments   []dto.Comment `json:"comments"`
+	NextCursor string        `json:"nextCursor"`
+	PrevCursor string        `json:"prevCursor"`
+}
@@ -11,6 +11,7 @@ type CreateCommentModel struct {
 	OwnerDisplayName string    `json:"ownerDisplayName"`
 	OwnerAvatar      string    `json:"ownerAvatar"`
 	PostId           uuid.UUID `json:"postId"`
+	ParentCommentId  uuid.UUID `json:"parentCommentId"`
 	Text             string    `json:"text"`
 	Deleted          bool      `json:"deleted"`
 	DeletedDate      int64     `json:"deletedDate"`
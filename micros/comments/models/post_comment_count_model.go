@@ -0,0 +1,14 @@
+package models
+
+import uuid "github.com/gofrs/uuid"
+
+// PostIdsModel the posts which the counters are requested for
+type PostIdsModel struct {
+	PostIds []uuid.UUID `json:"postIds"`
+}
+
+// PostCommentCountModel the number of the comments of a post which are not deleted
+type PostCommentCountModel struct {
+	PostId uuid.UUID `json:"postId" bson:"_id"`
+	Count  int64     `json:"count" bson:"count"`
+}
@@ -11,7 +11,10 @@ import (
 	"github.com/red-gold/telar-core/middleware/authcookie"
 	"github.com/red-gold/telar-core/middleware/authhmac"
 	"github.com/red-gold/telar-core/types"
+	"github.com/red-gold/ts-serverless/micros/comments/database"
 	"github.com/red-gold/ts-serverless/micros/comments/handlers"
+	service "github.com/red-gold/ts-serverless/micros/comments/services"
+	"github.com/red-gold/ts-serverless/micros/internal/index"
 )
 
 // SetupRoutes func
@@ -56,8 +59,27 @@ func SetupRoutes(app *fiber.App) {
 	app.Post("/", append(hmacCookieHandlers, handlers.CreateCommentHandle)...)
 	app.Put("/", append(hmacCookieHandlers, handlers.UpdateCommentHandle)...)
 	app.Put("/profile", append(hmacCookieHandlers, handlers.UpdateCommentProfileHandle)...)
+	app.Delete("/id/:commentId", append(hmacCookieHandlers, handlers.DeleteCommentHandle)...)
 	app.Delete("/id/:commentId/post/:postId", append(hmacCookieHandlers, handlers.DeleteCommentHandle)...)
 	app.Delete("/post/:postId", append(hmacCookieHandlers, handlers.DeleteCommentByPostIdHandle)...)
+	app.Put("/post/:postId/deleted", authHMACMiddleware(false), handlers.SetPostDeletedHandle)
+	app.Delete("/post/:postId/deleted", authHMACMiddleware(false), handlers.PurgePostCommentsHandle)
+	app.Post("/trash/purge", authHMACMiddleware(false), handlers.PurgeTrashHandle)
+	app.Get("/interactions/:userId", authHMACMiddleware(false), handlers.GetInteractionsHandle)
+	app.Post("/post/counts", authHMACMiddleware(false), handlers.CountPostCommentsHandle)
+	app.Put("/restore/:commentId", append(hmacCookieHandlers, handlers.RestoreCommentHandle)...)
+	app.Post("/index", authHMACMiddleware(false), index.InitHandle(newIndexService))
+	app.Get("/index", authHMACMiddleware(false), index.MissingHandle(newIndexService))
+	app.Post("/outbox/dispatch", authHMACMiddleware(false), handlers.DispatchOutboxHandle)
+	app.Get("/outbox/status", authHMACMiddleware(false), handlers.GetOutboxStatusHandle)
 	app.Get("/", append(hmacCookieHandlers, handlers.GetCommentsByPostIdHandle)...)
+	app.Get("/trash", append(hmacCookieHandlers, handlers.GetTrashCommentsHandle)...)
+	app.Get("/mention/:userId", append(hmacCookieHandlers, handlers.GetCommentsByMentionHandle)...)
+	app.Get("/replies/:commentId", append(hmacCookieHandlers, handlers.GetCommentRepliesHandle)...)
 	app.Get("/:commentId", append(hmacCookieHandlers, handlers.GetCommentHandle)...)
 }
+
+// newIndexService create the index service of the function
+func newIndexService() (index.Service, error) {
+	return service.NewIndexService(database.Db)
+}
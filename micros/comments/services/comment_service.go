@@ -11,6 +11,8 @@ import (
 	mongoRepo "github.com/red-gold/telar-core/data/mongodb"
 	"github.com/red-gold/telar-core/utils"
 	dto "github.com/red-gold/ts-serverless/micros/comments/dto"
+	"github.com/red-gold/ts-serverless/micros/internal/cursor"
+	"github.com/red-gold/ts-serverless/micros/internal/inmemory"
 )
 
 // CommentService handlers with injected dependencies
@@ -29,6 +31,10 @@ func NewCommentService(db interface{}) (CommentService, error) {
 		mongodb := db.(mongodb.MongoDatabase)
 		commentService.CommentRepo = mongoRepo.NewDataRepositoryMongo(mongodb)
 
+	case config.DB_INMEMORY:
+
+		commentService.CommentRepo = inmemory.NewDataRepositoryInMemory(db.(*inmemory.Database))
+
 	}
 
 	return commentService, nil
@@ -62,6 +68,9 @@ func (s CommentServiceImpl) FindOneComment(filter interface{}) (*dto.Comment, er
 
 	result := <-s.CommentRepo.FindOne(commentCollectionName, filter)
 	if result.Error() != nil {
+		if result.Error() == repo.ErrNoDocuments {
+			return nil, nil
+		}
 		return nil, result.Error()
 	}
 	if result.NoResult() {
@@ -137,10 +146,12 @@ func (s CommentServiceImpl) FindCommentsInclueProfile(filter interface{}, limit
 	project["objectId"] = 1
 	project["score"] = 1
 	project["text"] = 1
+	project["mentions"] = 1
 	project["ownerUserId"] = 1
 	project["ownerDisplayName"] = "$userinfo.fullName"
 	project["ownerAvatar"] = "$userinfo.avatar"
 	project["postId"] = 1
+	project["postOwnerUserId"] = 1
 	project["deleted"] = 1
 	project["deletedDate"] = 1
 	project["created_date"] = 1
@@ -267,8 +278,33 @@ func (s CommentServiceImpl) UpdateCommentById(data *dto.Comment) error {
 		OwnerUserId: data.OwnerUserId,
 	}
 	data.LastUpdated = utils.UTCNowUnix()
+
+	// The thread fields are kept as they are
+	updateData := struct {
+		Score            int64         `json:"score" bson:"score"`
+		OwnerDisplayName string        `json:"ownerDisplayName" bson:"ownerDisplayName"`
+		OwnerAvatar      string        `json:"ownerAvatar" bson:"ownerAvatar"`
+		PostId           uuid.UUID     `json:"postId" bson:"postId"`
+		Text             string        `json:"text" bson:"text"`
+		Mentions         []dto.Mention `json:"mentions" bson:"mentions"`
+		Deleted          bool          `json:"deleted" bson:"deleted"`
+		DeletedDate      int64         `json:"deletedDate" bson:"deletedDate"`
+		CreatedDate      int64         `json:"created_date" bson:"created_date"`
+		LastUpdated      int64         `json:"last_updated" bson:"last_updated"`
+	}{
+		Score:            data.Score,
+		OwnerDisplayName: data.OwnerDisplayName,
+		OwnerAvatar:      data.OwnerAvatar,
+		PostId:           data.PostId,
+		Text:             data.Text,
+		Mentions:         data.Mentions,
+		Deleted:          data.Deleted,
+		DeletedDate:      data.DeletedDate,
+		CreatedDate:      data.CreatedDate,
+		LastUpdated:      data.LastUpdated,
+	}
 	updateOperator := coreData.UpdateOperator{
-		Set: data,
+		Set: updateData,
 	}
 	err := s.UpdateComment(filter, updateOperator)
 	if err != nil {
@@ -321,39 +357,429 @@ func (s CommentServiceImpl) CreateCommentIndex(indexes map[string]interface{}) e
 	return result
 }
 
+// FindCommentListByCursor get the comments by filter in the page around the cursors
+func (s CommentServiceImpl) FindCommentListByCursor(filter map[string]interface{}, after *cursor.Model, before *cursor.Model, limit int64) ([]dto.Comment, error) {
+	var pipeline []interface{}
+
+	sort := cursor.AddFilter(filter, "created_date", after, before)
+
+	matchOperator := make(map[string]interface{})
+	matchOperator["$match"] = filter
+
+	sortOperator := make(map[string]interface{})
+	sortOperator["$sort"] = sort
+
+	limitOperator := make(map[string]interface{})
+	limitOperator["$limit"] = limit
+
+	pipeline = append(pipeline, matchOperator, sortOperator, limitOperator)
+
+	result := <-s.CommentRepo.Aggregate(commentCollectionName, pipeline)
+	defer result.Close()
+	if result.Error() != nil {
+		return nil, result.Error()
+	}
+	var commentList []dto.Comment
+	for result.Next() {
+		var comment dto.Comment
+		errDecode := result.Decode(&comment)
+		if errDecode != nil {
+			return nil, fmt.Errorf("Error docoding on dto.Comment")
+		}
+		commentList = append(commentList, comment)
+	}
+
+	// The page before the cursor is read in reverse order
+	if before != nil {
+		for i, j := 0, len(commentList)-1; i < j; i, j = i+1, j-1 {
+			commentList[i], commentList[j] = commentList[j], commentList[i]
+		}
+	}
+
+	return commentList, nil
+}
+
 // GetCommentByPostId get all comments by postId
-func (s CommentServiceImpl) GetCommentByPostId(postId *uuid.UUID, sortBy string, page int64) ([]dto.Comment, error) {
+func (s CommentServiceImpl) GetCommentByPostId(postId *uuid.UUID, sortBy string, page int64, hiddenUserIds []uuid.UUID) ([]dto.Comment, error) {
 	sortMap := make(map[string]int)
 	sortMap[sortBy] = -1
 	skip := numberOfItems * (page - 1)
 	limit := numberOfItems
 
-	filter := make(map[string]interface{})
+	filter := topLevelCommentFilter()
 
 	if postId != nil {
 		filter["postId"] = *postId
 	}
+	excludeOwners(filter, hiddenUserIds)
 
 	result, err := s.FindCommentList(filter, limit, skip, sortMap)
 
 	return result, err
 }
 
-// DeleteCommentsByPostId delete comments by postId
-func (s CommentServiceImpl) DeleteCommentsByPostId(ownerUserId uuid.UUID, postId uuid.UUID) error {
+// GetCommentByPostIdByCursor get the comments of a post in the page around the cursors
+func (s CommentServiceImpl) GetCommentByPostIdByCursor(postId *uuid.UUID, after *cursor.Model, before *cursor.Model, limit int64, hiddenUserIds []uuid.UUID) ([]dto.Comment, error) {
+	if limit <= 0 {
+		limit = numberOfItems
+	}
+
+	filter := topLevelCommentFilter()
+
+	if postId != nil {
+		filter["postId"] = *postId
+	}
+	excludeOwners(filter, hiddenUserIds)
+
+	return s.FindCommentListByCursor(filter, after, before, limit)
+}
+
+// topLevelCommentFilter create the filter of the comments which are not deleted and are not a reply
+func topLevelCommentFilter() map[string]interface{} {
+	noParent := make(map[string]interface{})
+	noParent["$in"] = []interface{}{nil, uuid.Nil}
+
+	filter := notDeletedCommentFilter()
+	filter["parentCommentId"] = noParent
+	return filter
+}
+
+// notDeletedCommentFilter create the filter of the comments which are not deleted and their post is not deleted
+func notDeletedCommentFilter() map[string]interface{} {
+	notDeleted := make(map[string]interface{})
+	notDeleted["$ne"] = true
+
+	filter := make(map[string]interface{})
+	filter["deleted"] = notDeleted
+	filter["postDeleted"] = notDeleted
+	return filter
+}
 
+// excludeOwners add the filter that excludes the comments of the users
+func excludeOwners(filter map[string]interface{}, userIds []uuid.UUID) {
+	if len(userIds) == 0 {
+		return
+	}
+	notInFilter := make(map[string]interface{})
+	notInFilter["$nin"] = userIds
+	filter["ownerUserId"] = notInFilter
+}
+
+// GetRepliesByCommentId get the replies of a comment by page
+func (s CommentServiceImpl) GetRepliesByCommentId(commentId uuid.UUID, sortBy string, page int64, hiddenUserIds []uuid.UUID) ([]dto.Comment, error) {
+	sortMap := make(map[string]int)
+	sortMap[sortBy] = -1
+	skip := numberOfItems * (page - 1)
+	limit := numberOfItems
+
+	filter := notDeletedCommentFilter()
+	filter["parentCommentId"] = commentId
+	excludeOwners(filter, hiddenUserIds)
+
+	return s.FindCommentList(filter, limit, skip, sortMap)
+}
+
+// GetRepliesByCommentIdByCursor get the replies of a comment in the page around the cursors
+func (s CommentServiceImpl) GetRepliesByCommentIdByCursor(commentId uuid.UUID, after *cursor.Model, before *cursor.Model, limit int64, hiddenUserIds []uuid.UUID) ([]dto.Comment, error) {
+	if limit <= 0 {
+		limit = numberOfItems
+	}
+
+	filter := notDeletedCommentFilter()
+	filter["parentCommentId"] = commentId
+	excludeOwners(filter, hiddenUserIds)
+
+	return s.FindCommentListByCursor(filter, after, before, limit)
+}
+
+// IncrementReplyCounter increment the reply counter of a comment
+func (s CommentServiceImpl) IncrementReplyCounter(commentId uuid.UUID, value int) error {
 	filter := struct {
-		PostId      uuid.UUID `json:"postId" bson:"postId"`
-		OwnerUserId uuid.UUID `json:"ownerUserId" bson:"ownerUserId"`
+		ObjectId uuid.UUID `json:"objectId" bson:"objectId"`
 	}{
-		PostId:      postId,
-		OwnerUserId: ownerUserId,
+		ObjectId: commentId,
 	}
-	err := s.DeleteManyComments(filter)
+
+	data := make(map[string]interface{})
+	data["replyCounter"] = value
+
+	incOperator := coreData.IncrementOperator{
+		Inc: data,
+	}
+	return s.UpdateComment(filter, incOperator)
+}
+
+// SoftDeleteCommentTree mark the comment of the owner and all the replies under it as deleted.
+// It returns the deleted comment and the number of the comments which are deleted, or nil if the comment is not found.
+func (s CommentServiceImpl) SoftDeleteCommentTree(ownerUserId uuid.UUID, commentId uuid.UUID) (*dto.Comment, int, error) {
+
+	rootFilter := notDeletedCommentFilter()
+	rootFilter["objectId"] = commentId
+	rootFilter["ownerUserId"] = ownerUserId
+	rootComment, err := s.FindOneComment(rootFilter)
 	if err != nil {
-		return err
+		return nil, 0, err
 	}
-	return nil
+	if rootComment == nil {
+		return nil, 0, nil
+	}
+
+	// Collect the subtree level by level
+	treeIds := []uuid.UUID{rootComment.ObjectId}
+	levelIds := []uuid.UUID{rootComment.ObjectId}
+	for len(levelIds) > 0 {
+		inParents := make(map[string]interface{})
+		inParents["$in"] = levelIds
+
+		filter := notDeletedCommentFilter()
+		filter["parentCommentId"] = inParents
+		replies, err := s.FindCommentList(filter, 0, 0, nil)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		levelIds = nil
+		for _, reply := range replies {
+			levelIds = append(levelIds, reply.ObjectId)
+		}
+		treeIds = append(treeIds, levelIds...)
+	}
+
+	inTree := make(map[string]interface{})
+	inTree["$in"] = treeIds
+	filter := notDeletedCommentFilter()
+	filter["objectId"] = inTree
+
+	now := utils.UTCNowUnix()
+	data := struct {
+		Deleted     bool  `json:"deleted" bson:"deleted"`
+		DeletedDate int64 `json:"deletedDate" bson:"deletedDate"`
+		LastUpdated int64 `json:"last_updated" bson:"last_updated"`
+	}{
+		Deleted:     true,
+		DeletedDate: now,
+		LastUpdated: now,
+	}
+	updateOperator := coreData.UpdateOperator{
+		Set: data,
+	}
+	if err := s.UpdateManyComment(filter, updateOperator); err != nil {
+		return nil, 0, err
+	}
+
+	if rootComment.ParentCommentId != uuid.Nil {
+		if err := s.IncrementReplyCounter(rootComment.ParentCommentId, -1); err != nil {
+			return nil, 0, err
+		}
+	}
+	return rootComment, len(treeIds), nil
+}
+
+// DeleteCommentsByPostId mark the comments of the owner on the post as deleted
+func (s CommentServiceImpl) DeleteCommentsByPostId(ownerUserId uuid.UUID, postId uuid.UUID) error {
+
+	filter := notDeletedCommentFilter()
+	filter["postId"] = postId
+	filter["ownerUserId"] = ownerUserId
+
+	now := utils.UTCNowUnix()
+	data := struct {
+		Deleted     bool  `json:"deleted" bson:"deleted"`
+		DeletedDate int64 `json:"deletedDate" bson:"deletedDate"`
+		LastUpdated int64 `json:"last_updated" bson:"last_updated"`
+	}{
+		Deleted:     true,
+		DeletedDate: now,
+		LastUpdated: now,
+	}
+	updateOperator := coreData.UpdateOperator{
+		Set: data,
+	}
+	return s.UpdateManyComment(filter, updateOperator)
+}
+
+// RestoreCommentTree restore the comment of the owner which is deleted since the date and the replies which are deleted with it.
+// It returns the restored comment and the number of the comments which are restored, or nil if the comment is not in the trash.
+func (s CommentServiceImpl) RestoreCommentTree(ownerUserId uuid.UUID, commentId uuid.UUID, deletedSince int64) (*dto.Comment, int, error) {
+
+	sinceFilter := make(map[string]interface{})
+	sinceFilter["$gte"] = deletedSince
+	notPostDeleted := make(map[string]interface{})
+	notPostDeleted["$ne"] = true
+
+	rootFilter := make(map[string]interface{})
+	rootFilter["objectId"] = commentId
+	rootFilter["ownerUserId"] = ownerUserId
+	rootFilter["deleted"] = true
+	rootFilter["deletedDate"] = sinceFilter
+	rootFilter["postDeleted"] = notPostDeleted
+	rootComment, err := s.FindOneComment(rootFilter)
+	if err != nil {
+		return nil, 0, err
+	}
+	if rootComment == nil {
+		return nil, 0, nil
+	}
+
+	// Collect the replies which are deleted with the comment level by level
+	treeIds := []uuid.UUID{rootComment.ObjectId}
+	levelIds := []uuid.UUID{rootComment.ObjectId}
+	for len(levelIds) > 0 {
+		inParents := make(map[string]interface{})
+		inParents["$in"] = levelIds
+
+		filter := make(map[string]interface{})
+		filter["parentCommentId"] = inParents
+		filter["deleted"] = true
+		filter["deletedDate"] = rootComment.DeletedDate
+		replies, err := s.FindCommentList(filter, 0, 0, nil)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		levelIds = nil
+		for _, reply := range replies {
+			levelIds = append(levelIds, reply.ObjectId)
+		}
+		treeIds = append(treeIds, levelIds...)
+	}
+
+	inTree := make(map[string]interface{})
+	inTree["$in"] = treeIds
+	filter := make(map[string]interface{})
+	filter["objectId"] = inTree
+	filter["deleted"] = true
+
+	data := struct {
+		Deleted     bool  `json:"deleted" bson:"deleted"`
+		DeletedDate int64 `json:"deletedDate" bson:"deletedDate"`
+		LastUpdated int64 `json:"last_updated" bson:"last_updated"`
+	}{
+		Deleted:     false,
+		DeletedDate: 0,
+		LastUpdated: utils.UTCNowUnix(),
+	}
+	updateOperator := coreData.UpdateOperator{
+		Set: data,
+	}
+	if err := s.UpdateManyComment(filter, updateOperator); err != nil {
+		return nil, 0, err
+	}
+
+	if rootComment.ParentCommentId != uuid.Nil {
+		if err := s.IncrementReplyCounter(rootComment.ParentCommentId, 1); err != nil {
+			return nil, 0, err
+		}
+	}
+	return rootComment, len(treeIds), nil
+}
+
+// QueryCommentByMention get the comments which mention the user by page
+func (s CommentServiceImpl) QueryCommentByMention(userId uuid.UUID, page int64, hiddenUserIds []uuid.UUID) ([]dto.Comment, error) {
+	if page < 1 {
+		page = 1
+	}
+	sortMap := make(map[string]int)
+	sortMap["created_date"] = -1
+
+	filter := notDeletedCommentFilter()
+	filter["mentions.userId"] = userId
+	excludeOwners(filter, hiddenUserIds)
+
+	return s.FindCommentList(filter, numberOfItems, numberOfItems*(page-1), sortMap)
+}
+
+// FindTrashByOwner get the deleted comments of the owner which are deleted since the date by page
+func (s CommentServiceImpl) FindTrashByOwner(ownerUserId uuid.UUID, deletedSince int64, page int64) ([]dto.Comment, error) {
+	if page < 1 {
+		page = 1
+	}
+	sortMap := make(map[string]int)
+	sortMap["deletedDate"] = -1
+
+	sinceFilter := make(map[string]interface{})
+	sinceFilter["$gte"] = deletedSince
+	notPostDeleted := make(map[string]interface{})
+	notPostDeleted["$ne"] = true
+
+	filter := make(map[string]interface{})
+	filter["ownerUserId"] = ownerUserId
+	filter["deleted"] = true
+	filter["deletedDate"] = sinceFilter
+	filter["postDeleted"] = notPostDeleted
+
+	return s.FindCommentList(filter, numberOfItems, numberOfItems*(page-1), sortMap)
+}
+
+// PurgeDeletedComments remove the comments which are deleted or their post is deleted before the date
+func (s CommentServiceImpl) PurgeDeletedComments(deletedBefore int64) (int64, error) {
+
+	beforeFilter := make(map[string]interface{})
+	beforeFilter["$lt"] = deletedBefore
+
+	deletedFilter := make(map[string]interface{})
+	deletedFilter["deleted"] = true
+	deletedFilter["deletedDate"] = beforeFilter
+
+	postDeletedFilter := make(map[string]interface{})
+	postDeletedFilter["postDeleted"] = true
+	postDeletedFilter["postDeletedDate"] = beforeFilter
+
+	filter := make(map[string]interface{})
+	filter["$or"] = []interface{}{deletedFilter, postDeletedFilter}
+
+	result := <-s.CommentRepo.Delete(commentCollectionName, filter, false)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	deletedCount, _ := result.Result.(int64)
+	return deletedCount, nil
+}
+
+// PurgeCommentsByPostId remove all the comments of the post
+func (s CommentServiceImpl) PurgeCommentsByPostId(postId uuid.UUID) error {
+
+	filter := struct {
+		PostId uuid.UUID `json:"postId" bson:"postId"`
+	}{
+		PostId: postId,
+	}
+	return s.DeleteManyComments(filter)
+}
+
+// SetPostDeleted hide the comments of the post when the post is deleted and show them when it is restored.
+// The comments which already applied a newer version of the post deleted state are not changed.
+func (s CommentServiceImpl) SetPostDeleted(postId uuid.UUID, deleted bool, version int64) error {
+
+	olderVersion := make(map[string]interface{})
+	olderVersion["$lt"] = version
+	noVersion := make(map[string]interface{})
+	noVersion["$exists"] = false
+
+	filter := make(map[string]interface{})
+	filter["postId"] = postId
+	filter["$or"] = []interface{}{
+		map[string]interface{}{"postDeletedVersion": olderVersion},
+		map[string]interface{}{"postDeletedVersion": noVersion},
+	}
+
+	postDeletedDate := int64(0)
+	if deleted {
+		postDeletedDate = version
+	}
+	data := struct {
+		PostDeleted        bool  `json:"postDeleted" bson:"postDeleted"`
+		PostDeletedDate    int64 `json:"postDeletedDate" bson:"postDeletedDate"`
+		PostDeletedVersion int64 `json:"postDeletedVersion" bson:"postDeletedVersion"`
+	}{
+		PostDeleted:        deleted,
+		PostDeletedDate:    postDeletedDate,
+		PostDeletedVersion: version,
+	}
+	updateOperator := coreData.UpdateOperator{
+		Set: data,
+	}
+	return s.UpdateManyComment(filter, updateOperator)
 }
 
 // UpdateCommentProfile update the post
@@ -0,0 +1,150 @@
+package service
+
+import (
+	"testing"
+
+	uuid "github.com/gofrs/uuid"
+	"github.com/red-gold/telar-core/config"
+	"github.com/red-gold/ts-serverless/micros/comments/dto"
+	"github.com/red-gold/ts-serverless/micros/internal/inmemory"
+)
+
+// newTestCommentService create a comment service on an empty in-memory database
+func newTestCommentService(t *testing.T) CommentService {
+	t.Helper()
+	dbType := config.DB_INMEMORY
+	config.AppConfig.DBType = &dbType
+	commentService, err := NewCommentService(inmemory.NewDatabase())
+	if err != nil {
+		t.Fatalf("NewCommentService: %s", err)
+	}
+	return commentService
+}
+
+// saveTestComment save a comment of the owner on the post under the parent comment
+func saveTestComment(t *testing.T, commentService CommentService, ownerUserId uuid.UUID, postId uuid.UUID, parentCommentId uuid.UUID) *dto.Comment {
+	t.Helper()
+	comment := &dto.Comment{
+		ObjectId:        uuid.Must(uuid.NewV4()),
+		OwnerUserId:     ownerUserId,
+		PostId:          postId,
+		ParentCommentId: parentCommentId,
+		Text:            "comment",
+	}
+	if result := <-commentService.SaveComment(comment); result.Error != nil {
+		t.Fatalf("SaveComment: %s", result.Error)
+	}
+	if parentCommentId != uuid.Nil {
+		if err := commentService.IncrementReplyCounter(parentCommentId, 1); err != nil {
+			t.Fatalf("IncrementReplyCounter: %s", err)
+		}
+	}
+	return comment
+}
+
+func TestSoftDeleteAndRestoreCommentTree(t *testing.T) {
+	commentService := newTestCommentService(t)
+	ownerUserId := uuid.Must(uuid.NewV4())
+	postId := uuid.Must(uuid.NewV4())
+
+	root := saveTestComment(t, commentService, ownerUserId, postId, uuid.Nil)
+	reply := saveTestComment(t, commentService, ownerUserId, postId, root.ObjectId)
+	saveTestComment(t, commentService, uuid.Must(uuid.NewV4()), postId, reply.ObjectId)
+	other := saveTestComment(t, commentService, ownerUserId, postId, uuid.Nil)
+
+	deleted, deletedCount, err := commentService.SoftDeleteCommentTree(ownerUserId, reply.ObjectId)
+	if err != nil {
+		t.Fatalf("SoftDeleteCommentTree: %s", err)
+	}
+	if deleted == nil || deletedCount != 2 {
+		t.Fatalf("got %d deleted comments, want the reply and its reply", deletedCount)
+	}
+
+	comments, err := commentService.GetCommentByPostId(&postId, "created_date", 1, nil)
+	if err != nil {
+		t.Fatalf("GetCommentByPostId: %s", err)
+	}
+	if len(comments) != 2 {
+		t.Errorf("got %d comments on the post, want the root and the other comment", len(comments))
+	}
+	parent, _ := commentService.FindById(root.ObjectId)
+	if parent.ReplyCounter != 0 {
+		t.Errorf("got reply counter %d, want 0 after the reply is deleted", parent.ReplyCounter)
+	}
+
+	restored, restoredCount, err := commentService.RestoreCommentTree(ownerUserId, reply.ObjectId, deleted.DeletedDate)
+	if err != nil {
+		t.Fatalf("RestoreCommentTree: %s", err)
+	}
+	if restored == nil || restoredCount != 2 {
+		t.Errorf("got %d restored comments, want 2", restoredCount)
+	}
+	parent, _ = commentService.FindById(root.ObjectId)
+	if parent.ReplyCounter != 1 {
+		t.Errorf("got reply counter %d, want 1 after the reply is restored", parent.ReplyCounter)
+	}
+
+	notFound, _, err := commentService.SoftDeleteCommentTree(uuid.Must(uuid.NewV4()), other.ObjectId)
+	if err != nil || notFound != nil {
+		t.Errorf("the comment of another owner is deleted")
+	}
+}
+
+func TestSetPostDeletedIgnoresOlderVersions(t *testing.T) {
+	commentService := newTestCommentService(t)
+	ownerUserId := uuid.Must(uuid.NewV4())
+	postId := uuid.Must(uuid.NewV4())
+	saveTestComment(t, commentService, ownerUserId, postId, uuid.Nil)
+
+	// The restore is delivered before the delete which it follows
+	tests := []struct {
+		name    string
+		deleted bool
+		version int64
+		want    int
+	}{
+		{name: "delete", deleted: true, version: 10, want: 0},
+		{name: "restore", deleted: false, version: 20, want: 1},
+		{name: "older delete", deleted: true, version: 10, want: 1},
+		{name: "newer delete", deleted: true, version: 30, want: 0},
+	}
+	for _, test := range tests {
+		if err := commentService.SetPostDeleted(postId, test.deleted, test.version); err != nil {
+			t.Fatalf("SetPostDeleted: %s", err)
+		}
+		comments, err := commentService.GetCommentByPostId(&postId, "created_date", 1, nil)
+		if err != nil {
+			t.Fatalf("GetCommentByPostId: %s", err)
+		}
+		if len(comments) != test.want {
+			t.Errorf("%s: got %d comments, want %d", test.name, len(comments), test.want)
+		}
+	}
+}
+
+func TestCountCommentsByPost(t *testing.T) {
+	commentService := newTestCommentService(t)
+	ownerUserId := uuid.Must(uuid.NewV4())
+	postId := uuid.Must(uuid.NewV4())
+	otherPostId := uuid.Must(uuid.NewV4())
+
+	saveTestComment(t, commentService, ownerUserId, postId, uuid.Nil)
+	deleted := saveTestComment(t, commentService, ownerUserId, postId, uuid.Nil)
+	saveTestComment(t, commentService, ownerUserId, otherPostId, uuid.Nil)
+	saveTestComment(t, commentService, ownerUserId, uuid.Must(uuid.NewV4()), uuid.Nil)
+	if _, _, err := commentService.SoftDeleteCommentTree(ownerUserId, deleted.ObjectId); err != nil {
+		t.Fatalf("SoftDeleteCommentTree: %s", err)
+	}
+
+	counts, err := commentService.CountCommentsByPost([]uuid.UUID{postId, otherPostId})
+	if err != nil {
+		t.Fatalf("CountCommentsByPost: %s", err)
+	}
+	got := make(map[uuid.UUID]int64)
+	for _, postCount := range counts {
+		got[postCount.PostId] = postCount.Count
+	}
+	if len(got) != 2 || got[postId] != 1 || got[otherPostId] != 1 {
+		t.Errorf("got counts %v, want one comment on each post", got)
+	}
+}
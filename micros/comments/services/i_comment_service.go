@@ -4,12 +4,15 @@ import (
 	uuid "github.com/gofrs/uuid"
 	coreData "github.com/red-gold/telar-core/data"
 	dto "github.com/red-gold/ts-serverless/micros/comments/dto"
+	models "github.com/red-gold/ts-serverless/micros/comments/models"
+	"github.com/red-gold/ts-serverless/micros/internal/cursor"
 )
 
 type CommentService interface {
 	SaveComment(comment *dto.Comment) <-chan SaveResultAsync
 	FindOneComment(filter interface{}) (*dto.Comment, error)
 	FindCommentList(filter interface{}, limit int64, skip int64, sort map[string]int) ([]dto.Comment, error)
+	FindCommentListByCursor(filter map[string]interface{}, after *cursor.Model, before *cursor.Model, limit int64) ([]dto.Comment, error)
 	QueryComment(search string, ownerUserId *uuid.UUID, commentTypeId *int, sortBy string, page int64) ([]dto.Comment, error)
 	QueryCommentIncludeProfile(search string, ownerUserId *uuid.UUID, commentTypeId *int, sortBy string, page int64) ([]dto.Comment, error)
 	FindById(objectId uuid.UUID) (*dto.Comment, error)
@@ -21,7 +24,20 @@ type CommentService interface {
 	DeleteCommentByOwner(ownerUserId uuid.UUID, commentId uuid.UUID) error
 	DeleteManyComments(filter interface{}) error
 	CreateCommentIndex(indexes map[string]interface{}) error
-	GetCommentByPostId(postId *uuid.UUID, sortBy string, page int64) ([]dto.Comment, error)
+	GetCommentByPostId(postId *uuid.UUID, sortBy string, page int64, hiddenUserIds []uuid.UUID) ([]dto.Comment, error)
+	GetCommentByPostIdByCursor(postId *uuid.UUID, after *cursor.Model, before *cursor.Model, limit int64, hiddenUserIds []uuid.UUID) ([]dto.Comment, error)
+	GetRepliesByCommentId(commentId uuid.UUID, sortBy string, page int64, hiddenUserIds []uuid.UUID) ([]dto.Comment, error)
+	GetRepliesByCommentIdByCursor(commentId uuid.UUID, after *cursor.Model, before *cursor.Model, limit int64, hiddenUserIds []uuid.UUID) ([]dto.Comment, error)
+	IncrementReplyCounter(commentId uuid.UUID, value int) error
+	SoftDeleteCommentTree(ownerUserId uuid.UUID, commentId uuid.UUID) (*dto.Comment, int, error)
 	DeleteCommentsByPostId(ownerUserId uuid.UUID, postId uuid.UUID) error
+	SetPostDeleted(postId uuid.UUID, deleted bool, version int64) error
+	RestoreCommentTree(ownerUserId uuid.UUID, commentId uuid.UUID, deletedSince int64) (*dto.Comment, int, error)
+	QueryCommentByMention(userId uuid.UUID, page int64, hiddenUserIds []uuid.UUID) ([]dto.Comment, error)
+	FindTrashByOwner(ownerUserId uuid.UUID, deletedSince int64, page int64) ([]dto.Comment, error)
+	PurgeDeletedComments(deletedBefore int64) (int64, error)
+	PurgeCommentsByPostId(postId uuid.UUID) error
 	UpdateCommentProfile(ownerUserId uuid.UUID, ownerDisplayName string, ownerAvatar string) error
+	CountInteractions(userId uuid.UUID, since int64) ([]models.InteractionCountModel, error)
+	CountCommentsByPost(postIds []uuid.UUID) ([]models.PostCommentCountModel, error)
 }
@@ -0,0 +1,19 @@
+package service
+
+import (
+	"github.com/red-gold/ts-serverless/micros/internal/index"
+)
+
+// requiredIndexes are the indexes which the comments function needs
+var requiredIndexes = []index.Model{
+	index.New(commentCollectionName, false, "postId", 1, "created_date", 1),
+	index.New(commentCollectionName, false, "mentions.userId", 1),
+	index.New(commentCollectionName, false, "postOwnerUserId", 1, "created_date", -1),
+	index.New(commentCollectionName, false, "ownerUserId", 1, "created_date", -1),
+	index.New(outboxCollectionName, false, "status", 1, "nextAttemptDate", 1),
+}
+
+// NewIndexService create the index service on the required indexes of the function
+func NewIndexService(db interface{}) (index.Service, error) {
+	return index.NewService(db, requiredIndexes)
+}
@@ -0,0 +1,50 @@
+package service
+
+import (
+	"fmt"
+
+	uuid "github.com/gofrs/uuid"
+	models "github.com/red-gold/ts-serverless/micros/comments/models"
+)
+
+// CountCommentsByPost count the comments which are not deleted for each post.
+// The comments of the posts in the trash are counted, they are back with the post when it is restored.
+func (s CommentServiceImpl) CountCommentsByPost(postIds []uuid.UUID) ([]models.PostCommentCountModel, error) {
+	var pipeline []interface{}
+
+	inFilter := make(map[string]interface{})
+	inFilter["$in"] = postIds
+	notDeleted := make(map[string]interface{})
+	notDeleted["$ne"] = true
+
+	match := make(map[string]interface{})
+	match["postId"] = inFilter
+	match["deleted"] = notDeleted
+	matchOperator := make(map[string]interface{})
+	matchOperator["$match"] = match
+
+	group := make(map[string]interface{})
+	group["_id"] = "$postId"
+	group["count"] = map[string]interface{}{"$sum": 1}
+	groupOperator := make(map[string]interface{})
+	groupOperator["$group"] = group
+
+	pipeline = append(pipeline, matchOperator, groupOperator)
+
+	result := <-s.CommentRepo.Aggregate(commentCollectionName, pipeline)
+	defer result.Close()
+	if result.Error() != nil {
+		return nil, result.Error()
+	}
+
+	countList := []models.PostCommentCountModel{}
+	for result.Next() {
+		var postCount models.PostCommentCountModel
+		errDecode := result.Decode(&postCount)
+		if errDecode != nil {
+			return nil, fmt.Errorf("Error docoding on post comment count")
+		}
+		countList = append(countList, postCount)
+	}
+	return countList, nil
+}
@@ -2,5 +2,6 @@ package service
 
 const (
 	commentCollectionName       = "comment"
+	outboxCollectionName        = "commentOutbox"
 	numberOfItems         int64 = 10
 )
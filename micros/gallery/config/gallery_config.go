@@ -5,8 +5,13 @@ type (
 		BaseRoute      string
 		QueryPrettyURL bool
 		Debug          bool // Debug enables verbose logging of claims / cookies
+
+		// TrashRetentionDays the days which the deleted items are kept in the trash before they are purged
+		TrashRetentionDays int64
 	}
 )
 
 // MediaConfig holds the configuration values from media-config.yml file
-var MediaConfig Configuration
+var MediaConfig = Configuration{
+	TrashRetentionDays: 30,
+}
@@ -11,6 +11,7 @@ import (
 
 	"github.com/red-gold/telar-core/config"
 	"github.com/red-gold/telar-core/data/mongodb"
+	"github.com/red-gold/ts-serverless/micros/internal/inmemory"
 )
 
 var Db interface{}
@@ -24,6 +25,9 @@ func Connect(ctx context.Context) error {
 		mongoClient, err := mongodb.NewMongoClient(ctx, *coreConfig.MongoDBHost, *coreConfig.Database)
 		Db = mongoClient
 		return err
+	case config.DB_INMEMORY:
+		Db = inmemory.NewDatabase()
+		return nil
 	}
 
 	return fmt.Errorf("Please set valid database type in confing file!")
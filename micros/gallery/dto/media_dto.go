@@ -24,4 +24,6 @@ type Media struct {
 	AccessUserList []string                      `json:"accessUserList" bson:"accessUserList"`
 	Permission     constants.UserPermissionConst `json:"permission" bson:"permission"`
 	Deleted        bool                          `json:"deleted" bson:"deleted"`
+	Released       bool                          `json:"released" bson:"released"`
+	ReleasedDate   int64                         `json:"releasedDate" bson:"releasedDate"`
 }
@@ -8,4 +8,7 @@ require (
 	github.com/gofrs/uuid v4.0.0+incompatible
 	github.com/red-gold/telar-core v0.1.16
 	github.com/red-gold/ts-serverless v0.1.33
+	go.mongodb.org/mongo-driver v1.5.1
 )
+
+replace github.com/red-gold/ts-serverless => ../..
@@ -3,6 +3,7 @@ package function
 import (
 	"context"
 	"net/http"
+	"time"
 
 	"github.com/gofiber/adaptor/v2"
 	"github.com/gofiber/fiber/v2"
@@ -13,16 +14,25 @@ import (
 	"github.com/red-gold/telar-core/config"
 	"github.com/red-gold/telar-core/pkg/log"
 	micros "github.com/red-gold/ts-serverless/micros"
+	mediaConfig "github.com/red-gold/ts-serverless/micros/gallery/config"
 	"github.com/red-gold/ts-serverless/micros/gallery/database"
+	"github.com/red-gold/ts-serverless/micros/gallery/handlers"
 	"github.com/red-gold/ts-serverless/micros/gallery/router"
+	service "github.com/red-gold/ts-serverless/micros/gallery/services"
+	"github.com/red-gold/ts-serverless/micros/internal/index"
+	"github.com/red-gold/ts-serverless/micros/internal/job"
 )
 
+// purgeInterval the interval of the job which removes the items which are out of the trash retention
+const purgeInterval = time.Hour
+
 // Cache state
 var app *fiber.App
 
 func init() {
 
 	micros.InitConfig()
+	mediaConfig.InitConfig()
 
 	// Initialize app
 	app = fiber.New()
@@ -53,9 +63,29 @@ func Handle(w http.ResponseWriter, r *http.Request) {
 			log.Error("Error startup: %s", startErr.Error())
 			w.WriteHeader(http.StatusInternalServerError)
 			w.Write([]byte(startErr.Error()))
+		} else {
+			go index.Reconcile(newIndexService)
+			go job.Run(ctx, "trash purge", purgeInterval, purgeTrash)
 		}
 	}
 
 	adaptor.FiberApp(app)(w, r)
 
 }
+
+// purgeTrash remove the media which are out of the trash retention
+func purgeTrash() error {
+	purged, err := handlers.PurgeTrash()
+	if err != nil {
+		return err
+	}
+	if purged > 0 {
+		log.Info("Trash purge removed %d media", purged)
+	}
+	return nil
+}
+
+// newIndexService create the index service of the function for the reconciler
+func newIndexService() (index.Service, error) {
+	return service.NewIndexService(database.Db)
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/red-gold/telar-core/types"
 	"github.com/red-gold/telar-core/utils"
 	"github.com/red-gold/ts-serverless/micros/gallery/database"
+	models "github.com/red-gold/ts-serverless/micros/gallery/models"
 	service "github.com/red-gold/ts-serverless/micros/gallery/services"
 )
 
@@ -89,3 +90,33 @@ func DeleteDirectoryHandle(c *fiber.Ctx) error {
 	return c.SendStatus(http.StatusOK)
 
 }
+
+// ReleaseAlbumMediaHandle handle release the media of a deleted post album or keep them when the post is restored
+func ReleaseAlbumMediaHandle(c *fiber.Ctx) error {
+
+	// Create the model object
+	model := new(models.AlbumReleaseModel)
+	if err := c.BodyParser(model); err != nil {
+		errorMessage := fmt.Sprintf("Parse AlbumReleaseModel Error %s", err.Error())
+		log.Error(errorMessage)
+		return c.Status(http.StatusBadRequest).JSON(utils.Error("parseModel", "Can not parse the model!"))
+	}
+
+	if model.OwnerUserId == uuid.Nil {
+		return c.Status(http.StatusBadRequest).JSON(utils.Error("ownerUserIdRequired", "Owner user id is required!"))
+	}
+
+	// Create service
+	mediaService, serviceErr := service.NewMediaService(database.Db)
+	if serviceErr != nil {
+		log.Error("NewMediaService %s", serviceErr.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/mediaService", "Error happened while creating mediaService!"))
+	}
+
+	if err := mediaService.SetAlbumReleased(model.OwnerUserId, model.MediaIds, model.URLs, model.Released); err != nil {
+		log.Error("[ReleaseAlbumMediaHandle.mediaService.SetAlbumReleased] %s", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/updateMedia", "Error happened while updating media!"))
+	}
+
+	return c.SendStatus(http.StatusOK)
+}
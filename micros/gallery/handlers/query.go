@@ -11,8 +11,10 @@ import (
 	"github.com/red-gold/telar-core/types"
 	utils "github.com/red-gold/telar-core/utils"
 	"github.com/red-gold/ts-serverless/micros/gallery/database"
+	dto "github.com/red-gold/ts-serverless/micros/gallery/dto"
 	models "github.com/red-gold/ts-serverless/micros/gallery/models"
 	service "github.com/red-gold/ts-serverless/micros/gallery/services"
+	"github.com/red-gold/ts-serverless/micros/internal/cursor"
 )
 
 type MediaQueryModel struct {
@@ -23,9 +25,11 @@ type MediaQueryModel struct {
 }
 
 type AlbumQueryModel struct {
-	Page  int64     `query:"page"`
-	Limit int64     `query:"limit"`
-	Album uuid.UUID `query:"album"`
+	Page   int64     `query:"page"`
+	Limit  int64     `query:"limit"`
+	Album  uuid.UUID `query:"album"`
+	After  string    `query:"after"`
+	Before string    `query:"before"`
 }
 
 // QueryMediaHandle handle query on media
@@ -56,6 +60,24 @@ func QueryMediaHandle(c *fiber.Ctx) error {
 
 }
 
+// newMediaPage create the page of media with the cursors of the next and previous pages
+func newMediaPage(mediaList []dto.Media, limit int64, readBefore bool) models.MediaPageModel {
+	page := models.MediaPageModel{
+		Media: []dto.Media{},
+	}
+	if len(mediaList) == 0 {
+		return page
+	}
+	page.Media = mediaList
+
+	positions := make([]cursor.Model, 0, len(mediaList))
+	for _, item := range mediaList {
+		positions = append(positions, cursor.Model{CreatedDate: item.CreatedDate, ObjectId: item.ObjectId})
+	}
+	page.NextCursor, page.PrevCursor = cursor.PageCursors(positions, limit, readBefore)
+	return page
+}
+
 // QueryAlbumHandle handle query on media
 func QueryAlbumHandle(c *fiber.Ctx) error {
 
@@ -80,13 +102,38 @@ func QueryAlbumHandle(c *fiber.Ctx) error {
 			"Can not get current user"))
 	}
 
-	mediaList, err := mediaService.QueryAlbum(currentUser.UserID, &query.Album, query.Page, query.Limit, "created_date")
+	// Numbered pages are kept for the clients which do not use cursors
+	if query.Page > 0 || (query.Limit == 0 && query.After == "" && query.Before == "") {
+		mediaList, err := mediaService.QueryAlbum(currentUser.UserID, &query.Album, query.Page, query.Limit, "created_date")
+		if err != nil {
+			log.Error("[QueryAlbumHandle.mediaService.QueryAlbum] %s ", err.Error())
+			return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/queryMedia", "Error happened while query media!"))
+		}
+
+		return c.JSON(mediaList)
+	}
+
+	if query.Limit < 0 || query.Limit > cursor.MaxPageLimit {
+		return c.Status(http.StatusBadRequest).JSON(utils.Error("limitIsNotValid", fmt.Sprintf("Limit should be between 1 and %d!", cursor.MaxPageLimit)))
+	}
+
+	after, before, err := cursor.ReadPage(query.After, query.Before)
 	if err != nil {
-		log.Error("[QueryAlbumHandle.mediaService.QueryAlbum] %s ", err.Error())
+		log.Error("[QueryAlbumHandle.readPageCursors] %s", err.Error())
+		return c.Status(http.StatusBadRequest).JSON(utils.Error("cursorIsNotValid", "Cursor is not valid!"))
+	}
+
+	limit := query.Limit
+	if limit == 0 {
+		limit = cursor.DefaultPageLimit
+	}
+	mediaList, err := mediaService.QueryAlbumByCursor(currentUser.UserID, &query.Album, after, before, limit)
+	if err != nil {
+		log.Error("[QueryAlbumHandle.mediaService.QueryAlbumByCursor] %s ", err.Error())
 		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/queryMedia", "Error happened while query media!"))
 	}
 
-	return c.JSON(mediaList)
+	return c.JSON(newMediaPage(mediaList, limit, before != nil))
 
 }
 
@@ -121,6 +168,10 @@ func GetMediaHandle(c *fiber.Ctx) error {
 		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/queryMedia", "Error happened while query media!"))
 	}
 
+	if foundMedia.Deleted || foundMedia.Released {
+		return c.Status(http.StatusNotFound).JSON(utils.Error("mediaNotFound", "Media not found!"))
+	}
+
 	mediaModel := models.MediaModel{
 		ObjectId:       foundMedia.ObjectId,
 		DeletedDate:    foundMedia.DeletedDate,
@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+	uuid "github.com/gofrs/uuid"
+	"github.com/red-gold/telar-core/pkg/log"
+	"github.com/red-gold/telar-core/pkg/parser"
+	"github.com/red-gold/telar-core/types"
+	"github.com/red-gold/telar-core/utils"
+	mediaConfig "github.com/red-gold/ts-serverless/micros/gallery/config"
+	"github.com/red-gold/ts-serverless/micros/gallery/database"
+	dto "github.com/red-gold/ts-serverless/micros/gallery/dto"
+	service "github.com/red-gold/ts-serverless/micros/gallery/services"
+)
+
+type TrashQueryModel struct {
+	Page int64 `query:"page"`
+}
+
+// trashCutoff the date which the media deleted before it are out of the trash and can be purged
+func trashCutoff() int64 {
+	return utils.UTCNowUnix() - mediaConfig.MediaConfig.TrashRetentionDays*24*60*60*1000
+}
+
+// GetTrashMediaHandle handle get the deleted media of the current user which can be restored
+func GetTrashMediaHandle(c *fiber.Ctx) error {
+
+	// Create service
+	mediaService, serviceErr := service.NewMediaService(database.Db)
+	if serviceErr != nil {
+		log.Error("NewMediaService %s", serviceErr.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/mediaService", "Error happened while creating mediaService!"))
+	}
+
+	query := new(TrashQueryModel)
+	if err := parser.QueryParser(c, query); err != nil {
+		log.Error("[GetTrashMediaHandle] QueryParser %s", err.Error())
+		return c.Status(http.StatusBadRequest).JSON(utils.Error("queryParser", "Error happened while parsing query!"))
+	}
+
+	currentUser, ok := c.Locals(types.UserCtxName).(types.UserContext)
+	if !ok {
+		log.Error("[GetTrashMediaHandle] Can not get current user")
+		return c.Status(http.StatusBadRequest).JSON(utils.Error("invalidCurrentUser", "Can not get current user"))
+	}
+
+	mediaList, err := mediaService.FindTrashByOwner(currentUser.UserID, trashCutoff(), query.Page)
+	if err != nil {
+		log.Error("[GetTrashMediaHandle.mediaService.FindTrashByOwner] %s", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/queryMedia", "Error happened while query media!"))
+	}
+	if mediaList == nil {
+		mediaList = []dto.Media{}
+	}
+
+	return c.JSON(mediaList)
+}
+
+// RestoreMediaHandle handle restore a media which is in the trash
+func RestoreMediaHandle(c *fiber.Ctx) error {
+
+	// params from /gallery/restore/:mediaId
+	mediaId := c.Params("mediaId")
+	if mediaId == "" {
+		errorMessage := fmt.Sprintf("Media Id is required!")
+		log.Error(errorMessage)
+		return c.Status(http.StatusBadRequest).JSON(utils.Error("mediaIdRequired", errorMessage))
+	}
+
+	mediaUUID, uuidErr := uuid.FromString(mediaId)
+	if uuidErr != nil {
+		errorMessage := fmt.Sprintf("UUID Error %s", uuidErr.Error())
+		log.Error(errorMessage)
+		return c.Status(http.StatusBadRequest).JSON(utils.Error("mediaIdIsNotValid", "Media id is not valid!"))
+	}
+
+	// Create service
+	mediaService, serviceErr := service.NewMediaService(database.Db)
+	if serviceErr != nil {
+		log.Error("NewMediaService %s", serviceErr.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/mediaService", "Error happened while creating mediaService!"))
+	}
+
+	currentUser, ok := c.Locals(types.UserCtxName).(types.UserContext)
+	if !ok {
+		log.Error("[RestoreMediaHandle] Can not get current user")
+		return c.Status(http.StatusBadRequest).JSON(utils.Error("invalidCurrentUser", "Can not get current user"))
+	}
+
+	restored, err := mediaService.RestoreMediaByOwner(currentUser.UserID, mediaUUID, trashCutoff())
+	if err != nil {
+		log.Error("[RestoreMediaHandle.mediaService.RestoreMediaByOwner] %s", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/restoreMedia", "Error happened while restoring media!"))
+	}
+
+	if !restored {
+		return c.Status(http.StatusNotFound).JSON(utils.Error("mediaNotFound", "Media not found!"))
+	}
+
+	return c.SendStatus(http.StatusOK)
+}
+
+// PurgeTrashHandle handle remove the media which are deleted or released before the trash retention
+func PurgeTrashHandle(c *fiber.Ctx) error {
+
+	purged, err := PurgeTrash()
+	if err != nil {
+		log.Error("[PurgeTrashHandle] %s", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/purgeMedia", "Error happened while purging media!"))
+	}
+
+	return c.JSON(fiber.Map{
+		"purged": purged,
+	})
+}
+
+// PurgeTrash remove the media which are deleted or released before the trash retention and return their number
+func PurgeTrash() (int64, error) {
+
+	// Create service
+	mediaService, err := service.NewMediaService(database.Db)
+	if err != nil {
+		return 0, fmt.Errorf("PurgeTrash/mediaService %s", err.Error())
+	}
+
+	purged, err := mediaService.PurgeDeletedMedia(trashCutoff())
+	if err != nil {
+		return 0, fmt.Errorf("PurgeTrash/purgeMedia %s", err.Error())
+	}
+	return purged, nil
+}
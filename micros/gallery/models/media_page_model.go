@@ -0,0 +1,9 @@
+package models
+
+import dto "github.com/red-gold/ts-serverless/micros/gallery/dto"
+
+type MediaPageModel struct {
+	Media      []dto.Media `json:"media"`
+	NextCursor string      `json:"nextCursor"`
+	PrevCursor string      `json:"prevCursor"`
+}
@@ -11,7 +11,10 @@ import (
 	"github.com/red-gold/telar-core/middleware/authcookie"
 	"github.com/red-gold/telar-core/middleware/authhmac"
 	"github.com/red-gold/telar-core/types"
+	"github.com/red-gold/ts-serverless/micros/gallery/database"
 	"github.com/red-gold/ts-serverless/micros/gallery/handlers"
+	service "github.com/red-gold/ts-serverless/micros/gallery/services"
+	"github.com/red-gold/ts-serverless/micros/internal/index"
 )
 
 // SetupRoutes func
@@ -53,12 +56,23 @@ func SetupRoutes(app *fiber.App) {
 	hmacCookieHandlers := []func(*fiber.Ctx) error{authHMACMiddleware(true), authCookieMiddleware(true)}
 
 	// Routers
+	app.Post("/index", authHMACMiddleware(false), index.InitHandle(newIndexService))
+	app.Get("/index", authHMACMiddleware(false), index.MissingHandle(newIndexService))
 	app.Post("/", append(hmacCookieHandlers, handlers.CreateMediaHandle)...)
 	app.Post("/list", append(hmacCookieHandlers, handlers.CreateMediaListHandle)...)
 	app.Put("/", append(hmacCookieHandlers, handlers.UpdateMediaHandle)...)
+	app.Put("/album/release", authHMACMiddleware(false), handlers.ReleaseAlbumMediaHandle)
+	app.Put("/restore/:mediaId", append(hmacCookieHandlers, handlers.RestoreMediaHandle)...)
+	app.Post("/trash/purge", authHMACMiddleware(false), handlers.PurgeTrashHandle)
 	app.Delete("/id/:mediaId", append(hmacCookieHandlers, handlers.DeleteMediaHandle)...)
 	app.Delete("/dir/:dir", append(hmacCookieHandlers, handlers.DeleteDirectoryHandle)...)
 	app.Get("/", append(hmacCookieHandlers, handlers.QueryAlbumHandle)...)
+	app.Get("/trash", append(hmacCookieHandlers, handlers.GetTrashMediaHandle)...)
 	app.Get("/id/:mediaId", append(hmacCookieHandlers, handlers.GetMediaHandle)...)
 	app.Get("/dir/:dir", append(hmacCookieHandlers, handlers.GetMediaByDirectoryHandle)...)
 }
+
+// newIndexService create the index service of the function
+func newIndexService() (index.Service, error) {
+	return service.NewIndexService(database.Db)
+}
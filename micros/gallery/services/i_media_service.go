@@ -4,6 +4,7 @@ import (
 	uuid "github.com/gofrs/uuid"
 	repo "github.com/red-gold/telar-core/data"
 	dto "github.com/red-gold/ts-serverless/micros/gallery/dto"
+	"github.com/red-gold/ts-serverless/micros/internal/cursor"
 )
 
 type MediaService interface {
@@ -11,6 +12,7 @@ type MediaService interface {
 	SaveManyMedia(medias []dto.Media) error
 	FindOneMedia(filter interface{}) (*dto.Media, error)
 	FindMediaList(filter interface{}, limit int64, skip int64, sort map[string]int) ([]dto.Media, error)
+	FindMediaListByCursor(filter map[string]interface{}, after *cursor.Model, before *cursor.Model, limit int64) ([]dto.Media, error)
 	QueryMedia(search string, ownerUserId *uuid.UUID, mediaTypeId *int, sortBy string, page int64) ([]dto.Media, error)
 	FindById(objectId uuid.UUID) (*dto.Media, error)
 	FindByOwnerUserId(ownerUserId uuid.UUID) ([]dto.Media, error)
@@ -22,5 +24,10 @@ type MediaService interface {
 	CreateMediaIndex(indexes map[string]interface{}) error
 	FindByDirectory(ownerUserId uuid.UUID, directory string, limit int64, skip int64) ([]dto.Media, error)
 	QueryAlbum(ownerUserId uuid.UUID, albumId *uuid.UUID, page int64, limit int64, sortBy string) ([]dto.Media, error)
+	QueryAlbumByCursor(ownerUserId uuid.UUID, albumId *uuid.UUID, after *cursor.Model, before *cursor.Model, limit int64) ([]dto.Media, error)
 	DeleteMediaByDirectory(ownerUserId uuid.UUID, directory string) error
+	SetAlbumReleased(ownerUserId uuid.UUID, mediaIds []uuid.UUID, urls []string, released bool) error
+	RestoreMediaByOwner(ownerUserId uuid.UUID, mediaId uuid.UUID, deletedSince int64) (bool, error)
+	FindTrashByOwner(ownerUserId uuid.UUID, deletedSince int64, page int64) ([]dto.Media, error)
+	PurgeDeletedMedia(deletedBefore int64) (int64, error)
 }
@@ -11,6 +11,8 @@ import (
 	mongoRepo "github.com/red-gold/telar-core/data/mongodb"
 	"github.com/red-gold/telar-core/utils"
 	dto "github.com/red-gold/ts-serverless/micros/gallery/dto"
+	"github.com/red-gold/ts-serverless/micros/internal/cursor"
+	"github.com/red-gold/ts-serverless/micros/internal/inmemory"
 )
 
 // MediaService handlers with injected dependencies
@@ -29,6 +31,10 @@ func NewMediaService(db interface{}) (MediaService, error) {
 		mongodb := db.(mongodb.MongoDatabase)
 		mediaService.MediaRepo = mongoRepo.NewDataRepositoryMongo(mongodb)
 
+	case config.DB_INMEMORY:
+
+		mediaService.MediaRepo = inmemory.NewDataRepositoryInMemory(db.(*inmemory.Database))
+
 	}
 
 	return mediaService, nil
@@ -122,7 +128,7 @@ func (s MediaServiceImpl) QueryMedia(search string, ownerUserId *uuid.UUID, medi
 	skip := numberOfItems * (page - 1)
 	limit := numberOfItems
 
-	filter := make(map[string]interface{})
+	filter := notDeletedMediaFilter()
 	if search != "" {
 		filter["$text"] = coreData.SearchOperator{Search: search}
 	}
@@ -202,21 +208,120 @@ func (s MediaServiceImpl) DeleteMedia(filter interface{}) error {
 	return nil
 }
 
-// DeleteMedia delete media by ownerUserId and mediaId
+// DeleteMediaByOwner mark the media of the owner as deleted
 func (s MediaServiceImpl) DeleteMediaByOwner(ownerUserId uuid.UUID, mediaId uuid.UUID) error {
 
-	filter := struct {
-		ObjectId    uuid.UUID `json:"objectId" bson:"objectId"`
-		OwnerUserId uuid.UUID `json:"ownerUserId" bson:"ownerUserId"`
+	filter := notDeletedMediaFilter()
+	filter["objectId"] = mediaId
+	filter["ownerUserId"] = ownerUserId
+	return s.softDeleteMedia(filter)
+}
+
+// softDeleteMedia mark the media which match the filter as deleted
+func (s MediaServiceImpl) softDeleteMedia(filter map[string]interface{}) error {
+
+	now := utils.UTCNowUnix()
+	data := struct {
+		Deleted     bool  `json:"deleted" bson:"deleted"`
+		DeletedDate int64 `json:"deletedDate" bson:"deletedDate"`
+		LastUpdated int64 `json:"last_updated" bson:"last_updated"`
 	}{
-		ObjectId:    mediaId,
-		OwnerUserId: ownerUserId,
+		Deleted:     true,
+		DeletedDate: now,
+		LastUpdated: now,
 	}
-	err := s.DeleteMedia(filter)
-	if err != nil {
-		return err
+	updateOperator := coreData.UpdateOperator{
+		Set: data,
 	}
-	return nil
+	result := <-s.MediaRepo.UpdateMany(mediaCollectionName, filter, updateOperator)
+	return result.Error
+}
+
+// notDeletedMediaFilter create the filter of the media which are not deleted and are not released
+func notDeletedMediaFilter() map[string]interface{} {
+	notDeleted := make(map[string]interface{})
+	notDeleted["$ne"] = true
+
+	filter := make(map[string]interface{})
+	filter["deleted"] = notDeleted
+	filter["released"] = notDeleted
+	return filter
+}
+
+// RestoreMediaByOwner restore the media of the owner which is deleted since the date, it returns false if the media is not in the trash
+func (s MediaServiceImpl) RestoreMediaByOwner(ownerUserId uuid.UUID, mediaId uuid.UUID, deletedSince int64) (bool, error) {
+
+	sinceFilter := make(map[string]interface{})
+	sinceFilter["$gte"] = deletedSince
+
+	filter := make(map[string]interface{})
+	filter["objectId"] = mediaId
+	filter["ownerUserId"] = ownerUserId
+	filter["deleted"] = true
+	filter["deletedDate"] = sinceFilter
+
+	data := struct {
+		Deleted     bool  `json:"deleted" bson:"deleted"`
+		DeletedDate int64 `json:"deletedDate" bson:"deletedDate"`
+		LastUpdated int64 `json:"last_updated" bson:"last_updated"`
+	}{
+		Deleted:     false,
+		DeletedDate: 0,
+		LastUpdated: utils.UTCNowUnix(),
+	}
+	updateOperator := coreData.UpdateOperator{
+		Set: data,
+	}
+	result := <-s.MediaRepo.Update(mediaCollectionName, filter, updateOperator)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	modifiedCount, _ := result.Result.(int64)
+	return modifiedCount > 0, nil
+}
+
+// FindTrashByOwner get the deleted media of the owner which are deleted since the date by page
+func (s MediaServiceImpl) FindTrashByOwner(ownerUserId uuid.UUID, deletedSince int64, page int64) ([]dto.Media, error) {
+	if page < 1 {
+		page = 1
+	}
+	sortMap := make(map[string]int)
+	sortMap["deletedDate"] = -1
+
+	sinceFilter := make(map[string]interface{})
+	sinceFilter["$gte"] = deletedSince
+
+	filter := make(map[string]interface{})
+	filter["ownerUserId"] = ownerUserId
+	filter["deleted"] = true
+	filter["deletedDate"] = sinceFilter
+
+	return s.FindMediaList(filter, numberOfItems, numberOfItems*(page-1), sortMap)
+}
+
+// PurgeDeletedMedia remove the media which are deleted or released before the date
+func (s MediaServiceImpl) PurgeDeletedMedia(deletedBefore int64) (int64, error) {
+
+	beforeFilter := make(map[string]interface{})
+	beforeFilter["$lt"] = deletedBefore
+
+	deletedFilter := make(map[string]interface{})
+	deletedFilter["deleted"] = true
+	deletedFilter["deletedDate"] = beforeFilter
+
+	releasedFilter := make(map[string]interface{})
+	releasedFilter["released"] = true
+	releasedFilter["releasedDate"] = beforeFilter
+
+	filter := make(map[string]interface{})
+	filter["$or"] = []interface{}{deletedFilter, releasedFilter}
+
+	result := <-s.MediaRepo.Delete(mediaCollectionName, filter, false)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	deletedCount, _ := result.Result.(int64)
+	return deletedCount, nil
 }
 
 // DeleteManyMedia delete many media by filter
@@ -239,23 +344,61 @@ func (s MediaServiceImpl) CreateMediaIndex(indexes map[string]interface{}) error
 func (s MediaServiceImpl) FindByDirectory(ownerUserId uuid.UUID, directory string, limit int64, skip int64) ([]dto.Media, error) {
 	sortMap := make(map[string]int)
 	sortMap["created_date"] = -1
-	filter := struct {
-		OwnerUserId uuid.UUID `json:"ownerUserId" bson:"ownerUserId"`
-		Directory   string    `json:"directory" bson:"directory"`
-	}{
-		OwnerUserId: ownerUserId,
-		Directory:   directory,
-	}
+	filter := notDeletedMediaFilter()
+	filter["ownerUserId"] = ownerUserId
+	filter["directory"] = directory
 	return s.FindMediaList(filter, limit, skip, sortMap)
 }
 
+// FindMediaListByCursor get the media by filter in the page around the cursors
+func (s MediaServiceImpl) FindMediaListByCursor(filter map[string]interface{}, after *cursor.Model, before *cursor.Model, limit int64) ([]dto.Media, error) {
+	var pipeline []interface{}
+
+	sort := cursor.AddFilter(filter, "created_date", after, before)
+
+	matchOperator := make(map[string]interface{})
+	matchOperator["$match"] = filter
+
+	sortOperator := make(map[string]interface{})
+	sortOperator["$sort"] = sort
+
+	limitOperator := make(map[string]interface{})
+	limitOperator["$limit"] = limit
+
+	pipeline = append(pipeline, matchOperator, sortOperator, limitOperator)
+
+	result := <-s.MediaRepo.Aggregate(mediaCollectionName, pipeline)
+	defer result.Close()
+	if result.Error() != nil {
+		return nil, result.Error()
+	}
+	var mediaList []dto.Media
+	for result.Next() {
+		var media dto.Media
+		errDecode := result.Decode(&media)
+		if errDecode != nil {
+			return nil, fmt.Errorf("Error docoding on dto.Media")
+		}
+		mediaList = append(mediaList, media)
+	}
+
+	// The page before the cursor is read in reverse order
+	if before != nil {
+		for i, j := 0, len(mediaList)-1; i < j; i, j = i+1, j-1 {
+			mediaList[i], mediaList[j] = mediaList[j], mediaList[i]
+		}
+	}
+
+	return mediaList, nil
+}
+
 // QueryAlbum query media by albumId
 func (s MediaServiceImpl) QueryAlbum(ownerUserId uuid.UUID, albumId *uuid.UUID, page int64, limit int64, sortBy string) ([]dto.Media, error) {
 	sortMap := make(map[string]int)
 	sortMap[sortBy] = -1
 	skip := numberOfItems * (page - 1)
 
-	filter := make(map[string]interface{})
+	filter := notDeletedMediaFilter()
 	filter["ownerUserId"] = ownerUserId
 	if albumId != nil {
 		filter["albumId"] = *albumId
@@ -264,17 +407,69 @@ func (s MediaServiceImpl) QueryAlbum(ownerUserId uuid.UUID, albumId *uuid.UUID,
 	return s.FindMediaList(filter, limit, skip, sortMap)
 }
 
-// DeleteMediaByDirectory delete media by ownerUserId and mediaId
-func (s MediaServiceImpl) DeleteMediaByDirectory(ownerUserId uuid.UUID, directory string) error {
+// QueryAlbumByCursor get the media of the album in the page around the cursors
+func (s MediaServiceImpl) QueryAlbumByCursor(ownerUserId uuid.UUID, albumId *uuid.UUID, after *cursor.Model, before *cursor.Model, limit int64) ([]dto.Media, error) {
+	if limit <= 0 {
+		limit = numberOfItems
+	}
 
-	filter := struct {
-		OwnerUserId uuid.UUID `json:"ownerUserId" bson:"ownerUserId"`
-		Directory   string    `json:"directory" bson:"directory"`
+	filter := notDeletedMediaFilter()
+	filter["ownerUserId"] = ownerUserId
+	if albumId != nil {
+		filter["albumId"] = *albumId
+	}
+
+	return s.FindMediaListByCursor(filter, after, before, limit)
+}
+
+// SetAlbumReleased release the media of the owner by ids or URLs when their album is deleted and keep them when it is restored
+func (s MediaServiceImpl) SetAlbumReleased(ownerUserId uuid.UUID, mediaIds []uuid.UUID, urls []string, released bool) error {
+	if len(mediaIds) == 0 && len(urls) == 0 {
+		return nil
+	}
+
+	inIds := make(map[string]interface{})
+	inIds["$in"] = mediaIds
+	idFilter := make(map[string]interface{})
+	idFilter["objectId"] = inIds
+
+	inURLs := make(map[string]interface{})
+	inURLs["$in"] = urls
+	urlFilter := make(map[string]interface{})
+	urlFilter["url"] = inURLs
+
+	filter := make(map[string]interface{})
+	filter["ownerUserId"] = ownerUserId
+	filter["$or"] = []interface{}{idFilter, urlFilter}
+
+	now := utils.UTCNowUnix()
+	releasedDate := int64(0)
+	if released {
+		releasedDate = now
+	}
+	data := struct {
+		Released     bool  `json:"released" bson:"released"`
+		ReleasedDate int64 `json:"releasedDate" bson:"releasedDate"`
+		LastUpdated  int64 `json:"last_updated" bson:"last_updated"`
 	}{
-		OwnerUserId: ownerUserId,
-		Directory:   directory,
+		Released:     released,
+		ReleasedDate: releasedDate,
+		LastUpdated:  now,
+	}
+	updateOperator := coreData.UpdateOperator{
+		Set: data,
 	}
-	err := s.DeleteManyMedia(filter)
+	result := <-s.MediaRepo.UpdateMany(mediaCollectionName, filter, updateOperator)
+	return result.Error
+}
+
+// DeleteMediaByDirectory mark the media of the owner in the directory as deleted
+func (s MediaServiceImpl) DeleteMediaByDirectory(ownerUserId uuid.UUID, directory string) error {
+
+	filter := notDeletedMediaFilter()
+	filter["ownerUserId"] = ownerUserId
+	filter["directory"] = directory
+	err := s.softDeleteMedia(filter)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,127 @@
+package harness
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http/httptest"
+	"strconv"
+
+	"github.com/alexellis/hmac"
+	"github.com/gofrs/uuid"
+	coreData "github.com/red-gold/telar-core/data"
+	"github.com/red-gold/telar-core/types"
+	"github.com/red-gold/ts-serverless/micros/internal/inmemory"
+)
+
+// profileCollectionName the collection of the profile service which the functions join the profiles from
+const profileCollectionName = "userProfile"
+
+// Response the response of a function
+type Response struct {
+	StatusCode int
+	Body       []byte
+}
+
+// Decode decode the JSON body of the response into the value
+func (r *Response) Decode(v interface{}) error {
+	return json.Unmarshal(r.Body, v)
+}
+
+// NewUser create a user with a profile
+func (h *Harness) NewUser(socialName string) (types.UserContext, error) {
+	user := types.UserContext{
+		UserID:      uuid.Must(uuid.NewV4()),
+		Username:    socialName + "@telar.test",
+		SocialName:  socialName,
+		DisplayName: socialName,
+		SystemRole:  "user",
+	}
+	err := h.AddProfile(Profile{
+		ObjectId:   user.UserID,
+		FullName:   user.DisplayName,
+		SocialName: user.SocialName,
+		Email:      user.Username,
+	})
+	return user, err
+}
+
+// AddProfile add or replace the profile in the profile stub and in the userProfile collection of the functions.
+// The functions share the database of the profile service in the stack, and some of their queries join the profiles.
+// The counters which the functions change through the profile stub are not copied to the collection.
+func (h *Harness) AddProfile(profile Profile) error {
+	h.Profiles.Add(profile)
+	upsert := true
+	filter := map[string]interface{}{"objectId": profile.ObjectId}
+	update := coreData.UpdateOperator{Set: &profile}
+	for _, function := range functions {
+		repo := inmemory.NewDataRepositoryInMemory(h.databases[function.name])
+		result := <-repo.Update(profileCollectionName, filter, update, &coreData.UpdateOptions{Upsert: &upsert})
+		if result.Error != nil {
+			return fmt.Errorf("save profile in %s: %s", function.name, result.Error.Error())
+		}
+	}
+	return nil
+}
+
+// Do send a request to the function like the internal gateway does, the request is signed with HMAC
+// and carries the headers of the user. The body is sent as JSON unless it is []byte, a nil user is anonymous.
+func (h *Harness) Do(name string, method string, path string, body interface{}, user *types.UserContext) (*Response, error) {
+	app, ok := h.apps[name]
+	if !ok {
+		return nil, fmt.Errorf("function %s is not found", name)
+	}
+
+	var bodyBytes []byte
+	switch typed := body.(type) {
+	case nil:
+		bodyBytes = []byte("")
+	case []byte:
+		bodyBytes = typed
+	default:
+		var err error
+		bodyBytes, err = json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	req := httptest.NewRequest(method, path, bytes.NewReader(bodyBytes))
+	req.Header.Set("Content-Type", "application/json")
+	digest := hmac.Sign(bodyBytes, []byte(h.payloadSecret))
+	req.Header.Set(types.HeaderHMACAuthenticate, "sha1="+hex.EncodeToString(digest))
+	if user != nil {
+		for key, values := range userHeaders(*user) {
+			req.Header[key] = values
+		}
+	}
+
+	res, err := app.Test(req, -1)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	resBody, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	return &Response{StatusCode: res.StatusCode, Body: resBody}, nil
+}
+
+// userHeaders the headers which the HMAC middleware reads the user context from
+func userHeaders(user types.UserContext) map[string][]string {
+	return map[string][]string{
+		"uid":         {user.UserID.String()},
+		"email":       {user.Username},
+		"avatar":      {user.Avatar},
+		"banner":      {user.Banner},
+		"tagLine":     {user.TagLine},
+		"displayName": {user.DisplayName},
+		"socialName":  {user.SocialName},
+		"role":        {user.SystemRole},
+		"createdDate": {strconv.FormatInt(user.CreatedDate, 10)},
+	}
+}
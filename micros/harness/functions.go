@@ -0,0 +1,80 @@
+package harness
+
+import (
+	"github.com/gofiber/fiber/v2"
+	circlesDatabase "github.com/red-gold/ts-serverless/micros/circles/database"
+	circlesRouter "github.com/red-gold/ts-serverless/micros/circles/router"
+	commentsDatabase "github.com/red-gold/ts-serverless/micros/comments/database"
+	commentsRouter "github.com/red-gold/ts-serverless/micros/comments/router"
+	galleryDatabase "github.com/red-gold/ts-serverless/micros/gallery/database"
+	galleryRouter "github.com/red-gold/ts-serverless/micros/gallery/router"
+	"github.com/red-gold/ts-serverless/micros/internal/inmemory"
+	postsDatabase "github.com/red-gold/ts-serverless/micros/posts/database"
+	postsRouter "github.com/red-gold/ts-serverless/micros/posts/router"
+	userRelsDatabase "github.com/red-gold/ts-serverless/micros/user-rels/database"
+	userRelsRouter "github.com/red-gold/ts-serverless/micros/user-rels/router"
+	vangDatabase "github.com/red-gold/ts-serverless/micros/vang/database"
+	vangRouter "github.com/red-gold/ts-serverless/micros/vang/router"
+	votesDatabase "github.com/red-gold/ts-serverless/micros/votes/database"
+	votesRouter "github.com/red-gold/ts-serverless/micros/votes/router"
+)
+
+// The names of the functions on the gateway, as they are deployed in stack.yml
+const (
+	CirclesFunction  = "circles"
+	CommentsFunction = "comments"
+	MediaFunction    = "media"
+	PostsFunction    = "posts"
+	UserRelsFunction = "user-rels"
+	VangFunction     = "vang"
+	VotesFunction    = "votes"
+
+	profileFunction       = "profile"
+	notificationsFunction = "notifications"
+	actionsFunction       = "actions"
+)
+
+// function a function of the stack which runs in the harness
+type function struct {
+	name        string
+	setupRoutes func(app *fiber.App)
+	setDatabase func(db *inmemory.Database)
+}
+
+var functions = []function{
+	{
+		name:        CirclesFunction,
+		setupRoutes: circlesRouter.SetupRoutes,
+		setDatabase: func(db *inmemory.Database) { circlesDatabase.Db = db },
+	},
+	{
+		name:        CommentsFunction,
+		setupRoutes: commentsRouter.SetupRoutes,
+		setDatabase: func(db *inmemory.Database) { commentsDatabase.Db = db },
+	},
+	{
+		name:        MediaFunction,
+		setupRoutes: galleryRouter.SetupRoutes,
+		setDatabase: func(db *inmemory.Database) { galleryDatabase.Db = db },
+	},
+	{
+		name:        PostsFunction,
+		setupRoutes: postsRouter.SetupRoutes,
+		setDatabase: func(db *inmemory.Database) { postsDatabase.Db = db },
+	},
+	{
+		name:        UserRelsFunction,
+		setupRoutes: userRelsRouter.SetupRoutes,
+		setDatabase: func(db *inmemory.Database) { userRelsDatabase.Db = db },
+	},
+	{
+		name:        VangFunction,
+		setupRoutes: vangRouter.SetupRoutes,
+		setDatabase: func(db *inmemory.Database) { vangDatabase.Db = db },
+	},
+	{
+		name:        VotesFunction,
+		setupRoutes: votesRouter.SetupRoutes,
+		setDatabase: func(db *inmemory.Database) { votesDatabase.Db = db },
+	},
+}
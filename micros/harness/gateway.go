@@ -0,0 +1,143 @@
+package harness
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+)
+
+// Call a request which a function sent to the internal gateway
+type Call struct {
+	Function   string
+	Method     string
+	URL        string
+	Header     http.Header
+	Body       []byte
+	StatusCode int
+}
+
+// gatewayHandler route the request /{function}/{path} to the app of the function with the path /{path}
+func (h *Harness) gatewayHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name, path := splitFunctionPath(r.URL.Path)
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		h.mu.Lock()
+		unavailable := h.unavailable[name]
+		h.mu.Unlock()
+		if unavailable {
+			h.recordCall(name, r, body, http.StatusServiceUnavailable)
+			http.Error(w, fmt.Sprintf("function %s is unavailable", name), http.StatusServiceUnavailable)
+			return
+		}
+
+		app, ok := h.apps[name]
+		if !ok {
+			h.recordCall(name, r, body, http.StatusNotFound)
+			http.Error(w, fmt.Sprintf("function %s is not found", name), http.StatusNotFound)
+			return
+		}
+
+		target := path
+		if r.URL.RawQuery != "" {
+			target += "?" + r.URL.RawQuery
+		}
+		req := httptest.NewRequest(r.Method, target, bytes.NewReader(body))
+		req.Header = r.Header.Clone()
+		res, err := app.Test(req, -1)
+		if err != nil {
+			h.recordCall(name, r, body, http.StatusBadGateway)
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		defer res.Body.Close()
+
+		for key, values := range res.Header {
+			w.Header()[key] = values
+		}
+		w.WriteHeader(res.StatusCode)
+		resBody, _ := ioutil.ReadAll(res.Body)
+		w.Write(resBody)
+		h.recordCall(name, r, body, res.StatusCode)
+	})
+}
+
+// splitFunctionPath split the gateway path to the function name and the path in the function
+func splitFunctionPath(gatewayPath string) (string, string) {
+	parts := strings.SplitN(strings.TrimPrefix(gatewayPath, "/"), "/", 2)
+	if len(parts) < 2 {
+		return parts[0], "/"
+	}
+	return parts[0], "/" + parts[1]
+}
+
+// recordCall store the call and wake up the waiting callers
+func (h *Harness) recordCall(name string, r *http.Request, body []byte, statusCode int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.calls = append(h.calls, Call{
+		Function:   name,
+		Method:     r.Method,
+		URL:        r.URL.RequestURI(),
+		Header:     r.Header.Clone(),
+		Body:       body,
+		StatusCode: statusCode,
+	})
+	close(h.wait)
+	h.wait = make(chan struct{})
+}
+
+// clearCalls remove the recorded calls
+func (h *Harness) clearCalls() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.calls = nil
+}
+
+// Calls get the calls which are received by the gateway
+func (h *Harness) Calls() []Call {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]Call{}, h.calls...)
+}
+
+// FindCalls get the calls with the method and the gateway URL, e.g. PUT /posts/comment/count
+func (h *Harness) FindCalls(method string, url string) []Call {
+	var found []Call
+	for _, call := range h.Calls() {
+		if call.Method == method && call.URL == url {
+			found = append(found, call)
+		}
+	}
+	return found
+}
+
+// WaitForCalls wait until the gateway received count calls with the method and the gateway URL.
+// The side effects of the functions are sent in the background, so a scenario waits for them before it asserts their result.
+func (h *Harness) WaitForCalls(method string, url string, count int, timeout time.Duration) ([]Call, error) {
+	deadline := time.After(timeout)
+	for {
+		h.mu.Lock()
+		wait := h.wait
+		h.mu.Unlock()
+
+		found := h.FindCalls(method, url)
+		if len(found) >= count {
+			return found, nil
+		}
+
+		select {
+		case <-wait:
+		case <-deadline:
+			return found, fmt.Errorf("%d calls of %s %s are received in %s, want %d", len(found), method, url, timeout, count)
+		}
+	}
+}
@@ -0,0 +1,29 @@
+module github.com/red-gold/ts-serverless/micros/harness
+
+go 1.16
+
+require (
+	github.com/alexellis/hmac v0.0.0-20180624211220-5c52ab81c0de
+	github.com/gofiber/fiber/v2 v2.10.0
+	github.com/gofrs/uuid v4.0.0+incompatible
+	github.com/red-gold/telar-core v0.1.16
+	github.com/red-gold/ts-serverless v0.1.33
+	github.com/red-gold/ts-serverless/micros/circles v0.0.0
+	github.com/red-gold/ts-serverless/micros/comments v0.0.0
+	github.com/red-gold/ts-serverless/micros/gallery v0.0.0
+	github.com/red-gold/ts-serverless/micros/posts v0.0.0
+	github.com/red-gold/ts-serverless/micros/user-rels v0.0.0
+	github.com/red-gold/ts-serverless/micros/vang v0.0.0
+	github.com/red-gold/ts-serverless/micros/votes v0.0.0
+)
+
+replace (
+	github.com/red-gold/ts-serverless => ../..
+	github.com/red-gold/ts-serverless/micros/circles => ../circles
+	github.com/red-gold/ts-serverless/micros/comments => ../comments
+	github.com/red-gold/ts-serverless/micros/gallery => ../gallery
+	github.com/red-gold/ts-serverless/micros/posts => ../posts
+	github.com/red-gold/ts-serverless/micros/user-rels => ../user-rels
+	github.com/red-gold/ts-serverless/micros/vang => ../vang
+	github.com/red-gold/ts-serverless/micros/votes => ../votes
+)
@@ -0,0 +1,149 @@
+// Package harness boots the functions in-process on in-memory databases, so the flows between them
+// can be exercised in go test without MongoDB or an OpenFaaS gateway.
+//
+// The internal gateway is a local server which routes the requests of functionCall to the fiber app of
+// the called function, the profile, notifications and actions services are replaced by stubs.
+// The configuration and the databases of the functions are global, so only one harness can run at a time.
+package harness
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"sync"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/recover"
+	"github.com/gofiber/fiber/v2/middleware/requestid"
+	"github.com/gofrs/uuid"
+	core "github.com/red-gold/telar-core"
+	"github.com/red-gold/telar-core/config"
+	"github.com/red-gold/ts-serverless/micros/internal/inmemory"
+)
+
+// Harness runs the functions and the stubs behind an in-process internal gateway
+type Harness struct {
+	// Gateway the internal gateway which the functions call
+	Gateway *httptest.Server
+
+	// Profiles the stub of the profile service
+	Profiles *ProfileStub
+
+	// Notifications the stub of the notifications service
+	Notifications *NotificationStub
+
+	// Actions the stub of the actions service
+	Actions *ActionStub
+
+	apps          map[string]*fiber.App
+	databases     map[string]*inmemory.Database
+	payloadSecret string
+
+	mu          sync.Mutex
+	calls       []Call
+	wait        chan struct{}
+	unavailable map[string]bool
+}
+
+// New start the functions on empty in-memory databases
+func New() (*Harness, error) {
+	h := &Harness{
+		Profiles:      newProfileStub(),
+		Notifications: newNotificationStub(),
+		Actions:       newActionStub(),
+		apps:          make(map[string]*fiber.App),
+		databases:     make(map[string]*inmemory.Database),
+		payloadSecret: uuid.Must(uuid.NewV4()).String(),
+		wait:          make(chan struct{}),
+	}
+	h.Gateway = httptest.NewServer(h.gatewayHandler())
+	initConfig(h.Gateway.URL, h.payloadSecret)
+
+	for _, function := range functions {
+		h.apps[function.name] = newApp(function.setupRoutes)
+	}
+	h.apps[profileFunction] = h.Profiles.app()
+	h.apps[notificationsFunction] = h.Notifications.app()
+	h.apps[actionsFunction] = h.Actions.app()
+
+	if err := h.Reset(); err != nil {
+		h.Close()
+		return nil, err
+	}
+	return h, nil
+}
+
+// initConfig set the core configuration which the routes and the handlers of the functions read
+func initConfig(internalGateway string, payloadSecret string) {
+	dbType := config.DB_INMEMORY
+	baseRoute := ""
+	origin := "*"
+	publicKey := ""
+	queryPrettyURL := false
+	debug := false
+	core.InitConfigFromData(config.Configuration{
+		BaseRoute:       &baseRoute,
+		InternalGateway: &internalGateway,
+		Gateway:         &internalGateway,
+		PayloadSecret:   &payloadSecret,
+		PublicKey:       &publicKey,
+		Origin:          &origin,
+		DBType:          &dbType,
+		QueryPrettyURL:  &queryPrettyURL,
+		Debug:           &debug,
+	})
+}
+
+// newApp create the fiber app of a function with the middlewares of its handler
+func newApp(setupRoutes func(app *fiber.App)) *fiber.App {
+	app := fiber.New()
+	app.Use(recover.New())
+	app.Use(requestid.New())
+	setupRoutes(app)
+	return app
+}
+
+// Reset replace the databases of the functions with empty ones, create their indexes and clear the stubs and the calls
+func (h *Harness) Reset() error {
+	for _, function := range functions {
+		db := inmemory.NewDatabase()
+		h.databases[function.name] = db
+		function.setDatabase(db)
+	}
+	h.Profiles.reset()
+	h.Notifications.reset()
+	h.Actions.reset()
+	h.clearCalls()
+	h.mu.Lock()
+	h.unavailable = make(map[string]bool)
+	h.mu.Unlock()
+
+	for _, function := range functions {
+		res, err := h.Do(function.name, fiber.MethodPost, "/index", nil, nil)
+		if err != nil {
+			return err
+		}
+		if res.StatusCode != fiber.StatusOK {
+			return fmt.Errorf("create indexes of %s: %d %s", function.name, res.StatusCode, string(res.Body))
+		}
+	}
+	return nil
+}
+
+// SetUnavailable make the gateway answer 503 to the calls of the function until the harness is reset
+func (h *Harness) SetUnavailable(name string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.unavailable[name] = true
+}
+
+// SetAvailable make the gateway send the calls of the function to it again
+func (h *Harness) SetAvailable(name string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.unavailable, name)
+}
+
+// Close stop the internal gateway
+func (h *Harness) Close() {
+	h.Gateway.Close()
+}
@@ -0,0 +1,562 @@
+package harness
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	coreData "github.com/red-gold/telar-core/data"
+	"github.com/red-gold/telar-core/types"
+	"github.com/red-gold/ts-serverless/micros/internal/inmemory"
+	postsConfig "github.com/red-gold/ts-serverless/micros/posts/config"
+	postsDto "github.com/red-gold/ts-serverless/micros/posts/dto"
+	postsModels "github.com/red-gold/ts-serverless/micros/posts/models"
+	userRelsDto "github.com/red-gold/ts-serverless/micros/user-rels/dto"
+	userRelsModels "github.com/red-gold/ts-serverless/micros/user-rels/models"
+)
+
+// callTimeout the time which a scenario waits for the side effects which are sent in the background
+const callTimeout = 5 * time.Second
+
+func newTestHarness(t *testing.T) *Harness {
+	t.Helper()
+	h, err := New()
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	t.Cleanup(h.Close)
+	return h
+}
+
+func newTestUser(t *testing.T, h *Harness, socialName string) types.UserContext {
+	t.Helper()
+	user, err := h.NewUser(socialName)
+	if err != nil {
+		t.Fatalf("NewUser: %v", err)
+	}
+	return user
+}
+
+// do send the request and check the status of the response
+func do(t *testing.T, h *Harness, name string, method string, path string, body interface{}, user *types.UserContext) *Response {
+	t.Helper()
+	res, err := h.Do(name, method, path, body, user)
+	if err != nil {
+		t.Fatalf("%s %s/%s: %v", method, name, path, err)
+	}
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("%s %s%s: got status %d %s, want 200", method, name, path, res.StatusCode, string(res.Body))
+	}
+	return res
+}
+
+// waitForCall wait for one call of the method and the gateway URL and check it is delivered
+func waitForCall(t *testing.T, h *Harness, method string, url string) Call {
+	t.Helper()
+	calls, err := h.WaitForCalls(method, url, 1, callTimeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls[0].StatusCode != http.StatusOK {
+		t.Fatalf("%s %s: got status %d, want 200", method, url, calls[0].StatusCode)
+	}
+	return calls[0]
+}
+
+func createPost(t *testing.T, h *Harness, owner types.UserContext, body string) uuid.UUID {
+	t.Helper()
+	res := do(t, h, PostsFunction, http.MethodPost, "/", map[string]interface{}{
+		"body":       body,
+		"permission": "Public",
+	}, &owner)
+	var created struct {
+		ObjectId uuid.UUID `json:"objectId"`
+	}
+	if err := res.Decode(&created); err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	return created.ObjectId
+}
+
+func getPost(t *testing.T, h *Harness, postId uuid.UUID, user types.UserContext) postsDto.Post {
+	t.Helper()
+	res := do(t, h, PostsFunction, http.MethodGet, "/"+postId.String(), nil, &user)
+	var post postsDto.Post
+	if err := res.Decode(&post); err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	return post
+}
+
+func follow(t *testing.T, h *Harness, user types.UserContext, followed types.UserContext) {
+	t.Helper()
+	do(t, h, UserRelsFunction, http.MethodPost, "/follow", map[string]interface{}{
+		"right": map[string]interface{}{
+			"userId":   followed.UserID,
+			"fullName": followed.DisplayName,
+		},
+	}, &user)
+}
+
+func TestCommentIncreasesPostCommentCount(t *testing.T) {
+	h := newTestHarness(t)
+	owner := newTestUser(t, h, "alice")
+	commenter := newTestUser(t, h, "bob")
+	postId := createPost(t, h, owner, "hello")
+
+	do(t, h, CommentsFunction, http.MethodPost, "/", map[string]interface{}{
+		"postId": postId,
+		"text":   "nice post",
+	}, &commenter)
+
+	call := waitForCall(t, h, http.MethodPut, "/posts/comment/count")
+	if call.Header.Get("X-Idempotency-Key") == "" {
+		t.Error("the comment count is sent without an idempotency key")
+	}
+	if got := getPost(t, h, postId, owner).CommentCounter; got != 1 {
+		t.Errorf("got comment counter %d, want 1", got)
+	}
+}
+
+func TestVoteIncreasesPostScore(t *testing.T) {
+	h := newTestHarness(t)
+	owner := newTestUser(t, h, "alice")
+	voter := newTestUser(t, h, "bob")
+	postId := createPost(t, h, owner, "hello")
+
+	do(t, h, VotesFunction, http.MethodPost, "/", map[string]interface{}{
+		"postId": postId,
+	}, &voter)
+
+	waitForCall(t, h, http.MethodPut, "/posts/score")
+	if got := getPost(t, h, postId, owner).Score; got != 1 {
+		t.Errorf("got score %d, want 1", got)
+	}
+}
+
+func TestReconcileCountersReadsCommentsAndVotes(t *testing.T) {
+	h := newTestHarness(t)
+	owner := newTestUser(t, h, "alice")
+	user := newTestUser(t, h, "bob")
+	postId := createPost(t, h, owner, "hello")
+
+	do(t, h, CommentsFunction, http.MethodPost, "/", map[string]interface{}{
+		"postId": postId,
+		"text":   "nice post",
+	}, &user)
+	do(t, h, VotesFunction, http.MethodPost, "/", map[string]interface{}{
+		"postId": postId,
+	}, &user)
+	waitForCall(t, h, http.MethodPut, "/posts/comment/count")
+	waitForCall(t, h, http.MethodPut, "/posts/score")
+
+	// The counters of the post drift from the comments and votes
+	repo := inmemory.NewDataRepositoryInMemory(h.databases[PostsFunction])
+	drifted := coreData.UpdateOperator{Set: map[string]interface{}{
+		"commentCounter": 5,
+		"score":          0,
+		"votes":          map[string]string{},
+		"reactions":      map[string]int64{},
+	}}
+	if result := <-repo.Update("post", map[string]interface{}{"objectId": postId}, drifted); result.Error != nil {
+		t.Fatalf("Update: %v", result.Error)
+	}
+
+	res := do(t, h, PostsFunction, http.MethodPost, "/counters/reconcile", map[string]interface{}{
+		"postId": postId,
+	}, nil)
+	var reconciled postsModels.ReconcileResultModel
+	if err := res.Decode(&reconciled); err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if reconciled.Checked != 1 || len(reconciled.Fixed) != 1 {
+		t.Fatalf("got %d checked and %d fixed posts, want the post to be fixed", reconciled.Checked, len(reconciled.Fixed))
+	}
+	waitForCall(t, h, http.MethodPost, "/comments/post/counts")
+	waitForCall(t, h, http.MethodPost, "/votes/post/voters")
+
+	post := getPost(t, h, postId, owner)
+	if post.CommentCounter != 1 || post.Score != 1 {
+		t.Errorf("got comment counter %d and score %d, want 1 and 1", post.CommentCounter, post.Score)
+	}
+	if _, ok := post.Votes[user.UserID.String()]; !ok {
+		t.Errorf("got votes %v, want the vote of the user", post.Votes)
+	}
+}
+
+func TestFollowIncreasesProfileCounters(t *testing.T) {
+	h := newTestHarness(t)
+	follower := newTestUser(t, h, "bob")
+	followed := newTestUser(t, h, "alice")
+
+	follow(t, h, follower, followed)
+
+	waitForCall(t, h, http.MethodPut, fmt.Sprintf("/profile/follow/inc/1/%s", follower.UserID))
+	waitForCall(t, h, http.MethodPut, fmt.Sprintf("/profile/follower/inc/1/%s", followed.UserID))
+	if profile, _ := h.Profiles.Get(follower.UserID); profile.FollowCount != 1 {
+		t.Errorf("got follow count %d, want 1", profile.FollowCount)
+	}
+	if profile, _ := h.Profiles.Get(followed.UserID); profile.FollowerCount != 1 {
+		t.Errorf("got follower count %d, want 1", profile.FollowerCount)
+	}
+}
+
+func TestFollowingIncludesProfile(t *testing.T) {
+	h := newTestHarness(t)
+	follower := newTestUser(t, h, "bob")
+	followed := newTestUser(t, h, "alice")
+	if err := h.AddProfile(Profile{
+		ObjectId:   followed.UserID,
+		FullName:   "Alice Liddell",
+		SocialName: followed.SocialName,
+		Avatar:     "alice.png",
+	}); err != nil {
+		t.Fatalf("AddProfile: %v", err)
+	}
+
+	follow(t, h, follower, followed)
+
+	res := do(t, h, UserRelsFunction, http.MethodGet, "/following", nil, &follower)
+	var page userRelsModels.UserRelPageModel
+	if err := res.Decode(&page); err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	following := page.Rels
+	if len(following) != 1 {
+		t.Fatalf("got %d following, want 1", len(following))
+	}
+	right := following[0].Right
+	if following[0].RightId != followed.UserID || right.FullName != "Alice Liddell" || right.Avatar != "alice.png" {
+		t.Errorf("got the followed user %s %q %q, want the profile of %s", following[0].RightId, right.FullName, right.Avatar, followed.UserID)
+	}
+}
+
+func TestFeedIncludesFollowedPosts(t *testing.T) {
+	h := newTestHarness(t)
+	follower := newTestUser(t, h, "bob")
+	followed := newTestUser(t, h, "alice")
+	stranger := newTestUser(t, h, "carol")
+	postId := createPost(t, h, followed, "hello followers")
+	createPost(t, h, stranger, "hello strangers")
+
+	follow(t, h, follower, followed)
+
+	res := do(t, h, PostsFunction, http.MethodGet, "/feed", nil, &follower)
+	var page postsModels.PostPageModel
+	if err := res.Decode(&page); err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if len(page.Posts) != 1 {
+		t.Fatalf("got %d posts, want the post of the followed user", len(page.Posts))
+	}
+	if page.Posts[0].ObjectId != postId || page.Posts[0].OwnerDisplayName != followed.DisplayName {
+		t.Errorf("got post %s of %q, want %s of %q", page.Posts[0].ObjectId, page.Posts[0].OwnerDisplayName, postId, followed.DisplayName)
+	}
+}
+
+func TestGroupRoomIncludesMemberProfiles(t *testing.T) {
+	h := newTestHarness(t)
+	owner := newTestUser(t, h, "alice")
+	member := newTestUser(t, h, "bob")
+
+	do(t, h, VangFunction, http.MethodPost, "/room/group", map[string]interface{}{
+		"title":   "friends",
+		"members": []uuid.UUID{member.UserID},
+	}, &owner)
+
+	call := waitForCall(t, h, http.MethodPost, "/profile/dto/ids")
+	var requested struct {
+		UserIds []string `json:"userIds"`
+	}
+	if err := json.Unmarshal(call.Body, &requested); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if len(requested.UserIds) != 2 {
+		t.Errorf("got %d requested profiles, want 2", len(requested.UserIds))
+	}
+
+	waitForCall(t, h, http.MethodPost, fmt.Sprintf("/actions/dispatch/%s", owner.UserID))
+	var users map[string]map[string]interface{}
+	for _, action := range h.Actions.All() {
+		if action.UserId != owner.UserID {
+			continue
+		}
+		var payload struct {
+			Users map[string]map[string]interface{} `json:"users"`
+		}
+		if err := json.Unmarshal(action.Payload, &payload); err != nil {
+			t.Fatalf("Unmarshal: %v", err)
+		}
+		users = payload.Users
+	}
+	if got := users[member.UserID.String()]["fullName"]; got != member.DisplayName {
+		t.Errorf("got member full name %v, want %q", got, member.DisplayName)
+	}
+}
+
+func TestPurgeTrashRemovesPostComments(t *testing.T) {
+	h := newTestHarness(t)
+	owner := newTestUser(t, h, "alice")
+	commenter := newTestUser(t, h, "bob")
+	postId := createPost(t, h, owner, "hello")
+
+	res := do(t, h, CommentsFunction, http.MethodPost, "/", map[string]interface{}{
+		"postId": postId,
+		"text":   "nice post",
+	}, &commenter)
+	var comment struct {
+		ObjectId uuid.UUID `json:"objectId"`
+	}
+	if err := res.Decode(&comment); err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	do(t, h, PostsFunction, http.MethodDelete, "/"+postId.String(), nil, &owner)
+
+	// The posts which are deleted before now are out of the trash
+	retentionDays := postsConfig.PostConfig.TrashRetentionDays
+	postsConfig.PostConfig.TrashRetentionDays = 0
+	defer func() { postsConfig.PostConfig.TrashRetentionDays = retentionDays }()
+	time.Sleep(2 * time.Millisecond)
+
+	res = do(t, h, PostsFunction, http.MethodPost, "/trash/purge", nil, nil)
+	var purged struct {
+		Purged int `json:"purged"`
+	}
+	if err := res.Decode(&purged); err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if purged.Purged != 1 {
+		t.Fatalf("got %d purged posts, want 1", purged.Purged)
+	}
+
+	waitForCall(t, h, http.MethodDelete, fmt.Sprintf("/comments/post/%s/deleted", postId))
+	waitForCall(t, h, http.MethodDelete, fmt.Sprintf("/votes/post/%s/deleted", postId))
+	// The comment is not found, so no comment is returned
+	res = do(t, h, CommentsFunction, http.MethodGet, "/"+comment.ObjectId.String(), nil, &commenter)
+	var found struct {
+		ObjectId uuid.UUID `json:"objectId"`
+	}
+	if err := res.Decode(&found); err == nil && found.ObjectId != uuid.Nil {
+		t.Errorf("got comment %s, want the comment of the purged post to be removed", found.ObjectId)
+	}
+}
+
+func TestDeleteCommentDecreasesCountOfItsPost(t *testing.T) {
+	h := newTestHarness(t)
+	owner := newTestUser(t, h, "alice")
+	commenter := newTestUser(t, h, "bob")
+	postId := createPost(t, h, owner, "hello")
+	otherPostId := createPost(t, h, owner, "hello again")
+
+	res := do(t, h, CommentsFunction, http.MethodPost, "/", map[string]interface{}{
+		"postId": postId,
+		"text":   "nice post",
+	}, &commenter)
+	var comment struct {
+		ObjectId uuid.UUID `json:"objectId"`
+	}
+	if err := res.Decode(&comment); err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	waitForCall(t, h, http.MethodPut, "/posts/comment/count")
+
+	// The post id of the route should be the post of the comment
+	res, err := h.Do(CommentsFunction, http.MethodDelete, fmt.Sprintf("/id/%s/post/%s", comment.ObjectId, otherPostId), nil, &commenter)
+	if err != nil {
+		t.Fatalf("Do: %v", err)
+	}
+	if res.StatusCode != http.StatusBadRequest {
+		t.Errorf("got status %d, want 400 for the post which the comment does not belong to", res.StatusCode)
+	}
+
+	do(t, h, CommentsFunction, http.MethodDelete, fmt.Sprintf("/id/%s", comment.ObjectId), nil, &commenter)
+	if _, err := h.WaitForCalls(http.MethodPut, "/posts/comment/count", 2, callTimeout); err != nil {
+		t.Fatal(err)
+	}
+	if got := getPost(t, h, postId, owner).CommentCounter; got != 0 {
+		t.Errorf("got comment counter %d, want 0", got)
+	}
+	if got := getPost(t, h, otherPostId, owner).CommentCounter; got != 0 {
+		t.Errorf("got comment counter %d on the other post, want 0", got)
+	}
+}
+
+func TestQueriesFailWhileRestrictionsUnavailable(t *testing.T) {
+	h := newTestHarness(t)
+	owner := newTestUser(t, h, "alice")
+	commenter := newTestUser(t, h, "bob")
+	postId := createPost(t, h, owner, "hello")
+
+	res := do(t, h, CommentsFunction, http.MethodPost, "/", map[string]interface{}{
+		"postId": postId,
+		"text":   "nice post",
+	}, &commenter)
+	var comment struct {
+		ObjectId uuid.UUID `json:"objectId"`
+	}
+	if err := res.Decode(&comment); err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+
+	// The posts and the comments of the blocked users must not be shown while the restrictions can not be read
+	h.SetUnavailable(UserRelsFunction)
+	for _, query := range []struct {
+		name string
+		path string
+	}{
+		{PostsFunction, "/"},
+		{CommentsFunction, fmt.Sprintf("/%s", comment.ObjectId)},
+	} {
+		res, err := h.Do(query.name, http.MethodGet, query.path, nil, &owner)
+		if err != nil {
+			t.Fatalf("Do: %v", err)
+		}
+		if res.StatusCode != http.StatusInternalServerError {
+			t.Errorf("GET %s%s: got status %d %s, want 500", query.name, query.path, res.StatusCode, string(res.Body))
+		}
+	}
+}
+
+func TestFollowersArePagedByDefault(t *testing.T) {
+	h := newTestHarness(t)
+	followed := newTestUser(t, h, "alice")
+	for i := 0; i < 12; i++ {
+		follow(t, h, newTestUser(t, h, fmt.Sprintf("follower%d", i)), followed)
+	}
+
+	res := do(t, h, UserRelsFunction, http.MethodGet, "/followers", nil, &followed)
+	var page userRelsModels.UserRelPageModel
+	if err := res.Decode(&page); err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if len(page.Rels) != 10 || page.NextCursor == "" {
+		t.Fatalf("got %d followers and next cursor %q, want the first page of 10", len(page.Rels), page.NextCursor)
+	}
+
+	res = do(t, h, UserRelsFunction, http.MethodGet, "/followers?after="+page.NextCursor, nil, &followed)
+	if err := res.Decode(&page); err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if len(page.Rels) != 2 {
+		t.Errorf("got %d followers on the second page, want 2", len(page.Rels))
+	}
+}
+
+func TestReplyNotifiesPostAndCommentOwners(t *testing.T) {
+	h := newTestHarness(t)
+	owner := newTestUser(t, h, "alice")
+	commenter := newTestUser(t, h, "bob")
+	replier := newTestUser(t, h, "carol")
+	postId := createPost(t, h, owner, "hello")
+
+	res := do(t, h, CommentsFunction, http.MethodPost, "/", map[string]interface{}{
+		"postId": postId,
+		"text":   "nice post",
+	}, &commenter)
+	var comment struct {
+		ObjectId uuid.UUID `json:"objectId"`
+	}
+	if err := res.Decode(&comment); err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	do(t, h, CommentsFunction, http.MethodPost, "/", map[string]interface{}{
+		"postId":          postId,
+		"parentCommentId": comment.ObjectId,
+		"text":            "thanks",
+	}, &replier)
+
+	// The notifications of the other scenarios which are still sent in the background are skipped
+	received := make(map[uuid.UUID][]string)
+	for count := 3; len(received[owner.UserID])+len(received[commenter.UserID]) < 3; count++ {
+		calls, err := h.WaitForCalls(http.MethodPost, "/notifications", count, callTimeout)
+		if err != nil {
+			t.Fatal(err)
+		}
+		received = make(map[uuid.UUID][]string)
+		for _, call := range calls {
+			var notification Notification
+			if err := json.Unmarshal(call.Body, &notification); err != nil {
+				t.Fatalf("Unmarshal notification %q: %v", string(call.Body), err)
+			}
+			if notification.TargetId == postId {
+				received[notification.NotifyRecieverUserId] = append(received[notification.NotifyRecieverUserId], notification.Type)
+			}
+		}
+	}
+	if got := received[owner.UserID]; len(got) != 2 || got[0] != "comment" || got[1] != "comment" {
+		t.Errorf("got notifications %v for the post owner, want two comments", got)
+	}
+	if got := received[commenter.UserID]; len(got) != 1 || got[0] != "reply" {
+		t.Errorf("got notifications %v for the comment owner, want one reply", got)
+	}
+}
+
+func TestFollowUnknownUserIsNotFound(t *testing.T) {
+	h := newTestHarness(t)
+	follower := newTestUser(t, h, "bob")
+
+	res, err := h.Do(UserRelsFunction, http.MethodPost, "/follow", map[string]interface{}{
+		"right": map[string]interface{}{
+			"userId":   uuid.Must(uuid.NewV4()),
+			"fullName": "nobody",
+		},
+	}, &follower)
+	if err != nil {
+		t.Fatalf("Do: %v", err)
+	}
+	if res.StatusCode != http.StatusNotFound {
+		t.Errorf("got status %d %s, want 404 for a user without a profile", res.StatusCode, string(res.Body))
+	}
+
+	res = do(t, h, UserRelsFunction, http.MethodGet, "/following", nil, &follower)
+	var page userRelsModels.UserRelPageModel
+	if err := res.Decode(&page); err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if len(page.Rels) != 0 {
+		t.Errorf("got %d following, want none", len(page.Rels))
+	}
+}
+
+func TestPartialSuggestionsAreNotCached(t *testing.T) {
+	h := newTestHarness(t)
+	owner := newTestUser(t, h, "alice")
+	commenter := newTestUser(t, h, "bob")
+	voter := newTestUser(t, h, "carol")
+	postId := createPost(t, h, owner, "hello")
+
+	do(t, h, CommentsFunction, http.MethodPost, "/", map[string]interface{}{
+		"postId": postId,
+		"text":   "nice post",
+	}, &commenter)
+	do(t, h, VotesFunction, http.MethodPost, "/", map[string]interface{}{
+		"postId": postId,
+	}, &voter)
+
+	suggestedIds := func() map[uuid.UUID]bool {
+		res := do(t, h, UserRelsFunction, http.MethodGet, "/suggestions", nil, &owner)
+		var suggestions []userRelsDto.SuggestedUser
+		if err := res.Decode(&suggestions); err != nil {
+			t.Fatalf("Decode: %v", err)
+		}
+		ids := make(map[uuid.UUID]bool)
+		for _, suggestedUser := range suggestions {
+			ids[suggestedUser.UserId] = true
+		}
+		return ids
+	}
+
+	// The suggestions are built without the votes while they are not available
+	h.SetUnavailable(VotesFunction)
+	if got := suggestedIds(); !got[commenter.UserID] || got[voter.UserID] {
+		t.Fatalf("got suggestions %v, want the commenter without the voter", got)
+	}
+
+	h.SetAvailable(VotesFunction)
+	if got := suggestedIds(); !got[commenter.UserID] || !got[voter.UserID] {
+		t.Errorf("got suggestions %v, want the commenter and the voter", got)
+	}
+}
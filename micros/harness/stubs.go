@@ -0,0 +1,271 @@
+package harness
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofrs/uuid"
+	"github.com/red-gold/telar-core/utils"
+)
+
+// Profile the profile of a user in the profile stub and in the userProfile collection of the functions
+type Profile struct {
+	ObjectId      uuid.UUID `json:"objectId" bson:"objectId"`
+	FullName      string    `json:"fullName" bson:"fullName"`
+	SocialName    string    `json:"socialName" bson:"socialName"`
+	Avatar        string    `json:"avatar" bson:"avatar"`
+	Banner        string    `json:"banner" bson:"banner"`
+	TagLine       string    `json:"tagLine" bson:"tagLine"`
+	CreatedDate   int64     `json:"created_date" bson:"created_date"`
+	Email         string    `json:"email" bson:"email"`
+	FollowCount   int64     `json:"followCount" bson:"followCount"`
+	FollowerCount int64     `json:"followerCount" bson:"followerCount"`
+	Permission    string    `json:"permission" bson:"permission"`
+}
+
+// ProfileStub the stub of the profile service which keeps the profiles in memory
+type ProfileStub struct {
+	mu       sync.Mutex
+	profiles map[uuid.UUID]*Profile
+}
+
+func newProfileStub() *ProfileStub {
+	return &ProfileStub{profiles: make(map[uuid.UUID]*Profile)}
+}
+
+// reset remove the profiles
+func (s *ProfileStub) reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.profiles = make(map[uuid.UUID]*Profile)
+}
+
+// Add add or replace the profile
+func (s *ProfileStub) Add(profile Profile) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.profiles[profile.ObjectId] = &profile
+}
+
+// Get get a copy of the profile of the user
+func (s *ProfileStub) Get(userId uuid.UUID) (Profile, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	profile, ok := s.profiles[userId]
+	if !ok {
+		return Profile{}, false
+	}
+	return *profile, true
+}
+
+// findBySocialName find the profile with the social name
+func (s *ProfileStub) findBySocialName(socialName string) (Profile, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, profile := range s.profiles {
+		if profile.SocialName == socialName {
+			return *profile, true
+		}
+	}
+	return Profile{}, false
+}
+
+// increment add the value to a counter of the profile
+func (s *ProfileStub) increment(c *fiber.Ctx, counter func(profile *Profile) *int64) error {
+	inc, err := strconv.ParseInt(c.Params("inc"), 10, 64)
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(utils.Error("incIsNotValid", "The inc is not valid!"))
+	}
+	userId, err := uuid.FromString(c.Params("userId"))
+	if err != nil {
+		return c.Status(http.StatusBadRequest).JSON(utils.Error("userIdIsNotValid", "The user id is not valid!"))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	profile, ok := s.profiles[userId]
+	if !ok {
+		return c.Status(http.StatusNotFound).JSON(utils.Error("notFoundProfile", "The profile is not found!"))
+	}
+	*counter(profile) += inc
+	return c.SendStatus(http.StatusOK)
+}
+
+// app the routes of the profile service which the functions call
+func (s *ProfileStub) app() *fiber.App {
+	app := fiber.New()
+	app.Get("/dto/id/:userId", func(c *fiber.Ctx) error {
+		userId, err := uuid.FromString(c.Params("userId"))
+		if err != nil {
+			return c.Status(http.StatusBadRequest).JSON(utils.Error("userIdIsNotValid", "The user id is not valid!"))
+		}
+		profile, ok := s.Get(userId)
+		if !ok {
+			return c.Status(http.StatusNotFound).JSON(utils.Error("notFoundProfile", "The profile is not found!"))
+		}
+		return c.JSON(profile)
+	})
+	app.Post("/dto/ids", func(c *fiber.Ctx) error {
+		model := struct {
+			UserIds []string `json:"userIds"`
+		}{}
+		if err := json.Unmarshal(c.Body(), &model); err != nil {
+			return c.Status(http.StatusBadRequest).JSON(utils.Error("parseModel", "Can not parse the model!"))
+		}
+		profiles := []Profile{}
+		for _, id := range model.UserIds {
+			userId, err := uuid.FromString(id)
+			if err != nil {
+				continue
+			}
+			if profile, ok := s.Get(userId); ok {
+				profiles = append(profiles, profile)
+			}
+		}
+		return c.JSON(profiles)
+	})
+	app.Get("/social/:socialName", func(c *fiber.Ctx) error {
+		profile, ok := s.findBySocialName(c.Params("socialName"))
+		if !ok {
+			return c.Status(http.StatusNotFound).JSON(utils.Error("notFoundProfile", "The profile is not found!"))
+		}
+		return c.JSON(profile)
+	})
+	app.Put("/follow/inc/:inc/:userId", func(c *fiber.Ctx) error {
+		return s.increment(c, func(profile *Profile) *int64 { return &profile.FollowCount })
+	})
+	app.Put("/follower/inc/:inc/:userId", func(c *fiber.Ctx) error {
+		return s.increment(c, func(profile *Profile) *int64 { return &profile.FollowerCount })
+	})
+	app.Post("/dispatch", func(c *fiber.Ctx) error {
+		return c.SendStatus(http.StatusOK)
+	})
+	return app
+}
+
+// Notification a notification which is sent to the notifications service
+type Notification struct {
+	ObjectId             uuid.UUID `json:"objectId"`
+	OwnerUserId          uuid.UUID `json:"ownerUserId"`
+	OwnerDisplayName     string    `json:"ownerDisplayName"`
+	OwnerAvatar          string    `json:"ownerAvatar"`
+	CreatedDate          int64     `json:"created_date"`
+	Title                string    `json:"title"`
+	Description          string    `json:"description"`
+	URL                  string    `json:"url"`
+	NotifyRecieverUserId uuid.UUID `json:"notifyRecieverUserId"`
+	TargetId             uuid.UUID `json:"targetId"`
+	IsSeen               bool      `json:"isSeen"`
+	Type                 string    `json:"type"`
+	EmailNotification    int16     `json:"emailNotification"`
+}
+
+// NotificationStub the stub of the notifications service which records the notifications
+type NotificationStub struct {
+	mu            sync.Mutex
+	notifications []Notification
+}
+
+func newNotificationStub() *NotificationStub {
+	return &NotificationStub{}
+}
+
+// reset remove the notifications
+func (s *NotificationStub) reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.notifications = nil
+}
+
+// All get the received notifications
+func (s *NotificationStub) All() []Notification {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Notification{}, s.notifications...)
+}
+
+// app the routes of the notifications service which the functions call
+func (s *NotificationStub) app() *fiber.App {
+	app := fiber.New()
+	app.Post("/", func(c *fiber.Ctx) error {
+		var notification Notification
+		if err := json.Unmarshal(c.Body(), &notification); err != nil {
+			return c.Status(http.StatusBadRequest).JSON(utils.Error("parseModel", "Can not parse the model!"))
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.notifications = append(s.notifications, notification)
+		return c.SendStatus(http.StatusOK)
+	})
+	app.Delete("/target/:targetId", func(c *fiber.Ctx) error {
+		targetId, err := uuid.FromString(c.Params("targetId"))
+		if err != nil {
+			return c.Status(http.StatusBadRequest).JSON(utils.Error("targetIdIsNotValid", "The target id is not valid!"))
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		kept := s.notifications[:0]
+		for _, notification := range s.notifications {
+			if notification.TargetId != targetId {
+				kept = append(kept, notification)
+			}
+		}
+		s.notifications = kept
+		return c.SendStatus(http.StatusOK)
+	})
+	return app
+}
+
+// Action an action which is dispatched to a user through the actions service
+type Action struct {
+	UserId  uuid.UUID       `json:"-"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+// ActionStub the stub of the actions service which records the dispatched actions
+type ActionStub struct {
+	mu      sync.Mutex
+	actions []Action
+}
+
+func newActionStub() *ActionStub {
+	return &ActionStub{}
+}
+
+// reset remove the actions
+func (s *ActionStub) reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.actions = nil
+}
+
+// All get the dispatched actions
+func (s *ActionStub) All() []Action {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Action{}, s.actions...)
+}
+
+// app the routes of the actions service which the functions call
+func (s *ActionStub) app() *fiber.App {
+	app := fiber.New()
+	app.Post("/dispatch/:userId", func(c *fiber.Ctx) error {
+		userId, err := uuid.FromString(c.Params("userId"))
+		if err != nil {
+			return c.Status(http.StatusBadRequest).JSON(utils.Error("userIdIsNotValid", "The user id is not valid!"))
+		}
+		action := Action{UserId: userId}
+		if err := json.Unmarshal(c.Body(), &action); err != nil {
+			return c.Status(http.StatusBadRequest).JSON(utils.Error("parseModel", "Can not parse the model!"))
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.actions = append(s.actions, action)
+		return c.SendStatus(http.StatusOK)
+	})
+	return app
+}
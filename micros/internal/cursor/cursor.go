@@ -0,0 +1,128 @@
+// Package cursor pages the items of a collection in created date and object id order with opaque cursor tokens.
+// The functions name the created date field differently, so the filter and the sort take the name of the field.
+package cursor
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+
+	uuid "github.com/gofrs/uuid"
+	"github.com/red-gold/telar-core/pkg/log"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const (
+	DefaultPageLimit int64 = 10
+	MaxPageLimit     int64 = 100
+)
+
+// Model the position of the last item of a page in created date and object id order
+type Model struct {
+	CreatedDate int64     `json:"createdDate"`
+	ObjectId    uuid.UUID `json:"objectId"`
+}
+
+// Encode create an opaque cursor token from the position of an item
+func Encode(createdDate int64, objectId uuid.UUID) string {
+	cursorData, err := json.Marshal(Model{CreatedDate: createdDate, ObjectId: objectId})
+	if err != nil {
+		log.Error("Marshal cursor -  %s", err.Error())
+		return ""
+	}
+	return base64.RawURLEncoding.EncodeToString(cursorData)
+}
+
+// Decode read the position of an item from an opaque cursor token
+func Decode(token string) (*Model, error) {
+	if token == "" {
+		return nil, nil
+	}
+	cursorData, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, fmt.Errorf("cursor.Decode/decode")
+	}
+	var cursor Model
+	err = json.Unmarshal(cursorData, &cursor)
+	if err != nil {
+		return nil, fmt.Errorf("cursor.Decode/unmarshal")
+	}
+	return &cursor, nil
+}
+
+// ReadPage read the cursors of a page query, a page is read either after or before a cursor
+func ReadPage(afterToken string, beforeToken string) (*Model, *Model, error) {
+	if afterToken != "" && beforeToken != "" {
+		return nil, nil, fmt.Errorf("cursor.ReadPage/afterAndBefore")
+	}
+	after, err := Decode(afterToken)
+	if err != nil {
+		return nil, nil, err
+	}
+	before, err := Decode(beforeToken)
+	if err != nil {
+		return nil, nil, err
+	}
+	return after, before, nil
+}
+
+// PageCursors get the cursors of the next (older) and previous (newer) pages from the positions of the page items
+func PageCursors(positions []Model, limit int64, readBefore bool) (nextCursor string, prevCursor string) {
+	if len(positions) == 0 {
+		return "", ""
+	}
+	first := positions[0]
+	last := positions[len(positions)-1]
+	if readBefore || int64(len(positions)) == limit {
+		nextCursor = Encode(last.CreatedDate, last.ObjectId)
+	}
+	prevCursor = Encode(first.CreatedDate, first.ObjectId)
+	return nextCursor, prevCursor
+}
+
+// timelineSort sort by the created date then the object id, newest items first or oldest items first
+func timelineSort(dateField string, order int) bson.D {
+	return bson.D{{Key: dateField, Value: order}, {Key: "objectId", Value: order}}
+}
+
+// Filter create the filter that matches the items after the cursor in timeline order, or before the cursor if before is true
+func Filter(dateField string, cursor *Model, before bool) map[string]interface{} {
+	operator := "$lt"
+	if before {
+		operator = "$gt"
+	}
+
+	dateCompare := make(map[string]interface{})
+	dateCompare[operator] = cursor.CreatedDate
+	dateFilter := make(map[string]interface{})
+	dateFilter[dateField] = dateCompare
+
+	idCompare := make(map[string]interface{})
+	idCompare[operator] = cursor.ObjectId
+	sameDateFilter := make(map[string]interface{})
+	sameDateFilter[dateField] = cursor.CreatedDate
+	sameDateFilter["objectId"] = idCompare
+
+	filter := make(map[string]interface{})
+	filter["$or"] = []interface{}{dateFilter, sameDateFilter}
+	return filter
+}
+
+// AddFilter add the cursor condition on the date field to the filter and return the sort of the page.
+// The items read before a cursor come in reverse timeline order and should be reversed by the caller.
+func AddFilter(filter map[string]interface{}, dateField string, after *Model, before *Model) bson.D {
+	var condition map[string]interface{}
+	sort := timelineSort(dateField, -1)
+	if before != nil {
+		condition = Filter(dateField, before, true)
+		sort = timelineSort(dateField, 1)
+	} else if after != nil {
+		condition = Filter(dateField, after, false)
+	}
+
+	if condition != nil {
+		andFilter, _ := filter["$and"].([]interface{})
+		filter["$and"] = append(andFilter, condition)
+	}
+	return sort
+}
@@ -0,0 +1,91 @@
+package cursor
+
+import (
+	"testing"
+
+	uuid "github.com/gofrs/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestEncodeDecode(t *testing.T) {
+	objectId := uuid.Must(uuid.NewV4())
+	cursor, err := Decode(Encode(42, objectId))
+	if err != nil {
+		t.Fatalf("Decode: %s", err)
+	}
+	if cursor.CreatedDate != 42 || cursor.ObjectId != objectId {
+		t.Errorf("got cursor %+v, want the encoded position", cursor)
+	}
+
+	if cursor, err := Decode(""); cursor != nil || err != nil {
+		t.Errorf("got cursor %v and error %v for an empty token, want none", cursor, err)
+	}
+	if _, err := Decode("not a cursor"); err == nil {
+		t.Error("got no error for a token which is not a cursor")
+	}
+}
+
+func TestReadPage(t *testing.T) {
+	token := Encode(1, uuid.Must(uuid.NewV4()))
+	if _, _, err := ReadPage(token, token); err == nil {
+		t.Error("got no error for a page after and before a cursor")
+	}
+	after, before, err := ReadPage(token, "")
+	if err != nil || after == nil || before != nil {
+		t.Errorf("got after %v, before %v and error %v, want the after cursor", after, before, err)
+	}
+}
+
+func TestPageCursors(t *testing.T) {
+	positions := []Model{
+		{CreatedDate: 3, ObjectId: uuid.Must(uuid.NewV4())},
+		{CreatedDate: 2, ObjectId: uuid.Must(uuid.NewV4())},
+	}
+
+	next, prev := PageCursors(positions, 2, false)
+	if next != Encode(2, positions[1].ObjectId) || prev != Encode(3, positions[0].ObjectId) {
+		t.Errorf("got next %q and previous %q, want the last and the first item", next, prev)
+	}
+
+	// A page which is not full is the last page
+	next, _ = PageCursors(positions, 10, false)
+	if next != "" {
+		t.Errorf("got next cursor %q on the last page, want none", next)
+	}
+}
+
+func TestAddFilter(t *testing.T) {
+	cursor := &Model{CreatedDate: 5, ObjectId: uuid.Must(uuid.NewV4())}
+	tests := []struct {
+		name      string
+		dateField string
+		after     *Model
+		before    *Model
+		sort      bson.D
+		condition bool
+	}{
+		{name: "first page", dateField: "created_date", sort: bson.D{{Key: "created_date", Value: -1}, {Key: "objectId", Value: -1}}},
+		{name: "after", dateField: "created_date", after: cursor, sort: bson.D{{Key: "created_date", Value: -1}, {Key: "objectId", Value: -1}}, condition: true},
+		{name: "before", dateField: "createdDate", before: cursor, sort: bson.D{{Key: "createdDate", Value: 1}, {Key: "objectId", Value: 1}}, condition: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			filter := make(map[string]interface{})
+			sort := AddFilter(filter, test.dateField, test.after, test.before)
+			if len(sort) != 2 || sort[0] != test.sort[0] || sort[1] != test.sort[1] {
+				t.Errorf("got sort %v, want %v", sort, test.sort)
+			}
+			andFilter, _ := filter["$and"].([]interface{})
+			if test.condition != (len(andFilter) == 1) {
+				t.Fatalf("got filter %v, want the cursor condition %t", filter, test.condition)
+			}
+			if !test.condition {
+				return
+			}
+			orFilter := andFilter[0].(map[string]interface{})["$or"].([]interface{})
+			if _, ok := orFilter[0].(map[string]interface{})[test.dateField]; !ok {
+				t.Errorf("got condition %v, want it on %s", orFilter, test.dateField)
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+// Package httperr describes why a request is rejected. The helpers of the handlers return an *Error
+// instead of writing the response, and the handler responds with it.
+package httperr
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/red-gold/telar-core/utils"
+)
+
+// Error a rejected request with the status and the error code of the response
+type Error struct {
+	Status  int
+	Code    string
+	Message string
+}
+
+// New create the error of a rejected request
+func New(status int, code string, message string) *Error {
+	return &Error{
+		Status:  status,
+		Code:    code,
+		Message: message,
+	}
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%d %s: %s", e.Status, e.Code, e.Message)
+}
+
+// Respond write the response of the error, the errors which are not an *Error are internal errors
+func Respond(c *fiber.Ctx, err error) error {
+	var reqErr *Error
+	if errors.As(err, &reqErr) {
+		return c.Status(reqErr.Status).JSON(utils.Error(reqErr.Code, reqErr.Message))
+	}
+	return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/error", "Error happened while handling the request!"))
+}
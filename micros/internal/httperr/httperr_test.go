@@ -0,0 +1,53 @@
+package httperr
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestRespond(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		code   string
+	}{
+		{name: "request error", err: New(http.StatusNotFound, "postNotFound", "Post not found!"), status: http.StatusNotFound, code: "postNotFound"},
+		{name: "wrapped request error", err: fmt.Errorf("readPost %w", New(http.StatusBadRequest, "postIdIsNotValid", "Post id is not valid!")), status: http.StatusBadRequest, code: "postIdIsNotValid"},
+		{name: "internal error", err: fmt.Errorf("connection refused"), status: http.StatusInternalServerError, code: "internal/error"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			app := fiber.New()
+			app.Get("/", func(c *fiber.Ctx) error {
+				return Respond(c, test.err)
+			})
+
+			res, err := app.Test(httptest.NewRequest(http.MethodGet, "/", nil))
+			if err != nil {
+				t.Fatalf("Test: %s", err)
+			}
+			if res.StatusCode != test.status {
+				t.Errorf("got status %d, want %d", res.StatusCode, test.status)
+			}
+			body, _ := ioutil.ReadAll(res.Body)
+			var errorBody struct {
+				Error struct {
+					Code string `json:"code"`
+				} `json:"error"`
+			}
+			if err := json.Unmarshal(body, &errorBody); err != nil {
+				t.Fatalf("Unmarshal %s: %s", string(body), err)
+			}
+			if errorBody.Error.Code != test.code {
+				t.Errorf("got code %q, want %q", errorBody.Error.Code, test.code)
+			}
+		})
+	}
+}
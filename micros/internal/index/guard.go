@@ -0,0 +1,34 @@
+package index
+
+import (
+	"sync/atomic"
+
+	"github.com/red-gold/telar-core/config"
+)
+
+// Guard reports whether an index which the writes of a function rely on is created.
+// Once the index is found in mongo it is not looked up again, the indexes are not dropped while the function runs.
+type Guard struct {
+	index Model
+	found int32
+}
+
+// NewGuard create the guard of the index
+func NewGuard(index Model) *Guard {
+	return &Guard{index: index}
+}
+
+// Check whether the index of the guard is created
+func (g *Guard) Check(indexService Service) (bool, error) {
+	if atomic.LoadInt32(&g.found) == 1 {
+		return true, nil
+	}
+	found, err := indexService.HasIndex(g.index)
+	if err != nil || !found {
+		return false, err
+	}
+	if *config.AppConfig.DBType == config.DB_MONGO {
+		atomic.StoreInt32(&g.found, 1)
+	}
+	return true, nil
+}
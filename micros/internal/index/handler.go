@@ -0,0 +1,72 @@
+package index
+
+import (
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/red-gold/telar-core/pkg/log"
+	"github.com/red-gold/telar-core/utils"
+)
+
+// InitHandle handle create the missing indexes of the function
+func InitHandle(newService func() (Service, error)) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+
+		// Create service
+		indexService, serviceErr := newService()
+		if serviceErr != nil {
+			log.Error("NewIndexService %s", serviceErr.Error())
+			return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/indexService", "Error happened while creating indexService!"))
+		}
+
+		created, err := indexService.ReconcileIndexes()
+		if err != nil {
+			log.Error("[InitIndexHandle.ReconcileIndexes] %s", err.Error())
+			return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/createIndex", "Error happened while creating index!"))
+		}
+
+		return c.JSON(fiber.Map{
+			"created": created,
+		})
+	}
+}
+
+// MissingHandle handle report the indexes of the function which are not created
+func MissingHandle(newService func() (Service, error)) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+
+		// Create service
+		indexService, serviceErr := newService()
+		if serviceErr != nil {
+			log.Error("NewIndexService %s", serviceErr.Error())
+			return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/indexService", "Error happened while creating indexService!"))
+		}
+
+		missing, err := indexService.FindMissingIndexes()
+		if err != nil {
+			log.Error("[GetMissingIndexHandle.FindMissingIndexes] %s", err.Error())
+			return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/findMissingIndexes", "Error happened while reading indexes!"))
+		}
+
+		return c.JSON(fiber.Map{
+			"missing": missing,
+		})
+	}
+}
+
+// Reconcile create the indexes which the function needs and are missing in the database, it is run at startup
+func Reconcile(newService func() (Service, error)) error {
+	indexService, err := newService()
+	if err != nil {
+		log.Error("NewIndexService %s", err.Error())
+		return err
+	}
+	created, err := indexService.ReconcileIndexes()
+	for _, index := range created {
+		log.Info("Index %s is created on %s", index.Name, index.Collection)
+	}
+	if err != nil {
+		log.Error("Error reconcile indexes: %s", err.Error())
+	}
+	return err
+}
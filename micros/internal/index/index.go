@@ -0,0 +1,38 @@
+// Package index reconciles the indexes which a function needs with the indexes of its database.
+// Each function declares its required indexes, the indexes which are missing are created at startup and on POST /index.
+package index
+
+import (
+	"fmt"
+	"strings"
+)
+
+// KeyModel is a field of an index, the value is 1, -1 or "text"
+type KeyModel struct {
+	Field string      `json:"field"`
+	Value interface{} `json:"value"`
+}
+
+// Model an index of a collection
+type Model struct {
+	Collection string     `json:"collection"`
+	Name       string     `json:"name"`
+	Keys       []KeyModel `json:"keys"`
+	Unique     bool       `json:"unique"`
+}
+
+// New create an index model from the pairs of field and value, the name is the default name of mongo
+func New(collection string, unique bool, fieldValues ...interface{}) Model {
+	index := Model{
+		Collection: collection,
+		Unique:     unique,
+	}
+	var nameParts []string
+	for i := 0; i+1 < len(fieldValues); i += 2 {
+		field := fieldValues[i].(string)
+		index.Keys = append(index.Keys, KeyModel{Field: field, Value: fieldValues[i+1]})
+		nameParts = append(nameParts, fmt.Sprintf("%s_%v", field, fieldValues[i+1]))
+	}
+	index.Name = strings.Join(nameParts, "_")
+	return index
+}
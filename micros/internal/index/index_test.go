@@ -0,0 +1,92 @@
+package index
+
+import (
+	"testing"
+
+	"github.com/red-gold/telar-core/config"
+	"github.com/red-gold/ts-serverless/micros/internal/inmemory"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestNew(t *testing.T) {
+	index := New("post", true, "tags", 1, "created_date", -1)
+	if index.Name != "tags_1_created_date_-1" {
+		t.Errorf("got name %s, want the default name of mongo", index.Name)
+	}
+	if len(index.Keys) != 2 || index.Keys[1].Field != "created_date" || index.Keys[1].Value != -1 {
+		t.Errorf("got keys %+v, want the fields in order", index.Keys)
+	}
+}
+
+func TestHasIndex(t *testing.T) {
+	unique := New("vote", true, "postId", 1, "ownerUserId", 1)
+	text := New("post", false, "body", "text")
+	existing := []existingIndex{
+		{Name: "postId_1_ownerUserId_1", Key: bson.D{{Key: "postId", Value: int32(1)}, {Key: "ownerUserId", Value: int32(1)}}},
+		{Name: "body_text_title_text", Key: bson.D{{Key: "_fts", Value: "text"}}, Weights: bson.M{"body": 1, "title": 1}},
+	}
+
+	if hasIndex(existing, unique) {
+		t.Error("a non-unique index covers the unique index on the same keys")
+	}
+	if !hasIndex(existing, text) {
+		t.Error("the text index on the fields does not cover the required text index")
+	}
+
+	existing[0].Unique = true
+	if !hasIndex(existing, unique) {
+		t.Error("the unique index on the same keys does not cover the required index")
+	}
+	if hasIndex(existing, New("vote", false, "ownerUserId", 1, "postId", 1)) {
+		t.Error("an index on the keys in another order covers the required index")
+	}
+}
+
+func TestReconcileIndexes(t *testing.T) {
+	dbType := config.DB_INMEMORY
+	config.AppConfig.DBType = &dbType
+	required := []Model{
+		New("vote", true, "postId", 1, "ownerUserId", 1),
+		New("vote", false, "ownerUserId", 1, "created_date", -1),
+	}
+	indexService, err := NewService(inmemory.NewDatabase(), required)
+	if err != nil {
+		t.Fatalf("NewService: %s", err)
+	}
+
+	missing, err := indexService.FindMissingIndexes()
+	if err != nil || len(missing) != 2 {
+		t.Fatalf("got missing %v and error %v, want the required indexes", missing, err)
+	}
+	created, err := indexService.ReconcileIndexes()
+	if err != nil || len(created) != 2 {
+		t.Fatalf("got created %v and error %v, want the required indexes", created, err)
+	}
+	if found, err := indexService.HasIndex(required[0]); err != nil || !found {
+		t.Errorf("got found %v and error %v, want the created index", found, err)
+	}
+	if created, err := indexService.ReconcileIndexes(); err != nil || len(created) != 0 {
+		t.Errorf("got created %v and error %v on the second run, want none", created, err)
+	}
+}
+
+func TestGuard(t *testing.T) {
+	dbType := config.DB_INMEMORY
+	config.AppConfig.DBType = &dbType
+	unique := New("userRel", true, "leftId", 1, "rightId", 1)
+	indexService, err := NewService(inmemory.NewDatabase(), []Model{unique})
+	if err != nil {
+		t.Fatalf("NewService: %s", err)
+	}
+	guard := NewGuard(unique)
+
+	if found, err := guard.Check(indexService); err != nil || found {
+		t.Errorf("got found %v and error %v before the index is created, want not found", found, err)
+	}
+	if _, err := indexService.ReconcileIndexes(); err != nil {
+		t.Fatalf("ReconcileIndexes: %s", err)
+	}
+	if found, err := guard.Check(indexService); err != nil || !found {
+		t.Errorf("got found %v and error %v after the index is created, want found", found, err)
+	}
+}
@@ -0,0 +1,239 @@
+package index
+
+import (
+	"fmt"
+
+	"github.com/red-gold/telar-core/config"
+	"github.com/red-gold/telar-core/data/mongodb"
+	"github.com/red-gold/ts-serverless/micros/internal/inmemory"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Service reads and creates the indexes of a function
+type Service interface {
+	FindMissingIndexes() ([]Model, error)
+	ReconcileIndexes() ([]Model, error)
+	HasIndex(index Model) (bool, error)
+}
+
+// ServiceImpl handlers with injected dependencies
+type ServiceImpl struct {
+	IndexDb  mongodb.MongoDatabase
+	MemoryDb *inmemory.Database
+	Required []Model
+}
+
+// NewService initializes the index Service's dependencies on the required indexes of the function
+func NewService(db interface{}, required []Model) (Service, error) {
+
+	indexService := &ServiceImpl{Required: required}
+
+	switch *config.AppConfig.DBType {
+	case config.DB_MONGO:
+
+		indexService.IndexDb = db.(mongodb.MongoDatabase)
+
+	case config.DB_INMEMORY:
+
+		indexService.MemoryDb = db.(*inmemory.Database)
+
+	}
+
+	return indexService, nil
+}
+
+// existingIndex is the part of an index specification which is compared with the required indexes
+type existingIndex struct {
+	Name    string `bson:"name"`
+	Key     bson.D `bson:"key"`
+	Unique  bool   `bson:"unique"`
+	Weights bson.M `bson:"weights"`
+}
+
+// sameKeys check whether the existing index has the keys of the required index in order
+func sameKeys(current existingIndex, index Model) bool {
+	if len(current.Key) != len(index.Keys) {
+		return false
+	}
+	for i, key := range index.Keys {
+		if current.Key[i].Key != key.Field || fmt.Sprint(current.Key[i].Value) != fmt.Sprint(key.Value) {
+			return false
+		}
+	}
+	return true
+}
+
+// hasIndex check whether one of the existing indexes covers the required index.
+// A text index is covered by a text index on the same fields, since a collection has one text index only.
+func hasIndex(existing []existingIndex, index Model) bool {
+	for _, current := range existing {
+		if index.Unique && !current.Unique {
+			continue
+		}
+		if current.Weights != nil {
+			if coversTextIndex(current, index) {
+				return true
+			}
+			continue
+		}
+		if sameKeys(current, index) {
+			return true
+		}
+	}
+	return false
+}
+
+// coversTextIndex check whether the existing text index has every field of the required index
+func coversTextIndex(current existingIndex, index Model) bool {
+	for _, key := range index.Keys {
+		if key.Value != "text" {
+			return false
+		}
+		if _, ok := current.Weights[key.Field]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// listIndexes read the indexes of the collection
+func (s ServiceImpl) listIndexes(collectionName string) ([]existingIndex, error) {
+	collection, err := s.IndexDb.GetCollection(collectionName)
+	if err != nil {
+		return nil, err
+	}
+	ctx, err := s.IndexDb.GetContext()
+	if err != nil {
+		return nil, err
+	}
+
+	cursor, err := collection.Indexes().List(ctx)
+	if err != nil {
+		// The collection is created with its first document or index
+		if cmdErr, ok := err.(mongo.CommandError); ok && cmdErr.Name == "NamespaceNotFound" {
+			return []existingIndex{}, nil
+		}
+		return nil, err
+	}
+	var existing []existingIndex
+	if err := cursor.All(ctx, &existing); err != nil {
+		return nil, err
+	}
+	return existing, nil
+}
+
+// indexFields get the fields of the index in order
+func indexFields(index Model) []string {
+	fields := make([]string, len(index.Keys))
+	for i, key := range index.Keys {
+		fields[i] = key.Field
+	}
+	return fields
+}
+
+// HasIndex check whether the index is created
+func (s ServiceImpl) HasIndex(index Model) (bool, error) {
+	if s.MemoryDb != nil {
+		return s.MemoryDb.HasIndex(index.Collection, indexFields(index), index.Unique), nil
+	}
+
+	existing, err := s.listIndexes(index.Collection)
+	if err != nil {
+		return false, err
+	}
+	return hasIndex(existing, index), nil
+}
+
+// FindMissingIndexes find the required indexes which are not in the database
+func (s ServiceImpl) FindMissingIndexes() ([]Model, error) {
+	missing := []Model{}
+	if s.MemoryDb != nil {
+		for _, index := range s.Required {
+			if !s.MemoryDb.HasIndex(index.Collection, indexFields(index), index.Unique) {
+				missing = append(missing, index)
+			}
+		}
+		return missing, nil
+	}
+
+	collectionIndexes := make(map[string][]existingIndex)
+	for _, index := range s.Required {
+		existing, ok := collectionIndexes[index.Collection]
+		if !ok {
+			var err error
+			existing, err = s.listIndexes(index.Collection)
+			if err != nil {
+				return nil, err
+			}
+			collectionIndexes[index.Collection] = existing
+		}
+		if !hasIndex(existing, index) {
+			missing = append(missing, index)
+		}
+	}
+	return missing, nil
+}
+
+// ReconcileIndexes create the missing indexes and return the created ones.
+// The indexes which can not be created do not stop the others, the first error is returned.
+func (s ServiceImpl) ReconcileIndexes() ([]Model, error) {
+	missing, err := s.FindMissingIndexes()
+	if err != nil {
+		return nil, err
+	}
+
+	created := []Model{}
+	var firstErr error
+	for _, index := range missing {
+		if err := s.createIndex(index); err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("create index %s on %s: %s", index.Name, index.Collection, err.Error())
+			}
+			continue
+		}
+		created = append(created, index)
+	}
+	return created, firstErr
+}
+
+// createIndex create the index in the database
+func (s ServiceImpl) createIndex(index Model) error {
+	if s.MemoryDb != nil {
+		return s.MemoryDb.CreateIndex(index.Collection, indexFields(index), index.Unique)
+	}
+
+	collection, err := s.IndexDb.GetCollection(index.Collection)
+	if err != nil {
+		return err
+	}
+	ctx, err := s.IndexDb.GetContext()
+	if err != nil {
+		return err
+	}
+
+	// A unique index replaces the index on the same keys which is not unique, mongo does not keep both
+	if index.Unique {
+		existing, err := s.listIndexes(index.Collection)
+		if err != nil {
+			return err
+		}
+		for _, current := range existing {
+			if current.Unique || current.Weights != nil || !sameKeys(current, index) {
+				continue
+			}
+			if _, err := collection.Indexes().DropOne(ctx, current.Name); err != nil {
+				return err
+			}
+		}
+	}
+
+	indexKeys := bson.D{}
+	for _, key := range index.Keys {
+		indexKeys = append(indexKeys, bson.E{Key: key.Field, Value: key.Value})
+	}
+	indexOptions := options.Index().SetName(index.Name).SetBackground(true).SetUnique(index.Unique)
+	_, err = collection.Indexes().CreateOne(ctx, mongo.IndexModel{Keys: indexKeys, Options: indexOptions})
+	return err
+}
@@ -0,0 +1,468 @@
+package inmemory
+
+import (
+	"fmt"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// aggregate run the stages of the pipeline on the documents.
+// The stages are $match, $sort, $skip, $limit, $lookup, $unwind, $project, $addFields, $set, $group and $count.
+func (db *Database) aggregate(docs []bson.D, pipeline []bson.D) ([]bson.D, error) {
+	for _, stageDoc := range pipeline {
+		if len(stageDoc) != 1 {
+			return nil, fmt.Errorf("a stage should have one operator")
+		}
+		stage := stageDoc[0]
+
+		var err error
+		switch stage.Key {
+		case "$match":
+			docs, err = db.matchStage(docs, stage.Value)
+		case "$sort":
+			sortKeys, ok := stage.Value.(bson.D)
+			if !ok {
+				return nil, fmt.Errorf("$sort needs a document")
+			}
+			sortDocuments(docs, sortKeys)
+		case "$skip":
+			skip, ok := normalizeNumber(stage.Value).(float64)
+			if !ok {
+				return nil, fmt.Errorf("$skip needs a number")
+			}
+			if int(skip) >= len(docs) {
+				docs = []bson.D{}
+			} else {
+				docs = docs[int(skip):]
+			}
+		case "$limit":
+			limit, ok := normalizeNumber(stage.Value).(float64)
+			if !ok {
+				return nil, fmt.Errorf("$limit needs a number")
+			}
+			if int(limit) < len(docs) {
+				docs = docs[:int(limit)]
+			}
+		case "$lookup":
+			docs, err = db.lookupStage(docs, stage.Value)
+		case "$unwind":
+			docs, err = unwindStage(docs, stage.Value)
+		case "$project":
+			docs, err = projectStage(docs, stage.Value)
+		case "$addFields", "$set":
+			docs, err = addFieldsStage(docs, stage.Value)
+		case "$group":
+			docs, err = groupStage(docs, stage.Value)
+		case "$count":
+			field, ok := stage.Value.(string)
+			if !ok {
+				return nil, fmt.Errorf("$count needs a field name")
+			}
+			if len(docs) == 0 {
+				docs = []bson.D{}
+			} else {
+				docs = []bson.D{{{Key: field, Value: int32(len(docs))}}}
+			}
+		default:
+			err = fmt.Errorf("the %s stage is not supported", stage.Key)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return docs, nil
+}
+
+// matchStage keep the documents which match the filter
+func (db *Database) matchStage(docs []bson.D, value interface{}) ([]bson.D, error) {
+	filter, ok := value.(bson.D)
+	if !ok {
+		return nil, fmt.Errorf("$match needs a document")
+	}
+	matched := []bson.D{}
+	for _, doc := range docs {
+		ok, err := matchDocument(doc, filter)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			matched = append(matched, doc)
+		}
+	}
+	return matched, nil
+}
+
+// lookupStage join the documents of another collection by equality of the local and foreign fields
+func (db *Database) lookupStage(docs []bson.D, value interface{}) ([]bson.D, error) {
+	spec, ok := value.(bson.D)
+	if !ok {
+		return nil, fmt.Errorf("$lookup needs a document")
+	}
+	from, _ := lookupKey(spec, "from")
+	localField, _ := lookupKey(spec, "localField")
+	foreignField, _ := lookupKey(spec, "foreignField")
+	as, _ := lookupKey(spec, "as")
+	fromName, ok1 := from.(string)
+	localName, ok2 := localField.(string)
+	foreignName, ok3 := foreignField.(string)
+	asName, ok4 := as.(string)
+	if !ok1 || !ok2 || !ok3 || !ok4 {
+		return nil, fmt.Errorf("$lookup needs from, localField, foreignField and as")
+	}
+
+	foreignDocs := db.collections[fromName]
+	joined := make([]bson.D, len(docs))
+	for i, doc := range docs {
+		localValues := expandArrays(resolvePath(doc, strings.Split(localName, ".")))
+		if len(localValues) == 0 {
+			localValues = []interface{}{nil}
+		}
+		matches := bson.A{}
+		for _, foreignDoc := range foreignDocs {
+			foreignValues := resolvePath(foreignDoc, strings.Split(foreignName, "."))
+			for _, localValue := range localValues {
+				if matchEqual(foreignValues, localValue) {
+					matches = append(matches, copyDocument(foreignDoc))
+					break
+				}
+			}
+		}
+		updated, err := setPath(copyDocument(doc), asName, matches)
+		if err != nil {
+			return nil, err
+		}
+		joined[i] = updated
+	}
+	return joined, nil
+}
+
+// unwindStage create a document for each element of the array field
+func unwindStage(docs []bson.D, value interface{}) ([]bson.D, error) {
+	path, preserve := "", false
+	switch typed := value.(type) {
+	case string:
+		path = typed
+	case bson.D:
+		pathValue, _ := lookupKey(typed, "path")
+		path, _ = pathValue.(string)
+		preserveValue, _ := lookupKey(typed, "preserveNullAndEmptyArrays")
+		preserve, _ = preserveValue.(bool)
+	}
+	if !strings.HasPrefix(path, "$") {
+		return nil, fmt.Errorf("$unwind needs a field path")
+	}
+	field := strings.TrimPrefix(path, "$")
+
+	unwound := []bson.D{}
+	for _, doc := range docs {
+		current, ok := getPath(doc, field)
+		array, isArray := current.(bson.A)
+		if !ok || current == nil || (isArray && len(array) == 0) {
+			if preserve {
+				unwound = append(unwound, copyDocument(doc))
+			}
+			continue
+		}
+		if !isArray {
+			unwound = append(unwound, copyDocument(doc))
+			continue
+		}
+		for _, item := range array {
+			updated, err := setPath(copyDocument(doc), field, copyValue(item))
+			if err != nil {
+				return nil, err
+			}
+			unwound = append(unwound, updated)
+		}
+	}
+	return unwound, nil
+}
+
+// projectStage keep the included fields and compute the fields with expressions, _id is kept unless it is excluded
+func projectStage(docs []bson.D, value interface{}) ([]bson.D, error) {
+	spec, ok := value.(bson.D)
+	if !ok {
+		return nil, fmt.Errorf("$project needs a document")
+	}
+
+	exclusion := true
+	for _, field := range spec {
+		if field.Key != "_id" && !isExcluded(field.Value) {
+			exclusion = false
+		}
+	}
+
+	projected := make([]bson.D, len(docs))
+	for i, doc := range docs {
+		if exclusion {
+			result := copyDocument(doc)
+			for _, field := range spec {
+				result = unsetPath(result, field.Key)
+			}
+			projected[i] = result
+			continue
+		}
+
+		result := bson.D{}
+		if id, ok := lookupKey(doc, "_id"); ok {
+			if idSpec, hasSpec := lookupKey(spec, "_id"); !hasSpec || !isExcluded(idSpec) {
+				result = append(result, bson.E{Key: "_id", Value: id})
+			}
+		}
+		for _, field := range spec {
+			if field.Key == "_id" || isExcluded(field.Value) {
+				continue
+			}
+			var fieldValue interface{}
+			if isIncluded(field.Value) {
+				current, ok := getPath(doc, field.Key)
+				if !ok {
+					continue
+				}
+				fieldValue = copyValue(current)
+			} else {
+				computed, ok, err := evaluateExpression(doc, field.Value)
+				if err != nil {
+					return nil, err
+				}
+				if !ok {
+					continue
+				}
+				fieldValue = computed
+			}
+			var err error
+			result, err = setPath(result, field.Key, fieldValue)
+			if err != nil {
+				return nil, err
+			}
+		}
+		projected[i] = result
+	}
+	return projected, nil
+}
+
+// isIncluded check whether the projection value includes the field
+func isIncluded(value interface{}) bool {
+	if included, ok := value.(bool); ok {
+		return included
+	}
+	number, ok := normalizeNumber(value).(float64)
+	return ok && number != 0
+}
+
+// isExcluded check whether the projection value excludes the field
+func isExcluded(value interface{}) bool {
+	if included, ok := value.(bool); ok {
+		return !included
+	}
+	number, ok := normalizeNumber(value).(float64)
+	return ok && number == 0
+}
+
+// addFieldsStage set the fields to the values of the expressions
+func addFieldsStage(docs []bson.D, value interface{}) ([]bson.D, error) {
+	spec, ok := value.(bson.D)
+	if !ok {
+		return nil, fmt.Errorf("$addFields needs a document")
+	}
+	updated := make([]bson.D, len(docs))
+	for i, doc := range docs {
+		result := copyDocument(doc)
+		for _, field := range spec {
+			computed, ok, err := evaluateExpression(doc, field.Value)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				continue
+			}
+			result, err = setPath(result, field.Key, computed)
+			if err != nil {
+				return nil, err
+			}
+		}
+		updated[i] = result
+	}
+	return updated, nil
+}
+
+// evaluateExpression compute an expression, a "$field" path, a literal or a document of expressions.
+// It returns false if the field of the path is missing.
+func evaluateExpression(doc bson.D, expression interface{}) (interface{}, bool, error) {
+	switch typed := expression.(type) {
+	case string:
+		if strings.HasPrefix(typed, "$") {
+			value, ok := getPath(doc, strings.TrimPrefix(typed, "$"))
+			return copyValue(value), ok, nil
+		}
+		return typed, true, nil
+	case bson.D:
+		if isOperatorDocument(typed) {
+			return evaluateOperator(doc, typed)
+		}
+		result := bson.D{}
+		for _, field := range typed {
+			value, ok, err := evaluateExpression(doc, field.Value)
+			if err != nil {
+				return nil, false, err
+			}
+			if ok {
+				result = append(result, bson.E{Key: field.Key, Value: value})
+			}
+		}
+		return result, true, nil
+	case bson.A:
+		result := bson.A{}
+		for _, item := range typed {
+			value, _, err := evaluateExpression(doc, item)
+			if err != nil {
+				return nil, false, err
+			}
+			result = append(result, value)
+		}
+		return result, true, nil
+	}
+	return expression, true, nil
+}
+
+// evaluateOperator compute the expression operators $size, $ifNull and $literal
+func evaluateOperator(doc bson.D, expression bson.D) (interface{}, bool, error) {
+	operator := expression[0]
+	switch operator.Key {
+	case "$literal":
+		return operator.Value, true, nil
+	case "$size":
+		value, _, err := evaluateExpression(doc, operator.Value)
+		if err != nil {
+			return nil, false, err
+		}
+		array, ok := value.(bson.A)
+		if !ok {
+			return nil, false, fmt.Errorf("$size needs an array")
+		}
+		return int32(len(array)), true, nil
+	case "$ifNull":
+		options, ok := operator.Value.(bson.A)
+		if !ok {
+			return nil, false, fmt.Errorf("$ifNull needs an array")
+		}
+		for _, option := range options {
+			value, ok, err := evaluateExpression(doc, option)
+			if err != nil {
+				return nil, false, err
+			}
+			if ok && value != nil {
+				return value, true, nil
+			}
+		}
+		return nil, true, nil
+	}
+	return nil, false, fmt.Errorf("the %s expression is not supported", operator.Key)
+}
+
+// groupStage group the documents by the _id expression and compute the accumulators
+// $sum, $push, $addToSet, $first, $last, $min and $max
+func groupStage(docs []bson.D, value interface{}) ([]bson.D, error) {
+	spec, ok := value.(bson.D)
+	if !ok {
+		return nil, fmt.Errorf("$group needs a document")
+	}
+	idExpression, ok := lookupKey(spec, "_id")
+	if !ok {
+		return nil, fmt.Errorf("$group needs an _id")
+	}
+
+	var groups []bson.D
+	for _, doc := range docs {
+		id, _, err := evaluateExpression(doc, idExpression)
+		if err != nil {
+			return nil, err
+		}
+
+		groupIndex := -1
+		for i, group := range groups {
+			if equalValues(group[0].Value, id) {
+				groupIndex = i
+				break
+			}
+		}
+		if groupIndex < 0 {
+			groups = append(groups, bson.D{{Key: "_id", Value: id}})
+			groupIndex = len(groups) - 1
+		}
+
+		for _, field := range spec {
+			if field.Key == "_id" {
+				continue
+			}
+			accumulator, ok := field.Value.(bson.D)
+			if !ok || len(accumulator) != 1 {
+				return nil, fmt.Errorf("the field %s needs an accumulator", field.Key)
+			}
+			fieldValue, found, err := evaluateExpression(doc, accumulator[0].Value)
+			if err != nil {
+				return nil, err
+			}
+			current, exists := lookupKey(groups[groupIndex], field.Key)
+			next, err := accumulate(accumulator[0].Key, current, exists, fieldValue, found)
+			if err != nil {
+				return nil, err
+			}
+			groups[groupIndex], err = setPath(groups[groupIndex], field.Key, next)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	if groups == nil {
+		groups = []bson.D{}
+	}
+	return groups, nil
+}
+
+// accumulate add the value of a document to the accumulated value of the group
+func accumulate(operator string, current interface{}, exists bool, value interface{}, found bool) (interface{}, error) {
+	switch operator {
+	case "$sum":
+		if !exists {
+			current = int32(0)
+		}
+		if _, ok := normalizeNumber(value).(float64); !found || !ok {
+			return current, nil
+		}
+		return addNumbers(current, value)
+	case "$push", "$addToSet":
+		array, _ := current.(bson.A)
+		if array == nil {
+			array = bson.A{}
+		}
+		if !found {
+			return array, nil
+		}
+		if operator == "$addToSet" && matchEqual([]interface{}{array}, value) {
+			return array, nil
+		}
+		return append(array, value), nil
+	case "$first":
+		if exists {
+			return current, nil
+		}
+		return value, nil
+	case "$last":
+		return value, nil
+	case "$min", "$max":
+		if !found || value == nil {
+			return current, nil
+		}
+		if !exists || current == nil {
+			return value, nil
+		}
+		result := compareValues(value, current)
+		if (operator == "$min" && result < 0) || (operator == "$max" && result > 0) {
+			return value, nil
+		}
+		return current, nil
+	}
+	return nil, fmt.Errorf("the %s accumulator is not supported", operator)
+}
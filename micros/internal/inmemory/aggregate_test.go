@@ -0,0 +1,166 @@
+package inmemory
+
+import (
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// testRel a relation which is joined to its owner item
+type testRel struct {
+	ObjectId string `bson:"objectId"`
+	ItemId   string `bson:"itemId"`
+}
+
+// newTestAggregateRepository create a repository with the items and relations to a, b and a missing item
+func newTestAggregateRepository(t *testing.T) *DataRepositoryInMemory {
+	t.Helper()
+	repo := newTestRepository(t, "item", testItems...)
+	rels := []interface{}{
+		testRel{ObjectId: "r1", ItemId: "a"},
+		testRel{ObjectId: "r2", ItemId: "b"},
+		testRel{ObjectId: "r3", ItemId: "missing"},
+		testRel{ObjectId: "r4", ItemId: "a"},
+	}
+	if result := <-repo.SaveMany("rel", rels); result.Error != nil {
+		t.Fatalf("SaveMany: %s", result.Error)
+	}
+	return repo
+}
+
+// aggregateDocuments run the pipeline and read the documents of the result
+func aggregateDocuments(t *testing.T, repo *DataRepositoryInMemory, collectionName string, pipeline interface{}) []bson.M {
+	t.Helper()
+	result := <-repo.Aggregate(collectionName, pipeline)
+	if err := result.Error(); err != nil {
+		t.Fatalf("Aggregate: %s", err)
+	}
+	docs := []bson.M{}
+	for result.Next() {
+		var doc bson.M
+		if err := result.Decode(&doc); err != nil {
+			t.Fatalf("Decode: %s", err)
+		}
+		delete(doc, "_id")
+		docs = append(docs, doc)
+	}
+	return docs
+}
+
+func TestAggregate(t *testing.T) {
+	repo := newTestAggregateRepository(t)
+	lookupItem := bson.M{"$lookup": bson.M{"from": "item", "localField": "itemId", "foreignField": "objectId", "as": "item"}}
+
+	tests := []struct {
+		name     string
+		pipeline bson.A
+		want     []bson.M
+	}{
+		{
+			name: "$lookup and $unwind",
+			pipeline: bson.A{
+				lookupItem,
+				bson.M{"$unwind": "$item"},
+				bson.M{"$project": bson.M{"objectId": 1, "title": "$item.title"}},
+			},
+			want: []bson.M{
+				{"objectId": "r1", "title": "Golang news"},
+				{"objectId": "r2", "title": "Hello world"},
+				{"objectId": "r4", "title": "Golang news"},
+			},
+		},
+		{
+			name: "$unwind preserve empty",
+			pipeline: bson.A{
+				bson.M{"$match": bson.M{"objectId": "r3"}},
+				lookupItem,
+				bson.M{"$unwind": bson.M{"path": "$item", "preserveNullAndEmptyArrays": true}},
+				bson.M{"$project": bson.M{"objectId": 1, "item": 1}},
+			},
+			want: []bson.M{{"objectId": "r3", "item": bson.A{}}},
+		},
+		{
+			name: "$lookup without matches",
+			pipeline: bson.A{
+				bson.M{"$match": bson.M{"objectId": "r3"}},
+				lookupItem,
+				bson.M{"$project": bson.M{"count": bson.M{"$size": "$item"}}},
+			},
+			want: []bson.M{{"count": int32(0)}},
+		},
+		{
+			name: "$match $sort $skip $limit",
+			pipeline: bson.A{
+				bson.M{"$match": bson.M{"itemId": bson.M{"$ne": "b"}}},
+				bson.M{"$sort": bson.D{{Key: "objectId", Value: -1}}},
+				bson.M{"$skip": 1},
+				bson.M{"$limit": 1},
+				bson.M{"$project": bson.M{"objectId": 1}},
+			},
+			want: []bson.M{{"objectId": "r3"}},
+		},
+		{
+			name: "$group $sum",
+			pipeline: bson.A{
+				bson.M{"$group": bson.M{"_id": "$itemId", "count": bson.M{"$sum": 1}}},
+				bson.M{"$sort": bson.D{{Key: "_id", Value: 1}}},
+				bson.M{"$project": bson.M{"_id": 0, "itemId": "$_id", "count": 1}},
+			},
+			want: []bson.M{
+				{"itemId": "a", "count": int32(2)},
+				{"itemId": "b", "count": int32(1)},
+				{"itemId": "missing", "count": int32(1)},
+			},
+		},
+		{
+			name: "$addFields $ifNull",
+			pipeline: bson.A{
+				bson.M{"$match": bson.M{"objectId": "r1"}},
+				bson.M{"$addFields": bson.M{"owner": bson.M{"$ifNull": bson.A{"$owner", "nobody"}}}},
+				bson.M{"$project": bson.M{"owner": 1}},
+			},
+			want: []bson.M{{"owner": "nobody"}},
+		},
+		{
+			name:     "$count",
+			pipeline: bson.A{bson.M{"$match": bson.M{"itemId": "a"}}, bson.M{"$count": "total"}},
+			want:     []bson.M{{"total": int32(2)}},
+		},
+		{
+			name:     "$count empty",
+			pipeline: bson.A{bson.M{"$match": bson.M{"itemId": "x"}}, bson.M{"$count": "total"}},
+			want:     []bson.M{},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := aggregateDocuments(t, repo, "rel", test.pipeline)
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestAggregateErrors(t *testing.T) {
+	repo := newTestAggregateRepository(t)
+
+	tests := []struct {
+		name     string
+		pipeline interface{}
+	}{
+		{name: "not an array", pipeline: bson.M{"$match": bson.M{}}},
+		{name: "unknown stage", pipeline: bson.A{bson.M{"$facet": bson.M{}}}},
+		{name: "$lookup without as", pipeline: bson.A{bson.M{"$lookup": bson.M{"from": "item"}}}},
+		{name: "$unwind without path", pipeline: bson.A{bson.M{"$unwind": "item"}}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result := <-repo.Aggregate("rel", test.pipeline)
+			if result.Error() == nil {
+				t.Errorf("got no error")
+			}
+		})
+	}
+}
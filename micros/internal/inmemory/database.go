@@ -0,0 +1,127 @@
+// Package inmemory keeps the collections of a function in memory.
+// The repository follows the part of the mongo query language which the services use,
+// so the services and handlers run without a database.
+package inmemory
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// duplicateKeyCode is the mongo error code of a duplicate key, mongo.IsDuplicateKeyError checks it
+const duplicateKeyCode = 11000
+
+// Index is an index of a collection, only the unique indexes are enforced
+type Index struct {
+	Keys   []string
+	Unique bool
+}
+
+// Database is a set of in-memory collections which the repositories share
+type Database struct {
+	mu          sync.RWMutex
+	collections map[string][]bson.D
+	indexes     map[string][]Index
+}
+
+// NewDatabase create an empty in-memory database
+func NewDatabase() *Database {
+	return &Database{
+		collections: make(map[string][]bson.D),
+		indexes:     make(map[string][]Index),
+	}
+}
+
+// Reset remove every document and index
+func (db *Database) Reset() {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.collections = make(map[string][]bson.D)
+	db.indexes = make(map[string][]Index)
+}
+
+// HasIndex check whether the collection has an index on the keys in the given order
+func (db *Database) HasIndex(collectionName string, keys []string, unique bool) bool {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	return db.findIndex(collectionName, keys, unique) >= 0
+}
+
+// CreateIndex create an index on the keys, the existing documents should not break a unique index
+func (db *Database) CreateIndex(collectionName string, keys []string, unique bool) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if db.findIndex(collectionName, keys, unique) >= 0 {
+		return nil
+	}
+
+	index := Index{Keys: keys, Unique: unique}
+	if unique {
+		seen := make(map[string]bool)
+		for _, doc := range db.collections[collectionName] {
+			key := indexKey(doc, index)
+			if seen[key] {
+				return duplicateKeyError(collectionName, index)
+			}
+			seen[key] = true
+		}
+	}
+	db.indexes[collectionName] = append(db.indexes[collectionName], index)
+	return nil
+}
+
+// findIndex find the position of the index, a unique index covers the non unique one
+func (db *Database) findIndex(collectionName string, keys []string, unique bool) int {
+	for i, index := range db.indexes[collectionName] {
+		if unique && !index.Unique {
+			continue
+		}
+		if strings.Join(index.Keys, ",") == strings.Join(keys, ",") {
+			return i
+		}
+	}
+	return -1
+}
+
+// checkUnique check the document against the unique indexes of the collection, the document at skip is ignored
+func (db *Database) checkUnique(collectionName string, doc bson.D, skip int) error {
+	for _, index := range db.indexes[collectionName] {
+		if !index.Unique {
+			continue
+		}
+		key := indexKey(doc, index)
+		for i, current := range db.collections[collectionName] {
+			if i != skip && indexKey(current, index) == key {
+				return duplicateKeyError(collectionName, index)
+			}
+		}
+	}
+	return nil
+}
+
+// indexKey create the comparable key of the document in the index
+func indexKey(doc bson.D, index Index) string {
+	parts := make([]string, len(index.Keys))
+	for i, key := range index.Keys {
+		value, _ := getPath(doc, key)
+		parts[i] = fmt.Sprintf("%#v", normalizeNumber(value))
+	}
+	return strings.Join(parts, "|")
+}
+
+// duplicateKeyError create the same error which mongo returns for a duplicate key
+func duplicateKeyError(collectionName string, index Index) error {
+	return mongo.WriteException{
+		WriteErrors: mongo.WriteErrors{
+			{
+				Code:    duplicateKeyCode,
+				Message: fmt.Sprintf("E11000 duplicate key error collection: %s index: %s", collectionName, strings.Join(index.Keys, "_")),
+			},
+		},
+	}
+}
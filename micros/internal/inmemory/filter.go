@@ -0,0 +1,295 @@
+package inmemory
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// matchDocument check whether the document matches the filter
+func matchDocument(doc bson.D, filter bson.D) (bool, error) {
+	for _, elem := range filter {
+		matched, err := matchElement(doc, elem)
+		if err != nil || !matched {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// matchElement check one key of the filter, a logical operator or a field condition
+func matchElement(doc bson.D, elem bson.E) (bool, error) {
+	switch elem.Key {
+	case "$and", "$or", "$nor":
+		conditions, ok := elem.Value.(bson.A)
+		if !ok {
+			return false, fmt.Errorf("%s needs an array", elem.Key)
+		}
+		for _, condition := range conditions {
+			conditionDoc, ok := condition.(bson.D)
+			if !ok {
+				return false, fmt.Errorf("%s needs an array of documents", elem.Key)
+			}
+			matched, err := matchDocument(doc, conditionDoc)
+			if err != nil {
+				return false, err
+			}
+			if elem.Key == "$and" && !matched {
+				return false, nil
+			}
+			if elem.Key == "$or" && matched {
+				return true, nil
+			}
+			if elem.Key == "$nor" && matched {
+				return false, nil
+			}
+		}
+		return elem.Key != "$or", nil
+	case "$text":
+		return matchText(doc, elem.Value)
+	}
+	if strings.HasPrefix(elem.Key, "$") {
+		return false, fmt.Errorf("the %s operator is not supported", elem.Key)
+	}
+	return matchField(doc, elem.Key, elem.Value)
+}
+
+// matchField check the condition of a field, the condition is a value or a document of operators
+func matchField(doc bson.D, path string, condition interface{}) (bool, error) {
+	values := resolvePath(doc, strings.Split(path, "."))
+	if operators, ok := condition.(bson.D); ok && isOperatorDocument(operators) {
+		for _, operator := range operators {
+			matched, err := matchOperator(values, operator, operators)
+			if err != nil || !matched {
+				return false, err
+			}
+		}
+		return true, nil
+	}
+	return matchEqual(values, condition), nil
+}
+
+// isOperatorDocument check whether the keys of the document are query operators
+func isOperatorDocument(doc bson.D) bool {
+	return len(doc) > 0 && strings.HasPrefix(doc[0].Key, "$")
+}
+
+// matchEqual check whether a value of the field or an element of an array field equals the value.
+// A nil value matches a missing field.
+func matchEqual(values []interface{}, value interface{}) bool {
+	if value == nil && len(values) == 0 {
+		return true
+	}
+	for _, current := range values {
+		if equalValues(current, value) {
+			return true
+		}
+		if array, ok := current.(bson.A); ok {
+			for _, item := range array {
+				if equalValues(item, value) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// matchOperator check the values of the field against a query operator
+func matchOperator(values []interface{}, operator bson.E, operators bson.D) (bool, error) {
+	switch operator.Key {
+	case "$eq":
+		return matchEqual(values, operator.Value), nil
+	case "$ne":
+		return !matchEqual(values, operator.Value), nil
+	case "$gt", "$gte", "$lt", "$lte":
+		for _, current := range expandArrays(values) {
+			if typeOrder(current) != typeOrder(operator.Value) {
+				continue
+			}
+			result := compareValues(current, operator.Value)
+			if (operator.Key == "$gt" && result > 0) || (operator.Key == "$gte" && result >= 0) ||
+				(operator.Key == "$lt" && result < 0) || (operator.Key == "$lte" && result <= 0) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case "$in", "$nin":
+		options, ok := operator.Value.(bson.A)
+		if !ok {
+			return false, fmt.Errorf("%s needs an array", operator.Key)
+		}
+		found := false
+		for _, option := range options {
+			if matchEqual(values, option) {
+				found = true
+				break
+			}
+		}
+		return found == (operator.Key == "$in"), nil
+	case "$all":
+		options, ok := operator.Value.(bson.A)
+		if !ok {
+			return false, fmt.Errorf("$all needs an array")
+		}
+		for _, option := range options {
+			if !matchEqual(values, option) {
+				return false, nil
+			}
+		}
+		return len(options) > 0, nil
+	case "$exists":
+		exists, _ := operator.Value.(bool)
+		return (len(values) > 0) == exists, nil
+	case "$size":
+		size, ok := normalizeNumber(operator.Value).(float64)
+		if !ok {
+			return false, fmt.Errorf("$size needs a number")
+		}
+		for _, current := range values {
+			if array, ok := current.(bson.A); ok && float64(len(array)) == size {
+				return true, nil
+			}
+		}
+		return false, nil
+	case "$regex":
+		options, _ := lookupKey(operators, "$options")
+		return matchRegex(values, operator.Value, options)
+	case "$options":
+		return true, nil
+	case "$not":
+		notOperators, ok := operator.Value.(bson.D)
+		if !ok {
+			return false, fmt.Errorf("$not needs a document of operators")
+		}
+		for _, notOperator := range notOperators {
+			matched, err := matchOperator(values, notOperator, notOperators)
+			if err != nil {
+				return false, err
+			}
+			if !matched {
+				return true, nil
+			}
+		}
+		return false, nil
+	case "$elemMatch":
+		condition, ok := operator.Value.(bson.D)
+		if !ok {
+			return false, fmt.Errorf("$elemMatch needs a document")
+		}
+		for _, current := range values {
+			array, ok := current.(bson.A)
+			if !ok {
+				continue
+			}
+			for _, item := range array {
+				matched, err := matchItem(item, condition)
+				if err != nil {
+					return false, err
+				}
+				if matched {
+					return true, nil
+				}
+			}
+		}
+		return false, nil
+	}
+	return false, fmt.Errorf("the %s operator is not supported", operator.Key)
+}
+
+// matchItem check an element of an array against a condition of $elemMatch or $pull
+func matchItem(item interface{}, condition interface{}) (bool, error) {
+	conditionDoc, ok := condition.(bson.D)
+	if !ok {
+		return equalValues(item, condition), nil
+	}
+	if isOperatorDocument(conditionDoc) {
+		for _, operator := range conditionDoc {
+			matched, err := matchOperator([]interface{}{item}, operator, conditionDoc)
+			if err != nil || !matched {
+				return false, err
+			}
+		}
+		return true, nil
+	}
+	itemDoc, ok := item.(bson.D)
+	if !ok {
+		return false, nil
+	}
+	return matchDocument(itemDoc, conditionDoc)
+}
+
+// matchRegex check whether a string value matches the pattern
+func matchRegex(values []interface{}, pattern interface{}, options interface{}) (bool, error) {
+	var expression string
+	flags, _ := options.(string)
+	switch typed := pattern.(type) {
+	case string:
+		expression = typed
+	case primitive.Regex:
+		expression = typed.Pattern
+		flags += typed.Options
+	default:
+		return false, fmt.Errorf("$regex needs a string")
+	}
+	if strings.Contains(flags, "i") {
+		expression = "(?i)" + expression
+	}
+	compiled, err := regexp.Compile(expression)
+	if err != nil {
+		return false, err
+	}
+	for _, current := range expandArrays(values) {
+		if text, ok := current.(string); ok && compiled.MatchString(text) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// matchText approximate a $text search, a document matches if one of its strings contains one of the search words
+func matchText(doc bson.D, value interface{}) (bool, error) {
+	textDoc, ok := value.(bson.D)
+	if !ok {
+		return false, fmt.Errorf("$text needs a document")
+	}
+	search, _ := lookupKey(textDoc, "$search")
+	searchText, ok := search.(string)
+	if !ok {
+		return false, fmt.Errorf("$text needs a $search string")
+	}
+	words := strings.Fields(strings.ToLower(searchText))
+	if len(words) == 0 {
+		return false, nil
+	}
+	return containsWord(doc, words), nil
+}
+
+// containsWord check whether one of the strings in the value contains one of the words
+func containsWord(value interface{}, words []string) bool {
+	switch typed := value.(type) {
+	case string:
+		text := strings.ToLower(typed)
+		for _, word := range words {
+			if strings.Contains(text, strings.Trim(word, "\"")) {
+				return true
+			}
+		}
+	case bson.D:
+		for _, elem := range typed {
+			if containsWord(elem.Value, words) {
+				return true
+			}
+		}
+	case bson.A:
+		for _, item := range typed {
+			if containsWord(item, words) {
+				return true
+			}
+		}
+	}
+	return false
+}
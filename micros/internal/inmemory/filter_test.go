@@ -0,0 +1,86 @@
+package inmemory
+
+import (
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestFilter(t *testing.T) {
+	repo := newTestRepository(t, "item", testItems...)
+
+	tests := []struct {
+		name   string
+		filter interface{}
+		want   []string
+	}{
+		{name: "empty", filter: bson.M{}, want: []string{"a", "b", "c", "d"}},
+		{name: "equal", filter: bson.M{"title": "Hello world"}, want: []string{"b"}},
+		{name: "equal array element", filter: bson.M{"tags": "go"}, want: []string{"a", "c"}},
+		{name: "missing field", filter: bson.M{"owner": nil}, want: []string{"a", "b", "c", "d"}},
+		{name: "$eq", filter: bson.M{"score": bson.M{"$eq": 2}}, want: []string{"c"}},
+		{name: "$ne", filter: bson.M{"deleted": bson.M{"$ne": true}}, want: []string{"a", "b", "d"}},
+		{name: "$gt", filter: bson.M{"score": bson.M{"$gt": 2}}, want: []string{"a", "d"}},
+		{name: "$gte and $lt", filter: bson.M{"score": bson.M{"$gte": 2, "$lt": 5}}, want: []string{"a", "c"}},
+		{name: "$lte", filter: bson.M{"score": bson.M{"$lte": 1}}, want: []string{"b"}},
+		{name: "$in", filter: bson.M{"objectId": bson.M{"$in": bson.A{"a", "d", "x"}}}, want: []string{"a", "d"}},
+		{name: "$in array field", filter: bson.M{"tags": bson.M{"$in": []string{"news", "hello"}}}, want: []string{"a", "b"}},
+		{name: "$in empty", filter: bson.M{"objectId": bson.M{"$in": bson.A{}}}, want: []string{}},
+		{name: "$nin", filter: bson.M{"objectId": bson.M{"$nin": bson.A{"a", "d"}}}, want: []string{"b", "c"}},
+		{name: "$all", filter: bson.M{"tags": bson.M{"$all": bson.A{"go", "news"}}}, want: []string{"a"}},
+		{name: "$exists", filter: bson.M{"owner": bson.M{"$exists": false}}, want: []string{"a", "b", "c", "d"}},
+		{name: "$size", filter: bson.M{"tags": bson.M{"$size": 0}}, want: []string{"d"}},
+		{name: "$regex", filter: bson.M{"title": bson.M{"$regex": "^hel", "$options": "i"}}, want: []string{"b"}},
+		{name: "$elemMatch", filter: bson.M{"tags": bson.M{"$elemMatch": bson.M{"$eq": "hello"}}}, want: []string{"b"}},
+		{name: "$not", filter: bson.M{"score": bson.M{"$not": bson.M{"$gt": 1}}}, want: []string{"b"}},
+		{
+			name:   "$and",
+			filter: bson.M{"$and": bson.A{bson.M{"tags": "go"}, bson.M{"deleted": false}}},
+			want:   []string{"a"},
+		},
+		{
+			name:   "$or",
+			filter: bson.M{"$or": bson.A{bson.M{"objectId": "b"}, bson.M{"score": 5}}},
+			want:   []string{"b", "d"},
+		},
+		{
+			name:   "$nor",
+			filter: bson.M{"$nor": bson.A{bson.M{"objectId": "b"}, bson.M{"score": 5}}},
+			want:   []string{"a", "c"},
+		},
+		{name: "$text", filter: bson.M{"$text": bson.M{"$search": "golang"}}, want: []string{"a"}},
+		{name: "$text any word", filter: bson.M{"$text": bson.M{"$search": "WORLD photos"}}, want: []string{"b", "d"}},
+		{name: "$text array", filter: bson.M{"$text": bson.M{"$search": "news"}}, want: []string{"a"}},
+		{name: "$text empty", filter: bson.M{"$text": bson.M{"$search": " "}}, want: []string{}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := readIds(t, <-repo.Find("item", test.filter, 0, 0, nil))
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestFilterErrors(t *testing.T) {
+	repo := newTestRepository(t, "item", testItems...)
+
+	tests := []struct {
+		name   string
+		filter interface{}
+	}{
+		{name: "unknown operator", filter: bson.M{"$where": "true"}},
+		{name: "$or needs an array", filter: bson.M{"$or": bson.M{"objectId": "a"}}},
+		{name: "$text needs a search", filter: bson.M{"$text": bson.M{}}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result := <-repo.Find("item", test.filter, 0, 0, nil)
+			if result.Error() == nil {
+				t.Errorf("got no error")
+			}
+		})
+	}
+}
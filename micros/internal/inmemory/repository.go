@@ -0,0 +1,329 @@
+package inmemory
+
+import (
+	"bytes"
+	"fmt"
+
+	coreData "github.com/red-gold/telar-core/data"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// DataRepositoryInMemory is a repository on the collections of an in-memory database
+type DataRepositoryInMemory struct {
+	Db *Database
+}
+
+// NewDataRepositoryInMemory create new data repository for the in-memory database.
+func NewDataRepositoryInMemory(db *Database) coreData.Repository {
+	return &DataRepositoryInMemory{Db: db}
+}
+
+// repositoryResult send the result on a closed channel
+func repositoryResult(result interface{}, err error) <-chan coreData.RepositoryResult {
+	r := make(chan coreData.RepositoryResult, 1)
+	r <- coreData.RepositoryResult{Result: result, Error: err}
+	close(r)
+	return r
+}
+
+// queryResult send the documents on a closed channel
+func queryResult(docs []bson.D, err error) <-chan coreData.QueryResult {
+	r := make(chan coreData.QueryResult, 1)
+	r <- &DataResult{docs: docs, index: -1, err: err}
+	close(r)
+	return r
+}
+
+// newStoredDocument convert the data to a document with an _id
+func newStoredDocument(data interface{}) (bson.D, error) {
+	doc, err := toDocument(data)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := lookupKey(doc, "_id"); !ok {
+		doc = append(bson.D{{Key: "_id", Value: primitive.NewObjectID()}}, doc...)
+	}
+	return doc, nil
+}
+
+// CreateIndex creates an index on each field of the map, the order and the type of the index are not kept.
+func (m *DataRepositoryInMemory) CreateIndex(collectionName string, indexes map[string]interface{}) <-chan error {
+	r := make(chan error, 1)
+	var err error
+	for key := range indexes {
+		if err = m.Db.CreateIndex(collectionName, []string{key}, false); err != nil {
+			break
+		}
+	}
+	r <- err
+	close(r)
+	return r
+}
+
+// Save storing the data object.
+func (m *DataRepositoryInMemory) Save(collectionName string, data interface{}) <-chan coreData.RepositoryResult {
+	doc, err := newStoredDocument(data)
+	if err != nil {
+		return repositoryResult(nil, err)
+	}
+
+	m.Db.mu.Lock()
+	defer m.Db.mu.Unlock()
+	if err := m.Db.checkUnique(collectionName, doc, -1); err != nil {
+		return repositoryResult(nil, err)
+	}
+	m.Db.collections[collectionName] = append(m.Db.collections[collectionName], doc)
+	return repositoryResult(nil, nil)
+}
+
+// SaveMany storing a list of objects, like an unordered insert the valid objects are stored if one fails.
+func (m *DataRepositoryInMemory) SaveMany(collectionName string, data []interface{}) <-chan coreData.RepositoryResult {
+	m.Db.mu.Lock()
+	defer m.Db.mu.Unlock()
+
+	var firstErr error
+	for _, item := range data {
+		doc, err := newStoredDocument(item)
+		if err == nil {
+			err = m.Db.checkUnique(collectionName, doc, -1)
+		}
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		m.Db.collections[collectionName] = append(m.Db.collections[collectionName], doc)
+	}
+	return repositoryResult(nil, firstErr)
+}
+
+// Aggregate run the pipeline on the collection.
+func (m *DataRepositoryInMemory) Aggregate(collectionName string, pipeline interface{}) <-chan coreData.QueryResult {
+	stages, err := toStages(pipeline)
+	if err != nil {
+		return queryResult(nil, err)
+	}
+
+	m.Db.mu.RLock()
+	defer m.Db.mu.RUnlock()
+	docs, err := m.Db.aggregate(m.Db.copyCollection(collectionName), stages)
+	return queryResult(docs, err)
+}
+
+// toStages convert the stages of the pipeline to documents
+func toStages(pipeline interface{}) ([]bson.D, error) {
+	value, err := toValue(pipeline)
+	if err != nil {
+		return nil, err
+	}
+	items, ok := value.(bson.A)
+	if !ok {
+		if value == nil {
+			return []bson.D{}, nil
+		}
+		return nil, fmt.Errorf("the pipeline should be an array of stages")
+	}
+	stages := make([]bson.D, len(items))
+	for i, item := range items {
+		stage, ok := item.(bson.D)
+		if !ok {
+			return nil, fmt.Errorf("the stage %d is not a document", i)
+		}
+		stages[i] = stage
+	}
+	return stages, nil
+}
+
+// copyCollection copy the documents of the collection
+func (db *Database) copyCollection(collectionName string) []bson.D {
+	docs := make([]bson.D, len(db.collections[collectionName]))
+	for i, doc := range db.collections[collectionName] {
+		docs[i] = copyDocument(doc)
+	}
+	return docs
+}
+
+// findDocuments find the documents which match the filter, sorted and paged
+func (db *Database) findDocuments(collectionName string, filter interface{}, limit int64, skip int64, sortKeys bson.D) ([]bson.D, error) {
+	filterDoc, err := toDocument(filter)
+	if err != nil {
+		return nil, err
+	}
+	docs, err := db.matchStage(db.copyCollection(collectionName), filterDoc)
+	if err != nil {
+		return nil, err
+	}
+	sortDocuments(docs, sortKeys)
+	if skip > 0 {
+		if skip >= int64(len(docs)) {
+			return []bson.D{}, nil
+		}
+		docs = docs[skip:]
+	}
+	if limit > 0 && limit < int64(len(docs)) {
+		docs = docs[:limit]
+	}
+	return docs, nil
+}
+
+// Find get list of object.
+func (m *DataRepositoryInMemory) Find(collectionName string, filter interface{}, limit int64, skip int64, sort map[string]int) <-chan coreData.QueryResult {
+	m.Db.mu.RLock()
+	defer m.Db.mu.RUnlock()
+	docs, err := m.Db.findDocuments(collectionName, filter, limit, skip, mapSort(sort))
+	return queryResult(docs, err)
+}
+
+// FindOne get object list
+func (m *DataRepositoryInMemory) FindOne(collectionName string, filter interface{}) <-chan coreData.QuerySingleResult {
+	r := make(chan coreData.QuerySingleResult, 1)
+	defer close(r)
+
+	m.Db.mu.RLock()
+	defer m.Db.mu.RUnlock()
+	docs, err := m.Db.findDocuments(collectionName, filter, 1, 0, nil)
+	if err != nil {
+		r <- &DataSingleResult{err: err}
+		return r
+	}
+	if len(docs) == 0 {
+		r <- &DataSingleResult{err: coreData.ErrNoDocuments}
+		return r
+	}
+	r <- &DataSingleResult{doc: docs[0]}
+	return r
+}
+
+// updateDocuments update the documents which match the filter and return the number of modified documents
+func (db *Database) updateDocuments(collectionName string, filter interface{}, data interface{}, justOne bool, opts ...*coreData.UpdateOptions) (int64, error) {
+	filterDoc, err := toDocument(filter)
+	if err != nil {
+		return 0, err
+	}
+	update, err := toDocument(data)
+	if err != nil {
+		return 0, err
+	}
+	updateOptions := coreData.MergeUpdateOptions(opts...)
+	if updateOptions.ArrayFilters != nil && len(updateOptions.ArrayFilters.Filters) > 0 {
+		return 0, fmt.Errorf("array filters are not supported")
+	}
+
+	matchedCount := 0
+	var modifiedCount int64
+	for i, doc := range db.collections[collectionName] {
+		matched, err := matchDocument(doc, filterDoc)
+		if err != nil {
+			return modifiedCount, err
+		}
+		if !matched {
+			continue
+		}
+		matchedCount++
+
+		updated, err := applyUpdate(doc, update, false)
+		if err != nil {
+			return modifiedCount, err
+		}
+		if !sameDocument(doc, updated) {
+			if err := db.checkUnique(collectionName, updated, i); err != nil {
+				return modifiedCount, err
+			}
+			db.collections[collectionName][i] = updated
+			modifiedCount++
+		}
+		if justOne {
+			break
+		}
+	}
+
+	// The upserted document is not counted as modified
+	if matchedCount == 0 && updateOptions.Upsert != nil && *updateOptions.Upsert {
+		doc, err := upsertDocument(filterDoc)
+		if err != nil {
+			return 0, err
+		}
+		inserted, err := applyUpdate(doc, update, true)
+		if err != nil {
+			return 0, err
+		}
+		if err := db.checkUnique(collectionName, inserted, -1); err != nil {
+			return 0, err
+		}
+		db.collections[collectionName] = append(db.collections[collectionName], inserted)
+	}
+	return modifiedCount, nil
+}
+
+// sameDocument check whether the documents have the same encoding
+func sameDocument(a, b bson.D) bool {
+	dataA, errA := bson.Marshal(a)
+	dataB, errB := bson.Marshal(b)
+	return errA == nil && errB == nil && bytes.Equal(dataA, dataB)
+}
+
+// Update update object.
+func (m *DataRepositoryInMemory) Update(collectionName string, filter interface{}, data interface{}, opts ...*coreData.UpdateOptions) <-chan coreData.RepositoryResult {
+	m.Db.mu.Lock()
+	defer m.Db.mu.Unlock()
+	modifiedCount, err := m.Db.updateDocuments(collectionName, filter, data, true, opts...)
+	return repositoryResult(modifiedCount, err)
+}
+
+// UpdateMany update many objects.
+func (m *DataRepositoryInMemory) UpdateMany(collectionName string, filter interface{}, data interface{}, opts ...*coreData.UpdateOptions) <-chan coreData.RepositoryResult {
+	m.Db.mu.Lock()
+	defer m.Db.mu.Unlock()
+	modifiedCount, err := m.Db.updateDocuments(collectionName, filter, data, false, opts...)
+	return repositoryResult(modifiedCount, err)
+}
+
+// BulkUpdateOne update one object for each item of the bulk, the items after a failed one are still applied.
+func (m *DataRepositoryInMemory) BulkUpdateOne(collectionName string, bulkData []coreData.BulkUpdateOne) <-chan coreData.RepositoryResult {
+	m.Db.mu.Lock()
+	defer m.Db.mu.Unlock()
+
+	var modifiedCount int64
+	var firstErr error
+	for _, item := range bulkData {
+		count, err := m.Db.updateDocuments(collectionName, item.Filter, item.Data, true)
+		modifiedCount += count
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return repositoryResult(modifiedCount, firstErr)
+}
+
+// Delete remove the objects which match the filter
+func (m *DataRepositoryInMemory) Delete(collectionName string, filter interface{}, justOne bool) <-chan coreData.RepositoryResult {
+	filterDoc, err := toDocument(filter)
+	if err != nil {
+		return repositoryResult(int64(0), err)
+	}
+
+	m.Db.mu.Lock()
+	defer m.Db.mu.Unlock()
+
+	kept := []bson.D{}
+	var deletedCount int64
+	for _, doc := range m.Db.collections[collectionName] {
+		if justOne && deletedCount > 0 {
+			kept = append(kept, doc)
+			continue
+		}
+		matched, err := matchDocument(doc, filterDoc)
+		if err != nil {
+			return repositoryResult(int64(0), err)
+		}
+		if matched {
+			deletedCount++
+			continue
+		}
+		kept = append(kept, doc)
+	}
+	m.Db.collections[collectionName] = kept
+	return repositoryResult(deletedCount, nil)
+}
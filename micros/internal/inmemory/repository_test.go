@@ -0,0 +1,209 @@
+package inmemory
+
+import (
+	"reflect"
+	"testing"
+
+	coreData "github.com/red-gold/telar-core/data"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// testItem the document which the tests store
+type testItem struct {
+	ObjectId string   `bson:"objectId"`
+	Title    string   `bson:"title"`
+	Score    int64    `bson:"score"`
+	Tags     []string `bson:"tags"`
+	Deleted  bool     `bson:"deleted"`
+}
+
+// testItems the documents of the tests, the order of the scores differs from the order of the ids
+var testItems = []testItem{
+	{ObjectId: "a", Title: "Golang news", Score: 3, Tags: []string{"go", "news"}},
+	{ObjectId: "b", Title: "Hello world", Score: 1, Tags: []string{"hello"}},
+	{ObjectId: "c", Title: "Deleted post", Score: 2, Tags: []string{"go"}, Deleted: true},
+	{ObjectId: "d", Title: "Weekend photos", Score: 5, Tags: []string{}},
+}
+
+// newTestRepository create a repository with the items in the collection
+func newTestRepository(t *testing.T, collectionName string, items ...testItem) *DataRepositoryInMemory {
+	t.Helper()
+	repo := &DataRepositoryInMemory{Db: NewDatabase()}
+	for _, item := range items {
+		if result := <-repo.Save(collectionName, item); result.Error != nil {
+			t.Fatalf("Save %s: %s", item.ObjectId, result.Error)
+		}
+	}
+	return repo
+}
+
+// readIds read the object ids of the query result
+func readIds(t *testing.T, result coreData.QueryResult) []string {
+	t.Helper()
+	if err := result.Error(); err != nil {
+		t.Fatalf("query: %s", err)
+	}
+	ids := []string{}
+	for result.Next() {
+		var item testItem
+		if err := result.Decode(&item); err != nil {
+			t.Fatalf("Decode: %s", err)
+		}
+		ids = append(ids, item.ObjectId)
+	}
+	return ids
+}
+
+// findItem find the item with the object id
+func findItem(t *testing.T, repo *DataRepositoryInMemory, collectionName string, objectId string) testItem {
+	t.Helper()
+	result := <-repo.FindOne(collectionName, bson.M{"objectId": objectId})
+	if err := result.Error(); err != nil {
+		t.Fatalf("FindOne %s: %s", objectId, err)
+	}
+	var item testItem
+	if err := result.Decode(&item); err != nil {
+		t.Fatalf("Decode %s: %s", objectId, err)
+	}
+	return item
+}
+
+func TestFindSortSkipLimit(t *testing.T) {
+	repo := newTestRepository(t, "item", testItems...)
+
+	tests := []struct {
+		name  string
+		limit int64
+		skip  int64
+		sort  map[string]int
+		want  []string
+	}{
+		{name: "insertion order", want: []string{"a", "b", "c", "d"}},
+		{name: "ascending", sort: map[string]int{"score": 1}, want: []string{"b", "c", "a", "d"}},
+		{name: "descending", sort: map[string]int{"score": -1}, want: []string{"d", "a", "c", "b"}},
+		{name: "limit", limit: 2, sort: map[string]int{"score": -1}, want: []string{"d", "a"}},
+		{name: "skip", skip: 1, sort: map[string]int{"score": -1}, want: []string{"a", "c", "b"}},
+		{name: "skip and limit", skip: 1, limit: 2, sort: map[string]int{"score": -1}, want: []string{"a", "c"}},
+		{name: "skip past the end", skip: 10, want: []string{}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := readIds(t, <-repo.Find("item", bson.M{}, test.limit, test.skip, test.sort))
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestFindOneNoDocuments(t *testing.T) {
+	repo := newTestRepository(t, "item", testItems...)
+
+	result := <-repo.FindOne("item", bson.M{"objectId": "missing"})
+	if result.Error() != coreData.ErrNoDocuments {
+		t.Errorf("got error %v, want %v", result.Error(), coreData.ErrNoDocuments)
+	}
+}
+
+func TestUniqueIndex(t *testing.T) {
+	repo := newTestRepository(t, "item", testItems...)
+	if err := repo.Db.CreateIndex("item", []string{"objectId"}, true); err != nil {
+		t.Fatalf("CreateIndex: %s", err)
+	}
+
+	tests := []struct {
+		name string
+		run  func() error
+	}{
+		{
+			name: "save",
+			run:  func() error { return (<-repo.Save("item", testItem{ObjectId: "a"})).Error },
+		},
+		{
+			name: "save many",
+			run: func() error {
+				return (<-repo.SaveMany("item", []interface{}{testItem{ObjectId: "e"}, testItem{ObjectId: "b"}})).Error
+			},
+		},
+		{
+			name: "update",
+			run: func() error {
+				return (<-repo.Update("item", bson.M{"objectId": "c"}, bson.M{"$set": bson.M{"objectId": "d"}})).Error
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := test.run(); !mongo.IsDuplicateKeyError(err) {
+				t.Errorf("got error %v, want a duplicate key error", err)
+			}
+		})
+	}
+
+	// The valid item of the unordered insert is stored
+	if got := findItem(t, repo, "item", "e"); got.ObjectId != "e" {
+		t.Errorf("item e is not stored by the unordered insert")
+	}
+}
+
+func TestCreateUniqueIndexOnDuplicates(t *testing.T) {
+	repo := newTestRepository(t, "item", testItem{ObjectId: "a"}, testItem{ObjectId: "a"})
+
+	err := repo.Db.CreateIndex("item", []string{"objectId"}, true)
+	if !mongo.IsDuplicateKeyError(err) {
+		t.Fatalf("got error %v, want a duplicate key error", err)
+	}
+	if repo.Db.HasIndex("item", []string{"objectId"}, true) {
+		t.Errorf("the unique index is created on duplicate documents")
+	}
+}
+
+func TestUpsert(t *testing.T) {
+	repo := newTestRepository(t, "item", testItems...)
+	upsert := true
+	options := &coreData.UpdateOptions{Upsert: &upsert}
+
+	update := bson.M{"$set": bson.M{"title": "New"}, "$setOnInsert": bson.M{"score": 7}}
+	if result := <-repo.Update("item", bson.M{"objectId": "e"}, update, options); result.Error != nil {
+		t.Fatalf("Update: %s", result.Error)
+	}
+	got := findItem(t, repo, "item", "e")
+	if got.Title != "New" || got.Score != 7 {
+		t.Errorf("got %+v, want the inserted item with the filter, $set and $setOnInsert fields", got)
+	}
+
+	update = bson.M{"$set": bson.M{"title": "Changed"}, "$setOnInsert": bson.M{"score": 9}}
+	if result := <-repo.Update("item", bson.M{"objectId": "e"}, update, options); result.Error != nil {
+		t.Fatalf("Update: %s", result.Error)
+	}
+	got = findItem(t, repo, "item", "e")
+	if got.Title != "Changed" || got.Score != 7 {
+		t.Errorf("got %+v, want $setOnInsert to be ignored on an existing item", got)
+	}
+}
+
+func TestDelete(t *testing.T) {
+	tests := []struct {
+		name    string
+		filter  bson.M
+		justOne bool
+		want    []string
+	}{
+		{name: "one", filter: bson.M{"tags": "go"}, justOne: true, want: []string{"b", "c", "d"}},
+		{name: "many", filter: bson.M{"tags": "go"}, want: []string{"b", "d"}},
+		{name: "none", filter: bson.M{"objectId": "missing"}, want: []string{"a", "b", "c", "d"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			repo := newTestRepository(t, "item", testItems...)
+			if result := <-repo.Delete("item", test.filter, test.justOne); result.Error != nil {
+				t.Fatalf("Delete: %s", result.Error)
+			}
+			got := readIds(t, <-repo.Find("item", bson.M{}, 0, 0, nil))
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package inmemory
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// DataSingleResult is the result of a find one
+type DataSingleResult struct {
+	doc bson.D
+	err error
+}
+
+// DataResult is the result of a find or an aggregate
+type DataResult struct {
+	docs  []bson.D
+	index int
+	err   error
+}
+
+// decodeDocument decode the document into the value like a mongo cursor does
+func decodeDocument(doc bson.D, v interface{}) error {
+	data, err := bson.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	return bson.Unmarshal(data, v)
+}
+
+// Decode single data result decoding
+func (sr *DataSingleResult) Decode(v interface{}) error {
+	if sr.doc == nil {
+		return nil
+	}
+	return decodeDocument(sr.doc, v)
+}
+
+// NoResult check whether no document is found
+func (sr *DataSingleResult) NoResult() bool {
+	return sr.doc == nil
+}
+
+// Error single data result error
+func (sr *DataSingleResult) Error() error {
+	return sr.err
+}
+
+// Next data result iterator
+func (sr *DataResult) Next() bool {
+	if sr.err != nil || sr.index+1 >= len(sr.docs) {
+		return false
+	}
+	sr.index++
+	return true
+}
+
+// Close close cursor
+func (sr *DataResult) Close() {
+	sr.index = len(sr.docs)
+}
+
+// Decode multi data result decoding
+func (sr *DataResult) Decode(v interface{}) error {
+	return decodeDocument(sr.docs[sr.index], v)
+}
+
+// Error data result error
+func (sr *DataResult) Error() error {
+	return sr.err
+}
@@ -0,0 +1,232 @@
+package inmemory
+
+import (
+	"fmt"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// applyUpdate apply the update operators to a copy of the document.
+// The $setOnInsert operator is only applied when the document is inserted by an upsert.
+func applyUpdate(doc bson.D, update bson.D, insert bool) (bson.D, error) {
+	updated := copyDocument(doc)
+	if len(update) > 0 && !strings.HasPrefix(update[0].Key, "$") {
+		return replaceDocument(updated, update), nil
+	}
+
+	for _, operator := range update {
+		fields, ok := operator.Value.(bson.D)
+		if !ok {
+			return nil, fmt.Errorf("%s needs a document", operator.Key)
+		}
+		for _, field := range fields {
+			var err error
+			switch operator.Key {
+			case "$set":
+				updated, err = setPath(updated, field.Key, copyValue(field.Value))
+			case "$setOnInsert":
+				if insert {
+					updated, err = setPath(updated, field.Key, copyValue(field.Value))
+				}
+			case "$unset":
+				updated = unsetPath(updated, field.Key)
+			case "$inc":
+				updated, err = incrementField(updated, field.Key, field.Value)
+			case "$push":
+				updated, err = pushField(updated, field.Key, field.Value)
+			case "$addToSet":
+				updated, err = addToSetField(updated, field.Key, field.Value)
+			case "$pull":
+				updated, err = pullField(updated, field.Key, field.Value)
+			default:
+				err = fmt.Errorf("the %s update operator is not supported", operator.Key)
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return updated, nil
+}
+
+// replaceDocument replace the fields of the document and keep its _id
+func replaceDocument(doc bson.D, replacement bson.D) bson.D {
+	replaced := bson.D{}
+	if id, ok := lookupKey(doc, "_id"); ok {
+		replaced = append(replaced, bson.E{Key: "_id", Value: id})
+	}
+	for _, elem := range copyDocument(replacement) {
+		if elem.Key != "_id" {
+			replaced = append(replaced, elem)
+		}
+	}
+	return replaced
+}
+
+// incrementField add the number to the field, a missing field is set to the number
+func incrementField(doc bson.D, path string, value interface{}) (bson.D, error) {
+	current, ok := getPath(doc, path)
+	if !ok || current == nil {
+		return setPath(doc, path, value)
+	}
+	sum, err := addNumbers(current, value)
+	if err != nil {
+		return nil, fmt.Errorf("cannot increment %s: %s", path, err.Error())
+	}
+	return setPath(doc, path, sum)
+}
+
+// addNumbers add two numbers, the result is float64 if one of them is, int64 if one of them is and int32 otherwise
+func addNumbers(a, b interface{}) (interface{}, error) {
+	switch typedA := a.(type) {
+	case int32:
+		switch typedB := b.(type) {
+		case int32:
+			return typedA + typedB, nil
+		case int64:
+			return int64(typedA) + typedB, nil
+		case float64:
+			return float64(typedA) + typedB, nil
+		}
+	case int64:
+		switch typedB := b.(type) {
+		case int32:
+			return typedA + int64(typedB), nil
+		case int64:
+			return typedA + typedB, nil
+		case float64:
+			return float64(typedA) + typedB, nil
+		}
+	case float64:
+		if typedB, ok := normalizeNumber(b).(float64); ok {
+			return typedA + typedB, nil
+		}
+	}
+	return nil, fmt.Errorf("%T and %T are not numbers", a, b)
+}
+
+// arrayField read the array of the field, a missing field is an empty array
+func arrayField(doc bson.D, path string) (bson.A, error) {
+	current, ok := getPath(doc, path)
+	if !ok || current == nil {
+		return bson.A{}, nil
+	}
+	array, ok := current.(bson.A)
+	if !ok {
+		return nil, fmt.Errorf("the field %s is not an array", path)
+	}
+	return append(bson.A{}, array...), nil
+}
+
+// eachValues read the values of an $each modifier, or the value itself without the modifier
+func eachValues(value interface{}) (bson.A, bson.D, error) {
+	modifiers, ok := value.(bson.D)
+	if !ok || len(modifiers) == 0 || modifiers[0].Key != "$each" {
+		return bson.A{value}, nil, nil
+	}
+	items, ok := modifiers[0].Value.(bson.A)
+	if !ok {
+		return nil, nil, fmt.Errorf("$each needs an array")
+	}
+	return items, modifiers[1:], nil
+}
+
+// pushField append the values to the array of the field, the $slice modifier keeps the first or the last items
+func pushField(doc bson.D, path string, value interface{}) (bson.D, error) {
+	array, err := arrayField(doc, path)
+	if err != nil {
+		return nil, err
+	}
+	items, modifiers, err := eachValues(value)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range items {
+		array = append(array, copyValue(item))
+	}
+	for _, modifier := range modifiers {
+		if modifier.Key != "$slice" {
+			return nil, fmt.Errorf("the %s modifier is not supported", modifier.Key)
+		}
+		limit, ok := normalizeNumber(modifier.Value).(float64)
+		if !ok {
+			return nil, fmt.Errorf("$slice needs a number")
+		}
+		size := int(limit)
+		if size >= 0 && size < len(array) {
+			array = array[:size]
+		} else if size < 0 && -size < len(array) {
+			array = array[len(array)+size:]
+		}
+	}
+	return setPath(doc, path, array)
+}
+
+// addToSetField append the values which are not in the array of the field
+func addToSetField(doc bson.D, path string, value interface{}) (bson.D, error) {
+	array, err := arrayField(doc, path)
+	if err != nil {
+		return nil, err
+	}
+	items, _, err := eachValues(value)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range items {
+		if !matchEqual([]interface{}{array}, item) {
+			array = append(array, copyValue(item))
+		}
+	}
+	return setPath(doc, path, array)
+}
+
+// pullField remove the items of the array which match the condition
+func pullField(doc bson.D, path string, condition interface{}) (bson.D, error) {
+	current, ok := getPath(doc, path)
+	if !ok {
+		return doc, nil
+	}
+	array, ok := current.(bson.A)
+	if !ok {
+		return nil, fmt.Errorf("the field %s is not an array", path)
+	}
+	kept := bson.A{}
+	for _, item := range array {
+		matched, err := matchItem(item, condition)
+		if err != nil {
+			return nil, err
+		}
+		if !matched {
+			kept = append(kept, item)
+		}
+	}
+	return setPath(doc, path, kept)
+}
+
+// upsertDocument create the document of an upsert from the equality conditions of the filter
+func upsertDocument(filter bson.D) (bson.D, error) {
+	doc := bson.D{}
+	for _, elem := range filter {
+		if strings.HasPrefix(elem.Key, "$") {
+			continue
+		}
+		if operators, ok := elem.Value.(bson.D); ok && isOperatorDocument(operators) {
+			value, ok := lookupKey(operators, "$eq")
+			if !ok {
+				continue
+			}
+			elem.Value = value
+		}
+		var err error
+		doc, err = setPath(doc, elem.Key, copyValue(elem.Value))
+		if err != nil {
+			return nil, err
+		}
+	}
+	if _, ok := lookupKey(doc, "_id"); !ok {
+		doc = append(bson.D{{Key: "_id", Value: primitive.NewObjectID()}}, doc...)
+	}
+	return doc, nil
+}
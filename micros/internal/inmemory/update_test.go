@@ -0,0 +1,126 @@
+package inmemory
+
+import (
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestUpdate(t *testing.T) {
+	tests := []struct {
+		name   string
+		update interface{}
+		want   testItem
+	}{
+		{
+			name:   "$set",
+			update: bson.M{"$set": bson.M{"title": "Changed"}},
+			want:   testItem{ObjectId: "a", Title: "Changed", Score: 3, Tags: []string{"go", "news"}},
+		},
+		{
+			name:   "$unset",
+			update: bson.M{"$unset": bson.M{"title": ""}},
+			want:   testItem{ObjectId: "a", Score: 3, Tags: []string{"go", "news"}},
+		},
+		{
+			name:   "$inc",
+			update: bson.M{"$inc": bson.M{"score": 2}},
+			want:   testItem{ObjectId: "a", Title: "Golang news", Score: 5, Tags: []string{"go", "news"}},
+		},
+		{
+			name:   "$inc negative",
+			update: bson.M{"$inc": bson.M{"score": -4}},
+			want:   testItem{ObjectId: "a", Title: "Golang news", Score: -1, Tags: []string{"go", "news"}},
+		},
+		{
+			name:   "$push",
+			update: bson.M{"$push": bson.M{"tags": "go"}},
+			want:   testItem{ObjectId: "a", Title: "Golang news", Score: 3, Tags: []string{"go", "news", "go"}},
+		},
+		{
+			name:   "$push $each",
+			update: bson.M{"$push": bson.M{"tags": bson.M{"$each": bson.A{"x", "y"}}}},
+			want:   testItem{ObjectId: "a", Title: "Golang news", Score: 3, Tags: []string{"go", "news", "x", "y"}},
+		},
+		{
+			name:   "$addToSet",
+			update: bson.M{"$addToSet": bson.M{"tags": bson.M{"$each": bson.A{"go", "x"}}}},
+			want:   testItem{ObjectId: "a", Title: "Golang news", Score: 3, Tags: []string{"go", "news", "x"}},
+		},
+		{
+			name:   "$pull",
+			update: bson.M{"$pull": bson.M{"tags": "go"}},
+			want:   testItem{ObjectId: "a", Title: "Golang news", Score: 3, Tags: []string{"news"}},
+		},
+		{
+			name:   "replacement",
+			update: bson.M{"objectId": "a", "title": "Replaced"},
+			want:   testItem{ObjectId: "a", Title: "Replaced"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			repo := newTestRepository(t, "item", testItems...)
+			if result := <-repo.Update("item", bson.M{"objectId": "a"}, test.update); result.Error != nil {
+				t.Fatalf("Update: %s", result.Error)
+			}
+			if got := findItem(t, repo, "item", "a"); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestIncrementMissingField(t *testing.T) {
+	repo := newTestRepository(t, "item", testItems...)
+
+	if result := <-repo.Update("item", bson.M{"objectId": "b"}, bson.M{"$inc": bson.M{"counter.likes": 1}}); result.Error != nil {
+		t.Fatalf("Update: %s", result.Error)
+	}
+	result := <-repo.FindOne("item", bson.M{"counter.likes": 1})
+	if err := result.Error(); err != nil {
+		t.Fatalf("FindOne: %s", err)
+	}
+	var item testItem
+	result.Decode(&item)
+	if item.ObjectId != "b" {
+		t.Errorf("got item %s, want the missing field to start from zero", item.ObjectId)
+	}
+}
+
+func TestUpdateMany(t *testing.T) {
+	repo := newTestRepository(t, "item", testItems...)
+
+	result := <-repo.UpdateMany("item", bson.M{"tags": "go"}, bson.M{"$inc": bson.M{"score": 10}})
+	if result.Error != nil {
+		t.Fatalf("UpdateMany: %s", result.Error)
+	}
+	if result.Result != int64(2) {
+		t.Errorf("got %v modified items, want 2", result.Result)
+	}
+	got := readIds(t, <-repo.Find("item", bson.M{"score": bson.M{"$gte": 10}}, 0, 0, nil))
+	if want := []string{"a", "c"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestUpdateErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		update interface{}
+	}{
+		{name: "unknown operator", update: bson.M{"$rename": bson.M{"title": "name"}}},
+		{name: "$inc a string", update: bson.M{"$inc": bson.M{"title": 1}}},
+		{name: "$inc by a string", update: bson.M{"$inc": bson.M{"score": "1"}}},
+		{name: "$push to a string", update: bson.M{"$push": bson.M{"title": "x"}}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			repo := newTestRepository(t, "item", testItems...)
+			if result := <-repo.Update("item", bson.M{"objectId": "a"}, test.update); result.Error == nil {
+				t.Errorf("got no error")
+			}
+		})
+	}
+}
@@ -0,0 +1,404 @@
+package inmemory
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// toDocument convert a struct or a map to an ordered document with the same encoding as mongo
+func toDocument(value interface{}) (bson.D, error) {
+	if value == nil {
+		return bson.D{}, nil
+	}
+	data, err := bson.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var doc bson.D
+	if err := bson.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// toValue convert a value to the type which a document keeps, e.g. uuid to binary and slices to arrays
+func toValue(value interface{}) (interface{}, error) {
+	doc, err := toDocument(bson.M{"v": value})
+	if err != nil {
+		return nil, err
+	}
+	return doc[0].Value, nil
+}
+
+// copyDocument copy the document deeply
+func copyDocument(doc bson.D) bson.D {
+	copied := make(bson.D, len(doc))
+	for i, elem := range doc {
+		copied[i] = bson.E{Key: elem.Key, Value: copyValue(elem.Value)}
+	}
+	return copied
+}
+
+// copyValue copy the nested documents and arrays of the value
+func copyValue(value interface{}) interface{} {
+	switch typed := value.(type) {
+	case bson.D:
+		return copyDocument(typed)
+	case bson.A:
+		copied := make(bson.A, len(typed))
+		for i, item := range typed {
+			copied[i] = copyValue(item)
+		}
+		return copied
+	}
+	return value
+}
+
+// lookupKey find the value of the key in the document
+func lookupKey(doc bson.D, key string) (interface{}, bool) {
+	for _, elem := range doc {
+		if elem.Key == key {
+			return elem.Value, true
+		}
+	}
+	return nil, false
+}
+
+// getPath read the value of the dotted path, the numeric parts index the arrays
+func getPath(doc bson.D, path string) (interface{}, bool) {
+	var current interface{} = doc
+	for _, part := range strings.Split(path, ".") {
+		switch typed := current.(type) {
+		case bson.D:
+			value, ok := lookupKey(typed, part)
+			if !ok {
+				return nil, false
+			}
+			current = value
+		case bson.A:
+			index, err := strconv.Atoi(part)
+			if err != nil || index < 0 || index >= len(typed) {
+				return nil, false
+			}
+			current = typed[index]
+		default:
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// resolvePath read the values of the dotted path for a query.
+// The arrays on the path are traversed, so a field of the documents in an array gives one value per document.
+func resolvePath(value interface{}, parts []string) []interface{} {
+	if len(parts) == 0 {
+		return []interface{}{value}
+	}
+	switch typed := value.(type) {
+	case bson.D:
+		child, ok := lookupKey(typed, parts[0])
+		if !ok {
+			return nil
+		}
+		return resolvePath(child, parts[1:])
+	case bson.A:
+		if index, err := strconv.Atoi(parts[0]); err == nil {
+			if index < 0 || index >= len(typed) {
+				return nil
+			}
+			return resolvePath(typed[index], parts[1:])
+		}
+		var values []interface{}
+		for _, item := range typed {
+			if _, ok := item.(bson.D); ok {
+				values = append(values, resolvePath(item, parts)...)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+// setPath set the value of the dotted path, the missing documents on the path are created
+func setPath(doc bson.D, path string, value interface{}) (bson.D, error) {
+	parts := strings.SplitN(path, ".", 2)
+	for i, elem := range doc {
+		if elem.Key != parts[0] {
+			continue
+		}
+		if len(parts) == 1 {
+			doc[i].Value = value
+			return doc, nil
+		}
+		child, err := setChildPath(elem.Value, parts[1], value)
+		if err != nil {
+			return nil, err
+		}
+		doc[i].Value = child
+		return doc, nil
+	}
+	if len(parts) == 1 {
+		return append(doc, bson.E{Key: path, Value: value}), nil
+	}
+	child, err := setPath(bson.D{}, parts[1], value)
+	if err != nil {
+		return nil, err
+	}
+	return append(doc, bson.E{Key: parts[0], Value: child}), nil
+}
+
+// setChildPath set the value in a nested document or array
+func setChildPath(current interface{}, path string, value interface{}) (interface{}, error) {
+	switch typed := current.(type) {
+	case bson.D:
+		return setPath(typed, path, value)
+	case bson.A:
+		parts := strings.SplitN(path, ".", 2)
+		index, err := strconv.Atoi(parts[0])
+		if err != nil || index < 0 {
+			return nil, fmt.Errorf("cannot create field %s in an array", parts[0])
+		}
+		for len(typed) <= index {
+			typed = append(typed, nil)
+		}
+		if len(parts) == 1 {
+			typed[index] = value
+			return typed, nil
+		}
+		child, err := setChildPath(typed[index], parts[1], value)
+		if err != nil {
+			return nil, err
+		}
+		typed[index] = child
+		return typed, nil
+	case nil:
+		return setPath(bson.D{}, path, value)
+	}
+	return nil, fmt.Errorf("cannot create field %s in a %T value", path, current)
+}
+
+// unsetPath remove the dotted path from the document
+func unsetPath(doc bson.D, path string) bson.D {
+	parts := strings.SplitN(path, ".", 2)
+	for i, elem := range doc {
+		if elem.Key != parts[0] {
+			continue
+		}
+		if len(parts) == 1 {
+			return append(doc[:i], doc[i+1:]...)
+		}
+		if child, ok := elem.Value.(bson.D); ok {
+			doc[i].Value = unsetPath(child, parts[1])
+		}
+		return doc
+	}
+	return doc
+}
+
+// normalizeNumber convert the numbers to float64 so the numbers of different types are comparable
+func normalizeNumber(value interface{}) interface{} {
+	switch typed := value.(type) {
+	case int:
+		return float64(typed)
+	case int32:
+		return float64(typed)
+	case int64:
+		return float64(typed)
+	case float32:
+		return float64(typed)
+	}
+	return value
+}
+
+// typeOrder is the order of the types when values of different types are sorted, it follows mongo
+func typeOrder(value interface{}) int {
+	switch value.(type) {
+	case nil, primitive.Null, primitive.Undefined:
+		return 1
+	case int, int32, int64, float32, float64, primitive.Decimal128:
+		return 2
+	case string, primitive.Symbol:
+		return 3
+	case bson.D:
+		return 4
+	case bson.A:
+		return 5
+	case primitive.Binary:
+		return 6
+	case primitive.ObjectID:
+		return 7
+	case bool:
+		return 8
+	case primitive.DateTime, time.Time:
+		return 9
+	case primitive.Timestamp:
+		return 10
+	case primitive.Regex:
+		return 11
+	}
+	return 12
+}
+
+// compareValues compare two values in the sort order of mongo
+func compareValues(a, b interface{}) int {
+	orderA, orderB := typeOrder(a), typeOrder(b)
+	if orderA != orderB {
+		return compareInts(int64(orderA), int64(orderB))
+	}
+
+	switch typedA := a.(type) {
+	case string:
+		return strings.Compare(typedA, b.(string))
+	case bool:
+		typedB := b.(bool)
+		if typedA == typedB {
+			return 0
+		}
+		if !typedA {
+			return -1
+		}
+		return 1
+	case primitive.Binary:
+		typedB := b.(primitive.Binary)
+		if len(typedA.Data) != len(typedB.Data) {
+			return compareInts(int64(len(typedA.Data)), int64(len(typedB.Data)))
+		}
+		if typedA.Subtype != typedB.Subtype {
+			return compareInts(int64(typedA.Subtype), int64(typedB.Subtype))
+		}
+		return bytes.Compare(typedA.Data, typedB.Data)
+	case primitive.ObjectID:
+		typedB := b.(primitive.ObjectID)
+		return bytes.Compare(typedA[:], typedB[:])
+	case primitive.DateTime:
+		if typedB, ok := b.(primitive.DateTime); ok {
+			return compareInts(int64(typedA), int64(typedB))
+		}
+	case bson.D:
+		typedB := b.(bson.D)
+		for i := 0; i < len(typedA) && i < len(typedB); i++ {
+			if result := strings.Compare(typedA[i].Key, typedB[i].Key); result != 0 {
+				return result
+			}
+			if result := compareValues(typedA[i].Value, typedB[i].Value); result != 0 {
+				return result
+			}
+		}
+		return compareInts(int64(len(typedA)), int64(len(typedB)))
+	case bson.A:
+		typedB := b.(bson.A)
+		for i := 0; i < len(typedA) && i < len(typedB); i++ {
+			if result := compareValues(typedA[i], typedB[i]); result != 0 {
+				return result
+			}
+		}
+		return compareInts(int64(len(typedA)), int64(len(typedB)))
+	}
+
+	if numberA, ok := normalizeNumber(a).(float64); ok {
+		if numberB, ok := normalizeNumber(b).(float64); ok {
+			switch {
+			case numberA < numberB:
+				return -1
+			case numberA > numberB:
+				return 1
+			}
+			return 0
+		}
+	}
+	if reflect.DeepEqual(a, b) {
+		return 0
+	}
+	return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+}
+
+// compareInts compare two integers
+func compareInts(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// equalValues check whether two values are equal, the numbers of different types are equal by value
+func equalValues(a, b interface{}) bool {
+	if typeOrder(a) != typeOrder(b) {
+		return false
+	}
+	return compareValues(a, b) == 0
+}
+
+// sortDocuments sort the documents by the ordered sort keys, 1 is ascending and -1 is descending.
+// The arrays are sorted by their smallest element for ascending order and by the largest one otherwise.
+func sortDocuments(docs []bson.D, sortKeys bson.D) {
+	if len(sortKeys) == 0 {
+		return
+	}
+	sort.SliceStable(docs, func(i, j int) bool {
+		for _, key := range sortKeys {
+			direction := 1
+			if number, ok := normalizeNumber(key.Value).(float64); ok && number < 0 {
+				direction = -1
+			}
+			valueI := sortValue(docs[i], key.Key, direction)
+			valueJ := sortValue(docs[j], key.Key, direction)
+			if result := compareValues(valueI, valueJ); result != 0 {
+				return result*direction < 0
+			}
+		}
+		return false
+	})
+}
+
+// sortValue read the value of the document which is used for sorting
+func sortValue(doc bson.D, path string, direction int) interface{} {
+	values := resolvePath(doc, strings.Split(path, "."))
+	if len(values) == 0 {
+		return nil
+	}
+	var selected interface{}
+	for i, value := range expandArrays(values) {
+		if i == 0 || compareValues(value, selected)*direction < 0 {
+			selected = value
+		}
+	}
+	return selected
+}
+
+// expandArrays add the elements of the arrays to the values
+func expandArrays(values []interface{}) []interface{} {
+	var expanded []interface{}
+	for _, value := range values {
+		if array, ok := value.(bson.A); ok {
+			expanded = append(expanded, array...)
+			continue
+		}
+		expanded = append(expanded, value)
+	}
+	return expanded
+}
+
+// mapSort convert the sort map of a find to ordered sort keys, the map has no order so the keys are sorted by name
+func mapSort(sortMap map[string]int) bson.D {
+	keys := make([]string, 0, len(sortMap))
+	for key := range sortMap {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	sortKeys := bson.D{}
+	for _, key := range keys {
+		sortKeys = append(sortKeys, bson.E{Key: key, Value: sortMap[key]})
+	}
+	return sortKeys
+}
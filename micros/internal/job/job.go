@@ -0,0 +1,31 @@
+// Package job runs the periodic work of a function, like the outbox dispatch and the trash purge,
+// in the process of the function.
+//
+// The functions are not scaled to zero (com.openfaas.scale.zero in stack.yml), so a job keeps running
+// once the first request connects the database. The work of each job is exposed by an HMAC endpoint as well,
+// which can be scheduled with a cron connector.
+package job
+
+import (
+	"context"
+	"time"
+
+	"github.com/red-gold/telar-core/pkg/log"
+)
+
+// Run call the run function on every interval until the context is done, the errors are logged
+func Run(ctx context.Context, name string, interval time.Duration, run func() error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := run(); err != nil {
+			log.Error("[job.Run] %s - %s", name, err.Error())
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/red-gold/telar-core/pkg/log"
+	"github.com/red-gold/ts-serverless/micros/internal/job"
+	"github.com/red-gold/ts-serverless/micros/internal/rpc"
+)
+
+// DispatchLimit the number of due events which are delivered on each dispatch
+const DispatchLimit int64 = 50
+
+// DispatchInterval the interval of RunDispatcher, it is the backoff of the first retry
+const DispatchInterval = time.Duration(backoffMillis) * time.Millisecond
+
+// SaveEvents save the events of a change, the change is reverted by the rollback function if the events can not be saved
+func SaveEvents(outboxService Service, events []Event, rollback func() error) error {
+	err := outboxService.SaveEvents(events)
+	if err == nil {
+		return nil
+	}
+	if rollbackErr := rollback(); rollbackErr != nil {
+		log.Error("[outbox.SaveEvents.rollback] %s", rollbackErr.Error())
+	}
+	return fmt.Errorf("outbox.SaveEvents/save %s", err.Error())
+}
+
+// Dispatch deliver the events and record the result of each attempt.
+// The event id is sent as the idempotency key, a request which fails is not retried until the next dispatch.
+func Dispatch(outboxService Service, events []Event) (delivered int, failed int) {
+	for index := range events {
+		event := &events[index]
+
+		headers := make(map[string][]string)
+		for k, v := range event.Headers {
+			headers[k] = v
+		}
+		headers[rpc.IdempotencyKeyHeader] = []string{event.ObjectId.String()}
+
+		_, callErr := rpc.Do(context.Background(), rpc.Request{
+			Method: event.Method,
+			URL:    event.URL,
+			Body:   []byte(event.Payload),
+			Header: headers,
+		})
+		if callErr != nil {
+			failed++
+			log.Error("[outbox.Dispatch] %s %s - %s", event.Method, event.URL, callErr.Error())
+			if err := outboxService.MarkEventAttemptFailed(event, callErr); err != nil {
+				log.Error("[outbox.Dispatch.MarkEventAttemptFailed] %s", err.Error())
+			}
+			continue
+		}
+
+		delivered++
+		if err := outboxService.MarkEventDelivered(event.ObjectId); err != nil {
+			log.Error("[outbox.Dispatch.MarkEventDelivered] %s", err.Error())
+		}
+	}
+	return delivered, failed
+}
+
+// DispatchDue lease and deliver the events which their next attempt is due
+func DispatchDue(outboxService Service) (*DispatchModel, error) {
+	dueEvents, err := outboxService.FindDueEvents(DispatchLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	// Lease the events so a concurrent dispatch does not deliver them at the same time
+	var events []Event
+	for index := range dueEvents {
+		leased, err := outboxService.LeaseEvent(&dueEvents[index])
+		if err != nil {
+			log.Error("[outbox.DispatchDue.LeaseEvent] %s", err.Error())
+			continue
+		}
+		if leased {
+			events = append(events, dueEvents[index])
+		}
+	}
+
+	delivered, failed := Dispatch(outboxService, events)
+	return &DispatchModel{
+		Dispatched: len(events),
+		Delivered:  delivered,
+		Failed:     failed,
+	}, nil
+}
+
+// ReadStatus count the pending and failed events and read the latest undelivered ones
+func ReadStatus(outboxService Service) (*StatusModel, error) {
+	pending, err := outboxService.CountEventsByStatus(StatusPending)
+	if err != nil {
+		return nil, err
+	}
+	failed, err := outboxService.CountEventsByStatus(StatusFailed)
+	if err != nil {
+		return nil, err
+	}
+	events, err := outboxService.FindUndeliveredEvents(DispatchLimit)
+	if err != nil {
+		return nil, err
+	}
+	if events == nil {
+		events = []Event{}
+	}
+	return &StatusModel{
+		Pending: pending,
+		Failed:  failed,
+		Events:  events,
+	}, nil
+}
+
+// RunDispatcher deliver the due events on every interval until the context is done
+func RunDispatcher(ctx context.Context, newService func() (Service, error), interval time.Duration) {
+	job.Run(ctx, "outbox dispatch", interval, func() error {
+		outboxService, err := newService()
+		if err != nil {
+			return err
+		}
+		result, err := DispatchDue(outboxService)
+		if err != nil {
+			return err
+		}
+		if result.Dispatched > 0 {
+			log.Info("Outbox dispatched %d events, %d delivered and %d failed", result.Dispatched, result.Delivered, result.Failed)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,156 @@
+package outbox
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	coreConfig "github.com/red-gold/telar-core/config"
+	"github.com/red-gold/ts-serverless/micros/internal/rpc"
+)
+
+// testGateway start a gateway which responds with the status and records the idempotency keys
+func testGateway(t *testing.T, status int) (*sync.Mutex, *[]string) {
+	t.Helper()
+	var mu sync.Mutex
+	keys := []string{}
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		keys = append(keys, r.Header.Get(rpc.IdempotencyKeyHeader))
+		mu.Unlock()
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(gateway.Close)
+
+	internalGateway := gateway.URL
+	payloadSecret := "secret"
+	baseRoute := ""
+	coreConfig.AppConfig.InternalGateway = &internalGateway
+	coreConfig.AppConfig.PayloadSecret = &payloadSecret
+	coreConfig.AppConfig.BaseRoute = &baseRoute
+	return &mu, &keys
+}
+
+// failingService a service which can not save the events
+type failingService struct {
+	*ServiceImpl
+}
+
+func (s failingService) SaveEvents(events []Event) error {
+	return errors.New("unavailable")
+}
+
+func TestSaveEventsRollback(t *testing.T) {
+	tests := []struct {
+		name           string
+		outboxService  Service
+		wantErr        bool
+		wantRolledBack bool
+	}{
+		{name: "saved", outboxService: newTestService(t)},
+		{name: "not saved", outboxService: failingService{newTestService(t)}, wantErr: true, wantRolledBack: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rolledBack := false
+			rollback := func() error {
+				rolledBack = true
+				return nil
+			}
+			err := SaveEvents(test.outboxService, []Event{NewEvent("PUT", "/posts/score", []byte("{}"), nil)}, rollback)
+			if (err != nil) != test.wantErr {
+				t.Errorf("got error %v, want error %v", err, test.wantErr)
+			}
+			if rolledBack != test.wantRolledBack {
+				t.Errorf("got rolled back %v, want %v", rolledBack, test.wantRolledBack)
+			}
+		})
+	}
+}
+
+func TestDispatchDue(t *testing.T) {
+	tests := []struct {
+		name          string
+		status        int
+		wantDelivered int
+		wantFailed    int
+		wantStatus    string
+	}{
+		{name: "delivered", status: http.StatusOK, wantDelivered: 2, wantStatus: StatusDelivered},
+		{name: "failed", status: http.StatusServiceUnavailable, wantFailed: 2, wantStatus: StatusPending},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := newTestService(t)
+			mu, keys := testGateway(t, test.status)
+
+			events := []Event{
+				NewEvent("PUT", "/posts/score", []byte("{}"), nil),
+				NewEvent("POST", "/notifications", []byte("{}"), nil),
+			}
+			if err := s.SaveEvents(events); err != nil {
+				t.Fatalf("SaveEvents: %v", err)
+			}
+			makeDue(t, s, events)
+
+			result, err := DispatchDue(s)
+			if err != nil {
+				t.Fatalf("DispatchDue: %v", err)
+			}
+			if result.Dispatched != 2 || result.Delivered != test.wantDelivered || result.Failed != test.wantFailed {
+				t.Errorf("got %+v, want %d delivered and %d failed", *result, test.wantDelivered, test.wantFailed)
+			}
+
+			// Each event is attempted once with its id as the idempotency key
+			mu.Lock()
+			gotKeys := append([]string{}, *keys...)
+			mu.Unlock()
+			if len(gotKeys) != len(events) {
+				t.Fatalf("got %d requests, want %d", len(gotKeys), len(events))
+			}
+			for index, event := range events {
+				if gotKeys[index] != event.ObjectId.String() {
+					t.Errorf("got idempotency key %q, want %q", gotKeys[index], event.ObjectId.String())
+				}
+				if got := findEvent(t, s, event.ObjectId).Status; got != test.wantStatus {
+					t.Errorf("got status %q, want %q", got, test.wantStatus)
+				}
+			}
+
+			// The events are not due again until their lease or backoff ends
+			result, err = DispatchDue(s)
+			if err != nil {
+				t.Fatalf("DispatchDue: %v", err)
+			}
+			if result.Dispatched != 0 {
+				t.Errorf("got %d dispatched, want 0", result.Dispatched)
+			}
+		})
+	}
+}
+
+func TestReadStatus(t *testing.T) {
+	s := newTestService(t)
+	events := []Event{
+		NewEvent("PUT", "/posts/score", []byte("{}"), nil),
+		NewEvent("PUT", "/posts/comment/count", []byte("{}"), nil),
+	}
+	if err := s.SaveEvents(events); err != nil {
+		t.Fatalf("SaveEvents: %v", err)
+	}
+	failedEvent := events[1]
+	failedEvent.Attempts = maxAttempts - 1
+	if err := s.MarkEventAttemptFailed(&failedEvent, errors.New("unavailable")); err != nil {
+		t.Fatalf("MarkEventAttemptFailed: %v", err)
+	}
+
+	status, err := ReadStatus(s)
+	if err != nil {
+		t.Fatalf("ReadStatus: %v", err)
+	}
+	if status.Pending != 1 || status.Failed != 1 || len(status.Events) != 2 {
+		t.Errorf("got %d pending, %d failed and %d events, want 1, 1 and 2", status.Pending, status.Failed, len(status.Events))
+	}
+}
@@ -0,0 +1,59 @@
+// Package outbox stores the requests which a change sends to other functions in the same logical unit as the change,
+// and delivers them through the internal gateway with retries and exponential backoff.
+//
+// A handler saves the events of its change with SaveEvents and makes the first attempt with Dispatch in the background.
+// The events which fail are picked up again by RunDispatcher, which each function starts when it connects to its database,
+// and by the POST /outbox/dispatch endpoint of the function.
+package outbox
+
+import (
+	uuid "github.com/gofrs/uuid"
+)
+
+// Event status
+const (
+	StatusPending   = "pending"
+	StatusDelivered = "delivered"
+	StatusFailed    = "failed"
+)
+
+// Event a request to another function which is stored with the change that caused it
+// The object id is sent as the idempotency key of the request
+type Event struct {
+	ObjectId        uuid.UUID           `json:"objectId" bson:"objectId"`
+	Method          string              `json:"method" bson:"method"`
+	URL             string              `json:"url" bson:"url"`
+	Payload         string              `json:"payload" bson:"payload"`
+	Headers         map[string][]string `json:"headers" bson:"headers"`
+	Status          string              `json:"status" bson:"status"`
+	Attempts        int                 `json:"attempts" bson:"attempts"`
+	NextAttemptDate int64               `json:"nextAttemptDate" bson:"nextAttemptDate"`
+	LastError       string              `json:"lastError" bson:"lastError"`
+	CreatedDate     int64               `json:"created_date" bson:"created_date"`
+	LastUpdated     int64               `json:"last_updated" bson:"last_updated"`
+}
+
+// StatusModel the pending and failed events of a function
+type StatusModel struct {
+	Pending int64   `json:"pending"`
+	Failed  int64   `json:"failed"`
+	Events  []Event `json:"events"`
+}
+
+// DispatchModel the result of a dispatch
+type DispatchModel struct {
+	Dispatched int `json:"dispatched"`
+	Delivered  int `json:"delivered"`
+	Failed     int `json:"failed"`
+}
+
+// NewEvent create an event for a request to another function
+func NewEvent(method string, url string, payload []byte, headers map[string][]string) Event {
+	return Event{
+		ObjectId: uuid.Must(uuid.NewV4()),
+		Method:   method,
+		URL:      url,
+		Payload:  string(payload),
+		Headers:  headers,
+	}
+}
@@ -0,0 +1,280 @@
+package outbox
+
+import (
+	"fmt"
+
+	uuid "github.com/gofrs/uuid"
+	"github.com/red-gold/telar-core/config"
+	coreData "github.com/red-gold/telar-core/data"
+	repo "github.com/red-gold/telar-core/data"
+	"github.com/red-gold/telar-core/data/mongodb"
+	mongoRepo "github.com/red-gold/telar-core/data/mongodb"
+	"github.com/red-gold/telar-core/utils"
+	"github.com/red-gold/ts-serverless/micros/internal/inmemory"
+)
+
+const (
+	maxAttempts            = 8
+	leaseMillis      int64 = 60 * 1000
+	backoffMillis    int64 = 30 * 1000
+	maxBackoffMillis int64 = 60 * 60 * 1000
+)
+
+type Service interface {
+	SaveEvents(events []Event) error
+	DeleteEvents(objectIds []uuid.UUID) error
+	FindEventList(filter interface{}, limit int64, skip int64, sort map[string]int) ([]Event, error)
+	FindDueEvents(limit int64) ([]Event, error)
+	FindUndeliveredEvents(limit int64) ([]Event, error)
+	CountEventsByStatus(status string) (int64, error)
+	LeaseEvent(event *Event) (bool, error)
+	MarkEventDelivered(objectId uuid.UUID) error
+	MarkEventAttemptFailed(event *Event, attemptErr error) error
+}
+
+// ServiceImpl handlers with injected dependencies
+type ServiceImpl struct {
+	OutboxRepo     repo.Repository
+	CollectionName string
+}
+
+// NewService initializes the outbox Service's dependencies on the collection of the function
+func NewService(db interface{}, collectionName string) (Service, error) {
+
+	outboxService := &ServiceImpl{CollectionName: collectionName}
+
+	switch *config.AppConfig.DBType {
+	case config.DB_MONGO:
+
+		mongodb := db.(mongodb.MongoDatabase)
+		outboxService.OutboxRepo = mongoRepo.NewDataRepositoryMongo(mongodb)
+
+	case config.DB_INMEMORY:
+
+		outboxService.OutboxRepo = inmemory.NewDataRepositoryInMemory(db.(*inmemory.Database))
+
+	}
+
+	return outboxService, nil
+}
+
+// SaveEvents save the events as pending.
+// The first attempt is leased to the request that created the events, the dispatcher picks them up after the lease.
+func (s ServiceImpl) SaveEvents(events []Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	now := utils.UTCNowUnix()
+	var items []interface{}
+	for index := range events {
+		if events[index].ObjectId == uuid.Nil {
+			events[index].ObjectId = uuid.Must(uuid.NewV4())
+		}
+		events[index].Status = StatusPending
+		events[index].Attempts = 0
+		events[index].NextAttemptDate = now + leaseMillis
+		events[index].CreatedDate = now
+		events[index].LastUpdated = now
+		items = append(items, events[index])
+	}
+
+	result := <-s.OutboxRepo.SaveMany(s.CollectionName, items)
+	return result.Error
+}
+
+// DeleteEvents delete the events by ids
+func (s ServiceImpl) DeleteEvents(objectIds []uuid.UUID) error {
+	inFilter := make(map[string]interface{})
+	inFilter["$in"] = objectIds
+	filter := make(map[string]interface{})
+	filter["objectId"] = inFilter
+
+	result := <-s.OutboxRepo.Delete(s.CollectionName, filter, false)
+	return result.Error
+}
+
+// FindEventList get all outbox events by filter
+func (s ServiceImpl) FindEventList(filter interface{}, limit int64, skip int64, sort map[string]int) ([]Event, error) {
+
+	result := <-s.OutboxRepo.Find(s.CollectionName, filter, limit, skip, sort)
+	defer result.Close()
+	if result.Error() != nil {
+		return nil, result.Error()
+	}
+	var eventList []Event
+	for result.Next() {
+		var event Event
+		errDecode := result.Decode(&event)
+		if errDecode != nil {
+			return nil, fmt.Errorf("Error docoding on outbox.Event")
+		}
+		eventList = append(eventList, event)
+	}
+
+	return eventList, nil
+}
+
+// FindDueEvents get the pending events which their next attempt is due
+func (s ServiceImpl) FindDueEvents(limit int64) ([]Event, error) {
+	sortMap := make(map[string]int)
+	sortMap["nextAttemptDate"] = 1
+
+	dueDate := make(map[string]interface{})
+	dueDate["$lte"] = utils.UTCNowUnix()
+
+	filter := make(map[string]interface{})
+	filter["status"] = StatusPending
+	filter["nextAttemptDate"] = dueDate
+
+	return s.FindEventList(filter, limit, 0, sortMap)
+}
+
+// FindUndeliveredEvents get the pending and failed events
+func (s ServiceImpl) FindUndeliveredEvents(limit int64) ([]Event, error) {
+	sortMap := make(map[string]int)
+	sortMap["created_date"] = -1
+
+	inFilter := make(map[string]interface{})
+	inFilter["$in"] = []string{StatusPending, StatusFailed}
+
+	filter := make(map[string]interface{})
+	filter["status"] = inFilter
+
+	return s.FindEventList(filter, limit, 0, sortMap)
+}
+
+// CountEventsByStatus count the events in the status
+func (s ServiceImpl) CountEventsByStatus(status string) (int64, error) {
+	var pipeline []interface{}
+
+	matchOperator := make(map[string]interface{})
+	matchOperator["$match"] = map[string]interface{}{"status": status}
+
+	countOperator := make(map[string]interface{})
+	countOperator["$count"] = "count"
+
+	pipeline = append(pipeline, matchOperator, countOperator)
+
+	result := <-s.OutboxRepo.Aggregate(s.CollectionName, pipeline)
+	defer result.Close()
+	if result.Error() != nil {
+		return 0, result.Error()
+	}
+
+	var countResult struct {
+		Count int64 `bson:"count"`
+	}
+	if result.Next() {
+		errDecode := result.Decode(&countResult)
+		if errDecode != nil {
+			return 0, fmt.Errorf("Error docoding on outbox count")
+		}
+	}
+	return countResult.Count, nil
+}
+
+// LeaseEvent postpone the next attempt of the due event while it is being delivered.
+// It returns false if another dispatcher leased the event since it was read.
+func (s ServiceImpl) LeaseEvent(event *Event) (bool, error) {
+	filter := struct {
+		ObjectId        uuid.UUID `json:"objectId" bson:"objectId"`
+		Status          string    `json:"status" bson:"status"`
+		NextAttemptDate int64     `json:"nextAttemptDate" bson:"nextAttemptDate"`
+	}{
+		ObjectId:        event.ObjectId,
+		Status:          StatusPending,
+		NextAttemptDate: event.NextAttemptDate,
+	}
+
+	now := utils.UTCNowUnix()
+	data := struct {
+		NextAttemptDate int64 `json:"nextAttemptDate" bson:"nextAttemptDate"`
+		LastUpdated     int64 `json:"last_updated" bson:"last_updated"`
+	}{
+		NextAttemptDate: now + leaseMillis,
+		LastUpdated:     now,
+	}
+	updateOperator := coreData.UpdateOperator{
+		Set: data,
+	}
+	result := <-s.OutboxRepo.Update(s.CollectionName, filter, updateOperator)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	modifiedCount, _ := result.Result.(int64)
+	if modifiedCount == 0 {
+		return false, nil
+	}
+	event.NextAttemptDate = data.NextAttemptDate
+	return true, nil
+}
+
+// MarkEventDelivered set the event status as delivered
+func (s ServiceImpl) MarkEventDelivered(objectId uuid.UUID) error {
+	filter := struct {
+		ObjectId uuid.UUID `json:"objectId" bson:"objectId"`
+	}{
+		ObjectId: objectId,
+	}
+
+	data := struct {
+		Status      string `json:"status" bson:"status"`
+		LastError   string `json:"lastError" bson:"lastError"`
+		LastUpdated int64  `json:"last_updated" bson:"last_updated"`
+	}{
+		Status:      StatusDelivered,
+		LastError:   "",
+		LastUpdated: utils.UTCNowUnix(),
+	}
+	updateOperator := coreData.UpdateOperator{
+		Set: data,
+	}
+	result := <-s.OutboxRepo.Update(s.CollectionName, filter, updateOperator)
+	return result.Error
+}
+
+// MarkEventAttemptFailed record the failed attempt and schedule the next one with exponential backoff.
+// The event is marked as failed when it runs out of attempts.
+func (s ServiceImpl) MarkEventAttemptFailed(event *Event, attemptErr error) error {
+	filter := struct {
+		ObjectId uuid.UUID `json:"objectId" bson:"objectId"`
+	}{
+		ObjectId: event.ObjectId,
+	}
+
+	now := utils.UTCNowUnix()
+	attempts := event.Attempts + 1
+	status := StatusPending
+	if attempts >= maxAttempts {
+		status = StatusFailed
+	}
+
+	data := struct {
+		Status          string `json:"status" bson:"status"`
+		Attempts        int    `json:"attempts" bson:"attempts"`
+		NextAttemptDate int64  `json:"nextAttemptDate" bson:"nextAttemptDate"`
+		LastError       string `json:"lastError" bson:"lastError"`
+		LastUpdated     int64  `json:"last_updated" bson:"last_updated"`
+	}{
+		Status:          status,
+		Attempts:        attempts,
+		NextAttemptDate: now + backoff(attempts),
+		LastError:       attemptErr.Error(),
+		LastUpdated:     now,
+	}
+	updateOperator := coreData.UpdateOperator{
+		Set: data,
+	}
+	result := <-s.OutboxRepo.Update(s.CollectionName, filter, updateOperator)
+	return result.Error
+}
+
+// backoff the delay before the next attempt after the number of failed attempts
+func backoff(attempts int) int64 {
+	delay := backoffMillis << uint(attempts-1)
+	if delay > maxBackoffMillis || delay <= 0 {
+		delay = maxBackoffMillis
+	}
+	return delay
+}
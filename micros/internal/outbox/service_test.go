@@ -0,0 +1,153 @@
+package outbox
+
+import (
+	"errors"
+	"testing"
+
+	uuid "github.com/gofrs/uuid"
+	"github.com/red-gold/telar-core/config"
+	coreData "github.com/red-gold/telar-core/data"
+	"github.com/red-gold/ts-serverless/micros/internal/inmemory"
+)
+
+const testCollectionName = "outbox"
+
+func newTestService(t *testing.T) *ServiceImpl {
+	t.Helper()
+	dbType := config.DB_INMEMORY
+	config.AppConfig.DBType = &dbType
+	outboxService, err := NewService(inmemory.NewDatabase(), testCollectionName)
+	if err != nil {
+		t.Fatalf("NewService: %v", err)
+	}
+	return outboxService.(*ServiceImpl)
+}
+
+// makeDue move the next attempt of the events to the past
+func makeDue(t *testing.T, s *ServiceImpl, events []Event) {
+	t.Helper()
+	for index := range events {
+		filter := map[string]interface{}{"objectId": events[index].ObjectId}
+		data := map[string]interface{}{"nextAttemptDate": int64(0)}
+		result := <-s.OutboxRepo.Update(s.CollectionName, filter, coreData.UpdateOperator{Set: data})
+		if result.Error != nil {
+			t.Fatalf("Update: %v", result.Error)
+		}
+		events[index].NextAttemptDate = 0
+	}
+}
+
+func findEvent(t *testing.T, s *ServiceImpl, objectId uuid.UUID) Event {
+	t.Helper()
+	events, err := s.FindEventList(map[string]interface{}{"objectId": objectId}, 1, 0, nil)
+	if err != nil {
+		t.Fatalf("FindEventList: %v", err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("got %d events, want 1", len(events))
+	}
+	return events[0]
+}
+
+func TestSaveEventsAreLeased(t *testing.T) {
+	s := newTestService(t)
+	events := []Event{NewEvent("PUT", "/posts/score", []byte("{}"), nil)}
+	if err := s.SaveEvents(events); err != nil {
+		t.Fatalf("SaveEvents: %v", err)
+	}
+
+	due, err := s.FindDueEvents(DispatchLimit)
+	if err != nil {
+		t.Fatalf("FindDueEvents: %v", err)
+	}
+	if len(due) != 0 {
+		t.Errorf("got %d due events, want the new events to be leased to their first attempt", len(due))
+	}
+
+	makeDue(t, s, events)
+	due, err = s.FindDueEvents(DispatchLimit)
+	if err != nil {
+		t.Fatalf("FindDueEvents: %v", err)
+	}
+	if len(due) != 1 {
+		t.Errorf("got %d due events, want 1", len(due))
+	}
+}
+
+func TestLeaseEvent(t *testing.T) {
+	s := newTestService(t)
+	events := []Event{NewEvent("PUT", "/posts/score", []byte("{}"), nil)}
+	if err := s.SaveEvents(events); err != nil {
+		t.Fatalf("SaveEvents: %v", err)
+	}
+	makeDue(t, s, events)
+
+	first := events[0]
+	second := events[0]
+	leased, err := s.LeaseEvent(&first)
+	if err != nil || !leased {
+		t.Fatalf("got leased %v error %v, want the first lease to succeed", leased, err)
+	}
+	leased, err = s.LeaseEvent(&second)
+	if err != nil || leased {
+		t.Fatalf("got leased %v error %v, want the second lease to be refused", leased, err)
+	}
+	if got := findEvent(t, s, first.ObjectId).NextAttemptDate; got != first.NextAttemptDate {
+		t.Errorf("got next attempt %d, want %d", got, first.NextAttemptDate)
+	}
+}
+
+func TestMarkEventAttemptFailed(t *testing.T) {
+	s := newTestService(t)
+	events := []Event{NewEvent("PUT", "/posts/score", []byte("{}"), nil)}
+	if err := s.SaveEvents(events); err != nil {
+		t.Fatalf("SaveEvents: %v", err)
+	}
+
+	event := events[0]
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		if err := s.MarkEventAttemptFailed(&event, errors.New("unavailable")); err != nil {
+			t.Fatalf("MarkEventAttemptFailed: %v", err)
+		}
+		event = findEvent(t, s, event.ObjectId)
+		if event.Attempts != attempt {
+			t.Fatalf("got %d attempts, want %d", event.Attempts, attempt)
+		}
+		wantStatus := StatusPending
+		if attempt == maxAttempts {
+			wantStatus = StatusFailed
+		}
+		if event.Status != wantStatus {
+			t.Fatalf("attempt %d: got status %q, want %q", attempt, event.Status, wantStatus)
+		}
+		if event.LastError != "unavailable" {
+			t.Errorf("got last error %q, want %q", event.LastError, "unavailable")
+		}
+	}
+
+	failed, err := s.CountEventsByStatus(StatusFailed)
+	if err != nil {
+		t.Fatalf("CountEventsByStatus: %v", err)
+	}
+	if failed != 1 {
+		t.Errorf("got %d failed events, want 1", failed)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     int64
+	}{
+		{attempts: 1, want: backoffMillis},
+		{attempts: 2, want: 2 * backoffMillis},
+		{attempts: 4, want: 8 * backoffMillis},
+		{attempts: 8, want: maxBackoffMillis},
+		{attempts: 80, want: maxBackoffMillis},
+	}
+	for _, test := range tests {
+		if got := backoff(test.attempts); got != test.want {
+			t.Errorf("backoff(%d) = %d, want %d", test.attempts, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,208 @@
+// Package rpc sends the requests of a function to other functions through the internal gateway.
+// The requests are signed with HMAC and carry the request id and the user of the incoming request.
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/alexellis/hmac"
+	coreConfig "github.com/red-gold/telar-core/config"
+	"github.com/red-gold/telar-core/types"
+	"github.com/red-gold/telar-core/utils"
+)
+
+// Request a request to another function
+type Request struct {
+	// Method the HTTP method
+	Method string
+
+	// URL the path of the function on the internal gateway, e.g. /posts/score
+	URL string
+
+	// Body the JSON body which is signed with HMAC
+	Body []byte
+
+	// Header the headers of the request, they replace the user headers of the context
+	Header map[string][]string
+
+	// Timeout the timeout of each attempt, the timeout of the client is used when it is zero
+	Timeout time.Duration
+
+	// IdempotencyKey the key which the receiver keeps to apply the request once, e.g. the counters of posts.
+	// A request with a key is retried like a GET, set it only when the receiver ignores a key it already handled.
+	IdempotencyKey string
+}
+
+// IdempotencyKeyHeader the header which lets the receiver ignore a request it already handled
+const IdempotencyKeyHeader = "X-Idempotency-Key"
+
+// Client sends the requests to the internal gateway on a shared pool of connections
+type Client struct {
+	// HTTPClient the client which keeps the connections to the gateway
+	HTTPClient *http.Client
+
+	// Timeout the timeout of each attempt
+	Timeout time.Duration
+
+	// MaxRetries the number of retries of a safe request or a request with an idempotency key
+	// after a network error or an unavailable gateway
+	MaxRetries int
+
+	// RetryDelay the delay before the first retry, it doubles on each retry
+	RetryDelay time.Duration
+}
+
+// NewClient create a client with its own pool of connections
+func NewClient() *Client {
+	transport := &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   5 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   32,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   5 * time.Second,
+		ExpectContinueTimeout: time.Second,
+	}
+	return &Client{
+		HTTPClient: &http.Client{Transport: transport},
+		Timeout:    10 * time.Second,
+		MaxRetries: 2,
+		RetryDelay: 100 * time.Millisecond,
+	}
+}
+
+// DefaultClient the client which is shared by the handlers of the function
+var DefaultClient = NewClient()
+
+// Do send the request with the default client
+func Do(ctx context.Context, req Request) ([]byte, error) {
+	return DefaultClient.Do(ctx, req)
+}
+
+// Call send a request with the body to the URL with the default client
+func Call(ctx context.Context, method string, url string, body []byte) ([]byte, error) {
+	return DefaultClient.Do(ctx, Request{Method: method, URL: url, Body: body})
+}
+
+// Do send the request and return the body of the response.
+// A response with a non-2xx status is returned as a *StatusError.
+func (c *Client) Do(ctx context.Context, req Request) ([]byte, error) {
+	// A request which changes the receiver may be applied by an attempt which timed out,
+	// so it is sent again only if the receiver can tell the attempts apart
+	retries := 0
+	if isSafe(req.Method) || req.IdempotencyKey != "" {
+		retries = c.MaxRetries
+	}
+	if req.IdempotencyKey != "" {
+		req = withIdempotencyKey(req)
+	}
+
+	delay := c.RetryDelay
+	for attempt := 0; ; attempt++ {
+		resData, err := c.send(ctx, req)
+		if err == nil || attempt >= retries || !isRetryable(err) {
+			return resData, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, err
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// send make one attempt of the request
+func (c *Client) send(ctx context.Context, req Request) ([]byte, error) {
+	timeout := req.Timeout
+	if timeout == 0 {
+		timeout = c.Timeout
+	}
+	attemptCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	fullURL := *coreConfig.AppConfig.InternalGateway + utils.GetPrettyURLf(req.URL)
+	httpReq, err := http.NewRequestWithContext(attemptCtx, req.Method, fullURL, bytes.NewReader(req.Body))
+	if err != nil {
+		return nil, err
+	}
+
+	digest := hmac.Sign(req.Body, []byte(*coreConfig.AppConfig.PayloadSecret))
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set(types.HeaderHMACAuthenticate, "sha1="+hex.EncodeToString(digest))
+	if requestID := RequestIDFromContext(ctx); requestID != "" {
+		httpReq.Header.Set(requestIDHeader, requestID)
+	}
+	if user, ok := UserFromContext(ctx); ok {
+		for k, v := range UserHeaders(user) {
+			httpReq.Header[k] = v
+		}
+	}
+	for k, v := range req.Header {
+		httpReq.Header[k] = v
+	}
+
+	res, err := c.HTTPClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("rpc: %s %s: %w", req.Method, req.URL, err)
+	}
+	defer res.Body.Close()
+
+	resData, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, fmt.Errorf("rpc: read response of %s %s: %w", req.Method, req.URL, err)
+	}
+
+	if res.StatusCode < http.StatusOK || res.StatusCode >= http.StatusMultipleChoices {
+		return nil, &StatusError{
+			Method:     req.Method,
+			URL:        req.URL,
+			StatusCode: res.StatusCode,
+			Body:       resData,
+		}
+	}
+	return resData, nil
+}
+
+// isSafe check whether the method only reads, so the request can be sent again
+func isSafe(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
+// withIdempotencyKey set the idempotency key of the request on the header of all the attempts
+func withIdempotencyKey(req Request) Request {
+	header := make(map[string][]string, len(req.Header)+1)
+	for k, v := range req.Header {
+		header[k] = v
+	}
+	header[IdempotencyKeyHeader] = []string{req.IdempotencyKey}
+	req.Header = header
+	return req
+}
+
+// isRetryable check whether the error is a network error or a status of an unavailable function
+func isRetryable(err error) bool {
+	if statusErr, ok := err.(*StatusError); ok {
+		switch statusErr.StatusCode {
+		case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		}
+		return false
+	}
+	return true
+}
@@ -0,0 +1,76 @@
+package rpc
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	coreConfig "github.com/red-gold/telar-core/config"
+)
+
+// unavailableGateway start a gateway which responds with service unavailable and records the idempotency keys
+func unavailableGateway(t *testing.T) (*sync.Mutex, *[]string) {
+	t.Helper()
+	var mu sync.Mutex
+	keys := []string{}
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		keys = append(keys, r.Header.Get(IdempotencyKeyHeader))
+		mu.Unlock()
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(gateway.Close)
+
+	internalGateway := gateway.URL
+	payloadSecret := "secret"
+	baseRoute := ""
+	coreConfig.AppConfig.InternalGateway = &internalGateway
+	coreConfig.AppConfig.PayloadSecret = &payloadSecret
+	coreConfig.AppConfig.BaseRoute = &baseRoute
+	return &mu, &keys
+}
+
+func TestClientRetries(t *testing.T) {
+	tests := []struct {
+		name           string
+		method         string
+		idempotencyKey string
+		wantAttempts   int
+	}{
+		{name: "GET is retried", method: http.MethodGet, wantAttempts: 3},
+		{name: "POST is not retried", method: http.MethodPost, wantAttempts: 1},
+		{name: "PUT is not retried", method: http.MethodPut, wantAttempts: 1},
+		{name: "DELETE is not retried", method: http.MethodDelete, wantAttempts: 1},
+		{name: "PUT with a key is retried", method: http.MethodPut, idempotencyKey: "key", wantAttempts: 3},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mu, keys := unavailableGateway(t)
+			client := NewClient()
+			client.RetryDelay = time.Millisecond
+
+			_, err := client.Do(context.Background(), Request{
+				Method:         test.method,
+				URL:            "/posts/score",
+				IdempotencyKey: test.idempotencyKey,
+			})
+			if StatusCode(err) != http.StatusServiceUnavailable {
+				t.Fatalf("got error %v, want a status error", err)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if len(*keys) != test.wantAttempts {
+				t.Errorf("got %d attempts, want %d", len(*keys), test.wantAttempts)
+			}
+			for _, key := range *keys {
+				if key != test.idempotencyKey {
+					t.Errorf("got idempotency key %q, want %q", key, test.idempotencyKey)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package rpc
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofrs/uuid"
+	"github.com/red-gold/telar-core/types"
+)
+
+// requestIDHeader the header which carries the request id between the functions
+const requestIDHeader = fiber.HeaderXRequestID
+
+// requestIDLocalKey the key of the request id in the locals of fiber, it is set by the requestid middleware
+const requestIDLocalKey = "requestid"
+
+type contextKey int
+
+const (
+	requestIDContextKey contextKey = iota
+	userContextKey
+)
+
+// WithRequestID return a context which carries the request id
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDContextKey, requestID)
+}
+
+// RequestIDFromContext get the request id of the context
+func RequestIDFromContext(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDContextKey).(string)
+	return requestID
+}
+
+// WithUser return a context which carries the user, the requests with the context are sent on behalf of the user
+func WithUser(ctx context.Context, user types.UserContext) context.Context {
+	return context.WithValue(ctx, userContextKey, user)
+}
+
+// UserFromContext get the user of the context
+func UserFromContext(ctx context.Context) (types.UserContext, bool) {
+	user, ok := ctx.Value(userContextKey).(types.UserContext)
+	return user, ok
+}
+
+// Context create the context of the requests which are sent while handling the request of fiber.
+// It carries the request id and the current user, and it stays valid after the handler returns,
+// so it can be used by the requests which are sent in the background.
+func Context(c *fiber.Ctx) context.Context {
+	ctx := context.Background()
+	if requestID, ok := c.Locals(requestIDLocalKey).(string); ok && requestID != "" {
+		ctx = WithRequestID(ctx, requestID)
+	}
+	if user, ok := c.Locals(types.UserCtxName).(types.UserContext); ok && user.UserID != uuid.Nil {
+		ctx = WithUser(ctx, user)
+	}
+	return ctx
+}
+
+// UserHeaders the headers which the HMAC middleware of the other function reads the user from
+func UserHeaders(user types.UserContext) map[string][]string {
+	return map[string][]string{
+		"uid":         {user.UserID.String()},
+		"email":       {user.Username},
+		"avatar":      {user.Avatar},
+		"banner":      {user.Banner},
+		"tagLine":     {user.TagLine},
+		"displayName": {user.DisplayName},
+		"socialName":  {user.SocialName},
+		"role":        {user.SystemRole},
+		"createdDate": {strconv.FormatInt(user.CreatedDate, 10)},
+	}
+}
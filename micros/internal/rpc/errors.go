@@ -0,0 +1,33 @@
+package rpc
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// StatusError a response of another function with a non-2xx status
+type StatusError struct {
+	Method     string
+	URL        string
+	StatusCode int
+	Body       []byte
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("rpc: %s %s: invalid status %d %s", e.Method, e.URL, e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+// StatusCode get the status of the response if the error is a *StatusError, otherwise zero
+func StatusCode(err error) int {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode
+	}
+	return 0
+}
+
+// IsNotFound check whether the other function responded with not found
+func IsNotFound(err error) bool {
+	return StatusCode(err) == http.StatusNotFound
+}
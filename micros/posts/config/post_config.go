@@ -5,8 +5,13 @@ type (
 		BaseRoute      string
 		QueryPrettyURL bool
 		Debug          bool // Debug enables verbose logging of claims / cookies
+
+		// TrashRetentionDays the days which the deleted items are kept in the trash before they are purged
+		TrashRetentionDays int64
 	}
 )
 
 // PostConfig holds the configuration values from post-config.yml file
-var PostConfig Configuration
+var PostConfig = Configuration{
+	TrashRetentionDays: 30,
+}
@@ -0,0 +1,11 @@
+package dto
+
+import (
+	uuid "github.com/gofrs/uuid"
+)
+
+// Mention a user who is mentioned by @socialName in the content
+type Mention struct {
+	UserId     uuid.UUID `json:"userId" bson:"userId"`
+	SocialName string    `json:"socialName" bson:"socialName"`
+}
@@ -10,12 +10,14 @@ type Post struct {
 	PostTypeId       int                           `json:"postTypeId" bson:"postTypeId"`
 	Score            int64                         `json:"score" bson:"score"`
 	Votes            map[string]string             `json:"votes" bson:"votes"`
+	Reactions        map[string]int64              `json:"reactions" bson:"reactions"`
 	ViewCount        int64                         `json:"viewCount" bson:"viewCount"`
 	Body             string                        `json:"body" bson:"body"`
 	OwnerUserId      uuid.UUID                     `json:"ownerUserId" bson:"ownerUserId"`
 	OwnerDisplayName string                        `json:"ownerDisplayName" bson:"ownerDisplayName"`
 	OwnerAvatar      string                        `json:"ownerAvatar" bson:"ownerAvatar"`
 	Tags             []string                      `json:"tags" bson:"tags"`
+	Mentions         []Mention                     `json:"mentions" bson:"mentions"`
 	CommentCounter   int64                         `json:"commentCounter" bson:"commentCounter"`
 	Image            string                        `json:"image" bson:"image"`
 	ImageFullPath    string                        `json:"imageFullPath" bson:"imageFullPath"`
@@ -25,6 +27,9 @@ type Post struct {
 	Album            *PostAlbum                    `json:"album" bson:"album"`
 	DisableComments  bool                          `json:"disableComments" bson:"disableComments"`
 	DisableSharing   bool                          `json:"disableSharing" bson:"disableSharing"`
+	HideComments     bool                          `json:"hideComments" bson:"hideComments"`
+	SharedPostId     uuid.UUID                     `json:"sharedPostId" bson:"sharedPostId"`
+	ShareCounter     int64                         `json:"shareCounter" bson:"shareCounter"`
 	Deleted          bool                          `json:"deleted" bson:"deleted"`
 	DeletedDate      int64                         `json:"deletedDate" bson:"deletedDate"`
 	CreatedDate      int64                         `json:"created_date" bson:"created_date"`
@@ -32,4 +37,9 @@ type Post struct {
 	AccessUserList   []string                      `json:"accessUserList" bson:"accessUserList"`
 	Permission       constants.UserPermissionConst `json:"permission" bson:"permission"`
 	Version          string                        `json:"version" bson:"version"`
+
+	// SharedPost the original post of a repost when the viewer can see it, it is not stored
+	SharedPost *Post `json:"sharedPost,omitempty" bson:"-"`
+	// SharedPostUnavailable the original post of a repost is deleted or hidden from the viewer, it is not stored
+	SharedPostUnavailable bool `json:"sharedPostUnavailable,omitempty" bson:"-"`
 }
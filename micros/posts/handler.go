@@ -3,6 +3,7 @@ package function
 import (
 	"context"
 	"net/http"
+	"time"
 
 	"github.com/gofiber/adaptor/v2"
 	"github.com/gofiber/fiber/v2"
@@ -14,16 +15,26 @@ import (
 	"github.com/red-gold/telar-core/pkg/log"
 	"github.com/red-gold/telar-core/utils"
 	micros "github.com/red-gold/ts-serverless/micros"
+	"github.com/red-gold/ts-serverless/micros/internal/index"
+	"github.com/red-gold/ts-serverless/micros/internal/job"
+	"github.com/red-gold/ts-serverless/micros/internal/outbox"
+	postConfig "github.com/red-gold/ts-serverless/micros/posts/config"
 	"github.com/red-gold/ts-serverless/micros/posts/database"
+	"github.com/red-gold/ts-serverless/micros/posts/handlers"
 	"github.com/red-gold/ts-serverless/micros/posts/router"
+	service "github.com/red-gold/ts-serverless/micros/posts/services"
 )
 
+// purgeInterval the interval of the job which removes the items which are out of the trash retention
+const purgeInterval = time.Hour
+
 // Cache state
 var app *fiber.App
 
 func init() {
 
 	micros.InitConfig()
+	postConfig.InitConfig()
 
 	// Initialize app
 	app = fiber.New(fiber.Config{
@@ -70,9 +81,35 @@ func Handle(w http.ResponseWriter, r *http.Request) {
 			log.Error("Error startup: %s", startErr.Error())
 			w.WriteHeader(http.StatusInternalServerError)
 			w.Write([]byte(startErr.Error()))
+		} else {
+			go index.Reconcile(newIndexService)
+			go job.Run(ctx, "trash purge", purgeInterval, purgeTrash)
+			go outbox.RunDispatcher(ctx, newOutboxService, outbox.DispatchInterval)
 		}
 	}
 
 	adaptor.FiberApp(app)(w, r)
 
 }
+
+// newIndexService create the index service of the function for the reconciler
+func newIndexService() (index.Service, error) {
+	return service.NewIndexService(database.Db)
+}
+
+// newOutboxService create the outbox service of the function for the dispatcher
+func newOutboxService() (outbox.Service, error) {
+	return service.NewOutboxService(database.Db)
+}
+
+// purgeTrash remove the posts which are out of the trash retention
+func purgeTrash() error {
+	purged, err := handlers.PurgeTrash()
+	if err != nil {
+		return err
+	}
+	if purged > 0 {
+		log.Info("Trash purge removed %d posts", purged)
+	}
+	return nil
+}
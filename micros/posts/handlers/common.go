@@ -1,22 +1,18 @@
 package handlers
 
 import (
-	"bytes"
-	"encoding/hex"
+	"context"
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
 	"math/rand"
 	"net/http"
 	"strings"
 	"time"
 
-	"github.com/alexellis/hmac"
 	uuid "github.com/gofrs/uuid"
-	coreConfig "github.com/red-gold/telar-core/config"
 	"github.com/red-gold/telar-core/pkg/log"
 	"github.com/red-gold/telar-core/types"
-	"github.com/red-gold/telar-core/utils"
+	"github.com/red-gold/ts-serverless/micros/internal/rpc"
 	models "github.com/red-gold/ts-serverless/micros/posts/models"
 )
 
@@ -51,67 +47,170 @@ func generatPostURLKey(socialName, body, postId string) string {
 	return strings.ToLower(fmt.Sprintf("%s_%s-post-%s-%s", socialName, strings.ReplaceAll(contetn, " ", "-"), strings.Split(postId, "-")[0], StringRand(5)))
 }
 
-// functionCall send request to another function/microservice using cookie validation
-func functionCall(method string, bytesReq []byte, url string, header map[string][]string) ([]byte, error) {
-	prettyURL := utils.GetPrettyURLf(url)
-	bodyReader := bytes.NewBuffer(bytesReq)
-
-	httpReq, httpErr := http.NewRequest(method, *coreConfig.AppConfig.InternalGateway+prettyURL, bodyReader)
-	if httpErr != nil {
-		return nil, httpErr
+// getUserProfileByID Get user profile by user ID
+func getUserProfileByID(ctx context.Context, userID uuid.UUID) (*models.UserProfileModel, error) {
+	profileURL := fmt.Sprintf("/profile/dto/id/%s", userID.String())
+	foundProfileData, err := rpc.Call(ctx, http.MethodGet, profileURL, []byte(""))
+	if err != nil {
+		if rpc.IsNotFound(err) {
+			return nil, nil
+		}
+		log.Error("rpc.Call (%s) -  %s", profileURL, err.Error())
+		return nil, fmt.Errorf("getUserProfileByID/rpc")
+	}
+	var foundProfile models.UserProfileModel
+	err = json.Unmarshal(foundProfileData, &foundProfile)
+	if err != nil {
+		log.Error("Unmarshal foundProfile -  %s", err.Error())
+		return nil, fmt.Errorf("getUserProfileByID/unmarshal")
 	}
-	payloadSecret := *coreConfig.AppConfig.PayloadSecret
+	return &foundProfile, nil
+}
 
-	digest := hmac.Sign(bytesReq, []byte(payloadSecret))
-	httpReq.Header.Set("Content-type", "application/json")
-	fmt.Printf("\ndigest: %s, header: %v \n", "sha1="+hex.EncodeToString(digest), types.HeaderHMACAuthenticate)
-	httpReq.Header.Add(types.HeaderHMACAuthenticate, "sha1="+hex.EncodeToString(digest))
-	if header != nil {
-		for k, v := range header {
-			httpReq.Header[k] = v
+// getProfileBySocialName Get user profile by social name
+func getProfileBySocialName(ctx context.Context, socialName string) (*models.UserProfileModel, error) {
+	profileURL := fmt.Sprintf("/profile/social/%s", socialName)
+	foundProfileData, err := rpc.Call(ctx, http.MethodGet, profileURL, []byte(""))
+	if err != nil {
+		if rpc.IsNotFound(err) {
+			return nil, nil
 		}
+		log.Error("rpc.Call (%s) -  %s", profileURL, err.Error())
+		return nil, fmt.Errorf("getProfileBySocialName/rpc")
 	}
-	c := http.Client{}
-	res, reqErr := c.Do(httpReq)
-	fmt.Printf("\nRes: %v\n", res)
-	if reqErr != nil {
-		return nil, fmt.Errorf("Error while sending admin check request!: %s", reqErr.Error())
+	var foundProfile models.UserProfileModel
+	err = json.Unmarshal(foundProfileData, &foundProfile)
+	if err != nil {
+		log.Error("Unmarshal foundProfile -  %s", err.Error())
+		return nil, fmt.Errorf("getProfileBySocialName/unmarshal")
+	}
+	return &foundProfile, nil
+}
+
+// getFollowingIds Get the ids of the users that the current user follows
+func getFollowingIds(ctx context.Context) ([]uuid.UUID, error) {
+	followingURL := "/user-rels/following/ids"
+	followingData, err := rpc.Call(ctx, http.MethodGet, followingURL, []byte(""))
+	if err != nil {
+		log.Error("rpc.Call (%s) -  %s", followingURL, err.Error())
+		return nil, fmt.Errorf("getFollowingIds/rpc")
 	}
-	if res.Body != nil {
-		defer res.Body.Close()
+	var followingIds []uuid.UUID
+	err = json.Unmarshal(followingData, &followingIds)
+	if err != nil {
+		log.Error("Unmarshal followingIds -  %s", err.Error())
+		return nil, fmt.Errorf("getFollowingIds/unmarshal")
 	}
+	return followingIds, nil
+}
 
-	resData, readErr := ioutil.ReadAll(res.Body)
-	if resData == nil || readErr != nil {
-		return nil, fmt.Errorf("failed to read response from admin check request.")
+// getCircleMemberships Get the circles that other users put the current user in
+func getCircleMemberships(ctx context.Context) ([]models.CircleMembershipModel, error) {
+	membershipURL := "/user-rels/circles/membership"
+	membershipData, err := rpc.Call(ctx, http.MethodGet, membershipURL, []byte(""))
+	if err != nil {
+		log.Error("rpc.Call (%s) -  %s", membershipURL, err.Error())
+		return nil, fmt.Errorf("getCircleMemberships/rpc")
 	}
+	var memberships []models.CircleMembershipModel
+	err = json.Unmarshal(membershipData, &memberships)
+	if err != nil {
+		log.Error("Unmarshal memberships -  %s", err.Error())
+		return nil, fmt.Errorf("getCircleMemberships/unmarshal")
+	}
+	return memberships, nil
+}
 
-	if res.StatusCode != http.StatusAccepted && res.StatusCode != http.StatusOK {
-		if res.StatusCode == http.StatusNotFound {
-			return nil, NotFoundHTTPStatusError
-		}
-		return nil, fmt.Errorf("failed to call %s api, invalid status: %s", prettyURL, res.Status)
+// getUserRestrictions Get the users which the current user blocked, muted or is blocked by
+func getUserRestrictions(ctx context.Context) (*models.UserRestrictionsModel, error) {
+	restrictionsURL := "/user-rels/restrictions"
+	restrictionsData, err := rpc.Call(ctx, http.MethodGet, restrictionsURL, []byte(""))
+	if err != nil {
+		log.Error("rpc.Call (%s) -  %s", restrictionsURL, err.Error())
+		return nil, fmt.Errorf("getUserRestrictions/rpc")
+	}
+	var restrictions models.UserRestrictionsModel
+	err = json.Unmarshal(restrictionsData, &restrictions)
+	if err != nil {
+		log.Error("Unmarshal restrictions -  %s", err.Error())
+		return nil, fmt.Errorf("getUserRestrictions/unmarshal")
 	}
+	return &restrictions, nil
+}
 
-	return resData, nil
+// getPostViewer Get the current user as post viewer
+// If the circle memberships are not available, the viewer only sees posts that are not shared through circles.
+// If the restrictions are not available, the request fails, the posts of the blocked and muted users are never shown.
+func getPostViewer(ctx context.Context, currentUser types.UserContext) (*models.PostViewerModel, error) {
+	viewer := &models.PostViewerModel{
+		UserId:  currentUser.UserID,
+		Circles: []models.CircleMembershipModel{},
+	}
+	if currentUser.UserID == uuid.Nil {
+		return viewer, nil
+	}
+	memberships, err := getCircleMemberships(ctx)
+	if err != nil {
+		log.Error("[getPostViewer] %s", err.Error())
+	} else {
+		viewer.Circles = memberships
+	}
+	restrictions, err := getUserRestrictions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	viewer.BlockedUserIds = restrictions.BlockedUserIds
+	viewer.MutedUserIds = restrictions.MutedUserIds
+	return viewer, nil
 }
 
-// getUserProfileByID Get user profile by user ID
-func getUserProfileByID(userID uuid.UUID) (*models.UserProfileModel, error) {
-	profileURL := fmt.Sprintf("/profile/dto/id/%s", userID.String())
-	foundProfileData, err := functionCall(http.MethodGet, []byte(""), profileURL, nil)
+// getPostCommentCounts Get the number of the comments which are not deleted for each post from the comments function
+func getPostCommentCounts(ctx context.Context, postIds []uuid.UUID) (map[uuid.UUID]int64, error) {
+	countsURL := "/comments/post/counts"
+	body, marshalErr := json.Marshal(models.PostIdsModel{PostIds: postIds})
+	if marshalErr != nil {
+		log.Error("Marshal models.PostIdsModel -  %s", marshalErr.Error())
+		return nil, fmt.Errorf("getPostCommentCounts/marshal")
+	}
+
+	countsData, err := rpc.Call(ctx, http.MethodPost, countsURL, body)
 	if err != nil {
-		if err == NotFoundHTTPStatusError {
-			return nil, nil
-		}
-		log.Error("functionCall (%s) -  %s", profileURL, err.Error())
-		return nil, fmt.Errorf("getUserProfileByID/functionCall")
+		log.Error("rpc.Call (%s) -  %s", countsURL, err.Error())
+		return nil, fmt.Errorf("getPostCommentCounts/rpc")
 	}
-	var foundProfile models.UserProfileModel
-	err = json.Unmarshal(foundProfileData, &foundProfile)
+	var countList []models.PostCommentCountModel
+	err = json.Unmarshal(countsData, &countList)
 	if err != nil {
-		log.Error("Unmarshal foundProfile -  %s", err.Error())
-		return nil, fmt.Errorf("getUserProfileByID/unmarshal")
+		log.Error("Unmarshal countList -  %s", err.Error())
+		return nil, fmt.Errorf("getPostCommentCounts/unmarshal")
 	}
-	return &foundProfile, nil
+
+	counts := make(map[uuid.UUID]int64)
+	for _, postCount := range countList {
+		counts[postCount.PostId] = postCount.Count
+	}
+	return counts, nil
+}
+
+// getPostVoters Get the voters of each post from the votes function
+func getPostVoters(ctx context.Context, postIds []uuid.UUID) ([]models.PostVotersModel, error) {
+	votersURL := "/votes/post/voters"
+	body, marshalErr := json.Marshal(models.PostIdsModel{PostIds: postIds})
+	if marshalErr != nil {
+		log.Error("Marshal models.PostIdsModel -  %s", marshalErr.Error())
+		return nil, fmt.Errorf("getPostVoters/marshal")
+	}
+
+	votersData, err := rpc.Call(ctx, http.MethodPost, votersURL, body)
+	if err != nil {
+		log.Error("rpc.Call (%s) -  %s", votersURL, err.Error())
+		return nil, fmt.Errorf("getPostVoters/rpc")
+	}
+	var postVoters []models.PostVotersModel
+	err = json.Unmarshal(votersData, &postVoters)
+	if err != nil {
+		log.Error("Unmarshal postVoters -  %s", err.Error())
+		return nil, fmt.Errorf("getPostVoters/unmarshal")
+	}
+	return postVoters, nil
 }
@@ -8,6 +8,8 @@ import (
 	"github.com/red-gold/telar-core/pkg/log"
 	"github.com/red-gold/telar-core/types"
 	"github.com/red-gold/telar-core/utils"
+	"github.com/red-gold/ts-serverless/micros/internal/outbox"
+	"github.com/red-gold/ts-serverless/micros/internal/rpc"
 	"github.com/red-gold/ts-serverless/micros/posts/database"
 	domain "github.com/red-gold/ts-serverless/micros/posts/dto"
 	models "github.com/red-gold/ts-serverless/micros/posts/models"
@@ -31,6 +33,12 @@ func CreatePostHandle(c *fiber.Ctx) error {
 		log.Error("NewPostService %s", serviceErr.Error())
 		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/postService", "Error happened while creating postService!"))
 	}
+	outboxService, serviceErr := service.NewOutboxService(database.Db)
+	if serviceErr != nil {
+		log.Error("NewOutboxService %s", serviceErr.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/outboxService", "Error happened while creating outboxService!"))
+	}
+
 	var newAlbum *domain.PostAlbum = nil
 
 	if len(model.Album.Photos) > 0 {
@@ -50,18 +58,26 @@ func CreatePostHandle(c *fiber.Ctx) error {
 			"Can not get current user"))
 	}
 
+	mentions, err := resolveMentions(rpc.Context(c), model.Body)
+	if err != nil {
+		log.Error("[CreatePostHandle.resolveMentions] %s", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/resolveMentions", "Error happened while resolving mentions!"))
+	}
+
 	newPost := &domain.Post{
 		ObjectId:         model.ObjectId,
 		PostTypeId:       model.PostTypeId,
 		OwnerUserId:      currentUser.UserID,
 		Score:            model.Score,
 		Votes:            make(map[string]string),
+		Reactions:        make(map[string]int64),
 		ViewCount:        model.ViewCount,
 		Body:             model.Body,
 		OwnerDisplayName: currentUser.DisplayName,
 		OwnerAvatar:      currentUser.Avatar,
 		URLKey:           generatPostURLKey(currentUser.SocialName, model.Body, model.ObjectId.String()),
-		Tags:             model.Tags,
+		Tags:             mergeTags(model.Tags, model.Body),
+		Mentions:         mentions,
 		CommentCounter:   model.CommentCounter,
 		Image:            model.Image,
 		ImageFullPath:    model.ImageFullPath,
@@ -85,31 +101,20 @@ func CreatePostHandle(c *fiber.Ctx) error {
 		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/savePost", "Error happened while save post!"))
 	}
 
-	return c.JSON(fiber.Map{
-		"objectId": newPost.ObjectId.String(),
-	})
-
-}
-
-// InitPostIndexHandle handle create a new post
-func InitPostIndexHandle(c *fiber.Ctx) error {
-
-	// Create service
-	postService, serviceErr := service.NewPostService(database.Db)
-	if serviceErr != nil {
-		log.Error("NewPostService %s", serviceErr.Error())
-		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/postService", "Error happened while creating postService!"))
+	// The post is removed if the notifications of the mentioned users can not be stored
+	events := mentionNotificationEvents(currentUser, mentions, newPost, rpc.UserHeaders(currentUser))
+	rollbackPost := func() error {
+		return postService.DeletePostByOwner(currentUser.UserID, newPost.ObjectId)
 	}
-
-	postIndexMap := make(map[string]interface{})
-	postIndexMap["body"] = "text"
-	postIndexMap["objectId"] = 1
-	if err := postService.CreatePostIndex(postIndexMap); err != nil {
-		errorMessage := fmt.Sprintf("Create post index Error %s", err.Error())
-		log.Error(errorMessage)
-		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/createPostIndex", "Error happened while creating post index!"))
+	if err := outbox.SaveEvents(outboxService, events, rollbackPost); err != nil {
+		log.Error("[CreatePostHandle.saveOutboxEvents] %s", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/savePost", "Error happened while save post!"))
 	}
 
-	return c.SendStatus(http.StatusOK)
+	go outbox.Dispatch(outboxService, events)
+
+	return c.JSON(fiber.Map{
+		"objectId": newPost.ObjectId.String(),
+	})
 
 }
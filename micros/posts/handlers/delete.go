@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
 
@@ -9,11 +10,16 @@ import (
 	"github.com/red-gold/telar-core/pkg/log"
 	"github.com/red-gold/telar-core/types"
 	"github.com/red-gold/telar-core/utils"
+	"github.com/red-gold/ts-serverless/micros/internal/outbox"
+	"github.com/red-gold/ts-serverless/micros/internal/rpc"
 	"github.com/red-gold/ts-serverless/micros/posts/database"
+	domain "github.com/red-gold/ts-serverless/micros/posts/dto"
+	models "github.com/red-gold/ts-serverless/micros/posts/models"
 	service "github.com/red-gold/ts-serverless/micros/posts/services"
 )
 
 // DeletePostHandle handle delete a post
+// The post is marked as deleted, its comments, votes and album media are hidden and its notifications are retracted.
 func DeletePostHandle(c *fiber.Ctx) error {
 
 	// params from /posts/:postId
@@ -38,6 +44,12 @@ func DeletePostHandle(c *fiber.Ctx) error {
 		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/postService", "Error happened while creating postService!"))
 	}
 
+	outboxService, serviceErr := service.NewOutboxService(database.Db)
+	if serviceErr != nil {
+		log.Error("NewOutboxService %s", serviceErr.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/outboxService", "Error happened while creating outboxService!"))
+	}
+
 	currentUser, ok := c.Locals(types.UserCtxName).(types.UserContext)
 	if !ok {
 		log.Error("[DeletePostHandle] Can not get current user")
@@ -45,12 +57,159 @@ func DeletePostHandle(c *fiber.Ctx) error {
 			"Can not get current user"))
 	}
 
-	if err := postService.DeletePostByOwner(currentUser.UserID, postUUID); err != nil {
+	deletedPost, err := postService.SoftDeletePostByOwner(currentUser.UserID, postUUID)
+	if err != nil {
 		errorMessage := fmt.Sprintf("Delete Post Error %s", err.Error())
 		log.Error(errorMessage)
 		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/deletePost", "Error happened while deleting post!"))
 	}
+	if deletedPost == nil {
+		return c.Status(http.StatusNotFound).JSON(utils.Error("postNotFound", "Post not found!"))
+	}
+
+	// The post is restored if the cascade can not be stored
+	events := postCascadeEvents(deletedPost, true, deletedPost.DeletedDate, rpc.UserHeaders(currentUser))
+	rollbackPost := func() error {
+		_, err := postService.RestorePostByOwner(currentUser.UserID, postUUID, deletedPost.DeletedDate)
+		return err
+	}
+	if err := outbox.SaveEvents(outboxService, events, rollbackPost); err != nil {
+		log.Error("[DeletePostHandle.saveOutboxEvents] %s", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/deletePost", "Error happened while deleting post!"))
+	}
+
+	// A deleted repost is not counted as a share of the original post
+	updateShareCounter(postService, deletedPost, -1)
+
+	go outbox.Dispatch(outboxService, events)
+
+	return c.SendStatus(http.StatusOK)
+
+}
+
+// RestorePostHandle handle restore a post which is in the trash
+// The comments, votes and album media of the post are shown again, the retracted notifications are not sent again.
+func RestorePostHandle(c *fiber.Ctx) error {
+
+	// params from /posts/restore/:postId
+	postId := c.Params("postId")
+	if postId == "" {
+		errorMessage := fmt.Sprintf("Post Id is required!")
+		log.Error(errorMessage)
+		return c.Status(http.StatusBadRequest).JSON(utils.Error("postIdRequired", errorMessage))
+	}
+
+	postUUID, uuidErr := uuid.FromString(postId)
+	if uuidErr != nil {
+		errorMessage := fmt.Sprintf("UUID Error %s", uuidErr.Error())
+		log.Error(errorMessage)
+		return c.Status(http.StatusBadRequest).JSON(utils.Error("postIdIsNotValid", "Post id is not valid!"))
+	}
+
+	// Create service
+	postService, serviceErr := service.NewPostService(database.Db)
+	if serviceErr != nil {
+		log.Error("NewPostService %s", serviceErr.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/postService", "Error happened while creating postService!"))
+	}
+
+	outboxService, serviceErr := service.NewOutboxService(database.Db)
+	if serviceErr != nil {
+		log.Error("NewOutboxService %s", serviceErr.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/outboxService", "Error happened while creating outboxService!"))
+	}
+
+	currentUser, ok := c.Locals(types.UserCtxName).(types.UserContext)
+	if !ok {
+		log.Error("[RestorePostHandle] Can not get current user")
+		return c.Status(http.StatusBadRequest).JSON(utils.Error("invalidCurrentUser",
+			"Can not get current user"))
+	}
+
+	restoredPost, err := postService.RestorePostByOwner(currentUser.UserID, postUUID, trashCutoff())
+	if err != nil {
+		log.Error("[RestorePostHandle.postService.RestorePostByOwner] %s", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/restorePost", "Error happened while restoring post!"))
+	}
+
+	if restoredPost == nil {
+		foundPost, err := postService.FindById(postUUID)
+		if err != nil {
+			log.Error("[RestorePostHandle.postService.FindById] %s", err.Error())
+			return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/restorePost", "Error happened while restoring post!"))
+		}
+		if foundPost == nil || foundPost.OwnerUserId != currentUser.UserID || !foundPost.Deleted {
+			return c.Status(http.StatusNotFound).JSON(utils.Error("postNotFound", "Post not found!"))
+		}
+		return c.Status(http.StatusBadRequest).JSON(utils.Error("restoreWindowExpired", "The post can not be restored anymore!"))
+	}
+
+	// The post is deleted again if the cascade can not be stored
+	events := postCascadeEvents(restoredPost, false, utils.UTCNowUnix(), rpc.UserHeaders(currentUser))
+	rollbackPost := func() error {
+		_, err := postService.SoftDeletePostByOwner(currentUser.UserID, postUUID)
+		return err
+	}
+	if err := outbox.SaveEvents(outboxService, events, rollbackPost); err != nil {
+		log.Error("[RestorePostHandle.saveOutboxEvents] %s", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/restorePost", "Error happened while restoring post!"))
+	}
+
+	updateShareCounter(postService, restoredPost, 1)
+
+	go outbox.Dispatch(outboxService, events)
 
 	return c.SendStatus(http.StatusOK)
+}
+
+// postCascadeEvents create the requests which hide or show the comments, the votes and the album media of the post.
+// The version orders the requests of a delete and a restore which are delivered out of order.
+// The notifications of the post are retracted when it is deleted.
+func postCascadeEvents(post *domain.Post, deleted bool, version int64, userHeaders map[string][]string) []outbox.Event {
+	var events []outbox.Event
+
+	postDeletedPayload, err := json.Marshal(models.PostDeletedModel{Deleted: deleted, Version: version})
+	if err != nil {
+		log.Error("[postCascadeEvents] Can not marshal post deleted model: %s", err.Error())
+		return events
+	}
+	events = append(events,
+		outbox.NewEvent(http.MethodPut, fmt.Sprintf("/comments/post/%s/deleted", post.ObjectId), postDeletedPayload, userHeaders),
+		outbox.NewEvent(http.MethodPut, fmt.Sprintf("/votes/post/%s/deleted", post.ObjectId), postDeletedPayload, userHeaders),
+	)
+
+	if release := albumRelease(post, deleted); release != nil {
+		releasePayload, err := json.Marshal(release)
+		if err != nil {
+			log.Error("[postCascadeEvents] Can not marshal album release model: %s", err.Error())
+		} else {
+			events = append(events, outbox.NewEvent(http.MethodPut, "/media/album/release", releasePayload, userHeaders))
+		}
+	}
 
+	if deleted {
+		events = append(events, outbox.NewEvent(http.MethodDelete, fmt.Sprintf("/notifications/target/%s", post.ObjectId), nil, userHeaders))
+	}
+	return events
+}
+
+// albumRelease create the model which releases the media of the post album, it is nil if the post has no album
+func albumRelease(post *domain.Post, released bool) *models.AlbumReleaseModel {
+	if post.Album == nil || (len(post.Album.Photos) == 0 && post.Album.CoverId == uuid.Nil) {
+		return nil
+	}
+
+	release := &models.AlbumReleaseModel{
+		OwnerUserId: post.OwnerUserId,
+		MediaIds:    []uuid.UUID{},
+		URLs:        post.Album.Photos,
+		Released:    released,
+	}
+	if post.Album.CoverId != uuid.Nil {
+		release.MediaIds = append(release.MediaIds, post.Album.CoverId)
+	}
+	if release.URLs == nil {
+		release.URLs = []string{}
+	}
+	return release
 }
@@ -1,5 +0,0 @@
-package handlers
-
-import "errors"
-
-var NotFoundHTTPStatusError = errors.New("NotFoundHTTPStatusError")
@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	uuid "github.com/gofrs/uuid"
+	"github.com/red-gold/telar-core/pkg/log"
+	"github.com/red-gold/telar-core/pkg/parser"
+	"github.com/red-gold/telar-core/types"
+	"github.com/red-gold/telar-core/utils"
+	"github.com/red-gold/ts-serverless/micros/internal/outbox"
+	"github.com/red-gold/ts-serverless/micros/internal/rpc"
+	"github.com/red-gold/ts-serverless/micros/posts/database"
+	domain "github.com/red-gold/ts-serverless/micros/posts/dto"
+	models "github.com/red-gold/ts-serverless/micros/posts/models"
+	service "github.com/red-gold/ts-serverless/micros/posts/services"
+)
+
+// maxMentions the number of mentions which are resolved in a content, the rest are kept as plain text
+const maxMentions = 20
+
+// mentionPattern matches @socialName which is not a part of a word or an email
+var mentionPattern = regexp.MustCompile(`(?:^|[^\w@.])@([\w.]+)`)
+
+type MentionQueryModel struct {
+	Page int64 `query:"page"`
+}
+
+// parseMentions get the distinct social names which are mentioned in the text
+func parseMentions(text string) []string {
+	socialNames := []string{}
+	seen := make(map[string]bool)
+	for _, match := range mentionPattern.FindAllStringSubmatch(text, -1) {
+		// The dots at the end belong to the sentence
+		socialName := strings.TrimRight(match[1], ".")
+		key := strings.ToLower(socialName)
+		if socialName == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		socialNames = append(socialNames, socialName)
+		if len(socialNames) == maxMentions {
+			break
+		}
+	}
+	return socialNames
+}
+
+// resolveMentions find the users who are mentioned in the text, the social names without a user are skipped
+func resolveMentions(ctx context.Context, text string) ([]domain.Mention, error) {
+	mentions := []domain.Mention{}
+	seen := make(map[uuid.UUID]bool)
+	for _, socialName := range parseMentions(text) {
+		profile, err := getProfileBySocialName(ctx, socialName)
+		if err != nil {
+			return nil, err
+		}
+		if profile == nil || seen[profile.ObjectId] {
+			continue
+		}
+		seen[profile.ObjectId] = true
+		mentions = append(mentions, domain.Mention{
+			UserId:     profile.ObjectId,
+			SocialName: profile.SocialName,
+		})
+	}
+	return mentions, nil
+}
+
+// addedMentions get the mentions which are not in the previous mentions
+func addedMentions(mentions []domain.Mention, previous []domain.Mention) []domain.Mention {
+	previousUsers := make(map[uuid.UUID]bool)
+	for _, mention := range previous {
+		previousUsers[mention.UserId] = true
+	}
+	var added []domain.Mention
+	for _, mention := range mentions {
+		if !previousUsers[mention.UserId] {
+			added = append(added, mention)
+		}
+	}
+	return added
+}
+
+// mentionNotificationEvents create the notification requests of the mentioned users, the author is not notified
+func mentionNotificationEvents(currentUser types.UserContext, mentions []domain.Mention, post *domain.Post, userHeaders map[string][]string) []outbox.Event {
+	var events []outbox.Event
+	for _, mention := range mentions {
+		if mention.UserId == currentUser.UserID {
+			continue
+		}
+		notificationModel := &models.NotificationModel{
+			OwnerUserId:          currentUser.UserID,
+			OwnerDisplayName:     currentUser.DisplayName,
+			OwnerAvatar:          currentUser.Avatar,
+			Title:                currentUser.DisplayName,
+			Description:          "mentioned you in a post.",
+			URL:                  fmt.Sprintf("/posts/%s", post.URLKey),
+			NotifyRecieverUserId: mention.UserId,
+			TargetId:             post.ObjectId,
+			IsSeen:               false,
+			Type:                 "mention",
+		}
+		notificationBytes, err := json.Marshal(notificationModel)
+		if err != nil {
+			log.Error("[mentionNotificationEvents] Cannot marshal notification! error: %s", err.Error())
+			continue
+		}
+		events = append(events, outbox.NewEvent(http.MethodPost, "/notifications", notificationBytes, userHeaders))
+	}
+	return events
+}
+
+// GetPostsByMentionHandle handle get the posts which mention a user and the current user is allowed to see
+func GetPostsByMentionHandle(c *fiber.Ctx) error {
+
+	// params from /posts/mention/:userId
+	userId := c.Params("userId")
+	if userId == "" {
+		errorMessage := fmt.Sprintf("User Id is required!")
+		log.Error(errorMessage)
+		return c.Status(http.StatusBadRequest).JSON(utils.Error("userIdRequired", errorMessage))
+	}
+
+	userUUID, uuidErr := uuid.FromString(userId)
+	if uuidErr != nil {
+		errorMessage := fmt.Sprintf("UUID Error %s", uuidErr.Error())
+		log.Error(errorMessage)
+		return c.Status(http.StatusBadRequest).JSON(utils.Error("userIdIsNotValid", "User id is not valid!"))
+	}
+
+	// Create service
+	postService, serviceErr := service.NewPostService(database.Db)
+	if serviceErr != nil {
+		log.Error("NewPostService %s", serviceErr.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/postService", "Error happened while creating postService!"))
+	}
+
+	query := new(MentionQueryModel)
+	if err := parser.QueryParser(c, query); err != nil {
+		log.Error("[GetPostsByMentionHandle] QueryParser %s", err.Error())
+		return c.Status(http.StatusBadRequest).JSON(utils.Error("queryParser", "Error happened while parsing query!"))
+	}
+
+	currentUser, ok := c.Locals(types.UserCtxName).(types.UserContext)
+	if !ok {
+		log.Error("[GetPostsByMentionHandle] Can not get current user")
+		return c.Status(http.StatusBadRequest).JSON(utils.Error("invalidCurrentUser", "Can not get current user"))
+	}
+
+	viewer, err := getPostViewer(rpc.Context(c), currentUser)
+	if err != nil {
+		log.Error("[GetPostsByMentionHandle.getPostViewer] %s", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/getUserRestrictions", "Error while getting user restrictions"))
+	}
+	postList, err := postService.QueryPostByMention(userUUID, query.Page, viewer)
+	if err != nil {
+		log.Error("[GetPostsByMentionHandle.postService.QueryPostByMention] %s", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/queryPost", "Error happened while query post!"))
+	}
+
+	if err := attachSharedPosts(postService, postList, viewer); err != nil {
+		log.Error("[GetPostsByMentionHandle.attachSharedPosts] %s ", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/queryPost", "Error happened while query post!"))
+	}
+
+	if postList == nil {
+		postList = []domain.Post{}
+	}
+
+	return c.JSON(postList)
+}
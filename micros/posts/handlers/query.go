@@ -8,17 +8,25 @@ import (
 	uuid "github.com/gofrs/uuid"
 	"github.com/red-gold/telar-core/pkg/log"
 	"github.com/red-gold/telar-core/pkg/parser"
+	"github.com/red-gold/telar-core/types"
 	utils "github.com/red-gold/telar-core/utils"
+	"github.com/red-gold/ts-serverless/micros/internal/cursor"
+	"github.com/red-gold/ts-serverless/micros/internal/rpc"
 	"github.com/red-gold/ts-serverless/micros/posts/database"
+	dto "github.com/red-gold/ts-serverless/micros/posts/dto"
 	models "github.com/red-gold/ts-serverless/micros/posts/models"
 	service "github.com/red-gold/ts-serverless/micros/posts/services"
 )
 
 type PostQueryModel struct {
 	Search string      `query:"search"`
+	Tag    string      `query:"tag"`
 	Page   int64       `query:"page"`
 	Owner  []uuid.UUID `query:"owner"`
 	Type   int         `query:"type"`
+	Limit  int64       `query:"limit"`
+	After  string      `query:"after"`
+	Before string      `query:"before"`
 }
 
 // QueryPostHandle handle query on post
@@ -38,13 +46,149 @@ func QueryPostHandle(c *fiber.Ctx) error {
 		return c.Status(http.StatusBadRequest).JSON(utils.Error("queryParser", "Error happened while parsing query!"))
 	}
 
-	postList, err := postService.QueryPostIncludeUser(query.Search, query.Owner, query.Type, "created_date", query.Page)
+	currentUser, ok := c.Locals(types.UserCtxName).(types.UserContext)
+	if !ok {
+		log.Error("[QueryPostHandle] Can not get current user")
+		return c.Status(http.StatusBadRequest).JSON(utils.Error("invalidCurrentUser", "Can not get current user"))
+	}
+
+	viewer, err := getPostViewer(rpc.Context(c), currentUser)
 	if err != nil {
-		log.Error("[QueryPostHandle.postService.QueryPostIncludeUser] %s ", err.Error())
+		log.Error("[QueryPostHandle.getPostViewer] %s", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/getUserRestrictions", "Error while getting user restrictions"))
+	}
+
+	// Numbered pages are kept for the clients which do not use cursors
+	if query.Page > 0 || (query.Limit == 0 && query.After == "" && query.Before == "") {
+		postList, err := postService.QueryPostIncludeUser(query.Search, normalizeTag(query.Tag), query.Owner, query.Type, "created_date", query.Page, viewer)
+		if err != nil {
+			log.Error("[QueryPostHandle.postService.QueryPostIncludeUser] %s ", err.Error())
+			return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/queryPost", "Error happened while query post!"))
+		}
+
+		if err := attachSharedPosts(postService, postList, viewer); err != nil {
+			log.Error("[QueryPostHandle.attachSharedPosts] %s ", err.Error())
+			return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/queryPost", "Error happened while query post!"))
+		}
+
+		return c.JSON(postList)
+	}
+
+	if query.Limit < 0 || query.Limit > cursor.MaxPageLimit {
+		return c.Status(http.StatusBadRequest).JSON(utils.Error("limitIsNotValid", fmt.Sprintf("Limit should be between 1 and %d!", cursor.MaxPageLimit)))
+	}
+
+	after, before, err := cursor.ReadPage(query.After, query.Before)
+	if err != nil {
+		log.Error("[QueryPostHandle.readPageCursors] %s", err.Error())
+		return c.Status(http.StatusBadRequest).JSON(utils.Error("cursorIsNotValid", "Cursor is not valid!"))
+	}
+
+	limit := query.Limit
+	if limit == 0 {
+		limit = cursor.DefaultPageLimit
+	}
+	postList, err := postService.QueryPostIncludeUserByCursor(query.Search, normalizeTag(query.Tag), query.Owner, query.Type, viewer, after, before, limit)
+	if err != nil {
+		log.Error("[QueryPostHandle.postService.QueryPostIncludeUserByCursor] %s ", err.Error())
 		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/queryPost", "Error happened while query post!"))
 	}
 
-	return c.JSON(postList)
+	if err := attachSharedPosts(postService, postList, viewer); err != nil {
+		log.Error("[QueryPostHandle.attachSharedPosts] %s ", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/queryPost", "Error happened while query post!"))
+	}
+
+	return c.JSON(newPostPage(postList, limit, before != nil))
+
+}
+
+type PostFeedQueryModel struct {
+	Limit  int64  `query:"limit"`
+	After  string `query:"after"`
+	Before string `query:"before"`
+}
+
+// newPostPage create the page of posts with the cursors of the next and previous pages
+func newPostPage(postList []dto.Post, limit int64, readBefore bool) models.PostPageModel {
+	page := models.PostPageModel{
+		Posts: []dto.Post{},
+	}
+	if len(postList) == 0 {
+		return page
+	}
+	page.Posts = postList
+
+	positions := make([]cursor.Model, 0, len(postList))
+	for _, post := range postList {
+		positions = append(positions, cursor.Model{CreatedDate: post.CreatedDate, ObjectId: post.ObjectId})
+	}
+	page.NextCursor, page.PrevCursor = cursor.PageCursors(positions, limit, readBefore)
+	return page
+}
+
+// QueryPostFeedHandle handle query on the posts of the users that auth user follows and auth user posts
+func QueryPostFeedHandle(c *fiber.Ctx) error {
+
+	// Create service
+	postService, serviceErr := service.NewPostService(database.Db)
+	if serviceErr != nil {
+		log.Error("NewPostService %s", serviceErr.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/postService", "Error happened while creating postService!"))
+	}
+
+	query := new(PostFeedQueryModel)
+
+	if err := parser.QueryParser(c, query); err != nil {
+		log.Error("[QueryPostFeedHandle] QueryParser %s", err.Error())
+		return c.Status(http.StatusBadRequest).JSON(utils.Error("queryParser", "Error happened while parsing query!"))
+	}
+
+	if query.Limit < 0 || query.Limit > cursor.MaxPageLimit {
+		return c.Status(http.StatusBadRequest).JSON(utils.Error("limitIsNotValid", fmt.Sprintf("Limit should be between 1 and %d!", cursor.MaxPageLimit)))
+	}
+
+	after, before, err := cursor.ReadPage(query.After, query.Before)
+	if err != nil {
+		log.Error("[QueryPostFeedHandle.readPageCursors] %s", err.Error())
+		return c.Status(http.StatusBadRequest).JSON(utils.Error("cursorIsNotValid", "Cursor is not valid!"))
+	}
+
+	currentUser, ok := c.Locals(types.UserCtxName).(types.UserContext)
+	if !ok {
+		log.Error("[QueryPostFeedHandle] Can not get current user")
+		return c.Status(http.StatusBadRequest).JSON(utils.Error("invalidCurrentUser", "Can not get current user"))
+	}
+
+	followingIds, err := getFollowingIds(rpc.Context(c))
+	if err != nil {
+		log.Error("[QueryPostFeedHandle.getFollowingIds] %s", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/getFollowing", "Error happened while reading following!"))
+	}
+
+	ownerUserIds := append([]uuid.UUID{currentUser.UserID}, followingIds...)
+
+	limit := query.Limit
+	if limit == 0 {
+		limit = cursor.DefaultPageLimit
+	}
+	viewer, err := getPostViewer(rpc.Context(c), currentUser)
+	if err != nil {
+		log.Error("[QueryPostFeedHandle.getPostViewer] %s", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/getUserRestrictions", "Error while getting user restrictions"))
+	}
+	postList, err := postService.QueryPostFeed(ownerUserIds, viewer, after, before, limit)
+	if err != nil {
+		log.Error("[QueryPostFeedHandle.postService.QueryPostFeed] %s ", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/queryPostFeed", "Error happened while query post feed!"))
+	}
+
+	if err := attachSharedPosts(postService, postList, viewer); err != nil {
+		log.Error("[QueryPostFeedHandle.attachSharedPosts] %s ", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/queryPostFeed", "Error happened while query post feed!"))
+	}
+
+	return c.JSON(newPostPage(postList, limit, before != nil))
 
 }
 
@@ -71,9 +215,30 @@ func GetPostHandle(c *fiber.Ctx) error {
 		return c.Status(http.StatusBadRequest).JSON(utils.Error("postIdIsNotValid", "Post id is not valid!"))
 	}
 
-	foundPost, err := postService.FindById(postUUID)
+	currentUser, ok := c.Locals(types.UserCtxName).(types.UserContext)
+	if !ok {
+		log.Error("[GetPostHandle] Can not get current user")
+		return c.Status(http.StatusBadRequest).JSON(utils.Error("invalidCurrentUser", "Can not get current user"))
+	}
+
+	viewer, err := getPostViewer(rpc.Context(c), currentUser)
 	if err != nil {
-		log.Error("[GetPostHandle.postService.FindById] %s ", err.Error())
+		log.Error("[GetPostHandle.getPostViewer] %s", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/getUserRestrictions", "Error while getting user restrictions"))
+	}
+	foundPost, err := postService.FindVisibleById(postUUID, viewer)
+	if err != nil {
+		log.Error("[GetPostHandle.postService.FindVisibleById] %s ", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/queryPost", "Error happened while query post!"))
+	}
+
+	// Posts the user is not allowed to see are reported as not found
+	if foundPost == nil {
+		return c.Status(http.StatusNotFound).JSON(utils.Error("postNotFound", "Post not found!"))
+	}
+
+	if err := attachSharedPost(postService, foundPost, viewer); err != nil {
+		log.Error("[GetPostHandle.attachSharedPost] %s ", err.Error())
 		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/queryPost", "Error happened while query post!"))
 	}
 
@@ -83,6 +248,7 @@ func GetPostHandle(c *fiber.Ctx) error {
 		OwnerUserId:      foundPost.OwnerUserId,
 		Score:            foundPost.Score,
 		Votes:            foundPost.Votes,
+		Reactions:        foundPost.Reactions,
 		ViewCount:        foundPost.ViewCount,
 		Body:             foundPost.Body,
 		OwnerDisplayName: foundPost.OwnerDisplayName,
@@ -90,6 +256,7 @@ func GetPostHandle(c *fiber.Ctx) error {
 		URLKey:           foundPost.URLKey,
 		Album:            models.PostAlbumModel{Photos: []string{}},
 		Tags:             foundPost.Tags,
+		Mentions:         foundPost.Mentions,
 		CommentCounter:   foundPost.CommentCounter,
 		Image:            foundPost.Image,
 		ImageFullPath:    foundPost.ImageFullPath,
@@ -97,6 +264,9 @@ func GetPostHandle(c *fiber.Ctx) error {
 		Thumbnail:        foundPost.Thumbnail,
 		DisableComments:  foundPost.DisableComments,
 		DisableSharing:   foundPost.DisableSharing,
+		HideComments:     foundPost.HideComments,
+		SharedPostId:     foundPost.SharedPostId,
+		ShareCounter:     foundPost.ShareCounter,
 		Deleted:          foundPost.Deleted,
 		DeletedDate:      foundPost.DeletedDate,
 		CreatedDate:      foundPost.CreatedDate,
@@ -116,6 +286,9 @@ func GetPostHandle(c *fiber.Ctx) error {
 		}
 	}
 
+	postModel.SharedPost = foundPost.SharedPost
+	postModel.SharedPostUnavailable = foundPost.SharedPostUnavailable
+
 	return c.JSON(postModel)
 
 }
@@ -136,17 +309,40 @@ func GetPostByURLKeyHandle(c *fiber.Ctx) error {
 		return c.Status(http.StatusBadRequest).JSON(utils.Error("urlKeyRequired", errorMessage))
 	}
 
-	foundPost, err := postService.FindByURLKey(urlKey)
+	currentUser, ok := c.Locals(types.UserCtxName).(types.UserContext)
+	if !ok {
+		log.Error("[GetPostByURLKeyHandle] Can not get current user")
+		return c.Status(http.StatusBadRequest).JSON(utils.Error("invalidCurrentUser", "Can not get current user"))
+	}
+
+	viewer, err := getPostViewer(rpc.Context(c), currentUser)
 	if err != nil {
-		log.Error("[GetPostHandle.postService.FindByURLKey] %s ", err.Error())
+		log.Error("[GetPostByURLKeyHandle.getPostViewer] %s", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/getUserRestrictions", "Error while getting user restrictions"))
+	}
+	foundPost, err := postService.FindVisibleByURLKey(urlKey, viewer)
+	if err != nil {
+		log.Error("[GetPostByURLKeyHandle.postService.FindVisibleByURLKey] %s ", err.Error())
 		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/queryPost", "Error happened while query post!"))
 	}
+
+	// Posts the user is not allowed to see are reported as not found
+	if foundPost == nil {
+		return c.Status(http.StatusNotFound).JSON(utils.Error("postNotFound", "Post not found!"))
+	}
+
+	if err := attachSharedPost(postService, foundPost, viewer); err != nil {
+		log.Error("[GetPostByURLKeyHandle.attachSharedPost] %s ", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/queryPost", "Error happened while query post!"))
+	}
+
 	postModel := models.PostModel{
 		ObjectId:         foundPost.ObjectId,
 		PostTypeId:       foundPost.PostTypeId,
 		OwnerUserId:      foundPost.OwnerUserId,
 		Score:            foundPost.Score,
 		Votes:            foundPost.Votes,
+		Reactions:        foundPost.Reactions,
 		ViewCount:        foundPost.ViewCount,
 		Body:             foundPost.Body,
 		OwnerDisplayName: foundPost.OwnerDisplayName,
@@ -154,6 +350,7 @@ func GetPostByURLKeyHandle(c *fiber.Ctx) error {
 		URLKey:           foundPost.URLKey,
 		Album:            models.PostAlbumModel{Photos: []string{}},
 		Tags:             foundPost.Tags,
+		Mentions:         foundPost.Mentions,
 		CommentCounter:   foundPost.CommentCounter,
 		Image:            foundPost.Image,
 		ImageFullPath:    foundPost.ImageFullPath,
@@ -161,6 +358,9 @@ func GetPostByURLKeyHandle(c *fiber.Ctx) error {
 		Thumbnail:        foundPost.Thumbnail,
 		DisableComments:  foundPost.DisableComments,
 		DisableSharing:   foundPost.DisableSharing,
+		HideComments:     foundPost.HideComments,
+		SharedPostId:     foundPost.SharedPostId,
+		ShareCounter:     foundPost.ShareCounter,
 		Deleted:          foundPost.Deleted,
 		DeletedDate:      foundPost.DeletedDate,
 		CreatedDate:      foundPost.CreatedDate,
@@ -181,6 +381,9 @@ func GetPostByURLKeyHandle(c *fiber.Ctx) error {
 		}
 	}
 
+	postModel.SharedPost = foundPost.SharedPost
+	postModel.SharedPostUnavailable = foundPost.SharedPostUnavailable
+
 	return c.JSON(postModel)
 
 }
@@ -214,8 +417,12 @@ func GeneratePostURLKeyHandle(c *fiber.Ctx) error {
 		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/queryPost", "Error happened while query post!"))
 	}
 
+	if foundPost == nil {
+		return c.Status(http.StatusNotFound).JSON(utils.Error("postNotFound", "Post not found!"))
+	}
+
 	if foundPost.URLKey == "" {
-		postOwnerProfile, err := getUserProfileByID(foundPost.OwnerUserId)
+		postOwnerProfile, err := getUserProfileByID(rpc.Context(c), foundPost.OwnerUserId)
 
 		if err != nil {
 			log.Error("[GetPostHandle.getUserProfileByID] %s ", err.Error())
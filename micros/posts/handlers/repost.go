@@ -0,0 +1,220 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+	uuid "github.com/gofrs/uuid"
+	"github.com/red-gold/telar-core/pkg/log"
+	"github.com/red-gold/telar-core/types"
+	"github.com/red-gold/telar-core/utils"
+	"github.com/red-gold/ts-serverless/micros/internal/outbox"
+	"github.com/red-gold/ts-serverless/micros/internal/rpc"
+	"github.com/red-gold/ts-serverless/micros/posts/database"
+	domain "github.com/red-gold/ts-serverless/micros/posts/dto"
+	models "github.com/red-gold/ts-serverless/micros/posts/models"
+	service "github.com/red-gold/ts-serverless/micros/posts/services"
+)
+
+// repostPostTypeId the post type of the reposts, it is constants.PostConstRepost
+const repostPostTypeId = 5
+
+// RepostHandle handle share a post as a new post of the current user with an optional quote
+func RepostHandle(c *fiber.Ctx) error {
+
+	// Create the model object
+	model := new(models.RepostModel)
+	if err := c.BodyParser(model); err != nil {
+		errorMessage := fmt.Sprintf("Parse RepostModel Error %s", err.Error())
+		log.Error(errorMessage)
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/parseModel", "Error happened while parsing model!"))
+	}
+
+	if model.PostId == uuid.Nil {
+		errorMessage := fmt.Sprintf("Post Id is required!")
+		log.Error(errorMessage)
+		return c.Status(http.StatusBadRequest).JSON(utils.Error("postIdRequired", errorMessage))
+	}
+
+	// Create service
+	postService, serviceErr := service.NewPostService(database.Db)
+	if serviceErr != nil {
+		log.Error("NewPostService %s", serviceErr.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/postService", "Error happened while creating postService!"))
+	}
+
+	outboxService, serviceErr := service.NewOutboxService(database.Db)
+	if serviceErr != nil {
+		log.Error("NewOutboxService %s", serviceErr.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/outboxService", "Error happened while creating outboxService!"))
+	}
+
+	currentUser, ok := c.Locals(types.UserCtxName).(types.UserContext)
+	if !ok {
+		log.Error("[RepostHandle] Can not get current user")
+		return c.Status(http.StatusBadRequest).JSON(utils.Error("invalidCurrentUser",
+			"Can not get current user"))
+	}
+
+	// Only the posts the user can see are shared, a repost of a repost shares the original post
+	viewer, err := getPostViewer(rpc.Context(c), currentUser)
+	if err != nil {
+		log.Error("[RepostHandle.getPostViewer] %s", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/getUserRestrictions", "Error while getting user restrictions"))
+	}
+	sharedPost, err := postService.FindVisibleById(model.PostId, viewer)
+	if err == nil && sharedPost != nil && sharedPost.SharedPostId != uuid.Nil {
+		sharedPost, err = postService.FindVisibleById(sharedPost.SharedPostId, viewer)
+	}
+	if err != nil {
+		log.Error("[RepostHandle.postService.FindVisibleById] %s", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/queryPost", "Error happened while query post!"))
+	}
+	if sharedPost == nil {
+		return c.Status(http.StatusNotFound).JSON(utils.Error("postNotFound", "Post not found!"))
+	}
+	if sharedPost.DisableSharing {
+		return c.Status(http.StatusForbidden).JSON(utils.Error("sharingDisabled", "Sharing is disabled on this post!"))
+	}
+
+	mentions, err := resolveMentions(rpc.Context(c), model.Body)
+	if err != nil {
+		log.Error("[RepostHandle.resolveMentions] %s", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/resolveMentions", "Error happened while resolving mentions!"))
+	}
+
+	newPost := &domain.Post{
+		ObjectId:         model.ObjectId,
+		PostTypeId:       repostPostTypeId,
+		OwnerUserId:      currentUser.UserID,
+		Votes:            make(map[string]string),
+		Reactions:        make(map[string]int64),
+		Body:             model.Body,
+		OwnerDisplayName: currentUser.DisplayName,
+		OwnerAvatar:      currentUser.Avatar,
+		URLKey:           generatPostURLKey(currentUser.SocialName, model.Body, model.ObjectId.String()),
+		Tags:             mergeTags(nil, model.Body),
+		Mentions:         mentions,
+		SharedPostId:     sharedPost.ObjectId,
+		CreatedDate:      utils.UTCNowUnix(),
+		AccessUserList:   model.AccessUserList,
+		Permission:       model.Permission,
+	}
+
+	if err := postService.SavePost(newPost); err != nil {
+		log.Error("[RepostHandle.postService.SavePost] %s", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/savePost", "Error happened while save post!"))
+	}
+
+	rollbackPost := func() error {
+		return postService.DeletePostByOwner(currentUser.UserID, newPost.ObjectId)
+	}
+	if err := postService.Increment(sharedPost.ObjectId, "shareCounter", 1); err != nil {
+		log.Error("[RepostHandle.postService.Increment] %s", err.Error())
+		if rollbackErr := rollbackPost(); rollbackErr != nil {
+			log.Error("[RepostHandle.rollbackPost] %s", rollbackErr.Error())
+		}
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/savePost", "Error happened while save post!"))
+	}
+
+	// The repost and its share are removed if the notifications can not be stored
+	userHeaders := rpc.UserHeaders(currentUser)
+	events := mentionNotificationEvents(currentUser, mentions, newPost, userHeaders)
+	if sharedPost.OwnerUserId != currentUser.UserID {
+		events = append(events, shareNotificationEvents(currentUser, sharedPost, newPost, userHeaders)...)
+	}
+	rollbackRepost := func() error {
+		if err := postService.Increment(sharedPost.ObjectId, "shareCounter", -1); err != nil {
+			return err
+		}
+		return rollbackPost()
+	}
+	if err := outbox.SaveEvents(outboxService, events, rollbackRepost); err != nil {
+		log.Error("[RepostHandle.saveOutboxEvents] %s", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/savePost", "Error happened while save post!"))
+	}
+
+	go outbox.Dispatch(outboxService, events)
+
+	return c.JSON(fiber.Map{
+		"objectId": newPost.ObjectId.String(),
+	})
+}
+
+// shareNotificationEvents create the request which notifies the owner of the shared post.
+// The notification targets the repost, so it is retracted when the repost is deleted.
+func shareNotificationEvents(currentUser types.UserContext, sharedPost *domain.Post, repost *domain.Post, userHeaders map[string][]string) []outbox.Event {
+	notificationModel := &models.NotificationModel{
+		OwnerUserId:          currentUser.UserID,
+		OwnerDisplayName:     currentUser.DisplayName,
+		OwnerAvatar:          currentUser.Avatar,
+		Title:                currentUser.DisplayName,
+		Description:          "shared your post.",
+		URL:                  fmt.Sprintf("/posts/%s", repost.URLKey),
+		NotifyRecieverUserId: sharedPost.OwnerUserId,
+		TargetId:             repost.ObjectId,
+		IsSeen:               false,
+		Type:                 "share",
+	}
+	notificationBytes, err := json.Marshal(notificationModel)
+	if err != nil {
+		log.Error("[shareNotificationEvents] Cannot marshal notification! error: %s", err.Error())
+		return nil
+	}
+	return []outbox.Event{outbox.NewEvent(http.MethodPost, "/notifications", notificationBytes, userHeaders)}
+}
+
+// updateShareCounter add the value to the share counter of the post which the repost shares
+func updateShareCounter(postService service.PostService, repost *domain.Post, value int) {
+	if repost.SharedPostId == uuid.Nil {
+		return
+	}
+	if err := postService.Increment(repost.SharedPostId, "shareCounter", value); err != nil {
+		log.Error("[updateShareCounter] %s", err.Error())
+	}
+}
+
+// attachSharedPost set the original post of a repost as the viewer sees it
+func attachSharedPost(postService service.PostService, post *domain.Post, viewer *models.PostViewerModel) error {
+	posts := []domain.Post{*post}
+	if err := attachSharedPosts(postService, posts, viewer); err != nil {
+		return err
+	}
+	*post = posts[0]
+	return nil
+}
+
+// attachSharedPosts set the original posts of the reposts which the viewer can see.
+// The reposts of the posts which are deleted or hidden from the viewer are marked as unavailable.
+func attachSharedPosts(postService service.PostService, posts []domain.Post, viewer *models.PostViewerModel) error {
+	var sharedPostIds []uuid.UUID
+	for _, post := range posts {
+		if post.SharedPostId != uuid.Nil {
+			sharedPostIds = append(sharedPostIds, post.SharedPostId)
+		}
+	}
+	if len(sharedPostIds) == 0 {
+		return nil
+	}
+
+	sharedPosts, err := postService.FindVisibleByIds(sharedPostIds, viewer)
+	if err != nil {
+		return err
+	}
+	sharedPostMap := make(map[uuid.UUID]*domain.Post)
+	for index := range sharedPosts {
+		sharedPostMap[sharedPosts[index].ObjectId] = &sharedPosts[index]
+	}
+
+	for index := range posts {
+		if posts[index].SharedPostId == uuid.Nil {
+			continue
+		}
+		sharedPost, ok := sharedPostMap[posts[index].SharedPostId]
+		posts[index].SharedPost = sharedPost
+		posts[index].SharedPostUnavailable = !ok
+	}
+	return nil
+}
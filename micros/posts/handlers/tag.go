@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/red-gold/telar-core/pkg/log"
+	"github.com/red-gold/telar-core/pkg/parser"
+	"github.com/red-gold/telar-core/types"
+	"github.com/red-gold/telar-core/utils"
+	"github.com/red-gold/ts-serverless/micros/internal/cursor"
+	"github.com/red-gold/ts-serverless/micros/internal/rpc"
+	"github.com/red-gold/ts-serverless/micros/posts/database"
+	service "github.com/red-gold/ts-serverless/micros/posts/services"
+)
+
+const (
+	// maxTags the number of tags which are kept on a post
+	maxTags = 30
+
+	// maxTagLength the number of characters of a tag
+	maxTagLength = 50
+
+	// defaultTrendingHours the time window of the trending tags
+	defaultTrendingHours int64 = 24
+
+	// maxTrendingHours the widest time window of the trending tags
+	maxTrendingHours int64 = 30 * 24
+)
+
+// hashtagPattern matches #tag which is not a part of a word, a URL fragment or an HTML entity
+var hashtagPattern = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_&#/])#([\p{L}\p{N}_]+)`)
+
+type TagQueryModel struct {
+	Prefix string `query:"prefix"`
+	Limit  int64  `query:"limit"`
+}
+
+type TrendingTagQueryModel struct {
+	Hours int64 `query:"hours"`
+	Limit int64 `query:"limit"`
+}
+
+// normalizeTag make the tag lower case without the leading #, it is empty if the tag is not valid
+func normalizeTag(tag string) string {
+	tag = strings.ToLower(strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(tag), "#")))
+	if utf8.RuneCountInString(tag) > maxTagLength {
+		return ""
+	}
+	return tag
+}
+
+// mergeTags add the hashtags of the body to the tags of the client, the tags are normalized and distinct
+func mergeTags(tags []string, body string) []string {
+	merged := []string{}
+	seen := make(map[string]bool)
+	addTag := func(tag string) {
+		tag = normalizeTag(tag)
+		if tag == "" || seen[tag] || len(merged) == maxTags {
+			return
+		}
+		seen[tag] = true
+		merged = append(merged, tag)
+	}
+
+	for _, tag := range tags {
+		addTag(tag)
+	}
+	for _, match := range hashtagPattern.FindAllStringSubmatch(body, -1) {
+		addTag(match[1])
+	}
+	return merged
+}
+
+// readTagLimit read the number of tags in the response, zero is the default limit
+func readTagLimit(limit int64) (int64, bool) {
+	if limit < 0 || limit > cursor.MaxPageLimit {
+		return 0, false
+	}
+	if limit == 0 {
+		return cursor.DefaultPageLimit, true
+	}
+	return limit, true
+}
+
+// QueryTagsHandle handle autocomplete the tags by prefix, the most used tags first
+func QueryTagsHandle(c *fiber.Ctx) error {
+
+	// Create service
+	postService, serviceErr := service.NewPostService(database.Db)
+	if serviceErr != nil {
+		log.Error("NewPostService %s", serviceErr.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/postService", "Error happened while creating postService!"))
+	}
+
+	query := new(TagQueryModel)
+	if err := parser.QueryParser(c, query); err != nil {
+		log.Error("[QueryTagsHandle] QueryParser %s", err.Error())
+		return c.Status(http.StatusBadRequest).JSON(utils.Error("queryParser", "Error happened while parsing query!"))
+	}
+
+	limit, ok := readTagLimit(query.Limit)
+	if !ok {
+		return c.Status(http.StatusBadRequest).JSON(utils.Error("limitIsNotValid", fmt.Sprintf("Limit should be between 1 and %d!", cursor.MaxPageLimit)))
+	}
+
+	prefix := normalizeTag(query.Prefix)
+	if prefix == "" {
+		return c.Status(http.StatusBadRequest).JSON(utils.Error("prefixIsRequired", "Tag prefix is required!"))
+	}
+
+	currentUser, ok := c.Locals(types.UserCtxName).(types.UserContext)
+	if !ok {
+		log.Error("[QueryTagsHandle] Can not get current user")
+		return c.Status(http.StatusBadRequest).JSON(utils.Error("invalidCurrentUser", "Can not get current user"))
+	}
+
+	viewer, err := getPostViewer(rpc.Context(c), currentUser)
+	if err != nil {
+		log.Error("[QueryTagsHandle.getPostViewer] %s", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/getUserRestrictions", "Error while getting user restrictions"))
+	}
+
+	tagList, err := postService.QueryTagsByPrefix(prefix, viewer, limit)
+	if err != nil {
+		log.Error("[QueryTagsHandle.postService.QueryTagsByPrefix] %s", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/queryTags", "Error happened while query tags!"))
+	}
+
+	return c.JSON(tagList)
+}
+
+// QueryTrendingTagsHandle handle get the tags which are used the most in the last hours
+func QueryTrendingTagsHandle(c *fiber.Ctx) error {
+
+	// Create service
+	postService, serviceErr := service.NewPostService(database.Db)
+	if serviceErr != nil {
+		log.Error("NewPostService %s", serviceErr.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/postService", "Error happened while creating postService!"))
+	}
+
+	query := new(TrendingTagQueryModel)
+	if err := parser.QueryParser(c, query); err != nil {
+		log.Error("[QueryTrendingTagsHandle] QueryParser %s", err.Error())
+		return c.Status(http.StatusBadRequest).JSON(utils.Error("queryParser", "Error happened while parsing query!"))
+	}
+
+	limit, ok := readTagLimit(query.Limit)
+	if !ok {
+		return c.Status(http.StatusBadRequest).JSON(utils.Error("limitIsNotValid", fmt.Sprintf("Limit should be between 1 and %d!", cursor.MaxPageLimit)))
+	}
+
+	hours := query.Hours
+	if hours == 0 {
+		hours = defaultTrendingHours
+	}
+	if hours < 0 || hours > maxTrendingHours {
+		return c.Status(http.StatusBadRequest).JSON(utils.Error("hoursIsNotValid", fmt.Sprintf("Hours should be between 1 and %d!", maxTrendingHours)))
+	}
+
+	currentUser, ok := c.Locals(types.UserCtxName).(types.UserContext)
+	if !ok {
+		log.Error("[QueryTrendingTagsHandle] Can not get current user")
+		return c.Status(http.StatusBadRequest).JSON(utils.Error("invalidCurrentUser", "Can not get current user"))
+	}
+
+	since := utils.UTCNowUnix() - hours*60*60*1000
+	viewer, err := getPostViewer(rpc.Context(c), currentUser)
+	if err != nil {
+		log.Error("[QueryTrendingTagsHandle.getPostViewer] %s", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/getUserRestrictions", "Error while getting user restrictions"))
+	}
+
+	tagList, err := postService.QueryTrendingTags(since, viewer, limit)
+	if err != nil {
+		log.Error("[QueryTrendingTagsHandle.postService.QueryTrendingTags] %s", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/queryTags", "Error happened while query tags!"))
+	}
+
+	return c.JSON(tagList)
+}
@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/red-gold/telar-core/pkg/log"
+	"github.com/red-gold/telar-core/pkg/parser"
+	"github.com/red-gold/telar-core/types"
+	"github.com/red-gold/telar-core/utils"
+	"github.com/red-gold/ts-serverless/micros/internal/outbox"
+	postConfig "github.com/red-gold/ts-serverless/micros/posts/config"
+	"github.com/red-gold/ts-serverless/micros/posts/database"
+	domain "github.com/red-gold/ts-serverless/micros/posts/dto"
+	service "github.com/red-gold/ts-serverless/micros/posts/services"
+)
+
+type TrashQueryModel struct {
+	Page int64 `query:"page"`
+}
+
+// trashCutoff the date which the posts deleted before it are out of the trash and can be purged
+func trashCutoff() int64 {
+	return utils.UTCNowUnix() - postConfig.PostConfig.TrashRetentionDays*24*60*60*1000
+}
+
+// GetTrashPostsHandle handle get the deleted posts of the current user which can be restored
+func GetTrashPostsHandle(c *fiber.Ctx) error {
+
+	// Create service
+	postService, serviceErr := service.NewPostService(database.Db)
+	if serviceErr != nil {
+		log.Error("NewPostService %s", serviceErr.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/postService", "Error happened while creating postService!"))
+	}
+
+	query := new(TrashQueryModel)
+	if err := parser.QueryParser(c, query); err != nil {
+		log.Error("[GetTrashPostsHandle] QueryParser %s", err.Error())
+		return c.Status(http.StatusBadRequest).JSON(utils.Error("queryParser", "Error happened while parsing query!"))
+	}
+
+	currentUser, ok := c.Locals(types.UserCtxName).(types.UserContext)
+	if !ok {
+		log.Error("[GetTrashPostsHandle] Can not get current user")
+		return c.Status(http.StatusBadRequest).JSON(utils.Error("invalidCurrentUser", "Can not get current user"))
+	}
+
+	postList, err := postService.FindTrashByOwner(currentUser.UserID, trashCutoff(), query.Page)
+	if err != nil {
+		log.Error("[GetTrashPostsHandle.postService.FindTrashByOwner] %s", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/queryPost", "Error happened while query post!"))
+	}
+	if postList == nil {
+		postList = []domain.Post{}
+	}
+
+	return c.JSON(postList)
+}
+
+// PurgeTrashHandle handle remove a batch of the posts which are deleted before the trash retention
+func PurgeTrashHandle(c *fiber.Ctx) error {
+
+	purged, err := PurgeTrash()
+	if err != nil {
+		log.Error("[PurgeTrashHandle] %s", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/purgePost", "Error happened while purging posts!"))
+	}
+
+	return c.JSON(fiber.Map{
+		"purged": purged,
+	})
+}
+
+// PurgeTrash remove a batch of the posts which are deleted before the trash retention and return their number.
+// The comments and votes of the removed posts are removed through the outbox.
+func PurgeTrash() (int, error) {
+
+	// Create service
+	postService, err := service.NewPostService(database.Db)
+	if err != nil {
+		return 0, fmt.Errorf("PurgeTrash/postService %s", err.Error())
+	}
+
+	outboxService, err := service.NewOutboxService(database.Db)
+	if err != nil {
+		return 0, fmt.Errorf("PurgeTrash/outboxService %s", err.Error())
+	}
+
+	cutoff := trashCutoff()
+	postIds, err := postService.FindPurgeablePostIds(cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("PurgeTrash/findPosts %s", err.Error())
+	}
+
+	var events []outbox.Event
+	for _, postId := range postIds {
+		events = append(events,
+			outbox.NewEvent(http.MethodDelete, fmt.Sprintf("/comments/post/%s/deleted", postId), nil, nil),
+			outbox.NewEvent(http.MethodDelete, fmt.Sprintf("/votes/post/%s/deleted", postId), nil, nil),
+		)
+	}
+
+	// The cleanup is stored before the posts are removed, so it is not lost if the function stops in between.
+	// If the posts can not be removed, they are purged again on the next run and the cleanup is idempotent.
+	if err := outboxService.SaveEvents(events); err != nil {
+		return 0, fmt.Errorf("PurgeTrash/saveEvents %s", err.Error())
+	}
+
+	if err := postService.PurgeDeletedPosts(postIds, cutoff); err != nil {
+		return 0, fmt.Errorf("PurgeTrash/purgePosts %s", err.Error())
+	}
+
+	go outbox.Dispatch(outboxService, events)
+
+	return len(postIds), nil
+}
@@ -9,6 +9,8 @@ import (
 	"github.com/red-gold/telar-core/pkg/log"
 	"github.com/red-gold/telar-core/types"
 	"github.com/red-gold/telar-core/utils"
+	"github.com/red-gold/ts-serverless/micros/internal/outbox"
+	"github.com/red-gold/ts-serverless/micros/internal/rpc"
 	"github.com/red-gold/ts-serverless/micros/posts/database"
 	models "github.com/red-gold/ts-serverless/micros/posts/models"
 	service "github.com/red-gold/ts-serverless/micros/posts/services"
@@ -31,6 +33,12 @@ func UpdatePostHandle(c *fiber.Ctx) error {
 		log.Error("NewPostService %s", serviceErr.Error())
 		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/postService", "Error happened while creating postService!"))
 	}
+	outboxService, serviceErr := service.NewOutboxService(database.Db)
+	if serviceErr != nil {
+		log.Error("NewOutboxService %s", serviceErr.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/outboxService", "Error happened while creating outboxService!"))
+	}
+
 	var updatedAlbum *models.PostAlbumModel = nil
 
 	updatedAlbum = &models.PostAlbumModel{
@@ -48,6 +56,21 @@ func UpdatePostHandle(c *fiber.Ctx) error {
 			"Can not get current user"))
 	}
 
+	foundPost, err := postService.FindById(model.ObjectId)
+	if err != nil {
+		log.Error("[UpdatePostHandle.postService.FindById] %s", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/updatePost", "Error happened while updating post!"))
+	}
+	if foundPost == nil || foundPost.OwnerUserId != currentUser.UserID || foundPost.Deleted {
+		return c.Status(http.StatusNotFound).JSON(utils.Error("postNotFound", "Post not found!"))
+	}
+
+	mentions, err := resolveMentions(rpc.Context(c), model.Body)
+	if err != nil {
+		log.Error("[UpdatePostHandle.resolveMentions] %s", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/resolveMentions", "Error happened while resolving mentions!"))
+	}
+
 	updatedPost := &models.PostUpdateModel{
 		ObjectId:         model.ObjectId,
 		PostTypeId:       model.PostTypeId,
@@ -58,7 +81,8 @@ func UpdatePostHandle(c *fiber.Ctx) error {
 		Body:             model.Body,
 		OwnerDisplayName: currentUser.DisplayName,
 		OwnerAvatar:      currentUser.Avatar,
-		Tags:             model.Tags,
+		Tags:             mergeTags(model.Tags, model.Body),
+		Mentions:         mentions,
 		CommentCounter:   model.CommentCounter,
 		Image:            model.Image,
 		ImageFullPath:    model.ImageFullPath,
@@ -79,6 +103,16 @@ func UpdatePostHandle(c *fiber.Ctx) error {
 		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/updatePost", "Error happened while updating post!"))
 	}
 
+	// Only the users who are mentioned by this update are notified
+	// The post is already updated, so the events are stored without a rollback
+	events := mentionNotificationEvents(currentUser, addedMentions(mentions, foundPost.Mentions), foundPost, rpc.UserHeaders(currentUser))
+	if err := outboxService.SaveEvents(events); err != nil {
+		log.Error("[UpdatePostHandle.outboxService.SaveEvents] %s", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/updatePost", "Error happened while updating post!"))
+	}
+
+	go outbox.Dispatch(outboxService, events)
+
 	return c.SendStatus(http.StatusOK)
 
 }
@@ -99,7 +133,14 @@ func IncrementScoreHandle(c *fiber.Ctx) error {
 		log.Error(errorMessage)
 		return c.Status(http.StatusBadRequest).JSON(utils.Error("postIdRequired", errorMessage))
 	}
-	if model.Count == 0 {
+	// Votes from the clients without reactions are likes
+	if model.Reaction == "" {
+		model.Reaction = models.ReactionName(0)
+	}
+	if !models.IsReaction(model.Reaction) || (model.PreviousReaction != "" && !models.IsReaction(model.PreviousReaction)) {
+		return c.Status(http.StatusBadRequest).JSON(utils.Error("reactionIsNotValid", "Reaction is not valid!"))
+	}
+	if model.Count == 0 && model.PreviousReaction == "" {
 		errorMessage := fmt.Sprintf("Count can not be zero!")
 		log.Error(errorMessage)
 		return c.Status(http.StatusBadRequest).JSON(utils.Error("countIsZero", errorMessage))
@@ -119,8 +160,18 @@ func IncrementScoreHandle(c *fiber.Ctx) error {
 			"Can not get current user"))
 	}
 
-	if model.Count > 0 {
-		err := postService.IncrementScoreCount(model.PostId, currentUser.UserID, currentUser.Avatar)
+	// Events from the outbox of other functions are applied once
+	eventKey := c.Get(rpc.IdempotencyKeyHeader)
+
+	if model.PreviousReaction != "" {
+		err := postService.SwitchReaction(model.PostId, model.PreviousReaction, model.Reaction, eventKey)
+		if err != nil {
+			errorMessage := fmt.Sprintf("[SwitchReaction] Update Post Error %s", err.Error())
+			log.Error(errorMessage)
+			return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/updatePost", "Error happened while updating post!"))
+		}
+	} else if model.Count > 0 {
+		err := postService.IncrementScoreCount(model.PostId, currentUser.UserID, currentUser.Avatar, model.Reaction, eventKey)
 		if err != nil {
 			errorMessage := fmt.Sprintf("[IncrementScoreCount] Update Post Error %s", err.Error())
 			log.Error(errorMessage)
@@ -128,7 +179,7 @@ func IncrementScoreHandle(c *fiber.Ctx) error {
 
 		}
 	} else if model.Count < 0 {
-		err := postService.DecrementScoreCount(model.PostId, currentUser.UserID)
+		err := postService.DecrementScoreCount(model.PostId, currentUser.UserID, model.Reaction, eventKey)
 		if err != nil {
 			errorMessage := fmt.Sprintf("[DecrementScoreCount] Update Post Error %s", err.Error())
 			log.Error(errorMessage)
@@ -170,22 +221,22 @@ func IncrementCommentHandle(c *fiber.Ctx) error {
 		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/postService", "Error happened while creating postService!"))
 	}
 
-	if model.Count > 0 {
-		err := postService.IncrementCommentCount(model.PostId)
+	// Events from the outbox of other functions are applied once
+	if eventKey := c.Get(rpc.IdempotencyKeyHeader); eventKey != "" {
+		err := postService.IncrementOnce(model.PostId, "commentCounter", model.Count, eventKey)
 		if err != nil {
-			errorMessage := fmt.Sprintf("[IncrementCommentCount] Update Post Error %s", err.Error())
+			errorMessage := fmt.Sprintf("[IncrementOnce] Update Post Error %s", err.Error())
 			log.Error(errorMessage)
 			return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/updatePost", "Error happened while updating post!"))
-
 		}
-	} else if model.Count < 0 {
-		err := postService.DecerementCommentCount(model.PostId)
-		if err != nil {
-			errorMessage := fmt.Sprintf("[DecerementCommentCount] Update Post Error %s", err.Error())
-			log.Error(errorMessage)
-			return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/updatePost", "Error happened while updating post!"))
+		return c.SendStatus(http.StatusOK)
+	}
 
-		}
+	// A deleted comment thread decreases the counter by the size of the thread
+	if err := postService.Increment(model.PostId, "commentCounter", model.Count); err != nil {
+		errorMessage := fmt.Sprintf("[Increment] Update Post Error %s", err.Error())
+		log.Error(errorMessage)
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/updatePost", "Error happened while updating post!"))
 	}
 	return c.SendStatus(http.StatusOK)
 
@@ -222,7 +273,7 @@ func DisableCommentHandle(c *fiber.Ctx) error {
 			"Can not get current user"))
 	}
 
-	err := postService.DisableCommnet(currentUser.UserID, model.PostId, model.Status)
+	err := postService.DisableCommnet(currentUser.UserID, model.PostId, model.Status, model.HideComments)
 	if err != nil {
 		errorMessage := fmt.Sprintf("[DisableCommnet] Update Post Error %s", err.Error())
 		log.Error(errorMessage)
@@ -303,3 +354,68 @@ func UpdatePostProfileHandle(c *fiber.Ctx) error {
 	return c.SendStatus(http.StatusOK)
 
 }
+
+// ReconcileCountersHandle handle recomputing comment counter and score of the posts from comments and votes.
+// The counts are read from the comments and votes functions for each batch of the posts.
+func ReconcileCountersHandle(c *fiber.Ctx) error {
+
+	// Create the model object
+	model := new(models.ReconcileCountersModel)
+	if err := c.BodyParser(model); err != nil {
+		errorMessage := fmt.Sprintf("Parse ReconcileCountersModel Error %s", err.Error())
+		log.Error(errorMessage)
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/parseModel", "Error happened while parsing model!"))
+	}
+
+	// Create service
+	postService, serviceErr := service.NewPostService(database.Db)
+	if serviceErr != nil {
+		log.Error("NewPostService %s", serviceErr.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/postService", "Error happened while creating postService!"))
+	}
+
+	result := &models.ReconcileResultModel{
+		Fixed: []models.CounterDiffModel{},
+	}
+
+	ctx := rpc.Context(c)
+	lastPostId := uuid.Nil
+	for {
+		posts, err := postService.FindReconcilePosts(model.PostId, model.OwnerUserId, lastPostId)
+		if err != nil {
+			log.Error("[ReconcileCountersHandle.postService.FindReconcilePosts] %s", err.Error())
+			return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/reconcileCounters", "Error happened while reconciling post counters!"))
+		}
+		if len(posts) == 0 {
+			break
+		}
+
+		var postIds []uuid.UUID
+		for _, post := range posts {
+			postIds = append(postIds, post.ObjectId)
+		}
+		commentCounts, err := getPostCommentCounts(ctx, postIds)
+		if err != nil {
+			log.Error("[ReconcileCountersHandle.getPostCommentCounts] %s", err.Error())
+			return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/reconcileCounters", "Error happened while reconciling post counters!"))
+		}
+		postVoters, err := getPostVoters(ctx, postIds)
+		if err != nil {
+			log.Error("[ReconcileCountersHandle.getPostVoters] %s", err.Error())
+			return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/reconcileCounters", "Error happened while reconciling post counters!"))
+		}
+
+		diffs, err := postService.ReconcilePostCounters(posts, commentCounts, postVoters)
+		if err != nil {
+			log.Error("[ReconcileCountersHandle.postService.ReconcilePostCounters] %s", err.Error())
+			return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/reconcileCounters", "Error happened while reconciling post counters!"))
+		}
+		result.Checked += len(posts)
+		result.Fixed = append(result.Fixed, diffs...)
+		lastPostId = posts[len(posts)-1].ObjectId
+	}
+
+	log.Info("Post counters reconciled: checked %d, fixed %d", result.Checked, len(result.Fixed))
+	return c.JSON(result)
+
+}
@@ -0,0 +1,10 @@
+package models
+
+import uuid "github.com/gofrs/uuid"
+
+type AlbumReleaseModel struct {
+	OwnerUserId uuid.UUID   `json:"ownerUserId"`
+	MediaIds    []uuid.UUID `json:"mediaIds"`
+	URLs        []string    `json:"urls"`
+	Released    bool        `json:"released"`
+}
@@ -0,0 +1,18 @@
+package models
+
+import uuid "github.com/gofrs/uuid"
+
+type CounterDiffModel struct {
+	PostId               uuid.UUID `json:"postId"`
+	CommentCounterBefore int64     `json:"commentCounterBefore"`
+	CommentCounterAfter  int64     `json:"commentCounterAfter"`
+	ScoreBefore          int64     `json:"scoreBefore"`
+	ScoreAfter           int64     `json:"scoreAfter"`
+	VotesFixed           bool      `json:"votesFixed"`
+	ReactionsFixed       bool      `json:"reactionsFixed"`
+}
+
+type ReconcileResultModel struct {
+	Checked int                `json:"checked"`
+	Fixed   []CounterDiffModel `json:"fixed"`
+}
@@ -3,6 +3,7 @@ package models
 import uuid "github.com/gofrs/uuid"
 
 type DisableCommentModel struct {
-	PostId uuid.UUID `json:"postId"`
-	Status bool      `json:"status"`
+	PostId       uuid.UUID `json:"postId"`
+	Status       bool      `json:"status"`
+	HideComments bool      `json:"hideComments"`
 }
@@ -3,6 +3,7 @@ package models
 import (
 	uuid "github.com/gofrs/uuid"
 	"github.com/red-gold/ts-serverless/constants"
+	dto "github.com/red-gold/ts-serverless/micros/posts/dto"
 )
 
 type PostModel struct {
@@ -10,6 +11,7 @@ type PostModel struct {
 	PostTypeId       int                           `json:"postTypeId" bson:"postTypeId"`
 	Score            int64                         `json:"score" bson:"score"`
 	Votes            map[string]string             `json:"votes" bson:"votes"`
+	Reactions        map[string]int64              `json:"reactions" bson:"reactions"`
 	ViewCount        int64                         `json:"viewCount" bson:"viewCount"`
 	Body             string                        `json:"body" bson:"body"`
 	OwnerUserId      uuid.UUID                     `json:"ownerUserId" bson:"ownerUserId"`
@@ -17,6 +19,7 @@ type PostModel struct {
 	OwnerAvatar      string                        `json:"ownerAvatar" bson:"ownerAvatar"`
 	URLKey           string                        `json:"urlKey" bson:"urlKey"`
 	Tags             []string                      `json:"tags" bson:"tags"`
+	Mentions         []dto.Mention                 `json:"mentions" bson:"mentions"`
 	CommentCounter   int64                         `json:"commentCounter" bson:"commentCounter"`
 	Image            string                        `json:"image" bson:"image"`
 	ImageFullPath    string                        `json:"imageFullPath" bson:"imageFullPath"`
@@ -25,6 +28,9 @@ type PostModel struct {
 	Album            PostAlbumModel                `json:"album" bson:"album"`
 	DisableComments  bool                          `json:"disableComments" bson:"disableComments"`
 	DisableSharing   bool                          `json:"disableSharing" bson:"disableSharing"`
+	HideComments     bool                          `json:"hideComments" bson:"hideComments"`
+	SharedPostId     uuid.UUID                     `json:"sharedPostId" bson:"sharedPostId"`
+	ShareCounter     int64                         `json:"shareCounter" bson:"shareCounter"`
 	Deleted          bool                          `json:"deleted" bson:"deleted"`
 	DeletedDate      int64                         `json:"deletedDate" bson:"deletedDate"`
 	CreatedDate      int64                         `json:"created_date" bson:"created_date"`
@@ -32,4 +38,7 @@ type PostModel struct {
 	AccessUserList   []string                      `json:"accessUserList" bson:"accessUserList"`
 	Permission       constants.UserPermissionConst `json:"permission" bson:"permission"`
 	Version          string                        `json:"version" bson:"version"`
+
+	SharedPost            *dto.Post `json:"sharedPost,omitempty" bson:"sharedPost"`
+	SharedPostUnavailable bool      `json:"sharedPostUnavailable,omitempty" bson:"sharedPostUnavailable"`
 }
@@ -0,0 +1,9 @@
+package models
+
+import dto "github.com/red-gold/ts-serverless/micros/posts/dto"
+
+type PostPageModel struct {
+	Posts      []dto.Post `json:"posts"`
+	NextCursor string     `json:"nextCursor"`
+	PrevCursor string     `json:"prevCursor"`
+}
@@ -3,6 +3,7 @@ package models
 import (
 	uuid "github.com/gofrs/uuid"
 	"github.com/red-gold/ts-serverless/constants"
+	dto "github.com/red-gold/ts-serverless/micros/posts/dto"
 )
 
 type PostUpdateModel struct {
@@ -16,6 +17,7 @@ type PostUpdateModel struct {
 	OwnerDisplayName string                        `json:"ownerDisplayName" bson:"ownerDisplayName"`
 	OwnerAvatar      string                        `json:"ownerAvatar" bson:"ownerAvatar"`
 	Tags             []string                      `json:"tags" bson:"tags"`
+	Mentions         []dto.Mention                 `json:"mentions" bson:"mentions"`
 	CommentCounter   int64                         `json:"commentCounter" bson:"commentCounter"`
 	Image            string                        `json:"image" bson:"image"`
 	ImageFullPath    string                        `json:"imageFullPath" bson:"imageFullPath"`
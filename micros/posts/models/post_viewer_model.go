@@ -0,0 +1,12 @@
+package models
+
+import uuid "github.com/gofrs/uuid"
+
+// PostViewerModel the user who reads the posts and the circles other users put them in.
+// The posts of the blocked users are hidden, the posts of the muted users are only hidden from the feed.
+type PostViewerModel struct {
+	UserId         uuid.UUID               `json:"userId"`
+	Circles        []CircleMembershipModel `json:"circles"`
+	BlockedUserIds []uuid.UUID             `json:"blockedUserIds"`
+	MutedUserIds   []uuid.UUID             `json:"mutedUserIds"`
+}
@@ -0,0 +1,27 @@
+package models
+
+import uuid "github.com/gofrs/uuid"
+
+// PostIdsModel the posts which the counts are requested for from the comments and votes functions
+type PostIdsModel struct {
+	PostIds []uuid.UUID `json:"postIds"`
+}
+
+// PostCommentCountModel the number of the comments of a post which are not deleted
+type PostCommentCountModel struct {
+	PostId uuid.UUID `json:"postId"`
+	Count  int64     `json:"count"`
+}
+
+// VoterModel the owner and the reaction of a vote
+type VoterModel struct {
+	OwnerUserId uuid.UUID `json:"ownerUserId"`
+	OwnerAvatar string    `json:"ownerAvatar"`
+	TypeId      int       `json:"type"`
+}
+
+// PostVotersModel the voters of a post
+type PostVotersModel struct {
+	PostId uuid.UUID    `json:"postId"`
+	Voters []VoterModel `json:"voters"`
+}
@@ -0,0 +1,22 @@
+package models
+
+// Reaction names of the votes service, the index is the vote type
+var reactionNames = []string{"", "like", "love", "laugh", "sad", "angry"}
+
+// IsReaction check whether the name is a reaction of the votes
+func IsReaction(name string) bool {
+	for _, reactionName := range reactionNames[1:] {
+		if reactionName == name {
+			return true
+		}
+	}
+	return false
+}
+
+// ReactionName return the reaction name of a vote type, the votes without a type are likes
+func ReactionName(typeId int) string {
+	if typeId <= 0 || typeId >= len(reactionNames) {
+		return reactionNames[1]
+	}
+	return reactionNames[typeId]
+}
@@ -0,0 +1,8 @@
+package models
+
+import uuid "github.com/gofrs/uuid"
+
+type ReconcileCountersModel struct {
+	PostId      uuid.UUID `json:"postId"`
+	OwnerUserId uuid.UUID `json:"ownerUserId"`
+}
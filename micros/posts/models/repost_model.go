@@ -0,0 +1,14 @@
+package models
+
+import (
+	uuid "github.com/gofrs/uuid"
+	"github.com/red-gold/ts-serverless/constants"
+)
+
+type RepostModel struct {
+	ObjectId       uuid.UUID                     `json:"objectId"`
+	PostId         uuid.UUID                     `json:"postId"`
+	Body           string                        `json:"body"`
+	AccessUserList []string                      `json:"accessUserList"`
+	Permission     constants.UserPermissionConst `json:"permission"`
+}
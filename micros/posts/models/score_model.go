@@ -5,4 +5,8 @@ import uuid "github.com/gofrs/uuid"
 type ScoreModel struct {
 	PostId uuid.UUID `json:"postId"`
 	Count  int       `json:"count"`
+	// Reaction of the vote, empty for like
+	Reaction string `json:"reaction"`
+	// PreviousReaction is set when a vote switches from a reaction to another
+	PreviousReaction string `json:"previousReaction"`
 }
@@ -0,0 +1,7 @@
+package models
+
+type TagCountModel struct {
+	Tag      string `json:"tag" bson:"_id"`
+	Count    int64  `json:"count" bson:"count"`
+	LastUsed int64  `json:"lastUsed" bson:"lastUsed"`
+}
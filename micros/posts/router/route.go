@@ -11,7 +11,10 @@ import (
 	"github.com/red-gold/telar-core/middleware/authcookie"
 	"github.com/red-gold/telar-core/middleware/authhmac"
 	"github.com/red-gold/telar-core/types"
+	"github.com/red-gold/ts-serverless/micros/internal/index"
+	"github.com/red-gold/ts-serverless/micros/posts/database"
 	"github.com/red-gold/ts-serverless/micros/posts/handlers"
+	service "github.com/red-gold/ts-serverless/micros/posts/services"
 )
 
 // SetupRoutes func
@@ -54,16 +57,33 @@ func SetupRoutes(app *fiber.App) {
 
 	// Routers
 	app.Post("/", append(hmacCookieHandlers, handlers.CreatePostHandle)...)
-	app.Post("/index", authHMACMiddleware(false), handlers.InitPostIndexHandle)
+	app.Post("/repost", append(hmacCookieHandlers, handlers.RepostHandle)...)
+	app.Post("/index", authHMACMiddleware(false), index.InitHandle(newIndexService))
+	app.Get("/index", authHMACMiddleware(false), index.MissingHandle(newIndexService))
 	app.Put("/", append(hmacCookieHandlers, handlers.UpdatePostHandle)...)
 	app.Put("/profile", append(hmacCookieHandlers, handlers.UpdatePostProfileHandle)...)
 	app.Put("/score", authHMACMiddleware(false), handlers.IncrementScoreHandle)
 	app.Put("/comment/count", authHMACMiddleware(false), handlers.IncrementCommentHandle)
+	app.Post("/counters/reconcile", authHMACMiddleware(false), handlers.ReconcileCountersHandle)
+	app.Post("/outbox/dispatch", authHMACMiddleware(false), handlers.DispatchOutboxHandle)
+	app.Get("/outbox/status", authHMACMiddleware(false), handlers.GetOutboxStatusHandle)
 	app.Put("/comment/disable", append(hmacCookieHandlers, handlers.DisableCommentHandle)...)
 	app.Put("/share/disable", append(hmacCookieHandlers, handlers.DisableSharingHandle)...)
 	app.Put("/urlkey/:postId", append(hmacCookieHandlers, handlers.GeneratePostURLKeyHandle)...)
+	app.Post("/trash/purge", authHMACMiddleware(false), handlers.PurgeTrashHandle)
+	app.Get("/trash", append(hmacCookieHandlers, handlers.GetTrashPostsHandle)...)
+	app.Put("/restore/:postId", append(hmacCookieHandlers, handlers.RestorePostHandle)...)
 	app.Delete("/:postId", append(hmacCookieHandlers, handlers.DeletePostHandle)...)
 	app.Get("/", append(hmacCookieHandlers, handlers.QueryPostHandle)...)
+	app.Get("/feed", append(hmacCookieHandlers, handlers.QueryPostFeedHandle)...)
+	app.Get("/mention/:userId", append(hmacCookieHandlers, handlers.GetPostsByMentionHandle)...)
+	app.Get("/tags", append(hmacCookieHandlers, handlers.QueryTagsHandle)...)
+	app.Get("/tags/trending", append(hmacCookieHandlers, handlers.QueryTrendingTagsHandle)...)
 	app.Get("/:postId", append(hmacCookieHandlers, handlers.GetPostHandle)...)
 	app.Get("/urlkey/:urlkey", append(hmacCookieHandlers, handlers.GetPostByURLKeyHandle)...)
 }
+
+// newIndexService create the index service of the function
+func newIndexService() (index.Service, error) {
+	return service.NewIndexService(database.Db)
+}
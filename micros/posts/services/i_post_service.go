@@ -3,6 +3,7 @@ package service
 import (
 	uuid "github.com/gofrs/uuid"
 	repo "github.com/red-gold/telar-core/data"
+	"github.com/red-gold/ts-serverless/micros/internal/cursor"
 	dto "github.com/red-gold/ts-serverless/micros/posts/dto"
 	"github.com/red-gold/ts-serverless/micros/posts/models"
 )
@@ -12,23 +13,40 @@ type PostService interface {
 	FindOnePost(filter interface{}) (*dto.Post, error)
 	FindPostList(filter interface{}, limit int64, skip int64, sort map[string]int) ([]dto.Post, error)
 	FindPostsIncludeProfile(filter interface{}, limit int64, skip int64, sort map[string]int) ([]dto.Post, error)
-	QueryPost(search string, ownerUserIds []uuid.UUID, postTypeId int, sortBy string, page int64) ([]dto.Post, error)
-	QueryPostIncludeUser(search string, ownerUserIds []uuid.UUID, postTypeId int, sortBy string, page int64) ([]dto.Post, error)
+	QueryPost(search string, tag string, ownerUserIds []uuid.UUID, postTypeId int, sortBy string, page int64, viewer *models.PostViewerModel) ([]dto.Post, error)
+	QueryPostIncludeUser(search string, tag string, ownerUserIds []uuid.UUID, postTypeId int, sortBy string, page int64, viewer *models.PostViewerModel) ([]dto.Post, error)
+	QueryPostIncludeUserByCursor(search string, tag string, ownerUserIds []uuid.UUID, postTypeId int, viewer *models.PostViewerModel, after *cursor.Model, before *cursor.Model, limit int64) ([]dto.Post, error)
+	QueryPostFeed(ownerUserIds []uuid.UUID, viewer *models.PostViewerModel, after *cursor.Model, before *cursor.Model, limit int64) ([]dto.Post, error)
+	QueryPostByMention(userId uuid.UUID, page int64, viewer *models.PostViewerModel) ([]dto.Post, error)
+	QueryTagsByPrefix(prefix string, viewer *models.PostViewerModel, limit int64) ([]models.TagCountModel, error)
+	QueryTrendingTags(since int64, viewer *models.PostViewerModel, limit int64) ([]models.TagCountModel, error)
 	FindById(objectId uuid.UUID) (*dto.Post, error)
 	FindByOwnerUserId(ownerUserId uuid.UUID) ([]dto.Post, error)
 	FindByURLKey(urlKey string) (*dto.Post, error)
+	FindVisibleById(objectId uuid.UUID, viewer *models.PostViewerModel) (*dto.Post, error)
+	FindVisibleByURLKey(urlKey string, viewer *models.PostViewerModel) (*dto.Post, error)
+	FindVisibleByIds(objectIds []uuid.UUID, viewer *models.PostViewerModel) ([]dto.Post, error)
 	UpdatePost(filter interface{}, data interface{}, opts ...*repo.UpdateOptions) error
 	UpdateManyPost(filter interface{}, data interface{}, opts ...*repo.UpdateOptions) error
 	UpdatePostById(data *models.PostUpdateModel) error
 	DeletePost(filter interface{}) error
 	DeletePostByOwner(ownerUserId uuid.UUID, postId uuid.UUID) error
+	SoftDeletePostByOwner(ownerUserId uuid.UUID, postId uuid.UUID) (*dto.Post, error)
+	RestorePostByOwner(ownerUserId uuid.UUID, postId uuid.UUID, deletedSince int64) (*dto.Post, error)
+	FindTrashByOwner(ownerUserId uuid.UUID, deletedSince int64, page int64) ([]dto.Post, error)
+	FindPurgeablePostIds(deletedBefore int64) ([]uuid.UUID, error)
+	PurgeDeletedPosts(postIds []uuid.UUID, deletedBefore int64) error
 	DeleteManyPost(filter interface{}) error
 	CreatePostIndex(indexes map[string]interface{}) error
-	DisableCommnet(OwnerUserId uuid.UUID, objectId uuid.UUID, value bool) error
+	DisableCommnet(OwnerUserId uuid.UUID, objectId uuid.UUID, value bool, hideComments bool) error
 	DisableSharing(OwnerUserId uuid.UUID, objectId uuid.UUID, value bool) error
-	IncrementScoreCount(objectId uuid.UUID, ownerUserId uuid.UUID, avatar string) error
-	DecrementScoreCount(objectId uuid.UUID, ownerUserId uuid.UUID) error
+	IncrementScoreCount(objectId uuid.UUID, ownerUserId uuid.UUID, avatar string, reaction string, eventKey string) error
+	DecrementScoreCount(objectId uuid.UUID, ownerUserId uuid.UUID, reaction string, eventKey string) error
+	SwitchReaction(objectId uuid.UUID, previousReaction string, reaction string, eventKey string) error
 	Increment(objectId uuid.UUID, field string, value int) error
+	FindReconcilePosts(postId uuid.UUID, ownerUserId uuid.UUID, lastPostId uuid.UUID) ([]dto.Post, error)
+	ReconcilePostCounters(posts []dto.Post, commentCounts map[uuid.UUID]int64, postVoters []models.PostVotersModel) ([]models.CounterDiffModel, error)
+	IncrementOnce(objectId uuid.UUID, field string, value int, eventKey string) error
 	IncrementCommentCount(objectId uuid.UUID) error
 	DecerementCommentCount(objectId uuid.UUID) error
 	UpdatePostProfile(ownerUserId uuid.UUID, ownerDisplayName string, ownerAvatar string) error
@@ -11,6 +11,9 @@ import (
 	mongoRepo "github.com/red-gold/telar-core/data/mongodb"
 	"github.com/red-gold/telar-core/pkg/log"
 	"github.com/red-gold/telar-core/utils"
+	"github.com/red-gold/ts-serverless/constants"
+	"github.com/red-gold/ts-serverless/micros/internal/cursor"
+	"github.com/red-gold/ts-serverless/micros/internal/inmemory"
 	dto "github.com/red-gold/ts-serverless/micros/posts/dto"
 	"github.com/red-gold/ts-serverless/micros/posts/models"
 )
@@ -31,6 +34,10 @@ func NewPostService(db interface{}) (PostService, error) {
 		mongodb := db.(mongodb.MongoDatabase)
 		postService.PostRepo = mongoRepo.NewDataRepositoryMongo(mongodb)
 
+	case config.DB_INMEMORY:
+
+		postService.PostRepo = inmemory.NewDataRepositoryInMemory(db.(*inmemory.Database))
+
 	}
 
 	return postService, nil
@@ -61,8 +68,14 @@ func (s PostServiceImpl) FindOnePost(filter interface{}) (*dto.Post, error) {
 
 	result := <-s.PostRepo.FindOne(postCollectionName, filter)
 	if result.Error() != nil {
+		if result.Error() == repo.ErrNoDocuments {
+			return nil, nil
+		}
 		return nil, result.Error()
 	}
+	if result.NoResult() {
+		return nil, nil
+	}
 
 	var postResult dto.Post
 	errDecode := result.Decode(&postResult)
@@ -96,6 +109,11 @@ func (s PostServiceImpl) FindPostList(filter interface{}, limit int64, skip int6
 
 // FindPostsIncludeProfile get all posts by filter including user profile entity
 func (s PostServiceImpl) FindPostsIncludeProfile(filter interface{}, limit int64, skip int64, sort map[string]int) ([]dto.Post, error) {
+	return s.findPostsIncludeProfile(filter, limit, skip, sort)
+}
+
+// findPostsIncludeProfile get all posts by filter including user profile entity with any sort document
+func (s PostServiceImpl) findPostsIncludeProfile(filter interface{}, limit int64, skip int64, sort interface{}) ([]dto.Post, error) {
 	var pipeline []interface{}
 
 	matchOperator := make(map[string]interface{})
@@ -136,12 +154,14 @@ func (s PostServiceImpl) FindPostsIncludeProfile(filter interface{}, limit int64
 	project["postTypeId"] = 1
 	project["score"] = 1
 	project["votes"] = 1
+	project["reactions"] = 1
 	project["viewCount"] = 1
 	project["body"] = 1
 	project["ownerUserId"] = 1
 	project["ownerDisplayName"] = "$userinfo.fullName"
 	project["ownerAvatar"] = "$userinfo.avatar"
 	project["tags"] = 1
+	project["mentions"] = 1
 	project["commentCounter"] = 1
 	project["image"] = 1
 	project["imageFullPath"] = 1
@@ -150,6 +170,9 @@ func (s PostServiceImpl) FindPostsIncludeProfile(filter interface{}, limit int64
 	project["album"] = 1
 	project["disableComments"] = 1
 	project["disableSharing"] = 1
+	project["hideComments"] = 1
+	project["sharedPostId"] = 1
+	project["shareCounter"] = 1
 	project["deleted"] = 1
 	project["deletedDate"] = 1
 	project["created_date"] = 1
@@ -181,8 +204,71 @@ func (s PostServiceImpl) FindPostsIncludeProfile(filter interface{}, limit int64
 	return postList, nil
 }
 
+// accessFilter create the filter that matches only the posts the viewer is allowed to see
+func accessFilter(viewer *models.PostViewerModel) []interface{} {
+
+	// Posts without a restricted permission are public
+	restricted := make(map[string]interface{})
+	restricted["$nin"] = []constants.UserPermissionConst{constants.OnlyMe, constants.Circles, constants.Custom}
+	publicFilter := make(map[string]interface{})
+	publicFilter["permission"] = restricted
+
+	ownerFilter := make(map[string]interface{})
+	ownerFilter["ownerUserId"] = viewer.UserId
+
+	customFilter := make(map[string]interface{})
+	customFilter["permission"] = constants.Custom
+	customFilter["accessUserList"] = viewer.UserId.String()
+
+	orFilter := []interface{}{publicFilter, ownerFilter, customFilter}
+
+	for _, membership := range viewer.Circles {
+		if len(membership.CircleIds) == 0 {
+			continue
+		}
+		inCircles := make(map[string]interface{})
+		inCircles["$in"] = membership.CircleIds
+
+		circleFilter := make(map[string]interface{})
+		circleFilter["permission"] = constants.Circles
+		circleFilter["ownerUserId"] = membership.OwnerUserId
+		circleFilter["accessUserList"] = inCircles
+		orFilter = append(orFilter, circleFilter)
+	}
+	return orFilter
+}
+
+// applyViewerFilter add the filters that match only the posts the viewer is allowed to see.
+// The posts of the users who blocked the viewer or are blocked by the viewer are excluded.
+func applyViewerFilter(filter map[string]interface{}, viewer *models.PostViewerModel) {
+	filter["$or"] = accessFilter(viewer)
+	excludeOwners(filter, viewer.BlockedUserIds)
+}
+
+// excludeOwners add the filter that excludes the posts of the users
+func excludeOwners(filter map[string]interface{}, userIds []uuid.UUID) {
+	if len(userIds) == 0 {
+		return
+	}
+	notInFilter := make(map[string]interface{})
+	notInFilter["$nin"] = userIds
+
+	ownerFilter := make(map[string]interface{})
+	ownerFilter["ownerUserId"] = notInFilter
+
+	andFilter, _ := filter["$and"].([]interface{})
+	filter["$and"] = append(andFilter, ownerFilter)
+}
+
+// notDeletedFilter create the filter that matches the posts which are not deleted
+func notDeletedFilter() map[string]interface{} {
+	notDeleted := make(map[string]interface{})
+	notDeleted["$ne"] = true
+	return notDeleted
+}
+
 // QueryPost get all posts by query
-func (s PostServiceImpl) QueryPost(search string, ownerUserIds []uuid.UUID, postTypeId int, sortBy string, page int64) ([]dto.Post, error) {
+func (s PostServiceImpl) QueryPost(search string, tag string, ownerUserIds []uuid.UUID, postTypeId int, sortBy string, page int64, viewer *models.PostViewerModel) ([]dto.Post, error) {
 	sortMap := make(map[string]int)
 	sortMap[sortBy] = -1
 	skip := numberOfItems * (page - 1)
@@ -197,9 +283,16 @@ func (s PostServiceImpl) QueryPost(search string, ownerUserIds []uuid.UUID, post
 		inFilter["$in"] = ownerUserIds
 		filter["ownerUserId"] = inFilter
 	}
+	if tag != "" {
+		filter["tags"] = tag
+	}
 	if postTypeId > 0 {
 		filter["postTypeId"] = postTypeId
 	}
+	if viewer != nil {
+		applyViewerFilter(filter, viewer)
+	}
+	filter["deleted"] = notDeletedFilter()
 	fmt.Println(filter)
 	result, err := s.FindPostList(filter, limit, skip, sortMap)
 
@@ -207,7 +300,7 @@ func (s PostServiceImpl) QueryPost(search string, ownerUserIds []uuid.UUID, post
 }
 
 // QueryPostIncludeUser get all posts by query including user entity
-func (s PostServiceImpl) QueryPostIncludeUser(search string, ownerUserIds []uuid.UUID, postTypeId int, sortBy string, page int64) ([]dto.Post, error) {
+func (s PostServiceImpl) QueryPostIncludeUser(search string, tag string, ownerUserIds []uuid.UUID, postTypeId int, sortBy string, page int64, viewer *models.PostViewerModel) ([]dto.Post, error) {
 	sortMap := make(map[string]int)
 	sortMap[sortBy] = -1
 	skip := numberOfItems * (page - 1)
@@ -222,15 +315,114 @@ func (s PostServiceImpl) QueryPostIncludeUser(search string, ownerUserIds []uuid
 		inFilter["$in"] = ownerUserIds
 		filter["ownerUserId"] = inFilter
 	}
+	if tag != "" {
+		filter["tags"] = tag
+	}
 	if postTypeId > 0 {
 		filter["postTypeId"] = postTypeId
 	}
+	if viewer != nil {
+		applyViewerFilter(filter, viewer)
+	}
+	filter["deleted"] = notDeletedFilter()
 
 	result, err := s.FindPostsIncludeProfile(filter, limit, skip, sortMap)
 
 	return result, err
 }
 
+// QueryPostFeed get the posts of the owners which the viewer is allowed to see in the page around the cursors
+func (s PostServiceImpl) QueryPostFeed(ownerUserIds []uuid.UUID, viewer *models.PostViewerModel, after *cursor.Model, before *cursor.Model, limit int64) ([]dto.Post, error) {
+	if limit <= 0 {
+		limit = numberOfItems
+	}
+
+	inFilter := make(map[string]interface{})
+	inFilter["$in"] = ownerUserIds
+
+	visibleFilter := make(map[string]interface{})
+	visibleFilter["$or"] = accessFilter(viewer)
+
+	filter := make(map[string]interface{})
+	filter["ownerUserId"] = inFilter
+	filter["deleted"] = notDeletedFilter()
+	filter["$and"] = []interface{}{visibleFilter}
+
+	// The posts of the muted users are hidden from the feed only
+	excludeOwners(filter, viewer.BlockedUserIds)
+	excludeOwners(filter, viewer.MutedUserIds)
+	sort := cursor.AddFilter(filter, "created_date", after, before)
+
+	result, err := s.findPostsIncludeProfile(filter, limit, 0, sort)
+	if err != nil {
+		return nil, err
+	}
+	if before != nil {
+		reversePosts(result)
+	}
+	return result, nil
+}
+
+// QueryPostIncludeUserByCursor get the posts by query including user entity in the page around the cursors
+func (s PostServiceImpl) QueryPostIncludeUserByCursor(search string, tag string, ownerUserIds []uuid.UUID, postTypeId int, viewer *models.PostViewerModel, after *cursor.Model, before *cursor.Model, limit int64) ([]dto.Post, error) {
+	if limit <= 0 {
+		limit = numberOfItems
+	}
+
+	filter := make(map[string]interface{})
+	if search != "" {
+		filter["$text"] = coreData.SearchOperator{Search: search}
+	}
+	if ownerUserIds != nil && len(ownerUserIds) > 0 {
+		inFilter := make(map[string]interface{})
+		inFilter["$in"] = ownerUserIds
+		filter["ownerUserId"] = inFilter
+	}
+	if tag != "" {
+		filter["tags"] = tag
+	}
+	if postTypeId > 0 {
+		filter["postTypeId"] = postTypeId
+	}
+	if viewer != nil {
+		applyViewerFilter(filter, viewer)
+	}
+	filter["deleted"] = notDeletedFilter()
+	sort := cursor.AddFilter(filter, "created_date", after, before)
+
+	result, err := s.findPostsIncludeProfile(filter, limit, 0, sort)
+	if err != nil {
+		return nil, err
+	}
+	if before != nil {
+		reversePosts(result)
+	}
+	return result, nil
+}
+
+// QueryPostByMention get the posts which mention the user and the viewer is allowed to see by page
+func (s PostServiceImpl) QueryPostByMention(userId uuid.UUID, page int64, viewer *models.PostViewerModel) ([]dto.Post, error) {
+	if page < 1 {
+		page = 1
+	}
+	sortMap := make(map[string]int)
+	sortMap["created_date"] = -1
+
+	filter := make(map[string]interface{})
+	filter["mentions.userId"] = userId
+	filter["deleted"] = notDeletedFilter()
+	applyViewerFilter(filter, viewer)
+
+	return s.FindPostList(filter, numberOfItems, numberOfItems*(page-1), sortMap)
+}
+
+// reversePosts reverse the order of the posts in place
+func reversePosts(posts []dto.Post) {
+	for i, j := 0, len(posts)-1; i < j; i, j = i+1, j-1 {
+		posts[i], posts[j] = posts[j], posts[i]
+	}
+}
+
 // FindByOwnerUserId find by owner user id
 func (s PostServiceImpl) FindByOwnerUserId(ownerUserId uuid.UUID) ([]dto.Post, error) {
 	sortMap := make(map[string]int)
@@ -265,6 +457,41 @@ func (s PostServiceImpl) FindByURLKey(urlKey string) (*dto.Post, error) {
 	return s.FindOnePost(filter)
 }
 
+// FindVisibleById find by post id if the viewer is allowed to see the post
+func (s PostServiceImpl) FindVisibleById(objectId uuid.UUID, viewer *models.PostViewerModel) (*dto.Post, error) {
+
+	filter := make(map[string]interface{})
+	filter["objectId"] = objectId
+	filter["deleted"] = notDeletedFilter()
+	applyViewerFilter(filter, viewer)
+	return s.FindOnePost(filter)
+}
+
+// FindVisibleByURLKey find by URL key if the viewer is allowed to see the post
+func (s PostServiceImpl) FindVisibleByURLKey(urlKey string, viewer *models.PostViewerModel) (*dto.Post, error) {
+
+	filter := make(map[string]interface{})
+	filter["urlKey"] = urlKey
+	filter["deleted"] = notDeletedFilter()
+	applyViewerFilter(filter, viewer)
+	return s.FindOnePost(filter)
+}
+
+// FindVisibleByIds find the posts by post ids which the viewer is allowed to see
+func (s PostServiceImpl) FindVisibleByIds(objectIds []uuid.UUID, viewer *models.PostViewerModel) ([]dto.Post, error) {
+	sortMap := make(map[string]int)
+	sortMap["created_date"] = -1
+
+	inFilter := make(map[string]interface{})
+	inFilter["$in"] = objectIds
+
+	filter := make(map[string]interface{})
+	filter["objectId"] = inFilter
+	filter["deleted"] = notDeletedFilter()
+	applyViewerFilter(filter, viewer)
+	return s.FindPostList(filter, 0, 0, sortMap)
+}
+
 // UpdatePost update the post
 func (s PostServiceImpl) UpdatePost(filter interface{}, data interface{}, opts ...*coreData.UpdateOptions) error {
 
@@ -333,6 +560,131 @@ func (s PostServiceImpl) DeletePostByOwner(ownerUserId uuid.UUID, postId uuid.UU
 	return nil
 }
 
+// SoftDeletePostByOwner mark the post of the owner as deleted, the post is nil if the owner has no such post which is not deleted
+func (s PostServiceImpl) SoftDeletePostByOwner(ownerUserId uuid.UUID, postId uuid.UUID) (*dto.Post, error) {
+
+	filter := make(map[string]interface{})
+	filter["objectId"] = postId
+	filter["ownerUserId"] = ownerUserId
+	filter["deleted"] = notDeletedFilter()
+
+	data := struct {
+		Deleted     bool  `json:"deleted" bson:"deleted"`
+		DeletedDate int64 `json:"deletedDate" bson:"deletedDate"`
+	}{
+		Deleted:     true,
+		DeletedDate: utils.UTCNowUnix(),
+	}
+	updateOperator := coreData.UpdateOperator{
+		Set: data,
+	}
+	result := <-s.PostRepo.Update(postCollectionName, filter, updateOperator)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	modifiedCount, _ := result.Result.(int64)
+	if modifiedCount == 0 {
+		return nil, nil
+	}
+	return s.FindById(postId)
+}
+
+// RestorePostByOwner restore the post of the owner which is deleted since the date,
+// the post is nil if the owner has no such post
+func (s PostServiceImpl) RestorePostByOwner(ownerUserId uuid.UUID, postId uuid.UUID, deletedSince int64) (*dto.Post, error) {
+
+	sinceFilter := make(map[string]interface{})
+	sinceFilter["$gte"] = deletedSince
+
+	filter := make(map[string]interface{})
+	filter["objectId"] = postId
+	filter["ownerUserId"] = ownerUserId
+	filter["deleted"] = true
+	filter["deletedDate"] = sinceFilter
+
+	data := struct {
+		Deleted     bool  `json:"deleted" bson:"deleted"`
+		DeletedDate int64 `json:"deletedDate" bson:"deletedDate"`
+	}{
+		Deleted:     false,
+		DeletedDate: 0,
+	}
+	updateOperator := coreData.UpdateOperator{
+		Set: data,
+	}
+	result := <-s.PostRepo.Update(postCollectionName, filter, updateOperator)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	modifiedCount, _ := result.Result.(int64)
+	if modifiedCount == 0 {
+		return nil, nil
+	}
+	return s.FindById(postId)
+}
+
+// FindTrashByOwner get the deleted posts of the owner which are deleted since the date by page
+func (s PostServiceImpl) FindTrashByOwner(ownerUserId uuid.UUID, deletedSince int64, page int64) ([]dto.Post, error) {
+	if page < 1 {
+		page = 1
+	}
+	sortMap := make(map[string]int)
+	sortMap["deletedDate"] = -1
+
+	sinceFilter := make(map[string]interface{})
+	sinceFilter["$gte"] = deletedSince
+
+	filter := make(map[string]interface{})
+	filter["ownerUserId"] = ownerUserId
+	filter["deleted"] = true
+	filter["deletedDate"] = sinceFilter
+
+	return s.FindPostList(filter, numberOfItems, numberOfItems*(page-1), sortMap)
+}
+
+// FindPurgeablePostIds get the ids of a batch of the posts which are deleted before the date
+func (s PostServiceImpl) FindPurgeablePostIds(deletedBefore int64) ([]uuid.UUID, error) {
+
+	sortMap := make(map[string]int)
+	sortMap["deletedDate"] = 1
+
+	posts, err := s.FindPostList(purgeableFilter(deletedBefore), purgeBatchSize, 0, sortMap)
+	if err != nil {
+		return nil, err
+	}
+
+	var postIds []uuid.UUID
+	for _, post := range posts {
+		postIds = append(postIds, post.ObjectId)
+	}
+	return postIds, nil
+}
+
+// PurgeDeletedPosts remove the posts by ids which are still deleted before the date
+func (s PostServiceImpl) PurgeDeletedPosts(postIds []uuid.UUID, deletedBefore int64) error {
+	if len(postIds) == 0 {
+		return nil
+	}
+
+	inFilter := make(map[string]interface{})
+	inFilter["$in"] = postIds
+
+	filter := purgeableFilter(deletedBefore)
+	filter["objectId"] = inFilter
+	return s.DeleteManyPost(filter)
+}
+
+// purgeableFilter create the filter of the posts which are deleted before the date
+func purgeableFilter(deletedBefore int64) map[string]interface{} {
+	beforeFilter := make(map[string]interface{})
+	beforeFilter["$lt"] = deletedBefore
+
+	filter := make(map[string]interface{})
+	filter["deleted"] = true
+	filter["deletedDate"] = beforeFilter
+	return filter
+}
+
 // DeleteManyPost delete many post by filter
 func (s PostServiceImpl) DeleteManyPost(filter interface{}) error {
 
@@ -349,48 +701,78 @@ func (s PostServiceImpl) CreatePostIndex(indexes map[string]interface{}) error {
 	return result
 }
 
-// IncrementScoreCount increment score of post
-func (s PostServiceImpl) IncrementScoreCount(objectId uuid.UUID, ownerUserId uuid.UUID, avatar string) error {
-	filter := struct {
-		ObjectId uuid.UUID `json:"objectId" bson:"objectId"`
-	}{
-		ObjectId: objectId,
-	}
+// IncrementScoreCount add the vote of the user to the post and increment score and reaction count of post
+func (s PostServiceImpl) IncrementScoreCount(objectId uuid.UUID, ownerUserId uuid.UUID, avatar string, reaction string, eventKey string) error {
 
-	data := make(map[string]interface{})
+	setData := make(map[string]interface{})
 	targetField := fmt.Sprintf("votes.%s", ownerUserId.String())
 	log.Info("IncrementScoreCount %v - %v - %v ", targetField, objectId, avatar)
-	data[targetField] = avatar
-	updateOperator := coreData.UpdateOperator{
-		Set: data,
-	}
-	options := &coreData.UpdateOptions{}
-	options.SetUpsert(true)
-	return s.UpdatePost(filter, updateOperator, options)
-}
+	setData[targetField] = avatar
 
-// DecrementScoreCount increment score of post
-func (s PostServiceImpl) DecrementScoreCount(objectId uuid.UUID, ownerUserId uuid.UUID) error {
+	incData := make(map[string]interface{})
+	incData["score"] = 1
+	incData[fmt.Sprintf("reactions.%s", reaction)] = 1
 
-	filter := struct {
-		ObjectId uuid.UUID `json:"objectId" bson:"objectId"`
-	}{
-		ObjectId: objectId,
-	}
+	updateOperator := make(map[string]interface{})
+	updateOperator["$set"] = setData
+	updateOperator["$inc"] = incData
+	return s.updatePostOnce(objectId, updateOperator, eventKey)
+}
 
-	data := make(map[string]interface{})
+// DecrementScoreCount remove the vote of the user from the post and decrement score and reaction count of post
+func (s PostServiceImpl) DecrementScoreCount(objectId uuid.UUID, ownerUserId uuid.UUID, reaction string, eventKey string) error {
+
+	unsetData := make(map[string]interface{})
 	targetField := fmt.Sprintf("votes.%s", ownerUserId.String())
-	data[targetField] = nil
-	updateOperator := coreData.UpdateOperator{
-		Set: data,
+	unsetData[targetField] = ""
+
+	incData := make(map[string]interface{})
+	incData["score"] = -1
+	incData[fmt.Sprintf("reactions.%s", reaction)] = -1
+
+	updateOperator := make(map[string]interface{})
+	updateOperator["$unset"] = unsetData
+	updateOperator["$inc"] = incData
+	return s.updatePostOnce(objectId, updateOperator, eventKey)
+}
+
+// SwitchReaction move a vote from a reaction count of post to another
+func (s PostServiceImpl) SwitchReaction(objectId uuid.UUID, previousReaction string, reaction string, eventKey string) error {
+
+	incData := make(map[string]interface{})
+	incData[fmt.Sprintf("reactions.%s", previousReaction)] = -1
+	incData[fmt.Sprintf("reactions.%s", reaction)] = 1
+
+	updateOperator := make(map[string]interface{})
+	updateOperator["$inc"] = incData
+	return s.updatePostOnce(objectId, updateOperator, eventKey)
+}
+
+// updatePostOnce apply the update operator on the post once for an event key.
+// An empty event key applies the update without the check.
+func (s PostServiceImpl) updatePostOnce(objectId uuid.UUID, updateOperator map[string]interface{}, eventKey string) error {
+
+	filter := make(map[string]interface{})
+	filter["objectId"] = objectId
+
+	if eventKey != "" {
+		notApplied := make(map[string]interface{})
+		notApplied["$ne"] = eventKey
+		filter["appliedEvents"] = notApplied
+
+		eventEach := make(map[string]interface{})
+		eventEach["$each"] = []string{eventKey}
+		eventEach["$slice"] = -appliedEventsLimit
+		pushData := make(map[string]interface{})
+		pushData["appliedEvents"] = eventEach
+		updateOperator["$push"] = pushData
 	}
-	options := &coreData.UpdateOptions{}
-	options.SetUpsert(true)
-	return s.UpdatePost(filter, updateOperator, options)
+
+	return s.UpdatePost(filter, updateOperator)
 }
 
-// DisableCommnet
-func (s PostServiceImpl) DisableCommnet(OwnerUserId uuid.UUID, objectId uuid.UUID, value bool) error {
+// DisableCommnet lock the comments of the post, the existing comments are hidden from other users if hideComments is set
+func (s PostServiceImpl) DisableCommnet(OwnerUserId uuid.UUID, objectId uuid.UUID, value bool, hideComments bool) error {
 
 	filter := struct {
 		ObjectId    uuid.UUID `json:"objectId" bson:"objectId"`
@@ -402,6 +784,7 @@ func (s PostServiceImpl) DisableCommnet(OwnerUserId uuid.UUID, objectId uuid.UUI
 
 	data := make(map[string]interface{})
 	data["disableComments"] = value
+	data["hideComments"] = value && hideComments
 
 	incOperator := coreData.UpdateOperator{
 		Set: data,
@@ -447,6 +830,18 @@ func (s PostServiceImpl) Increment(objectId uuid.UUID, field string, value int)
 	return s.UpdatePost(filter, incOperator)
 }
 
+// IncrementOnce increment a post field once for an event key, a redelivered event does not change the post.
+// The post keeps the keys of its latest applied events.
+func (s PostServiceImpl) IncrementOnce(objectId uuid.UUID, field string, value int, eventKey string) error {
+
+	incData := make(map[string]interface{})
+	incData[field] = value
+
+	updateOperator := make(map[string]interface{})
+	updateOperator["$inc"] = incData
+	return s.updatePostOnce(objectId, updateOperator, eventKey)
+}
+
 // IncerementCommentCount increment comment count of post
 func (s PostServiceImpl) IncrementCommentCount(objectId uuid.UUID) error {
 	return s.Increment(objectId, "commentCounter", 1)
@@ -506,3 +901,126 @@ func (s PostServiceImpl) UpdatePostURLKey(postId uuid.UUID, urlKey string) error
 	}
 	return nil
 }
+
+// FindReconcilePosts get the next batch of the posts whose counters are reconciled, after the last post of the previous batch.
+// The posts are selected by post id or owner user id, all the posts are checked when both are empty.
+func (s PostServiceImpl) FindReconcilePosts(postId uuid.UUID, ownerUserId uuid.UUID, lastPostId uuid.UUID) ([]dto.Post, error) {
+	sortMap := make(map[string]int)
+	sortMap["objectId"] = 1
+
+	filter := make(map[string]interface{})
+	if ownerUserId != uuid.Nil {
+		filter["ownerUserId"] = ownerUserId
+	}
+	if postId != uuid.Nil {
+		if lastPostId != uuid.Nil {
+			return nil, nil
+		}
+		filter["objectId"] = postId
+	} else if lastPostId != uuid.Nil {
+		afterFilter := make(map[string]interface{})
+		afterFilter["$gt"] = lastPostId
+		filter["objectId"] = afterFilter
+	}
+
+	return s.FindPostList(filter, reconcileBatchSize, 0, sortMap)
+}
+
+// ReconcilePostCounters fix the counters of the posts which are not equal to the comment counts and the voters of the posts
+func (s PostServiceImpl) ReconcilePostCounters(posts []dto.Post, commentCounts map[uuid.UUID]int64, postVoters []models.PostVotersModel) ([]models.CounterDiffModel, error) {
+	postVotes := make(map[uuid.UUID]map[string]string)
+	postReactions := make(map[uuid.UUID]map[string]int64)
+	for _, voters := range postVoters {
+		votes := make(map[string]string)
+		reactions := make(map[string]int64)
+		for _, voter := range voters.Voters {
+			votes[voter.OwnerUserId.String()] = voter.OwnerAvatar
+			reactions[models.ReactionName(voter.TypeId)]++
+		}
+		postVotes[voters.PostId] = votes
+		postReactions[voters.PostId] = reactions
+	}
+
+	diffs := []models.CounterDiffModel{}
+	for _, post := range posts {
+		commentCounter := commentCounts[post.ObjectId]
+		votes, ok := postVotes[post.ObjectId]
+		if !ok {
+			votes = make(map[string]string)
+		}
+		reactions, ok := postReactions[post.ObjectId]
+		if !ok {
+			reactions = make(map[string]int64)
+		}
+		score := int64(len(votes))
+		votesFixed := !equalVotes(post.Votes, votes)
+		reactionsFixed := !equalReactions(post.Reactions, reactions)
+
+		if post.CommentCounter == commentCounter && post.Score == score && !votesFixed && !reactionsFixed {
+			continue
+		}
+
+		filter := struct {
+			ObjectId uuid.UUID `json:"objectId" bson:"objectId"`
+		}{
+			ObjectId: post.ObjectId,
+		}
+		data := struct {
+			CommentCounter int64             `json:"commentCounter" bson:"commentCounter"`
+			Score          int64             `json:"score" bson:"score"`
+			Votes          map[string]string `json:"votes" bson:"votes"`
+			Reactions      map[string]int64  `json:"reactions" bson:"reactions"`
+		}{
+			CommentCounter: commentCounter,
+			Score:          score,
+			Votes:          votes,
+			Reactions:      reactions,
+		}
+		updateOperator := coreData.UpdateOperator{
+			Set: data,
+		}
+		if err := s.UpdatePost(filter, updateOperator); err != nil {
+			return nil, err
+		}
+
+		diffs = append(diffs, models.CounterDiffModel{
+			PostId:               post.ObjectId,
+			CommentCounterBefore: post.CommentCounter,
+			CommentCounterAfter:  commentCounter,
+			ScoreBefore:          post.Score,
+			ScoreAfter:           score,
+			VotesFixed:           votesFixed,
+			ReactionsFixed:       reactionsFixed,
+		})
+	}
+	return diffs, nil
+}
+
+// equalReactions check whether the reaction counts of a post are the same, the reactions with zero count are ignored
+func equalReactions(current map[string]int64, expected map[string]int64) bool {
+	for reaction, count := range current {
+		if count != expected[reaction] {
+			return false
+		}
+	}
+	for reaction, count := range expected {
+		if count != current[reaction] {
+			return false
+		}
+	}
+	return true
+}
+
+// equalVotes check whether the votes of a post are the same
+func equalVotes(current map[string]string, expected map[string]string) bool {
+	if len(current) != len(expected) {
+		return false
+	}
+	for userId, avatar := range expected {
+		currentAvatar, ok := current[userId]
+		if !ok || currentAvatar != avatar {
+			return false
+		}
+	}
+	return true
+}
@@ -1,8 +1,12 @@
 package service
 
 const (
-	postCollectionName       = "post"
-	numberOfItems      int64 = 10
+	postCollectionName         = "post"
+	outboxCollectionName       = "postOutbox"
+	reconcileBatchSize   int64 = 100
+	purgeBatchSize       int64 = 100
+	numberOfItems        int64 = 10
+	appliedEventsLimit         = 100
 
 	// Error
 	alreadyIncrementScoreError = "alreadyIncrementScoreError"
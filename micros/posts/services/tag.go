@@ -0,0 +1,102 @@
+package service
+
+import (
+	"fmt"
+	"regexp"
+
+	models "github.com/red-gold/ts-serverless/micros/posts/models"
+)
+
+// tagCountSortModel sort by the number of posts then the last use then the tag, the struct keeps the order of the keys
+type tagCountSortModel struct {
+	Count    int `bson:"count"`
+	LastUsed int `bson:"lastUsed"`
+	Tag      int `bson:"_id"`
+}
+
+// tagCountSort the most used tags first
+var tagCountSort = tagCountSortModel{Count: -1, LastUsed: -1, Tag: 1}
+
+// QueryTagsByPrefix get the tags which start with the prefix on the posts the viewer can see, the most used tags first
+func (s PostServiceImpl) QueryTagsByPrefix(prefix string, viewer *models.PostViewerModel, limit int64) ([]models.TagCountModel, error) {
+	prefixFilter := make(map[string]interface{})
+	prefixFilter["$regex"] = "^" + regexp.QuoteMeta(prefix)
+
+	filter := make(map[string]interface{})
+	filter["tags"] = prefixFilter
+
+	return s.countTags(filter, prefixFilter, viewer, limit)
+}
+
+// QueryTrendingTags get the tags which are used the most on the posts created since the date the viewer can see
+func (s PostServiceImpl) QueryTrendingTags(since int64, viewer *models.PostViewerModel, limit int64) ([]models.TagCountModel, error) {
+	sinceFilter := make(map[string]interface{})
+	sinceFilter["$gte"] = since
+	hasTags := make(map[string]interface{})
+	hasTags["$ne"] = nil
+
+	filter := make(map[string]interface{})
+	filter["created_date"] = sinceFilter
+	filter["tags"] = hasTags
+
+	return s.countTags(filter, nil, viewer, limit)
+}
+
+// countTags count the posts of each tag on the posts which match the filter.
+// The tag filter keeps only the matching tags of a post, it is nil to count all the tags of the posts.
+func (s PostServiceImpl) countTags(filter map[string]interface{}, tagFilter map[string]interface{}, viewer *models.PostViewerModel, limit int64) ([]models.TagCountModel, error) {
+	if limit <= 0 {
+		limit = numberOfItems
+	}
+	var pipeline []interface{}
+
+	filter["deleted"] = notDeletedFilter()
+	applyViewerFilter(filter, viewer)
+	matchOperator := make(map[string]interface{})
+	matchOperator["$match"] = filter
+
+	unwindOperator := make(map[string]interface{})
+	unwindOperator["$unwind"] = "$tags"
+
+	pipeline = append(pipeline, matchOperator, unwindOperator)
+
+	if tagFilter != nil {
+		tagMatch := make(map[string]interface{})
+		tagMatch["tags"] = tagFilter
+		tagMatchOperator := make(map[string]interface{})
+		tagMatchOperator["$match"] = tagMatch
+		pipeline = append(pipeline, tagMatchOperator)
+	}
+
+	group := make(map[string]interface{})
+	group["_id"] = "$tags"
+	group["count"] = map[string]interface{}{"$sum": 1}
+	group["lastUsed"] = map[string]interface{}{"$max": "$created_date"}
+	groupOperator := make(map[string]interface{})
+	groupOperator["$group"] = group
+
+	sortOperator := make(map[string]interface{})
+	sortOperator["$sort"] = tagCountSort
+
+	limitOperator := make(map[string]interface{})
+	limitOperator["$limit"] = limit
+
+	pipeline = append(pipeline, groupOperator, sortOperator, limitOperator)
+
+	result := <-s.PostRepo.Aggregate(postCollectionName, pipeline)
+	defer result.Close()
+	if result.Error() != nil {
+		return nil, result.Error()
+	}
+
+	tagList := []models.TagCountModel{}
+	for result.Next() {
+		var tagCount models.TagCountModel
+		errDecode := result.Decode(&tagCount)
+		if errDecode != nil {
+			return nil, fmt.Errorf("Error docoding on tag count")
+		}
+		tagList = append(tagList, tagCount)
+	}
+	return tagList, nil
+}
@@ -41,4 +41,26 @@ func loadConfigFromEnvironment() {
 		UserRelConfig.Debug = parsedDebug
 		log.Printf("[INFO]: Debug information loaded from env.")
 	}
+
+	suggestionRefreshHours, ok := os.LookupEnv("suggestion_refresh_hours")
+	if ok {
+		parsedSuggestionRefreshHours, errParse := strconv.ParseInt(suggestionRefreshHours, 10, 64)
+		if errParse != nil || parsedSuggestionRefreshHours <= 0 {
+			log.Printf("[ERROR]: Suggestion refresh hours information loading error: %s", suggestionRefreshHours)
+		} else {
+			UserRelConfig.SuggestionRefreshHours = parsedSuggestionRefreshHours
+			log.Printf("[INFO]: Suggestion refresh hours information loaded from env.")
+		}
+	}
+
+	suggestionInteractionDays, ok := os.LookupEnv("suggestion_interaction_days")
+	if ok {
+		parsedSuggestionInteractionDays, errParse := strconv.ParseInt(suggestionInteractionDays, 10, 64)
+		if errParse != nil || parsedSuggestionInteractionDays <= 0 {
+			log.Printf("[ERROR]: Suggestion interaction days information loading error: %s", suggestionInteractionDays)
+		} else {
+			UserRelConfig.SuggestionInteractionDays = parsedSuggestionInteractionDays
+			log.Printf("[INFO]: Suggestion interaction days information loaded from env.")
+		}
+	}
 }
@@ -5,8 +5,17 @@ type (
 		BaseRoute      string
 		QueryPrettyURL bool
 		Debug          bool // Debug enables verbose logging of claims / cookies
+
+		// SuggestionRefreshHours the hours which the follow suggestions of a user are cached before they are built again
+		SuggestionRefreshHours int64
+
+		// SuggestionInteractionDays the days of the comments and votes which are counted for the follow suggestions
+		SuggestionInteractionDays int64
 	}
 )
 
 // UserRelConfig holds the configuration values from user-rel-config.yml file
-var UserRelConfig Configuration
+var UserRelConfig = Configuration{
+	SuggestionRefreshHours:    24,
+	SuggestionInteractionDays: 30,
+}
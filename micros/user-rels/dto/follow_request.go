@@ -0,0 +1,14 @@
+package dto
+
+import uuid "github.com/gofrs/uuid"
+
+// FollowRequest the pending request of the left user to follow the right user who has a private profile
+type FollowRequest struct {
+	ObjectId    uuid.UUID   `json:"objectId" bson:"objectId"`
+	CreatedDate int64       `json:"created_date" bson:"created_date"`
+	Left        UserRelMeta `json:"left" bson:"left"`
+	LeftId      uuid.UUID   `json:"leftId" bson:"leftId"`
+	Right       UserRelMeta `json:"right" bson:"right"`
+	RightId     uuid.UUID   `json:"rightId" bson:"rightId"`
+	CircleIds   []string    `json:"circleIds" bson:"circleIds"`
+}
@@ -0,0 +1,11 @@
+package dto
+
+import uuid "github.com/gofrs/uuid"
+
+// SuggestionDismissal a user whom the owner does not want to be suggested anymore
+type SuggestionDismissal struct {
+	ObjectId    uuid.UUID `json:"objectId" bson:"objectId"`
+	CreatedDate int64     `json:"created_date" bson:"created_date"`
+	OwnerUserId uuid.UUID `json:"ownerUserId" bson:"ownerUserId"`
+	UserId      uuid.UUID `json:"userId" bson:"userId"`
+}
@@ -0,0 +1,12 @@
+package dto
+
+import uuid "github.com/gofrs/uuid"
+
+// UserRestriction the block or mute relation which the left user puts on the right user
+type UserRestriction struct {
+	ObjectId    uuid.UUID `json:"objectId" bson:"objectId"`
+	CreatedDate int64     `json:"created_date" bson:"created_date"`
+	LeftId      uuid.UUID `json:"leftId" bson:"leftId"`
+	RightId     uuid.UUID `json:"rightId" bson:"rightId"`
+	Type        string    `json:"type" bson:"type"`
+}
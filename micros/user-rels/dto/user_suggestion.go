@@ -0,0 +1,23 @@
+package dto
+
+import uuid "github.com/gofrs/uuid"
+
+// SuggestedUser a user who is suggested to follow with the signals of the suggestion
+type SuggestedUser struct {
+	UserId            uuid.UUID `json:"userId" bson:"userId"`
+	FullName          string    `json:"fullName" bson:"fullName"`
+	SocialName        string    `json:"socialName" bson:"socialName"`
+	Avatar            string    `json:"avatar" bson:"avatar"`
+	Score             int64     `json:"score" bson:"score"`
+	MutualCount       int64     `json:"mutualCount" bson:"mutualCount"`
+	SharedCircleCount int64     `json:"sharedCircleCount" bson:"sharedCircleCount"`
+	InteractionCount  int64     `json:"interactionCount" bson:"interactionCount"`
+}
+
+// UserSuggestion the cached suggestions of a user
+type UserSuggestion struct {
+	ObjectId    uuid.UUID       `json:"objectId" bson:"objectId"`
+	CreatedDate int64           `json:"created_date" bson:"created_date"`
+	OwnerUserId uuid.UUID       `json:"ownerUserId" bson:"ownerUserId"`
+	Suggestions []SuggestedUser `json:"suggestions" bson:"suggestions"`
+}
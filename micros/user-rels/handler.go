@@ -3,6 +3,7 @@ package function
 import (
 	"context"
 	"net/http"
+	"time"
 
 	"github.com/gofiber/adaptor/v2"
 	"github.com/gofiber/fiber/v2"
@@ -13,10 +14,18 @@ import (
 	"github.com/red-gold/telar-core/config"
 	"github.com/red-gold/telar-core/pkg/log"
 	micros "github.com/red-gold/ts-serverless/micros"
+	"github.com/red-gold/ts-serverless/micros/internal/index"
+	"github.com/red-gold/ts-serverless/micros/internal/job"
+	"github.com/red-gold/ts-serverless/micros/internal/outbox"
 	"github.com/red-gold/ts-serverless/micros/user-rels/database"
+	"github.com/red-gold/ts-serverless/micros/user-rels/handlers"
 	"github.com/red-gold/ts-serverless/micros/user-rels/router"
+	service "github.com/red-gold/ts-serverless/micros/user-rels/services"
 )
 
+// suggestionRefreshInterval the interval of the job which builds again the follow suggestions which are out of the refresh hours
+const suggestionRefreshInterval = time.Hour
+
 // Cache state
 var app *fiber.App
 
@@ -53,9 +62,44 @@ func Handle(w http.ResponseWriter, r *http.Request) {
 			log.Error("Error startup: %s", startErr.Error())
 			w.WriteHeader(http.StatusInternalServerError)
 			w.Write([]byte(startErr.Error()))
+		} else {
+			go reconcileIndexes()
+			go job.Run(ctx, "suggestions refresh", suggestionRefreshInterval, refreshSuggestions)
+			go outbox.RunDispatcher(ctx, newOutboxService, outbox.DispatchInterval)
 		}
 	}
 
 	adaptor.FiberApp(app)(w, r)
 
 }
+
+// reconcileIndexes create the indexes which the function needs and are missing in the database.
+// Follows are refused until the unique index of the relations is created, POST /counters/reconcile removes
+// the duplicate relations which prevent it.
+func reconcileIndexes() {
+	if err := index.Reconcile(newIndexService); err != nil {
+		log.Error("Follows are refused until the unique follow index is created")
+	}
+}
+
+// newIndexService create the index service of the function for the reconciler
+func newIndexService() (index.Service, error) {
+	return service.NewIndexService(database.Db)
+}
+
+// newOutboxService create the outbox service of the function for the dispatcher
+func newOutboxService() (outbox.Service, error) {
+	return service.NewOutboxService(database.Db)
+}
+
+// refreshSuggestions build again the follow suggestions which are out of the refresh hours
+func refreshSuggestions() error {
+	refreshed, err := handlers.RefreshSuggestions(context.Background())
+	if err != nil {
+		return err
+	}
+	if refreshed > 0 {
+		log.Info("Suggestions refresh built the suggestions of %d users", refreshed)
+	}
+	return nil
+}
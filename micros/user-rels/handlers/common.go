@@ -1,20 +1,19 @@
 package handlers
 
 import (
-	"bytes"
-	"encoding/hex"
+	"context"
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
 	"net/http"
 
-	"github.com/alexellis/hmac"
 	"github.com/gofiber/fiber/v2"
 	uuid "github.com/gofrs/uuid"
-	coreConfig "github.com/red-gold/telar-core/config"
 	"github.com/red-gold/telar-core/pkg/log"
 	"github.com/red-gold/telar-core/types"
-	"github.com/red-gold/telar-core/utils"
+	"github.com/red-gold/ts-serverless/constants"
+	"github.com/red-gold/ts-serverless/micros/internal/outbox"
+	"github.com/red-gold/ts-serverless/micros/internal/rpc"
+	domain "github.com/red-gold/ts-serverless/micros/user-rels/dto"
 	models "github.com/red-gold/ts-serverless/micros/user-rels/models"
 	socialModels "github.com/red-gold/ts-serverless/micros/user-rels/models"
 )
@@ -68,86 +67,30 @@ func getHeaderInfoReq(c *fiber.Ctx) map[string][]string {
 	return getHeadersFromUserInfoReq(getUserInfoReq(c))
 }
 
-// functionCall send request to another function/microservice using HMAC validation
-func functionCall(method string, bytesReq []byte, url string, header map[string][]string) ([]byte, error) {
-	prettyURL := utils.GetPrettyURLf(url)
-	bodyReader := bytes.NewBuffer(bytesReq)
-
-	httpReq, httpErr := http.NewRequest(method, *coreConfig.AppConfig.InternalGateway+prettyURL, bodyReader)
-	if httpErr != nil {
-		return nil, httpErr
-	}
-
-	digest := hmac.Sign(bytesReq, []byte(*coreConfig.AppConfig.PayloadSecret))
-	httpReq.Header.Set("Content-type", "application/json")
-	fmt.Printf("\ndigest: %s, header: %v \n", "sha1="+hex.EncodeToString(digest), types.HeaderHMACAuthenticate)
-	httpReq.Header.Add(types.HeaderHMACAuthenticate, "sha1="+hex.EncodeToString(digest))
-
-	if header != nil {
-		for k, v := range header {
-			httpReq.Header[k] = v
-		}
-	}
-
-	c := http.Client{}
-	res, reqErr := c.Do(httpReq)
-	fmt.Printf("\nRes: %v\n", res)
-	if reqErr != nil {
-		return nil, fmt.Errorf("Error while sending admin check request!: %s", reqErr.Error())
-	}
-	if res.Body != nil {
-		defer res.Body.Close()
-	}
-
-	resData, readErr := ioutil.ReadAll(res.Body)
-	if resData == nil || readErr != nil {
-		return nil, fmt.Errorf("failed to read response from admin check request.")
-	}
-
-	if res.StatusCode != http.StatusAccepted && res.StatusCode != http.StatusOK {
-		if res.StatusCode == http.StatusNotFound {
-			return nil, NotFoundHTTPStatusError
-		}
-		return nil, fmt.Errorf("failed to call %s api, invalid status: %s", prettyURL, res.Status)
-	}
-
-	return resData, nil
-}
-
-// increaseUserFollowCount Increase user follow count
-func increaseUserFollowCount(userId uuid.UUID, inc int, userInfoInReq *UserInfoInReq) {
+// increaseUserFollowCountEvent Create the outbox event to increase user follow count
+func increaseUserFollowCountEvent(userId uuid.UUID, inc int, userInfoInReq *UserInfoInReq) outbox.Event {
 
 	actionURL := fmt.Sprintf("/profile/follow/inc/%d/%s", inc, userId.String())
 
 	// Create user headers for http request
 	userHeaders := getHeadersFromUserInfoReq(userInfoInReq)
 
-	_, actionErr := functionCall(http.MethodPut, []byte(actionURL), actionURL, userHeaders)
-
-	if actionErr != nil {
-		errorMessage := fmt.Sprintf("Function call error: %s - %s", actionURL, actionErr.Error())
-		log.Error(errorMessage)
-	}
+	return outbox.NewEvent(http.MethodPut, actionURL, []byte(actionURL), userHeaders)
 }
 
-// increaseUserFollowerCount Increase user follower count
-func increaseUserFollowerCount(userId uuid.UUID, inc int, userInfoInReq *UserInfoInReq) {
+// increaseUserFollowerCountEvent Create the outbox event to increase user follower count
+func increaseUserFollowerCountEvent(userId uuid.UUID, inc int, userInfoInReq *UserInfoInReq) outbox.Event {
 
 	actionURL := fmt.Sprintf("/profile/follower/inc/%d/%s", inc, userId.String())
 
 	// Create user headers for http request
 	userHeaders := getHeadersFromUserInfoReq(userInfoInReq)
 
-	_, actionErr := functionCall(http.MethodPut, []byte(actionURL), actionURL, userHeaders)
-
-	if actionErr != nil {
-		errorMessage := fmt.Sprintf("Function call error: %s - %s", actionURL, actionErr.Error())
-		log.Error(errorMessage)
-	}
+	return outbox.NewEvent(http.MethodPut, actionURL, []byte(actionURL), userHeaders)
 }
 
-// sendFollowNotification Send follow notification
-func sendFollowNotification(model *socialModels.FollowModel, userInfoInReq *UserInfoInReq) {
+// followNotificationEvent Create the outbox event to send follow notification
+func followNotificationEvent(model *socialModels.FollowModel, userInfoInReq *UserInfoInReq) (outbox.Event, error) {
 
 	// Create user headers for http request
 	userHeaders := getHeadersFromUserInfoReq(userInfoInReq)
@@ -167,14 +110,113 @@ func sendFollowNotification(model *socialModels.FollowModel, userInfoInReq *User
 	}
 	notificationBytes, marshalErr := json.Marshal(notificationModel)
 	if marshalErr != nil {
-		fmt.Printf("Cannot marshal notification! error: %s", marshalErr.Error())
+		log.Error("Marshal notification -  %s", marshalErr.Error())
+		return outbox.Event{}, fmt.Errorf("followNotificationEvent/marshal")
+	}
 
+	return outbox.NewEvent(http.MethodPost, "/notifications", notificationBytes, userHeaders), nil
+}
+
+// followRequestNotificationEvent Create the outbox event to send the notification of a follow request to the receiver.
+// The notification targets the request, so it can be retracted when the request is resolved.
+func followRequestNotificationEvent(request *domain.FollowRequest, receiverUserId uuid.UUID, notificationType string, description string, userInfoInReq *UserInfoInReq) (outbox.Event, error) {
+
+	// Create user headers for http request
+	userHeaders := getHeadersFromUserInfoReq(userInfoInReq)
+
+	URL := fmt.Sprintf("/@/%s", userInfoInReq.SocialName)
+	notificationModel := &models.NotificationModel{
+		OwnerUserId:          userInfoInReq.UserId,
+		OwnerDisplayName:     userInfoInReq.DisplayName,
+		OwnerAvatar:          userInfoInReq.Avatar,
+		Title:                userInfoInReq.DisplayName,
+		Description:          fmt.Sprintf(description, userInfoInReq.DisplayName),
+		URL:                  URL,
+		NotifyRecieverUserId: receiverUserId,
+		TargetId:             request.ObjectId,
+		IsSeen:               false,
+		Type:                 notificationType,
 	}
+	notificationBytes, marshalErr := json.Marshal(notificationModel)
+	if marshalErr != nil {
+		log.Error("Marshal notification -  %s", marshalErr.Error())
+		return outbox.Event{}, fmt.Errorf("followRequestNotificationEvent/marshal")
+	}
+
+	return outbox.NewEvent(http.MethodPost, "/notifications", notificationBytes, userHeaders), nil
+}
 
-	notificationURL := "/notifications"
-	_, notificationIndexErr := functionCall(http.MethodPost, notificationBytes, notificationURL, userHeaders)
-	if notificationIndexErr != nil {
-		fmt.Printf("\nCannot save notification on follow user! error: %s\n", notificationIndexErr.Error())
+// retractNotificationEvent Create the outbox event to remove the notifications of a target
+func retractNotificationEvent(targetId uuid.UUID, userInfoInReq *UserInfoInReq) outbox.Event {
+	userHeaders := getHeadersFromUserInfoReq(userInfoInReq)
+	return outbox.NewEvent(http.MethodDelete, fmt.Sprintf("/notifications/target/%s", targetId), nil, userHeaders)
+}
+
+// getUserProfileByID Get user profile by user ID
+func getUserProfileByID(ctx context.Context, userID uuid.UUID) (*models.UserProfileModel, error) {
+	profileURL := fmt.Sprintf("/profile/dto/id/%s", userID.String())
+	foundProfileData, err := rpc.Call(ctx, http.MethodGet, profileURL, []byte(""))
+	if err != nil {
+		if rpc.IsNotFound(err) {
+			return nil, nil
+		}
+		log.Error("rpc.Call (%s) -  %s", profileURL, err.Error())
+		return nil, fmt.Errorf("getUserProfileByID/rpc")
+	}
+	var foundProfile models.UserProfileModel
+	err = json.Unmarshal(foundProfileData, &foundProfile)
+	if err != nil {
+		log.Error("Unmarshal foundProfile -  %s", err.Error())
+		return nil, fmt.Errorf("getUserProfileByID/unmarshal")
+	}
+	return &foundProfile, nil
+}
+
+// isPrivateProfile check whether the owner of the profile approves the followers.
+// Profiles without a permission are public.
+func isPrivateProfile(profile *models.UserProfileModel) bool {
+	return profile.Permission != "" && profile.Permission != constants.Public
+}
+
+// getProfilesByUserIds Get user profiles by user IDs
+func getProfilesByUserIds(ctx context.Context, model models.GetProfilesModel) ([]models.UserProfileModel, error) {
+	profileURL := "/profile/dto/ids"
+	body, marshalErr := json.Marshal(model)
+	if marshalErr != nil {
+		log.Error("Marshal models.GetProfilesModel -  %s", marshalErr.Error())
+		return nil, fmt.Errorf("getProfilesByUserIds/marshal")
 	}
 
+	foundProfilesData, err := rpc.Call(ctx, http.MethodPost, profileURL, body)
+	if err != nil {
+		if rpc.IsNotFound(err) {
+			return nil, nil
+		}
+		log.Error("rpc.Call (%s) -  %s", profileURL, err.Error())
+		return nil, fmt.Errorf("getProfilesByUserIds/rpc")
+	}
+	var foundProfiles []models.UserProfileModel
+	err = json.Unmarshal(foundProfilesData, &foundProfiles)
+	if err != nil {
+		log.Error("Unmarshal foundProfiles -  %s", err.Error())
+		return nil, fmt.Errorf("getProfilesByUserIds/unmarshal")
+	}
+	return foundProfiles, nil
+}
+
+// getInteractions Get the number of the comments or votes between the user and each other user since the date
+func getInteractions(ctx context.Context, function string, userID uuid.UUID, since int64) ([]models.UserCountModel, error) {
+	interactionsURL := fmt.Sprintf("/%s/interactions/%s?since=%d", function, userID.String(), since)
+	interactionsData, err := rpc.Call(ctx, http.MethodGet, interactionsURL, []byte(""))
+	if err != nil {
+		log.Error("rpc.Call (%s) -  %s", interactionsURL, err.Error())
+		return nil, fmt.Errorf("getInteractions/rpc")
+	}
+	var interactions []models.UserCountModel
+	err = json.Unmarshal(interactionsData, &interactions)
+	if err != nil {
+		log.Error("Unmarshal interactions -  %s", err.Error())
+		return nil, fmt.Errorf("getInteractions/unmarshal")
+	}
+	return interactions, nil
 }
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 
@@ -8,6 +9,8 @@ import (
 	"github.com/red-gold/telar-core/pkg/log"
 	"github.com/red-gold/telar-core/types"
 	"github.com/red-gold/telar-core/utils"
+	"github.com/red-gold/ts-serverless/micros/internal/outbox"
+	"github.com/red-gold/ts-serverless/micros/internal/rpc"
 	"github.com/red-gold/ts-serverless/micros/user-rels/database"
 	domain "github.com/red-gold/ts-serverless/micros/user-rels/dto"
 	socialModels "github.com/red-gold/ts-serverless/micros/user-rels/models"
@@ -43,7 +46,7 @@ func CreateUserRelHandle(c *fiber.Ctx) error {
 
 }
 
-//FollowHandle handle create a new userRel
+// FollowHandle handle create a new userRel
 func FollowHandle(c *fiber.Ctx) error {
 
 	// Create the model object
@@ -61,6 +64,12 @@ func FollowHandle(c *fiber.Ctx) error {
 		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/userRelService", "Error happened while creating userRelService!"))
 	}
 
+	outboxService, serviceErr := service.NewOutboxService(database.Db)
+	if serviceErr != nil {
+		log.Error("NewOutboxService %s", serviceErr.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/outboxService", "Error happened while creating outboxService!"))
+	}
+
 	currentUser, ok := c.Locals(types.UserCtxName).(types.UserContext)
 	if !ok {
 		log.Error("[FollowHandle] Can not get current user")
@@ -68,6 +77,34 @@ func FollowHandle(c *fiber.Ctx) error {
 			"Can not get current user"))
 	}
 
+	// Users can not follow each other while one of them blocks the other
+	restrictionService, serviceErr := service.NewUserRestrictionService(database.Db)
+	if serviceErr != nil {
+		log.Error("NewUserRestrictionService %s", serviceErr.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/userRestrictionService", "Error happened while creating userRestrictionService!"))
+	}
+	blocked, err := restrictionService.IsBlocked(currentUser.UserID, model.RightUser.UserId)
+	if err != nil {
+		log.Error("[FollowHandle.restrictionService.IsBlocked] %s", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/saveUserRel", "Error happened while saving UserRel!"))
+	}
+	if blocked {
+		return c.Status(http.StatusForbidden).JSON(utils.Error("userBlocked", "You can not follow this user!"))
+	}
+
+	// Users with a private profile approve the followers through follow requests
+	rightProfile, err := getUserProfileByID(rpc.Context(c), model.RightUser.UserId)
+	if err != nil {
+		log.Error("[FollowHandle.getUserProfileByID] %s", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/getUserProfile", "Error happened while reading user profile!"))
+	}
+	if rightProfile == nil {
+		return c.Status(http.StatusNotFound).JSON(utils.Error("userNotFound", "User not found!"))
+	}
+	if isPrivateProfile(rightProfile) {
+		return requestFollow(c, model, currentUser)
+	}
+
 	// Left User Meta
 	leftUserMeta := domain.UserRelMeta{
 		UserId:   currentUser.UserID,
@@ -82,19 +119,48 @@ func FollowHandle(c *fiber.Ctx) error {
 		Avatar:   model.RightUser.Avatar,
 	}
 
-	// Store the relation
-	if err := userRelService.FollowUser(leftUserMeta, rightUserMeta, model.CircleIds, []string{"status:follow"}); err != nil {
+	// The notification is created before the relation is stored, a relation is not stored without it
+	userInfoReq := getUserInfoReq(c)
+	notificationEvent, err := followNotificationEvent(model, userInfoReq)
+	if err != nil {
+		log.Error("[FollowHandle.followNotificationEvent] %s", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/saveUserRel", "Error happened while saving UserRel!"))
+	}
+
+	// Store the relation, following a user twice keeps the relation and the counts as they are
+	created, err := userRelService.FollowUser(leftUserMeta, rightUserMeta, model.CircleIds, []string{"status:follow"})
+	if errors.Is(err, service.ErrFollowIndexMissing) {
+		log.Error("[FollowHandle.FollowUser] %s", err.Error())
+		return c.Status(http.StatusServiceUnavailable).JSON(utils.Error("followIndexMissing", "Follows are not accepted until the follow index is created!"))
+	}
+	if err != nil {
 		errorMessage := fmt.Sprintf("Save UserRel Error %s", err.Error())
 		log.Error(errorMessage)
 		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/saveUserRel", "Error happened while saving UserRel!"))
 	}
+	if !created {
+		return c.SendStatus(http.StatusOK)
+	}
+
+	events := []outbox.Event{
+		// Create notification
+		notificationEvent,
+		// Increase user follow count
+		increaseUserFollowCountEvent(currentUser.UserID, 1, userInfoReq),
+		// Increase user follower count
+		increaseUserFollowerCountEvent(model.RightUser.UserId, 1, userInfoReq),
+	}
+
+	// The relation is removed if its side effects can not be stored
+	rollbackFollow := func() error {
+		return userRelService.UnfollowUser(currentUser.UserID, model.RightUser.UserId)
+	}
+	if err := outbox.SaveEvents(outboxService, events, rollbackFollow); err != nil {
+		log.Error("[FollowHandle.saveOutboxEvents] %s", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/saveUserRel", "Error happened while saving UserRel!"))
+	}
 
-	// Create notification
-	go sendFollowNotification(model, getUserInfoReq(c))
-	// Increase user follow count
-	go increaseUserFollowCount(currentUser.UserID, 1, getUserInfoReq(c))
-	// Increase user follower count
-	go increaseUserFollowerCount(model.RightUser.UserId, 1, getUserInfoReq(c))
+	go outbox.Dispatch(outboxService, events)
 
 	return c.SendStatus(http.StatusOK)
 }
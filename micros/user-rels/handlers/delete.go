@@ -9,6 +9,7 @@ import (
 	"github.com/red-gold/telar-core/pkg/log"
 	"github.com/red-gold/telar-core/types"
 	"github.com/red-gold/telar-core/utils"
+	"github.com/red-gold/ts-serverless/micros/internal/outbox"
 	"github.com/red-gold/ts-serverless/micros/user-rels/database"
 	service "github.com/red-gold/ts-serverless/micros/user-rels/services"
 )
@@ -80,6 +81,12 @@ func UnfollowHandle(c *fiber.Ctx) error {
 		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/userRelService", "Error happened while creating userRelService!"))
 	}
 
+	outboxService, serviceErr := service.NewOutboxService(database.Db)
+	if serviceErr != nil {
+		log.Error("NewOutboxService %s", serviceErr.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/outboxService", "Error happened while creating outboxService!"))
+	}
+
 	currentUser, ok := c.Locals(types.UserCtxName).(types.UserContext)
 	if !ok {
 		log.Error("[UnfollowHandle] Can not get current user")
@@ -87,16 +94,32 @@ func UnfollowHandle(c *fiber.Ctx) error {
 			"Can not get current user"))
 	}
 
-	if err := userRelService.UnfollowUser(currentUser.UserID, userFollowingUUID); err != nil {
+	// Unfollowing a user who is not followed keeps the counts as they are
+	removed, err := userRelService.RemoveFollow(currentUser.UserID, userFollowingUUID)
+	if err != nil {
 		errorMessage := fmt.Sprintf("Delete UserRel Error %s", err.Error())
 		log.Error(errorMessage)
 		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/unfollowUser", "Error happened while removing user-rel!"))
 	}
+	if !removed {
+		return c.SendStatus(http.StatusOK)
+	}
+
+	userInfoReq := getUserInfoReq(c)
+	events := []outbox.Event{
+		// Decrease user follow count
+		increaseUserFollowCountEvent(currentUser.UserID, -1, userInfoReq),
+		// Decrease user follower count
+		increaseUserFollowerCountEvent(userFollowingUUID, -1, userInfoReq),
+	}
+
+	// The relation is already removed, so the events are stored without a rollback
+	if err := outboxService.SaveEvents(events); err != nil {
+		log.Error("[UnfollowHandle.outboxService.SaveEvents] %s", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/unfollowUser", "Error happened while removing user-rel!"))
+	}
 
-	// Decrease user follow count
-	go increaseUserFollowCount(currentUser.UserID, -1, getUserInfoReq(c))
-	// Decrease user follower count
-	go increaseUserFollowerCount(userFollowingUUID, -1, getUserInfoReq(c))
+	go outbox.Dispatch(outboxService, events)
 
 	return c.SendStatus(http.StatusOK)
 }
@@ -0,0 +1,350 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+	uuid "github.com/gofrs/uuid"
+	"github.com/red-gold/telar-core/pkg/log"
+	"github.com/red-gold/telar-core/types"
+	"github.com/red-gold/telar-core/utils"
+	"github.com/red-gold/ts-serverless/micros/internal/httperr"
+	"github.com/red-gold/ts-serverless/micros/internal/outbox"
+	"github.com/red-gold/ts-serverless/micros/user-rels/database"
+	domain "github.com/red-gold/ts-serverless/micros/user-rels/dto"
+	socialModels "github.com/red-gold/ts-serverless/micros/user-rels/models"
+	service "github.com/red-gold/ts-serverless/micros/user-rels/services"
+)
+
+// Notification types of the follow requests
+const (
+	followRequestNotificationType  = "followRequest"
+	followAcceptedNotificationType = "followAccepted"
+)
+
+// requestFollow store the request of the current user to follow a user with a private profile.
+// A pending request is kept as is and the receiver is notified once.
+func requestFollow(c *fiber.Ctx, model *socialModels.FollowModel, currentUser types.UserContext) error {
+
+	// Create service
+	followRequestService, serviceErr := service.NewFollowRequestService(database.Db)
+	if serviceErr != nil {
+		log.Error("NewFollowRequestService %s", serviceErr.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/followRequestService", "Error happened while creating followRequestService!"))
+	}
+
+	outboxService, serviceErr := service.NewOutboxService(database.Db)
+	if serviceErr != nil {
+		log.Error("NewOutboxService %s", serviceErr.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/outboxService", "Error happened while creating outboxService!"))
+	}
+
+	newRequest := &domain.FollowRequest{
+		Left: domain.UserRelMeta{
+			UserId:   currentUser.UserID,
+			FullName: currentUser.DisplayName,
+			Avatar:   currentUser.Avatar,
+		},
+		LeftId: currentUser.UserID,
+		Right: domain.UserRelMeta{
+			UserId:   model.RightUser.UserId,
+			FullName: model.RightUser.FullName,
+			Avatar:   model.RightUser.Avatar,
+		},
+		RightId:   model.RightUser.UserId,
+		CircleIds: model.CircleIds,
+	}
+	request, created, err := followRequestService.UpsertFollowRequest(newRequest)
+	if err != nil {
+		log.Error("[requestFollow.followRequestService.UpsertFollowRequest] %s", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/saveFollowRequest", "Error happened while saving follow request!"))
+	}
+
+	if created {
+
+		// The request is removed if its notification can not be created or stored
+		rollbackRequest := func() error {
+			_, err := followRequestService.DeleteFollowRequest(request.LeftId, request.RightId)
+			return err
+		}
+
+		notificationEvent, err := followRequestNotificationEvent(request, request.RightId, followRequestNotificationType, "%s wants to follow you.", getUserInfoReq(c))
+		if err != nil {
+			log.Error("[requestFollow.followRequestNotificationEvent] %s", err.Error())
+			if rollbackErr := rollbackRequest(); rollbackErr != nil {
+				log.Error("[requestFollow.rollbackRequest] %s", rollbackErr.Error())
+			}
+			return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/saveFollowRequest", "Error happened while saving follow request!"))
+		}
+		events := []outbox.Event{notificationEvent}
+
+		if err := outbox.SaveEvents(outboxService, events, rollbackRequest); err != nil {
+			log.Error("[requestFollow.saveOutboxEvents] %s", err.Error())
+			return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/saveFollowRequest", "Error happened while saving follow request!"))
+		}
+
+		go outbox.Dispatch(outboxService, events)
+	}
+
+	return c.Status(http.StatusAccepted).JSON(request)
+}
+
+// readRequestUserId read the id of the other user of the follow request from the params
+func readRequestUserId(c *fiber.Ctx) (uuid.UUID, error) {
+
+	// params from /user-rels/requests/incoming/:userId and /user-rels/requests/outgoing/:userId
+	userId := c.Params("userId")
+	if userId == "" {
+		return uuid.Nil, httperr.New(http.StatusBadRequest, "userIdRequired", "User Id is required!")
+	}
+
+	userUUID, uuidErr := uuid.FromString(userId)
+	if uuidErr != nil {
+		log.Error("UUID Error %s", uuidErr.Error())
+		return uuid.Nil, httperr.New(http.StatusBadRequest, "userIdIsNotValid", "User id is not valid!")
+	}
+	return userUUID, nil
+}
+
+// GetIncomingRequestsHandle handle get the pending requests of other users to follow the current user
+func GetIncomingRequestsHandle(c *fiber.Ctx) error {
+
+	// Create service
+	followRequestService, serviceErr := service.NewFollowRequestService(database.Db)
+	if serviceErr != nil {
+		log.Error("NewFollowRequestService %s", serviceErr.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/followRequestService", "Error happened while creating followRequestService!"))
+	}
+
+	currentUser, ok := c.Locals(types.UserCtxName).(types.UserContext)
+	if !ok {
+		log.Error("[GetIncomingRequestsHandle] Can not get current user")
+		return c.Status(http.StatusBadRequest).JSON(utils.Error("invalidCurrentUser",
+			"Can not get current user"))
+	}
+
+	requests, err := followRequestService.GetIncomingRequests(currentUser.UserID)
+	if err != nil {
+		log.Error("[GetIncomingRequestsHandle.followRequestService.GetIncomingRequests] %s", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/getFollowRequests", "Error happened while reading follow requests!"))
+	}
+	if requests == nil {
+		requests = []domain.FollowRequest{}
+	}
+
+	return c.JSON(requests)
+}
+
+// GetOutgoingRequestsHandle handle get the pending requests of the current user to follow other users
+func GetOutgoingRequestsHandle(c *fiber.Ctx) error {
+
+	// Create service
+	followRequestService, serviceErr := service.NewFollowRequestService(database.Db)
+	if serviceErr != nil {
+		log.Error("NewFollowRequestService %s", serviceErr.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/followRequestService", "Error happened while creating followRequestService!"))
+	}
+
+	currentUser, ok := c.Locals(types.UserCtxName).(types.UserContext)
+	if !ok {
+		log.Error("[GetOutgoingRequestsHandle] Can not get current user")
+		return c.Status(http.StatusBadRequest).JSON(utils.Error("invalidCurrentUser",
+			"Can not get current user"))
+	}
+
+	requests, err := followRequestService.GetOutgoingRequests(currentUser.UserID)
+	if err != nil {
+		log.Error("[GetOutgoingRequestsHandle.followRequestService.GetOutgoingRequests] %s", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/getFollowRequests", "Error happened while reading follow requests!"))
+	}
+	if requests == nil {
+		requests = []domain.FollowRequest{}
+	}
+
+	return c.JSON(requests)
+}
+
+// AcceptFollowRequestHandle handle accept the request of a user to follow the current user.
+// The relation is created, the follow counters are increased and the requester is notified.
+func AcceptFollowRequestHandle(c *fiber.Ctx) error {
+
+	requesterId, err := readRequestUserId(c)
+	if err != nil {
+		return httperr.Respond(c, err)
+	}
+
+	// Create service
+	followRequestService, serviceErr := service.NewFollowRequestService(database.Db)
+	if serviceErr != nil {
+		log.Error("NewFollowRequestService %s", serviceErr.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/followRequestService", "Error happened while creating followRequestService!"))
+	}
+
+	userRelService, serviceErr := service.NewUserRelService(database.Db)
+	if serviceErr != nil {
+		log.Error("NewUserRelService %s", serviceErr.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/userRelService", "Error happened while creating userRelService!"))
+	}
+
+	outboxService, serviceErr := service.NewOutboxService(database.Db)
+	if serviceErr != nil {
+		log.Error("NewOutboxService %s", serviceErr.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/outboxService", "Error happened while creating outboxService!"))
+	}
+
+	currentUser, ok := c.Locals(types.UserCtxName).(types.UserContext)
+	if !ok {
+		log.Error("[AcceptFollowRequestHandle] Can not get current user")
+		return c.Status(http.StatusBadRequest).JSON(utils.Error("invalidCurrentUser",
+			"Can not get current user"))
+	}
+
+	request, err := followRequestService.FindFollowRequest(requesterId, currentUser.UserID)
+	if err != nil {
+		log.Error("[AcceptFollowRequestHandle.followRequestService.FindFollowRequest] %s", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/acceptFollowRequest", "Error happened while accepting follow request!"))
+	}
+	if request == nil {
+		return c.Status(http.StatusNotFound).JSON(utils.Error("followRequestNotFound", "Follow request not found!"))
+	}
+
+	// The notification is created before the relation is stored, a relation is not stored without it
+	userInfoReq := getUserInfoReq(c)
+	notificationEvent, err := followRequestNotificationEvent(request, request.LeftId, followAcceptedNotificationType, "%s accepted your follow request.", userInfoReq)
+	if err != nil {
+		log.Error("[AcceptFollowRequestHandle.followRequestNotificationEvent] %s", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/acceptFollowRequest", "Error happened while accepting follow request!"))
+	}
+
+	// Store the relation, the counts change only when the requester did not follow the user yet
+	created, err := userRelService.FollowUser(request.Left, request.Right, request.CircleIds, []string{"status:follow"})
+	if errors.Is(err, service.ErrFollowIndexMissing) {
+		log.Error("[AcceptFollowRequestHandle.userRelService.FollowUser] %s", err.Error())
+		return c.Status(http.StatusServiceUnavailable).JSON(utils.Error("followIndexMissing", "Follows are not accepted until the follow index is created!"))
+	}
+	if err != nil {
+		log.Error("[AcceptFollowRequestHandle.userRelService.FollowUser] %s", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/acceptFollowRequest", "Error happened while accepting follow request!"))
+	}
+
+	events := []outbox.Event{
+		// Retract the request notification
+		retractNotificationEvent(request.ObjectId, userInfoReq),
+		// Notify the requester
+		notificationEvent,
+	}
+	if created {
+		events = append(events,
+			// Increase user follow count
+			increaseUserFollowCountEvent(request.LeftId, 1, userInfoReq),
+			// Increase user follower count
+			increaseUserFollowerCountEvent(request.RightId, 1, userInfoReq),
+		)
+	}
+
+	// The relation is removed if its side effects can not be stored
+	rollbackFollow := func() error {
+		if !created {
+			return nil
+		}
+		return userRelService.UnfollowUser(request.LeftId, request.RightId)
+	}
+	if err := outbox.SaveEvents(outboxService, events, rollbackFollow); err != nil {
+		log.Error("[AcceptFollowRequestHandle.saveOutboxEvents] %s", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/acceptFollowRequest", "Error happened while accepting follow request!"))
+	}
+
+	// The relation is already stored, a request which is not removed is only reported
+	if _, err := followRequestService.DeleteFollowRequest(request.LeftId, request.RightId); err != nil {
+		log.Error("[AcceptFollowRequestHandle.followRequestService.DeleteFollowRequest] %s", err.Error())
+	}
+
+	go outbox.Dispatch(outboxService, events)
+
+	return c.SendStatus(http.StatusOK)
+}
+
+// DeclineFollowRequestHandle handle decline the request of a user to follow the current user
+func DeclineFollowRequestHandle(c *fiber.Ctx) error {
+
+	currentUser, ok := c.Locals(types.UserCtxName).(types.UserContext)
+	if !ok {
+		log.Error("[DeclineFollowRequestHandle] Can not get current user")
+		return c.Status(http.StatusBadRequest).JSON(utils.Error("invalidCurrentUser",
+			"Can not get current user"))
+	}
+
+	requesterId, err := readRequestUserId(c)
+	if err != nil {
+		return httperr.Respond(c, err)
+	}
+	return removeFollowRequest(c, requesterId, currentUser.UserID)
+}
+
+// CancelFollowRequestHandle handle cancel the request of the current user to follow a user
+func CancelFollowRequestHandle(c *fiber.Ctx) error {
+
+	currentUser, ok := c.Locals(types.UserCtxName).(types.UserContext)
+	if !ok {
+		log.Error("[CancelFollowRequestHandle] Can not get current user")
+		return c.Status(http.StatusBadRequest).JSON(utils.Error("invalidCurrentUser",
+			"Can not get current user"))
+	}
+
+	receiverId, err := readRequestUserId(c)
+	if err != nil {
+		return httperr.Respond(c, err)
+	}
+	return removeFollowRequest(c, currentUser.UserID, receiverId)
+}
+
+// removeFollowRequest remove the request of the left user to follow the right user and retract its notification
+func removeFollowRequest(c *fiber.Ctx, leftId uuid.UUID, rightId uuid.UUID) error {
+
+	// Create service
+	followRequestService, serviceErr := service.NewFollowRequestService(database.Db)
+	if serviceErr != nil {
+		log.Error("NewFollowRequestService %s", serviceErr.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/followRequestService", "Error happened while creating followRequestService!"))
+	}
+
+	outboxService, serviceErr := service.NewOutboxService(database.Db)
+	if serviceErr != nil {
+		log.Error("NewOutboxService %s", serviceErr.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/outboxService", "Error happened while creating outboxService!"))
+	}
+
+	request, err := followRequestService.FindFollowRequest(leftId, rightId)
+	if err != nil {
+		log.Error("[removeFollowRequest.followRequestService.FindFollowRequest] %s", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/removeFollowRequest", "Error happened while removing follow request!"))
+	}
+	if request == nil {
+		return c.Status(http.StatusNotFound).JSON(utils.Error("followRequestNotFound", "Follow request not found!"))
+	}
+
+	removed, err := followRequestService.DeleteFollowRequest(leftId, rightId)
+	if err != nil {
+		log.Error("[removeFollowRequest.followRequestService.DeleteFollowRequest] %s", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/removeFollowRequest", "Error happened while removing follow request!"))
+	}
+
+	// The request is resolved by another request in the meantime
+	if !removed {
+		return c.Status(http.StatusNotFound).JSON(utils.Error("followRequestNotFound", "Follow request not found!"))
+	}
+
+	// The request is already removed, so the events are stored without a rollback
+	events := []outbox.Event{
+		retractNotificationEvent(request.ObjectId, getUserInfoReq(c)),
+	}
+	if err := outboxService.SaveEvents(events); err != nil {
+		log.Error("[removeFollowRequest.outboxService.SaveEvents] %s", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/removeFollowRequest", "Error happened while removing follow request!"))
+	}
+
+	go outbox.Dispatch(outboxService, events)
+
+	return c.SendStatus(http.StatusOK)
+}
@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/red-gold/telar-core/pkg/log"
+	"github.com/red-gold/telar-core/utils"
+	"github.com/red-gold/ts-serverless/micros/internal/outbox"
+	"github.com/red-gold/ts-serverless/micros/user-rels/database"
+	service "github.com/red-gold/ts-serverless/micros/user-rels/services"
+)
+
+// DispatchOutboxHandle handle delivering the outbox events which their next attempt is due
+func DispatchOutboxHandle(c *fiber.Ctx) error {
+
+	// Create service
+	outboxService, serviceErr := service.NewOutboxService(database.Db)
+	if serviceErr != nil {
+		log.Error("NewOutboxService %s", serviceErr.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/outboxService", "Error happened while creating outboxService!"))
+	}
+
+	result, err := outbox.DispatchDue(outboxService)
+	if err != nil {
+		log.Error("[DispatchOutboxHandle.outbox.DispatchDue] %s", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/findDueEvents", "Error happened while reading outbox events!"))
+	}
+
+	return c.JSON(result)
+}
+
+// GetOutboxStatusHandle handle get the pending and failed outbox events
+func GetOutboxStatusHandle(c *fiber.Ctx) error {
+
+	// Create service
+	outboxService, serviceErr := service.NewOutboxService(database.Db)
+	if serviceErr != nil {
+		log.Error("NewOutboxService %s", serviceErr.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/outboxService", "Error happened while creating outboxService!"))
+	}
+
+	status, err := outbox.ReadStatus(outboxService)
+	if err != nil {
+		log.Error("[GetOutboxStatusHandle.outbox.ReadStatus] %s", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/readOutboxStatus", "Error happened while reading outbox events!"))
+	}
+
+	return c.JSON(status)
+}
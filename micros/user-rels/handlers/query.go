@@ -10,7 +10,11 @@ import (
 	"github.com/red-gold/telar-core/pkg/parser"
 	"github.com/red-gold/telar-core/types"
 	utils "github.com/red-gold/telar-core/utils"
+	"github.com/red-gold/ts-serverless/micros/internal/cursor"
+	"github.com/red-gold/ts-serverless/micros/internal/httperr"
 	"github.com/red-gold/ts-serverless/micros/user-rels/database"
+	dto "github.com/red-gold/ts-serverless/micros/user-rels/dto"
+	models "github.com/red-gold/ts-serverless/micros/user-rels/models"
 	service "github.com/red-gold/ts-serverless/micros/user-rels/services"
 )
 
@@ -20,6 +24,13 @@ type UserRelQueryModel struct {
 	Owner  uuid.UUID `query:"owner"`
 }
 
+type FollowQueryModel struct {
+	Search string `query:"search"`
+	Limit  int64  `query:"limit"`
+	After  string `query:"after"`
+	Before string `query:"before"`
+}
+
 // QueryUserRelHandle handle query on userRel
 func QueryUserRelHandle(c *fiber.Ctx) error {
 
@@ -79,7 +90,87 @@ func GetUserRelHandle(c *fiber.Ctx) error {
 	return c.JSON(foundUserRel)
 }
 
-// GetFollowersHandle handle get auth user followers
+// readFollowPageQuery read the limit and the cursors of a page of followers or following
+func readFollowPageQuery(query *FollowQueryModel) (after *cursor.Model, before *cursor.Model, limit int64, err error) {
+	if query.Limit < 0 || query.Limit > cursor.MaxPageLimit {
+		return nil, nil, 0, httperr.New(http.StatusBadRequest, "limitIsNotValid", fmt.Sprintf("Limit should be between 1 and %d!", cursor.MaxPageLimit))
+	}
+
+	after, before, err = cursor.ReadPage(query.After, query.Before)
+	if err != nil {
+		log.Error("[readFollowPageQuery.readPageCursors] %s", err.Error())
+		return nil, nil, 0, httperr.New(http.StatusBadRequest, "cursorIsNotValid", "Cursor is not valid!")
+	}
+
+	limit = query.Limit
+	if limit == 0 {
+		limit = cursor.DefaultPageLimit
+	}
+	return after, before, limit, nil
+}
+
+// newUserRelEntries create the entries of the relations, the follow back flag is set for the relations
+// whose other user is in the follow back ids
+func newUserRelEntries(rels []dto.UserRel, followBackIds []uuid.UUID, otherUserId func(rel dto.UserRel) uuid.UUID) []models.UserRelEntryModel {
+	followBack := make(map[uuid.UUID]bool)
+	for _, userId := range followBackIds {
+		followBack[userId] = true
+	}
+
+	entries := []models.UserRelEntryModel{}
+	for _, rel := range rels {
+		entries = append(entries, models.UserRelEntryModel{
+			UserRel:         rel,
+			IsFollowingBack: followBack[otherUserId(rel)],
+		})
+	}
+	return entries
+}
+
+// followerEntries create the entries of the followers of the user, a follower is followed back when the user follows them
+func followerEntries(userRelService service.UserRelService, userId uuid.UUID, rels []dto.UserRel) ([]models.UserRelEntryModel, error) {
+	var followerIds []uuid.UUID
+	for _, rel := range rels {
+		followerIds = append(followerIds, rel.LeftId)
+	}
+	followedIds, err := userRelService.FindFollowedIds(userId, followerIds)
+	if err != nil {
+		return nil, err
+	}
+	return newUserRelEntries(rels, followedIds, func(rel dto.UserRel) uuid.UUID { return rel.LeftId }), nil
+}
+
+// followingEntries create the entries of the users whom the user follows, a user follows back when they follow the user
+func followingEntries(userRelService service.UserRelService, userId uuid.UUID, rels []dto.UserRel) ([]models.UserRelEntryModel, error) {
+	var followingIds []uuid.UUID
+	for _, rel := range rels {
+		followingIds = append(followingIds, rel.RightId)
+	}
+	followerIds, err := userRelService.FindFollowerIds(userId, followingIds)
+	if err != nil {
+		return nil, err
+	}
+	return newUserRelEntries(rels, followerIds, func(rel dto.UserRel) uuid.UUID { return rel.RightId }), nil
+}
+
+// newUserRelPage create the page of relations with the cursors of the next and previous pages
+func newUserRelPage(entries []models.UserRelEntryModel, limit int64, readBefore bool) models.UserRelPageModel {
+	page := models.UserRelPageModel{
+		Rels: entries,
+	}
+	if len(entries) == 0 {
+		return page
+	}
+
+	positions := make([]cursor.Model, 0, len(entries))
+	for _, item := range entries {
+		positions = append(positions, cursor.Model{CreatedDate: item.CreatedDate, ObjectId: item.ObjectId})
+	}
+	page.NextCursor, page.PrevCursor = cursor.PageCursors(positions, limit, readBefore)
+	return page
+}
+
+// GetFollowersHandle handle get a page of the auth user followers, the page has the default limit when no limit is in the query
 func GetFollowersHandle(c *fiber.Ctx) error {
 
 	// Create service
@@ -89,6 +180,12 @@ func GetFollowersHandle(c *fiber.Ctx) error {
 		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/userRelService", "Error happened while creating userRelService!"))
 	}
 
+	query := new(FollowQueryModel)
+	if err := parser.QueryParser(c, query); err != nil {
+		log.Error("[GetFollowersHandle] QueryParser %s", err.Error())
+		return c.Status(http.StatusBadRequest).JSON(utils.Error("queryParser", "Error happened while parsing query!"))
+	}
+
 	currentUser, ok := c.Locals(types.UserCtxName).(types.UserContext)
 	if !ok {
 		log.Error("[GetFollowersHandle] Can not get current user")
@@ -96,16 +193,27 @@ func GetFollowersHandle(c *fiber.Ctx) error {
 			"Can not get current user"))
 	}
 
-	followers, err := userRelService.GetFollowers(currentUser.UserID)
+	after, before, limit, err := readFollowPageQuery(query)
+	if err != nil {
+		return httperr.Respond(c, err)
+	}
+
+	followers, err := userRelService.GetFollowersByCursor(currentUser.UserID, query.Search, after, before, limit)
+	if err != nil {
+		log.Error("[GetFollowersHandle.userRelService.GetFollowersByCursor] %s", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/getFollowers", "Error happened while reading followers!"))
+	}
+
+	entries, err := followerEntries(userRelService, currentUser.UserID, followers)
 	if err != nil {
-		log.Error("[GetFollowersHandle.userRelService.GetFollowers] %s", err.Error())
+		log.Error("[GetFollowersHandle.followerEntries] %s", err.Error())
 		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/getFollowers", "Error happened while reading followers!"))
 	}
 
-	return c.JSON(followers)
+	return c.JSON(newUserRelPage(entries, limit, before != nil))
 }
 
-// GetFollowingHandle handle get auth user following
+// GetFollowingHandle handle get a page of the users whom the auth user follows, the page has the default limit when no limit is in the query
 func GetFollowingHandle(c *fiber.Ctx) error {
 
 	// Create service
@@ -115,6 +223,12 @@ func GetFollowingHandle(c *fiber.Ctx) error {
 		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/userRelService", "Error happened while creating userRelService!"))
 	}
 
+	query := new(FollowQueryModel)
+	if err := parser.QueryParser(c, query); err != nil {
+		log.Error("[GetFollowingHandle] QueryParser %s", err.Error())
+		return c.Status(http.StatusBadRequest).JSON(utils.Error("queryParser", "Error happened while parsing query!"))
+	}
+
 	currentUser, ok := c.Locals(types.UserCtxName).(types.UserContext)
 	if !ok {
 		log.Error("[GetFollowingHandle] Can not get current user")
@@ -122,11 +236,138 @@ func GetFollowingHandle(c *fiber.Ctx) error {
 			"Can not get current user"))
 	}
 
-	following, err := userRelService.GetFollowing(currentUser.UserID)
+	after, before, limit, err := readFollowPageQuery(query)
+	if err != nil {
+		return httperr.Respond(c, err)
+	}
+
+	following, err := userRelService.GetFollowingByCursor(currentUser.UserID, query.Search, after, before, limit)
+	if err != nil {
+		log.Error("[GetFollowingHandle.userRelService.GetFollowingByCursor] %s", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/getFollowing", "Error happened while reading following!"))
+	}
+
+	entries, err := followingEntries(userRelService, currentUser.UserID, following)
+	if err != nil {
+		log.Error("[GetFollowingHandle.followingEntries] %s", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/getFollowing", "Error happened while reading following!"))
+	}
+
+	return c.JSON(newUserRelPage(entries, limit, before != nil))
+}
+
+// GetFollowingIdsHandle handle get the ids of all the users whom the auth user follows, the functions read them to build the feed
+func GetFollowingIdsHandle(c *fiber.Ctx) error {
+
+	// Create service
+	userRelService, serviceErr := service.NewUserRelService(database.Db)
+	if serviceErr != nil {
+		log.Error("NewUserRelService %s", serviceErr.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/userRelService", "Error happened while creating userRelService!"))
+	}
+
+	currentUser, ok := c.Locals(types.UserCtxName).(types.UserContext)
+	if !ok {
+		log.Error("[GetFollowingIdsHandle] Can not get current user")
+		return c.Status(http.StatusBadRequest).JSON(utils.Error("invalidCurrentUser",
+			"Can not get current user"))
+	}
+
+	followingIds, err := userRelService.GetFollowingIds(currentUser.UserID)
 	if err != nil {
-		log.Error("[GetFollowingHandle.userRelService.GetFollowing] %s", err.Error())
+		log.Error("[GetFollowingIdsHandle.userRelService.GetFollowingIds] %s", err.Error())
 		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/getFollowing", "Error happened while reading following!"))
 	}
 
-	return c.JSON(following)
+	return c.JSON(followingIds)
+}
+
+// GetMutualFollowersHandle handle get the users who follow both the auth user and the user in the params
+func GetMutualFollowersHandle(c *fiber.Ctx) error {
+
+	// params from /user-rels/mutual/:userId
+	userId := c.Params("userId")
+	if userId == "" {
+		return c.Status(http.StatusBadRequest).JSON(utils.Error("userIdRequired", "User Id is required!"))
+	}
+
+	userUUID, uuidErr := uuid.FromString(userId)
+	if uuidErr != nil {
+		log.Error("UUID Error %s", uuidErr.Error())
+		return c.Status(http.StatusBadRequest).JSON(utils.Error("userIdIsNotValid", "User id is not valid!"))
+	}
+
+	// Create service
+	userRelService, serviceErr := service.NewUserRelService(database.Db)
+	if serviceErr != nil {
+		log.Error("NewUserRelService %s", serviceErr.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/userRelService", "Error happened while creating userRelService!"))
+	}
+
+	query := new(FollowQueryModel)
+	if err := parser.QueryParser(c, query); err != nil {
+		log.Error("[GetMutualFollowersHandle] QueryParser %s", err.Error())
+		return c.Status(http.StatusBadRequest).JSON(utils.Error("queryParser", "Error happened while parsing query!"))
+	}
+
+	currentUser, ok := c.Locals(types.UserCtxName).(types.UserContext)
+	if !ok {
+		log.Error("[GetMutualFollowersHandle] Can not get current user")
+		return c.Status(http.StatusBadRequest).JSON(utils.Error("invalidCurrentUser",
+			"Can not get current user"))
+	}
+
+	after, before, limit, err := readFollowPageQuery(query)
+	if err != nil {
+		return httperr.Respond(c, err)
+	}
+
+	followers, err := userRelService.GetMutualFollowersByCursor(currentUser.UserID, userUUID, after, before, limit)
+	if err != nil {
+		log.Error("[GetMutualFollowersHandle.userRelService.GetMutualFollowersByCursor] %s", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/getMutualFollowers", "Error happened while reading mutual followers!"))
+	}
+
+	// The mutual followers follow the auth user, so the flag tells whether the auth user follows them
+	entries, err := followerEntries(userRelService, currentUser.UserID, followers)
+	if err != nil {
+		log.Error("[GetMutualFollowersHandle.followerEntries] %s", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/getMutualFollowers", "Error happened while reading mutual followers!"))
+	}
+
+	return c.JSON(newUserRelPage(entries, limit, before != nil))
+}
+
+// GetCircleMembershipHandle handle get the circles auth user is a member of
+func GetCircleMembershipHandle(c *fiber.Ctx) error {
+
+	// Create service
+	userRelService, serviceErr := service.NewUserRelService(database.Db)
+	if serviceErr != nil {
+		log.Error("NewUserRelService %s", serviceErr.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/userRelService", "Error happened while creating userRelService!"))
+	}
+
+	currentUser, ok := c.Locals(types.UserCtxName).(types.UserContext)
+	if !ok {
+		log.Error("[GetCircleMembershipHandle] Can not get current user")
+		return c.Status(http.StatusBadRequest).JSON(utils.Error("invalidCurrentUser",
+			"Can not get current user"))
+	}
+
+	rels, err := userRelService.GetCircleMembership(currentUser.UserID)
+	if err != nil {
+		log.Error("[GetCircleMembershipHandle.userRelService.GetCircleMembership] %s", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/getCircleMembership", "Error happened while reading circle membership!"))
+	}
+
+	membership := []models.CircleMembershipModel{}
+	for _, rel := range rels {
+		membership = append(membership, models.CircleMembershipModel{
+			OwnerUserId: rel.LeftId,
+			CircleIds:   rel.CircleIds,
+		})
+	}
+
+	return c.JSON(membership)
 }
@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+	uuid "github.com/gofrs/uuid"
+	"github.com/red-gold/telar-core/pkg/log"
+	"github.com/red-gold/telar-core/utils"
+	"github.com/red-gold/ts-serverless/micros/internal/outbox"
+	"github.com/red-gold/ts-serverless/micros/internal/rpc"
+	"github.com/red-gold/ts-serverless/micros/user-rels/database"
+	models "github.com/red-gold/ts-serverless/micros/user-rels/models"
+	service "github.com/red-gold/ts-serverless/micros/user-rels/services"
+)
+
+// reconcileBatchSize the number of the users whose counts are compared with their profiles at once
+const reconcileBatchSize = 100
+
+// ReconcileCountersHandle handle recomputing the follow and follower counts of the users from the user relations.
+// The duplicated relations are removed first, then the counts which differ from the profiles are corrected
+// through the outbox. All the users who have a relation are checked when no user is given.
+func ReconcileCountersHandle(c *fiber.Ctx) error {
+
+	// Create the model object
+	model := new(models.ReconcileCountersModel)
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(model); err != nil {
+			errorMessage := fmt.Sprintf("Parse ReconcileCountersModel Error %s", err.Error())
+			log.Error(errorMessage)
+			return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/parseModel", "Error happened while parsing model!"))
+		}
+	}
+
+	// Create service
+	userRelService, serviceErr := service.NewUserRelService(database.Db)
+	if serviceErr != nil {
+		log.Error("NewUserRelService %s", serviceErr.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/userRelService", "Error happened while creating userRelService!"))
+	}
+
+	outboxService, serviceErr := service.NewOutboxService(database.Db)
+	if serviceErr != nil {
+		log.Error("NewOutboxService %s", serviceErr.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/outboxService", "Error happened while creating outboxService!"))
+	}
+
+	result := &models.ReconcileResultModel{
+		Fixed: []models.CounterDiffModel{},
+	}
+
+	removed, err := userRelService.RemoveDuplicateRels(model.UserIds)
+	if err != nil {
+		log.Error("[ReconcileCountersHandle.userRelService.RemoveDuplicateRels] %s", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/reconcileCounters", "Error happened while reconciling follow counters!"))
+	}
+	result.RemovedDuplicates = removed
+
+	userIds := model.UserIds
+	if len(userIds) == 0 {
+		userIds, err = userRelService.FindRelUserIds()
+		if err != nil {
+			log.Error("[ReconcileCountersHandle.userRelService.FindRelUserIds] %s", err.Error())
+			return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/reconcileCounters", "Error happened while reconciling follow counters!"))
+		}
+	}
+
+	userInfoReq := getUserInfoReq(c)
+	for start := 0; start < len(userIds); start += reconcileBatchSize {
+		end := start + reconcileBatchSize
+		if end > len(userIds) {
+			end = len(userIds)
+		}
+
+		diffs, err := reconcileUserCounters(rpc.Context(c), userRelService, userIds[start:end])
+		if err != nil {
+			log.Error("[ReconcileCountersHandle.reconcileUserCounters] %s", err.Error())
+			return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/reconcileCounters", "Error happened while reconciling follow counters!"))
+		}
+
+		// The profile service increments the counts, so the difference moves them to the counted totals
+		var events []outbox.Event
+		for _, diff := range diffs {
+			if diff.FollowCountAfter != diff.FollowCountBefore {
+				events = append(events, increaseUserFollowCountEvent(diff.UserId, int(diff.FollowCountAfter-diff.FollowCountBefore), userInfoReq))
+			}
+			if diff.FollowerCountAfter != diff.FollowerCountBefore {
+				events = append(events, increaseUserFollowerCountEvent(diff.UserId, int(diff.FollowerCountAfter-diff.FollowerCountBefore), userInfoReq))
+			}
+		}
+		if err := outboxService.SaveEvents(events); err != nil {
+			log.Error("[ReconcileCountersHandle.outboxService.SaveEvents] %s", err.Error())
+			return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/reconcileCounters", "Error happened while reconciling follow counters!"))
+		}
+		go outbox.Dispatch(outboxService, events)
+
+		result.Checked += end - start
+		result.Fixed = append(result.Fixed, diffs...)
+	}
+
+	log.Info("Follow counters reconciled: checked %d, fixed %d, removed duplicates %d", result.Checked, len(result.Fixed), result.RemovedDuplicates)
+	return c.JSON(result)
+}
+
+// reconcileUserCounters compare the follow and follower counts of the user profiles with the user relations
+// and return the counts which differ. The users without a profile are skipped.
+func reconcileUserCounters(ctx context.Context, userRelService service.UserRelService, userIds []uuid.UUID) ([]models.CounterDiffModel, error) {
+	followCounts, err := userRelService.CountFollowing(userIds)
+	if err != nil {
+		return nil, err
+	}
+	followerCounts, err := userRelService.CountFollowers(userIds)
+	if err != nil {
+		return nil, err
+	}
+
+	var profileIds []string
+	for _, userId := range userIds {
+		profileIds = append(profileIds, userId.String())
+	}
+	profiles, err := getProfilesByUserIds(ctx, models.GetProfilesModel{UserIds: profileIds})
+	if err != nil {
+		return nil, err
+	}
+
+	var diffs []models.CounterDiffModel
+	for _, profile := range profiles {
+		followCount := followCounts[profile.ObjectId]
+		followerCount := followerCounts[profile.ObjectId]
+		if profile.FollowCount == followCount && profile.FollowerCount == followerCount {
+			continue
+		}
+		diffs = append(diffs, models.CounterDiffModel{
+			UserId:              profile.ObjectId,
+			FollowCountBefore:   profile.FollowCount,
+			FollowCountAfter:    followCount,
+			FollowerCountBefore: profile.FollowerCount,
+			FollowerCountAfter:  followerCount,
+		})
+	}
+	return diffs, nil
+}
@@ -0,0 +1,297 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+	uuid "github.com/gofrs/uuid"
+	"github.com/red-gold/telar-core/pkg/log"
+	"github.com/red-gold/telar-core/types"
+	"github.com/red-gold/telar-core/utils"
+	"github.com/red-gold/ts-serverless/micros/internal/httperr"
+	"github.com/red-gold/ts-serverless/micros/internal/outbox"
+	"github.com/red-gold/ts-serverless/micros/user-rels/database"
+	domain "github.com/red-gold/ts-serverless/micros/user-rels/dto"
+	models "github.com/red-gold/ts-serverless/micros/user-rels/models"
+	service "github.com/red-gold/ts-serverless/micros/user-rels/services"
+)
+
+// readRestrictedUserId read the id of the user who is blocked or muted from the params.
+// A user can not restrict themselves.
+func readRestrictedUserId(c *fiber.Ctx, currentUser types.UserContext) (uuid.UUID, error) {
+
+	// params from /user-rels/block/:userId and /user-rels/mute/:userId
+	userId := c.Params("userId")
+	if userId == "" {
+		return uuid.Nil, httperr.New(http.StatusBadRequest, "userIdRequired", "User Id is required!")
+	}
+
+	userUUID, uuidErr := uuid.FromString(userId)
+	if uuidErr != nil {
+		log.Error("UUID Error %s", uuidErr.Error())
+		return uuid.Nil, httperr.New(http.StatusBadRequest, "userIdIsNotValid", "User id is not valid!")
+	}
+
+	if userUUID == currentUser.UserID {
+		return uuid.Nil, httperr.New(http.StatusBadRequest, "cannotRestrictYourself", "You can not block or mute yourself!")
+	}
+	return userUUID, nil
+}
+
+// BlockHandle handle block a user
+// The follow relations and the pending follow requests between the users are removed in both directions.
+func BlockHandle(c *fiber.Ctx) error {
+
+	currentUser, ok := c.Locals(types.UserCtxName).(types.UserContext)
+	if !ok {
+		log.Error("[BlockHandle] Can not get current user")
+		return c.Status(http.StatusBadRequest).JSON(utils.Error("invalidCurrentUser",
+			"Can not get current user"))
+	}
+
+	blockedUserId, err := readRestrictedUserId(c, currentUser)
+	if err != nil {
+		return httperr.Respond(c, err)
+	}
+
+	// Create service
+	restrictionService, serviceErr := service.NewUserRestrictionService(database.Db)
+	if serviceErr != nil {
+		log.Error("NewUserRestrictionService %s", serviceErr.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/userRestrictionService", "Error happened while creating userRestrictionService!"))
+	}
+
+	userRelService, serviceErr := service.NewUserRelService(database.Db)
+	if serviceErr != nil {
+		log.Error("NewUserRelService %s", serviceErr.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/userRelService", "Error happened while creating userRelService!"))
+	}
+
+	followRequestService, serviceErr := service.NewFollowRequestService(database.Db)
+	if serviceErr != nil {
+		log.Error("NewFollowRequestService %s", serviceErr.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/followRequestService", "Error happened while creating followRequestService!"))
+	}
+
+	outboxService, serviceErr := service.NewOutboxService(database.Db)
+	if serviceErr != nil {
+		log.Error("NewOutboxService %s", serviceErr.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/outboxService", "Error happened while creating outboxService!"))
+	}
+
+	if err := restrictionService.AddRestriction(currentUser.UserID, blockedUserId, service.RestrictionTypeBlock); err != nil {
+		log.Error("[BlockHandle.restrictionService.AddRestriction] %s", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/blockUser", "Error happened while blocking user!"))
+	}
+
+	userInfoReq := getUserInfoReq(c)
+	var events []outbox.Event
+
+	following, err := userRelService.RemoveFollow(currentUser.UserID, blockedUserId)
+	if err != nil {
+		log.Error("[BlockHandle.userRelService.RemoveFollow] %s", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/blockUser", "Error happened while blocking user!"))
+	}
+	if following {
+		events = append(events,
+			increaseUserFollowCountEvent(currentUser.UserID, -1, userInfoReq),
+			increaseUserFollowerCountEvent(blockedUserId, -1, userInfoReq),
+		)
+	}
+
+	followedBy, err := userRelService.RemoveFollow(blockedUserId, currentUser.UserID)
+	if err != nil {
+		log.Error("[BlockHandle.userRelService.RemoveFollow] %s", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/blockUser", "Error happened while blocking user!"))
+	}
+	if followedBy {
+		events = append(events,
+			increaseUserFollowCountEvent(blockedUserId, -1, userInfoReq),
+			increaseUserFollowerCountEvent(currentUser.UserID, -1, userInfoReq),
+		)
+	}
+
+	// The pending follow requests between the users are dropped
+	for _, pair := range [][2]uuid.UUID{{currentUser.UserID, blockedUserId}, {blockedUserId, currentUser.UserID}} {
+		request, err := followRequestService.FindFollowRequest(pair[0], pair[1])
+		if err != nil {
+			log.Error("[BlockHandle.followRequestService.FindFollowRequest] %s", err.Error())
+			return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/blockUser", "Error happened while blocking user!"))
+		}
+		if request == nil {
+			continue
+		}
+		if _, err := followRequestService.DeleteFollowRequest(pair[0], pair[1]); err != nil {
+			log.Error("[BlockHandle.followRequestService.DeleteFollowRequest] %s", err.Error())
+			return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/blockUser", "Error happened while blocking user!"))
+		}
+		events = append(events, retractNotificationEvent(request.ObjectId, userInfoReq))
+	}
+
+	// The relations are already removed, so the events are stored without a rollback
+	if err := outboxService.SaveEvents(events); err != nil {
+		log.Error("[BlockHandle.outboxService.SaveEvents] %s", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/blockUser", "Error happened while blocking user!"))
+	}
+
+	go outbox.Dispatch(outboxService, events)
+
+	return c.SendStatus(http.StatusOK)
+}
+
+// UnblockHandle handle unblock a user, the removed follow relations are not restored
+func UnblockHandle(c *fiber.Ctx) error {
+	return removeRestriction(c, service.RestrictionTypeBlock)
+}
+
+// MuteHandle handle mute a user, the posts of a muted user are not shown in the feed
+func MuteHandle(c *fiber.Ctx) error {
+
+	currentUser, ok := c.Locals(types.UserCtxName).(types.UserContext)
+	if !ok {
+		log.Error("[MuteHandle] Can not get current user")
+		return c.Status(http.StatusBadRequest).JSON(utils.Error("invalidCurrentUser",
+			"Can not get current user"))
+	}
+
+	mutedUserId, err := readRestrictedUserId(c, currentUser)
+	if err != nil {
+		return httperr.Respond(c, err)
+	}
+
+	// Create service
+	restrictionService, serviceErr := service.NewUserRestrictionService(database.Db)
+	if serviceErr != nil {
+		log.Error("NewUserRestrictionService %s", serviceErr.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/userRestrictionService", "Error happened while creating userRestrictionService!"))
+	}
+
+	if err := restrictionService.AddRestriction(currentUser.UserID, mutedUserId, service.RestrictionTypeMute); err != nil {
+		log.Error("[MuteHandle.restrictionService.AddRestriction] %s", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/muteUser", "Error happened while muting user!"))
+	}
+
+	return c.SendStatus(http.StatusOK)
+}
+
+// UnmuteHandle handle unmute a user
+func UnmuteHandle(c *fiber.Ctx) error {
+	return removeRestriction(c, service.RestrictionTypeMute)
+}
+
+// removeRestriction remove the restriction of the current user on the user in the params
+func removeRestriction(c *fiber.Ctx, restrictionType string) error {
+
+	currentUser, ok := c.Locals(types.UserCtxName).(types.UserContext)
+	if !ok {
+		log.Error("[removeRestriction] Can not get current user")
+		return c.Status(http.StatusBadRequest).JSON(utils.Error("invalidCurrentUser",
+			"Can not get current user"))
+	}
+
+	restrictedUserId, err := readRestrictedUserId(c, currentUser)
+	if err != nil {
+		return httperr.Respond(c, err)
+	}
+
+	// Create service
+	restrictionService, serviceErr := service.NewUserRestrictionService(database.Db)
+	if serviceErr != nil {
+		log.Error("NewUserRestrictionService %s", serviceErr.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/userRestrictionService", "Error happened while creating userRestrictionService!"))
+	}
+
+	if err := restrictionService.RemoveRestriction(currentUser.UserID, restrictedUserId, restrictionType); err != nil {
+		log.Error("[removeRestriction.restrictionService.RemoveRestriction] %s", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/removeRestriction", "Error happened while removing user restriction!"))
+	}
+
+	return c.SendStatus(http.StatusOK)
+}
+
+// GetBlockedHandle handle get the users which the current user blocked
+func GetBlockedHandle(c *fiber.Ctx) error {
+	return getRestrictions(c, service.RestrictionTypeBlock)
+}
+
+// GetMutedHandle handle get the users which the current user muted
+func GetMutedHandle(c *fiber.Ctx) error {
+	return getRestrictions(c, service.RestrictionTypeMute)
+}
+
+// getRestrictions get the restrictions of a type which the current user put on other users
+func getRestrictions(c *fiber.Ctx, restrictionType string) error {
+
+	// Create service
+	restrictionService, serviceErr := service.NewUserRestrictionService(database.Db)
+	if serviceErr != nil {
+		log.Error("NewUserRestrictionService %s", serviceErr.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/userRestrictionService", "Error happened while creating userRestrictionService!"))
+	}
+
+	currentUser, ok := c.Locals(types.UserCtxName).(types.UserContext)
+	if !ok {
+		log.Error("[getRestrictions] Can not get current user")
+		return c.Status(http.StatusBadRequest).JSON(utils.Error("invalidCurrentUser",
+			"Can not get current user"))
+	}
+
+	restrictions, err := restrictionService.GetRestrictionsByType(currentUser.UserID, restrictionType)
+	if err != nil {
+		log.Error("[getRestrictions.restrictionService.GetRestrictionsByType] %s", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/getRestrictions", "Error happened while reading user restrictions!"))
+	}
+	if restrictions == nil {
+		restrictions = []domain.UserRestriction{}
+	}
+
+	return c.JSON(restrictions)
+}
+
+// GetUserRestrictionsHandle handle get the users which are hidden from the current user for other functions
+func GetUserRestrictionsHandle(c *fiber.Ctx) error {
+
+	// Create service
+	restrictionService, serviceErr := service.NewUserRestrictionService(database.Db)
+	if serviceErr != nil {
+		log.Error("NewUserRestrictionService %s", serviceErr.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/userRestrictionService", "Error happened while creating userRestrictionService!"))
+	}
+
+	currentUser, ok := c.Locals(types.UserCtxName).(types.UserContext)
+	if !ok {
+		log.Error("[GetUserRestrictionsHandle] Can not get current user")
+		return c.Status(http.StatusBadRequest).JSON(utils.Error("invalidCurrentUser",
+			"Can not get current user"))
+	}
+
+	restrictions, err := restrictionService.GetUserRestrictions(currentUser.UserID)
+	if err != nil {
+		log.Error("[GetUserRestrictionsHandle.restrictionService.GetUserRestrictions] %s", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/getRestrictions", "Error happened while reading user restrictions!"))
+	}
+
+	return c.JSON(newUserRestrictions(currentUser.UserID, restrictions))
+}
+
+// newUserRestrictions create the users which the user muted and the users which the user blocked or is blocked by
+func newUserRestrictions(userId uuid.UUID, restrictions []domain.UserRestriction) models.UserRestrictionsModel {
+	userRestrictions := models.UserRestrictionsModel{
+		BlockedUserIds: []uuid.UUID{},
+		MutedUserIds:   []uuid.UUID{},
+	}
+	blocked := make(map[uuid.UUID]bool)
+	for _, restriction := range restrictions {
+		switch {
+		case restriction.Type == service.RestrictionTypeMute:
+			userRestrictions.MutedUserIds = append(userRestrictions.MutedUserIds, restriction.RightId)
+		case restriction.LeftId == userId && !blocked[restriction.RightId]:
+			blocked[restriction.RightId] = true
+			userRestrictions.BlockedUserIds = append(userRestrictions.BlockedUserIds, restriction.RightId)
+		case restriction.RightId == userId && !blocked[restriction.LeftId]:
+			blocked[restriction.LeftId] = true
+			userRestrictions.BlockedUserIds = append(userRestrictions.BlockedUserIds, restriction.LeftId)
+		}
+	}
+	return userRestrictions
+}
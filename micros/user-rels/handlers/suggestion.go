@@ -0,0 +1,436 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/gofiber/fiber/v2"
+	uuid "github.com/gofrs/uuid"
+	"github.com/red-gold/telar-core/pkg/log"
+	"github.com/red-gold/telar-core/pkg/parser"
+	"github.com/red-gold/telar-core/types"
+	"github.com/red-gold/telar-core/utils"
+	"github.com/red-gold/ts-serverless/micros/internal/cursor"
+	"github.com/red-gold/ts-serverless/micros/internal/httperr"
+	"github.com/red-gold/ts-serverless/micros/internal/rpc"
+	userRelConfig "github.com/red-gold/ts-serverless/micros/user-rels/config"
+	"github.com/red-gold/ts-serverless/micros/user-rels/database"
+	domain "github.com/red-gold/ts-serverless/micros/user-rels/dto"
+	models "github.com/red-gold/ts-serverless/micros/user-rels/models"
+	service "github.com/red-gold/ts-serverless/micros/user-rels/services"
+)
+
+const (
+	// suggestionCacheSize the number of the suggestions which are cached for a user
+	suggestionCacheSize = 50
+	// suggestionCandidateLimit the number of the candidates which are read from each relation signal
+	suggestionCandidateLimit int64 = 200
+	// suggestionRefreshBatch the number of the users whose suggestions are built again in one refresh
+	suggestionRefreshBatch int64 = 100
+)
+
+// The weights of the signals in the score of a suggestion
+const (
+	mutualWeight       int64 = 3
+	sharedCircleWeight int64 = 2
+	interactionWeight  int64 = 1
+)
+
+type SuggestionQueryModel struct {
+	Limit int64 `query:"limit"`
+}
+
+// suggestionServices the services which the follow suggestions are built from
+type suggestionServices struct {
+	userRel       service.UserRelService
+	restriction   service.UserRestrictionService
+	followRequest service.FollowRequestService
+	suggestion    service.SuggestionService
+}
+
+// newSuggestionServices create the services of the follow suggestions
+func newSuggestionServices() (*suggestionServices, error) {
+	userRelService, serviceErr := service.NewUserRelService(database.Db)
+	if serviceErr != nil {
+		log.Error("NewUserRelService %s", serviceErr.Error())
+		return nil, httperr.New(http.StatusInternalServerError, "internal/userRelService", "Error happened while creating userRelService!")
+	}
+
+	restrictionService, serviceErr := service.NewUserRestrictionService(database.Db)
+	if serviceErr != nil {
+		log.Error("NewUserRestrictionService %s", serviceErr.Error())
+		return nil, httperr.New(http.StatusInternalServerError, "internal/userRestrictionService", "Error happened while creating userRestrictionService!")
+	}
+
+	followRequestService, serviceErr := service.NewFollowRequestService(database.Db)
+	if serviceErr != nil {
+		log.Error("NewFollowRequestService %s", serviceErr.Error())
+		return nil, httperr.New(http.StatusInternalServerError, "internal/followRequestService", "Error happened while creating followRequestService!")
+	}
+
+	suggestionService, serviceErr := service.NewSuggestionService(database.Db)
+	if serviceErr != nil {
+		log.Error("NewSuggestionService %s", serviceErr.Error())
+		return nil, httperr.New(http.StatusInternalServerError, "internal/suggestionService", "Error happened while creating suggestionService!")
+	}
+
+	return &suggestionServices{
+		userRel:       userRelService,
+		restriction:   restrictionService,
+		followRequest: followRequestService,
+		suggestion:    suggestionService,
+	}, nil
+}
+
+// suggestionRefreshCutoff the date which the suggestions cached before it are built again
+func suggestionRefreshCutoff() int64 {
+	return utils.UTCNowUnix() - userRelConfig.UserRelConfig.SuggestionRefreshHours*60*60*1000
+}
+
+// hiddenSuggestionIds get the users who are not suggested to the user in the list: the users whom
+// the user follows or asked to follow, the users who block the user or are blocked by the user
+func hiddenSuggestionIds(services *suggestionServices, userId uuid.UUID, userIds []uuid.UUID) (map[uuid.UUID]bool, error) {
+	hidden := make(map[uuid.UUID]bool)
+
+	followedIds, err := services.userRel.FindFollowedIds(userId, userIds)
+	if err != nil {
+		return nil, err
+	}
+	for _, followedId := range followedIds {
+		hidden[followedId] = true
+	}
+
+	restrictions, err := services.restriction.GetUserRestrictions(userId)
+	if err != nil {
+		return nil, err
+	}
+	for _, blockedId := range newUserRestrictions(userId, restrictions).BlockedUserIds {
+		hidden[blockedId] = true
+	}
+
+	requests, err := services.followRequest.GetOutgoingRequests(userId)
+	if err != nil {
+		return nil, err
+	}
+	for _, request := range requests {
+		hidden[request.RightId] = true
+	}
+	return hidden, nil
+}
+
+// buildSuggestions rank the users whom the user may know by the users they follow in common with the
+// followed users, the circles they share and the comments and votes between them on their posts.
+// The suggestions are not complete if the interactions or the profiles are not available.
+func buildSuggestions(ctx context.Context, services *suggestionServices, userId uuid.UUID) ([]domain.SuggestedUser, bool, error) {
+	followingIds, err := services.userRel.GetFollowingIds(userId)
+	if err != nil {
+		return nil, false, err
+	}
+
+	dismissedIds, err := services.suggestion.GetDismissedIds(userId)
+	if err != nil {
+		return nil, false, err
+	}
+
+	hidden, err := hiddenSuggestionIds(services, userId, followingIds)
+	if err != nil {
+		return nil, false, err
+	}
+	hidden[userId] = true
+	for _, followingId := range followingIds {
+		hidden[followingId] = true
+	}
+	for _, dismissedId := range dismissedIds {
+		hidden[dismissedId] = true
+	}
+	excludeIds := make([]uuid.UUID, 0, len(hidden))
+	for hiddenId := range hidden {
+		excludeIds = append(excludeIds, hiddenId)
+	}
+
+	// The circles of other users which the user is a member of
+	memberships, err := services.userRel.GetCircleMembership(userId)
+	if err != nil {
+		return nil, false, err
+	}
+	var circleIds []string
+	for _, membership := range memberships {
+		circleIds = append(circleIds, membership.CircleIds...)
+	}
+
+	mutualCounts, err := services.suggestion.CountFriendsOfFriends(followingIds, excludeIds, suggestionCandidateLimit)
+	if err != nil {
+		return nil, false, err
+	}
+
+	sharedCircleCounts, err := services.suggestion.CountSharedCircles(circleIds, excludeIds, suggestionCandidateLimit)
+	if err != nil {
+		return nil, false, err
+	}
+
+	// The suggestions are built without the interactions which are not available
+	complete := true
+	since := utils.UTCNowUnix() - userRelConfig.UserRelConfig.SuggestionInteractionDays*24*60*60*1000
+	var interactionCounts []models.UserCountModel
+	for _, function := range []string{"comments", "votes"} {
+		counts, err := getInteractions(ctx, function, userId, since)
+		if err != nil {
+			log.Error("[buildSuggestions] %s", err.Error())
+			complete = false
+			continue
+		}
+		interactionCounts = append(interactionCounts, counts...)
+	}
+
+	candidates := make(map[uuid.UUID]*domain.SuggestedUser)
+	candidate := func(candidateId uuid.UUID) *domain.SuggestedUser {
+		if _, ok := candidates[candidateId]; !ok {
+			candidates[candidateId] = &domain.SuggestedUser{UserId: candidateId}
+		}
+		return candidates[candidateId]
+	}
+	for _, count := range mutualCounts {
+		candidate(count.UserId).MutualCount += count.Count
+	}
+	for _, count := range sharedCircleCounts {
+		candidate(count.UserId).SharedCircleCount += count.Count
+	}
+	for _, count := range interactionCounts {
+		if hidden[count.UserId] || count.UserId == uuid.Nil {
+			continue
+		}
+		candidate(count.UserId).InteractionCount += count.Count
+	}
+
+	suggestions := []domain.SuggestedUser{}
+	for _, suggestedUser := range candidates {
+		suggestedUser.Score = suggestedUser.MutualCount*mutualWeight +
+			suggestedUser.SharedCircleCount*sharedCircleWeight +
+			suggestedUser.InteractionCount*interactionWeight
+		suggestions = append(suggestions, *suggestedUser)
+	}
+	sort.Slice(suggestions, func(i, j int) bool {
+		if suggestions[i].Score != suggestions[j].Score {
+			return suggestions[i].Score > suggestions[j].Score
+		}
+		if suggestions[i].MutualCount != suggestions[j].MutualCount {
+			return suggestions[i].MutualCount > suggestions[j].MutualCount
+		}
+		return suggestions[i].UserId.String() < suggestions[j].UserId.String()
+	})
+	if len(suggestions) > suggestionCacheSize {
+		suggestions = suggestions[:suggestionCacheSize]
+	}
+
+	suggestions, withProfiles := withSuggestionProfiles(ctx, suggestions)
+	return suggestions, complete && withProfiles, nil
+}
+
+// withSuggestionProfiles add the name and the avatar of the suggested users, the users without a profile are removed.
+// The suggestions are kept without the profiles if the profiles are not available.
+func withSuggestionProfiles(ctx context.Context, suggestions []domain.SuggestedUser) ([]domain.SuggestedUser, bool) {
+	if len(suggestions) == 0 {
+		return suggestions, true
+	}
+
+	var userIds []string
+	for _, suggestedUser := range suggestions {
+		userIds = append(userIds, suggestedUser.UserId.String())
+	}
+	profiles, err := getProfilesByUserIds(ctx, models.GetProfilesModel{UserIds: userIds})
+	if err != nil {
+		log.Error("[withSuggestionProfiles] %s", err.Error())
+		return suggestions, false
+	}
+
+	profileMap := make(map[uuid.UUID]models.UserProfileModel)
+	for _, profile := range profiles {
+		profileMap[profile.ObjectId] = profile
+	}
+	withProfiles := []domain.SuggestedUser{}
+	for _, suggestedUser := range suggestions {
+		profile, ok := profileMap[suggestedUser.UserId]
+		if !ok {
+			continue
+		}
+		suggestedUser.FullName = profile.FullName
+		suggestedUser.SocialName = profile.SocialName
+		suggestedUser.Avatar = profile.Avatar
+		withProfiles = append(withProfiles, suggestedUser)
+	}
+	return withProfiles, true
+}
+
+// refreshSuggestions build the suggestions of the user and cache them.
+// The suggestions which are not complete are returned without being cached, so they are built again on the next read.
+func refreshSuggestions(ctx context.Context, services *suggestionServices, userId uuid.UUID) ([]domain.SuggestedUser, bool, error) {
+	suggestions, complete, err := buildSuggestions(ctx, services, userId)
+	if err != nil {
+		return nil, false, err
+	}
+	if !complete {
+		return suggestions, false, nil
+	}
+	if err := services.suggestion.SaveUserSuggestion(userId, suggestions, utils.UTCNowUnix()); err != nil {
+		return nil, false, err
+	}
+	return suggestions, true, nil
+}
+
+// GetSuggestionsHandle handle get the users whom the current user may know.
+// The cached suggestions are used until they are older than the refresh hours.
+func GetSuggestionsHandle(c *fiber.Ctx) error {
+
+	query := new(SuggestionQueryModel)
+	if err := parser.QueryParser(c, query); err != nil {
+		log.Error("[GetSuggestionsHandle] QueryParser %s", err.Error())
+		return c.Status(http.StatusBadRequest).JSON(utils.Error("queryParser", "Error happened while parsing query!"))
+	}
+
+	if query.Limit < 0 || query.Limit > cursor.MaxPageLimit {
+		return c.Status(http.StatusBadRequest).JSON(utils.Error("limitIsNotValid", fmt.Sprintf("Limit should be between 1 and %d!", cursor.MaxPageLimit)))
+	}
+	limit := query.Limit
+	if limit == 0 {
+		limit = cursor.DefaultPageLimit
+	}
+
+	services, err := newSuggestionServices()
+	if err != nil {
+		return httperr.Respond(c, err)
+	}
+
+	currentUser, ok := c.Locals(types.UserCtxName).(types.UserContext)
+	if !ok {
+		log.Error("[GetSuggestionsHandle] Can not get current user")
+		return c.Status(http.StatusBadRequest).JSON(utils.Error("invalidCurrentUser",
+			"Can not get current user"))
+	}
+
+	cached, err := services.suggestion.FindUserSuggestion(currentUser.UserID)
+	if err != nil {
+		log.Error("[GetSuggestionsHandle.suggestionService.FindUserSuggestion] %s", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/getSuggestions", "Error happened while reading suggestions!"))
+	}
+
+	var suggestions []domain.SuggestedUser
+	if cached == nil || cached.CreatedDate < suggestionRefreshCutoff() {
+		suggestions, _, err = refreshSuggestions(rpc.Context(c), services, currentUser.UserID)
+		if err != nil {
+			log.Error("[GetSuggestionsHandle.refreshSuggestions] %s", err.Error())
+			return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/getSuggestions", "Error happened while reading suggestions!"))
+		}
+	} else {
+		// The users who are followed, requested or blocked after the suggestions are cached are not suggested
+		var suggestedIds []uuid.UUID
+		for _, suggestedUser := range cached.Suggestions {
+			suggestedIds = append(suggestedIds, suggestedUser.UserId)
+		}
+		hidden, err := hiddenSuggestionIds(services, currentUser.UserID, suggestedIds)
+		if err != nil {
+			log.Error("[GetSuggestionsHandle.hiddenSuggestionIds] %s", err.Error())
+			return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/getSuggestions", "Error happened while reading suggestions!"))
+		}
+		for _, suggestedUser := range cached.Suggestions {
+			if !hidden[suggestedUser.UserId] {
+				suggestions = append(suggestions, suggestedUser)
+			}
+		}
+	}
+
+	if suggestions == nil {
+		suggestions = []domain.SuggestedUser{}
+	}
+	if int64(len(suggestions)) > limit {
+		suggestions = suggestions[:limit]
+	}
+
+	return c.JSON(suggestions)
+}
+
+// DismissSuggestionHandle handle stop suggesting a user to the current user
+func DismissSuggestionHandle(c *fiber.Ctx) error {
+
+	// params from /user-rels/suggestions/:userId
+	userId := c.Params("userId")
+	if userId == "" {
+		return c.Status(http.StatusBadRequest).JSON(utils.Error("userIdRequired", "User Id is required!"))
+	}
+
+	userUUID, uuidErr := uuid.FromString(userId)
+	if uuidErr != nil {
+		log.Error("UUID Error %s", uuidErr.Error())
+		return c.Status(http.StatusBadRequest).JSON(utils.Error("userIdIsNotValid", "User id is not valid!"))
+	}
+
+	// Create service
+	suggestionService, serviceErr := service.NewSuggestionService(database.Db)
+	if serviceErr != nil {
+		log.Error("NewSuggestionService %s", serviceErr.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/suggestionService", "Error happened while creating suggestionService!"))
+	}
+
+	currentUser, ok := c.Locals(types.UserCtxName).(types.UserContext)
+	if !ok {
+		log.Error("[DismissSuggestionHandle] Can not get current user")
+		return c.Status(http.StatusBadRequest).JSON(utils.Error("invalidCurrentUser",
+			"Can not get current user"))
+	}
+
+	if err := suggestionService.AddDismissal(currentUser.UserID, userUUID); err != nil {
+		log.Error("[DismissSuggestionHandle.suggestionService.AddDismissal] %s", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/dismissSuggestion", "Error happened while dismissing suggestion!"))
+	}
+
+	if err := suggestionService.RemoveSuggestedUser(currentUser.UserID, userUUID); err != nil {
+		log.Error("[DismissSuggestionHandle.suggestionService.RemoveSuggestedUser] %s", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/dismissSuggestion", "Error happened while dismissing suggestion!"))
+	}
+
+	return c.SendStatus(http.StatusOK)
+}
+
+// RefreshSuggestionsHandle handle build again a batch of the cached suggestions which are older than the refresh hours
+func RefreshSuggestionsHandle(c *fiber.Ctx) error {
+
+	refreshed, err := RefreshSuggestions(rpc.Context(c))
+	if err != nil {
+		log.Error("[RefreshSuggestionsHandle] %s", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/refreshSuggestions", "Error happened while refreshing suggestions!"))
+	}
+
+	return c.JSON(fiber.Map{
+		"refreshed": refreshed,
+	})
+}
+
+// RefreshSuggestions build again a batch of the cached suggestions which are older than the refresh hours and return
+// the number of the users whose suggestions are refreshed. The users whose suggestions can not be built completely
+// keep their cached suggestions until the next refresh.
+func RefreshSuggestions(ctx context.Context) (int, error) {
+
+	services, err := newSuggestionServices()
+	if err != nil {
+		return 0, fmt.Errorf("RefreshSuggestions/services %s", err.Error())
+	}
+
+	ownerIds, err := services.suggestion.FindStaleSuggestionOwners(suggestionRefreshCutoff(), suggestionRefreshBatch)
+	if err != nil {
+		return 0, fmt.Errorf("RefreshSuggestions/findOwners %s", err.Error())
+	}
+
+	refreshed := 0
+	for _, ownerId := range ownerIds {
+		_, complete, err := refreshSuggestions(ctx, services, ownerId)
+		if err != nil {
+			log.Error("[RefreshSuggestions.refreshSuggestions] %s: %s", ownerId, err.Error())
+			continue
+		}
+		if complete {
+			refreshed++
+		}
+	}
+	return refreshed, nil
+}
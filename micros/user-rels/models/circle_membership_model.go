@@ -0,0 +1,8 @@
+package models
+
+import uuid "github.com/gofrs/uuid"
+
+type CircleMembershipModel struct {
+	OwnerUserId uuid.UUID `json:"ownerUserId"`
+	CircleIds   []string  `json:"circleIds"`
+}
@@ -0,0 +1,17 @@
+package models
+
+import uuid "github.com/gofrs/uuid"
+
+type CounterDiffModel struct {
+	UserId              uuid.UUID `json:"userId"`
+	FollowCountBefore   int64     `json:"followCountBefore"`
+	FollowCountAfter    int64     `json:"followCountAfter"`
+	FollowerCountBefore int64     `json:"followerCountBefore"`
+	FollowerCountAfter  int64     `json:"followerCountAfter"`
+}
+
+type ReconcileResultModel struct {
+	Checked           int                `json:"checked"`
+	RemovedDuplicates int64              `json:"removedDuplicates"`
+	Fixed             []CounterDiffModel `json:"fixed"`
+}
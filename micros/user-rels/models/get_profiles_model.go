@@ -0,0 +1,5 @@
+package models
+
+type GetProfilesModel struct {
+	UserIds []string `json:"userIds" bson:"userIds"`
+}
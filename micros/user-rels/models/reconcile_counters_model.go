@@ -0,0 +1,7 @@
+package models
+
+import uuid "github.com/gofrs/uuid"
+
+type ReconcileCountersModel struct {
+	UserIds []uuid.UUID `json:"userIds"`
+}
@@ -0,0 +1,9 @@
+package models
+
+import uuid "github.com/gofrs/uuid"
+
+// UserCountModel the number of the relations or interactions which connect the user to another user
+type UserCountModel struct {
+	UserId uuid.UUID `json:"userId" bson:"_id"`
+	Count  int64     `json:"count" bson:"count"`
+}
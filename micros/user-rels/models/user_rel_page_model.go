@@ -0,0 +1,15 @@
+package models
+
+import dto "github.com/red-gold/ts-serverless/micros/user-rels/dto"
+
+// UserRelEntryModel a relation in a list of followers or following with the follow back state of the other user
+type UserRelEntryModel struct {
+	dto.UserRel
+	IsFollowingBack bool `json:"isFollowingBack"`
+}
+
+type UserRelPageModel struct {
+	Rels       []UserRelEntryModel `json:"rels"`
+	NextCursor string              `json:"nextCursor"`
+	PrevCursor string              `json:"prevCursor"`
+}
@@ -11,7 +11,10 @@ import (
 	"github.com/red-gold/telar-core/middleware/authcookie"
 	"github.com/red-gold/telar-core/middleware/authhmac"
 	"github.com/red-gold/telar-core/types"
+	"github.com/red-gold/ts-serverless/micros/internal/index"
+	"github.com/red-gold/ts-serverless/micros/user-rels/database"
 	"github.com/red-gold/ts-serverless/micros/user-rels/handlers"
+	service "github.com/red-gold/ts-serverless/micros/user-rels/services"
 )
 
 // SetupRoutes func
@@ -53,10 +56,38 @@ func SetupRoutes(app *fiber.App) {
 	hmacCookieHandlers := []func(*fiber.Ctx) error{authHMACMiddleware(true), authCookieMiddleware(true)}
 
 	// Routers
+	app.Post("/index", authHMACMiddleware(false), index.InitHandle(newIndexService))
+	app.Get("/index", authHMACMiddleware(false), index.MissingHandle(newIndexService))
 	app.Post("/follow", append(hmacCookieHandlers, handlers.FollowHandle)...)
 	app.Delete("/unfollow/:userId", append(hmacCookieHandlers, handlers.UnfollowHandle)...)
 	app.Delete("/circle/:circleId", append(hmacCookieHandlers, handlers.DeleteCircle)...)
 	app.Put("/circles", append(hmacCookieHandlers, handlers.UpdateRelCirclesHandle)...)
 	app.Get("/followers", append(hmacCookieHandlers, handlers.GetFollowersHandle)...)
 	app.Get("/following", append(hmacCookieHandlers, handlers.GetFollowingHandle)...)
+	app.Get("/following/ids", authHMACMiddleware(false), handlers.GetFollowingIdsHandle)
+	app.Get("/mutual/:userId", append(hmacCookieHandlers, handlers.GetMutualFollowersHandle)...)
+	app.Get("/circles/membership", authHMACMiddleware(false), handlers.GetCircleMembershipHandle)
+	app.Post("/block/:userId", append(hmacCookieHandlers, handlers.BlockHandle)...)
+	app.Delete("/block/:userId", append(hmacCookieHandlers, handlers.UnblockHandle)...)
+	app.Post("/mute/:userId", append(hmacCookieHandlers, handlers.MuteHandle)...)
+	app.Delete("/mute/:userId", append(hmacCookieHandlers, handlers.UnmuteHandle)...)
+	app.Get("/blocked", append(hmacCookieHandlers, handlers.GetBlockedHandle)...)
+	app.Get("/muted", append(hmacCookieHandlers, handlers.GetMutedHandle)...)
+	app.Get("/restrictions", authHMACMiddleware(false), handlers.GetUserRestrictionsHandle)
+	app.Get("/requests/incoming", append(hmacCookieHandlers, handlers.GetIncomingRequestsHandle)...)
+	app.Get("/requests/outgoing", append(hmacCookieHandlers, handlers.GetOutgoingRequestsHandle)...)
+	app.Put("/requests/incoming/:userId", append(hmacCookieHandlers, handlers.AcceptFollowRequestHandle)...)
+	app.Delete("/requests/incoming/:userId", append(hmacCookieHandlers, handlers.DeclineFollowRequestHandle)...)
+	app.Delete("/requests/outgoing/:userId", append(hmacCookieHandlers, handlers.CancelFollowRequestHandle)...)
+	app.Get("/suggestions", append(hmacCookieHandlers, handlers.GetSuggestionsHandle)...)
+	app.Post("/suggestions/refresh", authHMACMiddleware(false), handlers.RefreshSuggestionsHandle)
+	app.Delete("/suggestions/:userId", append(hmacCookieHandlers, handlers.DismissSuggestionHandle)...)
+	app.Post("/counters/reconcile", authHMACMiddleware(false), handlers.ReconcileCountersHandle)
+	app.Post("/outbox/dispatch", authHMACMiddleware(false), handlers.DispatchOutboxHandle)
+	app.Get("/outbox/status", authHMACMiddleware(false), handlers.GetOutboxStatusHandle)
+}
+
+// newIndexService create the index service of the function
+func newIndexService() (index.Service, error) {
+	return service.NewIndexService(database.Db)
 }
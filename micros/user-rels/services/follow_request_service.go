@@ -0,0 +1,178 @@
+package service
+
+import (
+	"fmt"
+
+	uuid "github.com/gofrs/uuid"
+	"github.com/red-gold/telar-core/config"
+	repo "github.com/red-gold/telar-core/data"
+	"github.com/red-gold/telar-core/data/mongodb"
+	mongoRepo "github.com/red-gold/telar-core/data/mongodb"
+	"github.com/red-gold/telar-core/utils"
+	"github.com/red-gold/ts-serverless/micros/internal/inmemory"
+	dto "github.com/red-gold/ts-serverless/micros/user-rels/dto"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// FollowRequestService handlers with injected dependencies
+type FollowRequestServiceImpl struct {
+	FollowRequestRepo repo.Repository
+}
+
+// NewFollowRequestService initializes FollowRequestService's dependencies and create new FollowRequestService struct
+func NewFollowRequestService(db interface{}) (FollowRequestService, error) {
+
+	followRequestService := &FollowRequestServiceImpl{}
+
+	switch *config.AppConfig.DBType {
+	case config.DB_MONGO:
+
+		mongodb := db.(mongodb.MongoDatabase)
+		followRequestService.FollowRequestRepo = mongoRepo.NewDataRepositoryMongo(mongodb)
+
+	case config.DB_INMEMORY:
+
+		followRequestService.FollowRequestRepo = inmemory.NewDataRepositoryInMemory(db.(*inmemory.Database))
+
+	}
+
+	return followRequestService, nil
+}
+
+// SaveFollowRequest save the follow request
+func (s FollowRequestServiceImpl) SaveFollowRequest(request *dto.FollowRequest) error {
+
+	if request.ObjectId == uuid.Nil {
+		var uuidErr error
+		request.ObjectId, uuidErr = uuid.NewV4()
+		if uuidErr != nil {
+			return uuidErr
+		}
+	}
+
+	if request.CreatedDate == 0 {
+		request.CreatedDate = utils.UTCNowUnix()
+	}
+
+	result := <-s.FollowRequestRepo.Save(followRequestCollectionName, request)
+
+	return result.Error
+}
+
+// UpsertFollowRequest save the follow request if the left user has no pending request to the right user.
+// It returns the stored request and whether the request is new.
+func (s FollowRequestServiceImpl) UpsertFollowRequest(request *dto.FollowRequest) (*dto.FollowRequest, bool, error) {
+	err := s.SaveFollowRequest(request)
+	if err == nil {
+		return request, true, nil
+	}
+
+	// The unique index on left and right user rejects the second request
+	if !mongo.IsDuplicateKeyError(err) {
+		return nil, false, err
+	}
+	currentRequest, err := s.FindFollowRequest(request.LeftId, request.RightId)
+	if err != nil {
+		return nil, false, err
+	}
+	if currentRequest == nil {
+		return nil, false, fmt.Errorf("follow request of user %s to user %s is not found", request.LeftId, request.RightId)
+	}
+	return currentRequest, false, nil
+}
+
+// FindOneFollowRequest get one follow request
+func (s FollowRequestServiceImpl) FindOneFollowRequest(filter interface{}) (*dto.FollowRequest, error) {
+
+	result := <-s.FollowRequestRepo.FindOne(followRequestCollectionName, filter)
+	if result.Error() != nil {
+		if result.Error() == repo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, result.Error()
+	}
+	if result.NoResult() {
+		return nil, nil
+	}
+
+	var requestResult dto.FollowRequest
+	errDecode := result.Decode(&requestResult)
+	if errDecode != nil {
+		return nil, fmt.Errorf("Error docoding on dto.FollowRequest")
+	}
+	return &requestResult, nil
+}
+
+// FindFollowRequestList get all follow requests by filter
+func (s FollowRequestServiceImpl) FindFollowRequestList(filter interface{}, limit int64, skip int64, sort map[string]int) ([]dto.FollowRequest, error) {
+
+	result := <-s.FollowRequestRepo.Find(followRequestCollectionName, filter, limit, skip, sort)
+	defer result.Close()
+	if result.Error() != nil {
+		return nil, result.Error()
+	}
+	var requestList []dto.FollowRequest
+	for result.Next() {
+		var request dto.FollowRequest
+		errDecode := result.Decode(&request)
+		if errDecode != nil {
+			return nil, fmt.Errorf("Error docoding on dto.FollowRequest")
+		}
+		requestList = append(requestList, request)
+	}
+
+	return requestList, nil
+}
+
+// FindFollowRequest find the request of the left user to follow the right user
+func (s FollowRequestServiceImpl) FindFollowRequest(leftId uuid.UUID, rightId uuid.UUID) (*dto.FollowRequest, error) {
+	filter := struct {
+		LeftId  uuid.UUID `json:"leftId" bson:"leftId"`
+		RightId uuid.UUID `json:"rightId" bson:"rightId"`
+	}{
+		LeftId:  leftId,
+		RightId: rightId,
+	}
+	return s.FindOneFollowRequest(filter)
+}
+
+// GetIncomingRequests get the pending requests of other users to follow the user
+func (s FollowRequestServiceImpl) GetIncomingRequests(rightId uuid.UUID) ([]dto.FollowRequest, error) {
+	sortMap := make(map[string]int)
+	sortMap["created_date"] = -1
+	filter := struct {
+		RightId uuid.UUID `json:"rightId" bson:"rightId"`
+	}{
+		RightId: rightId,
+	}
+	return s.FindFollowRequestList(filter, 0, 0, sortMap)
+}
+
+// GetOutgoingRequests get the pending requests of the user to follow other users
+func (s FollowRequestServiceImpl) GetOutgoingRequests(leftId uuid.UUID) ([]dto.FollowRequest, error) {
+	sortMap := make(map[string]int)
+	sortMap["created_date"] = -1
+	filter := struct {
+		LeftId uuid.UUID `json:"leftId" bson:"leftId"`
+	}{
+		LeftId: leftId,
+	}
+	return s.FindFollowRequestList(filter, 0, 0, sortMap)
+}
+
+// DeleteFollowRequest delete the request of the left user to follow the right user and report whether the request existed
+func (s FollowRequestServiceImpl) DeleteFollowRequest(leftId uuid.UUID, rightId uuid.UUID) (bool, error) {
+	filter := struct {
+		LeftId  uuid.UUID `json:"leftId" bson:"leftId"`
+		RightId uuid.UUID `json:"rightId" bson:"rightId"`
+	}{
+		LeftId:  leftId,
+		RightId: rightId,
+	}
+	result := <-s.FollowRequestRepo.Delete(followRequestCollectionName, filter, true)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	deletedCount, _ := result.Result.(int64)
+	return deletedCount > 0, nil
+}
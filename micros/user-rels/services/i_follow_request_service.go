@@ -0,0 +1,17 @@
+package service
+
+import (
+	uuid "github.com/gofrs/uuid"
+	dto "github.com/red-gold/ts-serverless/micros/user-rels/dto"
+)
+
+type FollowRequestService interface {
+	SaveFollowRequest(request *dto.FollowRequest) error
+	UpsertFollowRequest(request *dto.FollowRequest) (*dto.FollowRequest, bool, error)
+	FindOneFollowRequest(filter interface{}) (*dto.FollowRequest, error)
+	FindFollowRequestList(filter interface{}, limit int64, skip int64, sort map[string]int) ([]dto.FollowRequest, error)
+	FindFollowRequest(leftId uuid.UUID, rightId uuid.UUID) (*dto.FollowRequest, error)
+	GetIncomingRequests(rightId uuid.UUID) ([]dto.FollowRequest, error)
+	GetOutgoingRequests(leftId uuid.UUID) ([]dto.FollowRequest, error)
+	DeleteFollowRequest(leftId uuid.UUID, rightId uuid.UUID) (bool, error)
+}
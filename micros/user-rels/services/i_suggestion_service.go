@@ -0,0 +1,18 @@
+package service
+
+import (
+	uuid "github.com/gofrs/uuid"
+	dto "github.com/red-gold/ts-serverless/micros/user-rels/dto"
+	models "github.com/red-gold/ts-serverless/micros/user-rels/models"
+)
+
+type SuggestionService interface {
+	CountFriendsOfFriends(followingIds []uuid.UUID, excludeIds []uuid.UUID, limit int64) ([]models.UserCountModel, error)
+	CountSharedCircles(circleIds []string, excludeIds []uuid.UUID, limit int64) ([]models.UserCountModel, error)
+	FindUserSuggestion(ownerUserId uuid.UUID) (*dto.UserSuggestion, error)
+	SaveUserSuggestion(ownerUserId uuid.UUID, suggestions []dto.SuggestedUser, createdDate int64) error
+	RemoveSuggestedUser(ownerUserId uuid.UUID, userId uuid.UUID) error
+	FindStaleSuggestionOwners(createdBefore int64, limit int64) ([]uuid.UUID, error)
+	AddDismissal(ownerUserId uuid.UUID, userId uuid.UUID) error
+	GetDismissedIds(ownerUserId uuid.UUID) ([]uuid.UUID, error)
+}
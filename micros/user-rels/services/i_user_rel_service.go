@@ -2,6 +2,7 @@ package service
 
 import (
 	uuid "github.com/gofrs/uuid"
+	"github.com/red-gold/ts-serverless/micros/internal/cursor"
 	dto "github.com/red-gold/ts-serverless/micros/user-rels/dto"
 )
 
@@ -20,8 +21,21 @@ type UserRelService interface {
 	CreateUserRelIndex(indexes map[string]interface{}) error
 	GetFollowers(userId uuid.UUID) ([]dto.UserRel, error)
 	GetFollowing(userId uuid.UUID) ([]dto.UserRel, error)
-	FollowUser(leftUser dto.UserRelMeta, rightUser dto.UserRelMeta, circleIds []string, tags []string) error
+	GetFollowingIds(userId uuid.UUID) ([]uuid.UUID, error)
+	FindRelsIncludeProfileByCursor(filter map[string]interface{}, after *cursor.Model, before *cursor.Model, limit int64) ([]dto.UserRel, error)
+	GetFollowersByCursor(userId uuid.UUID, search string, after *cursor.Model, before *cursor.Model, limit int64) ([]dto.UserRel, error)
+	GetFollowingByCursor(userId uuid.UUID, search string, after *cursor.Model, before *cursor.Model, limit int64) ([]dto.UserRel, error)
+	GetMutualFollowersByCursor(userId uuid.UUID, otherUserId uuid.UUID, after *cursor.Model, before *cursor.Model, limit int64) ([]dto.UserRel, error)
+	FindFollowedIds(userId uuid.UUID, userIds []uuid.UUID) ([]uuid.UUID, error)
+	FindFollowerIds(userId uuid.UUID, userIds []uuid.UUID) ([]uuid.UUID, error)
+	GetCircleMembership(userId uuid.UUID) ([]dto.UserRel, error)
+	FollowUser(leftUser dto.UserRelMeta, rightUser dto.UserRelMeta, circleIds []string, tags []string) (bool, error)
 	UpdateRelCircles(leftId uuid.UUID, rightId uuid.UUID, circleIds []string) error
 	UnfollowUser(leftId uuid.UUID, rightId uuid.UUID) error
+	RemoveFollow(leftId uuid.UUID, rightId uuid.UUID) (bool, error)
 	DeleteCircle(circleId string) error
+	RemoveDuplicateRels(userIds []uuid.UUID) (int64, error)
+	FindRelUserIds() ([]uuid.UUID, error)
+	CountFollowing(userIds []uuid.UUID) (map[uuid.UUID]int64, error)
+	CountFollowers(userIds []uuid.UUID) (map[uuid.UUID]int64, error)
 }
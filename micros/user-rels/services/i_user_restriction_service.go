@@ -0,0 +1,15 @@
+package service
+
+import (
+	uuid "github.com/gofrs/uuid"
+	dto "github.com/red-gold/ts-serverless/micros/user-rels/dto"
+)
+
+type UserRestrictionService interface {
+	FindRestrictionList(filter interface{}, limit int64, skip int64, sort map[string]int) ([]dto.UserRestriction, error)
+	AddRestriction(leftId uuid.UUID, rightId uuid.UUID, restrictionType string) error
+	RemoveRestriction(leftId uuid.UUID, rightId uuid.UUID, restrictionType string) error
+	GetRestrictionsByType(leftId uuid.UUID, restrictionType string) ([]dto.UserRestriction, error)
+	GetUserRestrictions(userId uuid.UUID) ([]dto.UserRestriction, error)
+	IsBlocked(userId uuid.UUID, otherUserId uuid.UUID) (bool, error)
+}
@@ -0,0 +1,32 @@
+package service
+
+import (
+	"github.com/red-gold/ts-serverless/micros/internal/index"
+)
+
+// uniqueFollowIndex keeps one relation of a user following another user, FollowUser relies on it
+var uniqueFollowIndex = index.New(userRelCollectionName, true, "leftId", 1, "rightId", 1)
+
+// uniqueFollowIndexGuard refuses the follows while the unique follow index is not created
+var uniqueFollowIndexGuard = index.NewGuard(uniqueFollowIndex)
+
+// requiredIndexes are the indexes which the user relations function needs
+var requiredIndexes = []index.Model{
+	uniqueFollowIndex,
+	index.New(userRelCollectionName, false, "rightId", 1),
+	index.New(userRelCollectionName, false, "leftId", 1, "created_date", -1, "objectId", -1),
+	index.New(userRelCollectionName, false, "rightId", 1, "created_date", -1, "objectId", -1),
+	index.New(outboxCollectionName, false, "status", 1, "nextAttemptDate", 1),
+	index.New(restrictionCollectionName, true, "leftId", 1, "rightId", 1, "type", 1),
+	index.New(restrictionCollectionName, false, "rightId", 1, "type", 1),
+	index.New(followRequestCollectionName, true, "leftId", 1, "rightId", 1),
+	index.New(followRequestCollectionName, false, "rightId", 1, "created_date", -1),
+	index.New(suggestionCollectionName, true, "ownerUserId", 1),
+	index.New(suggestionCollectionName, false, "created_date", 1),
+	index.New(dismissalCollectionName, true, "ownerUserId", 1, "userId", 1),
+}
+
+// NewIndexService create the index service on the required indexes of the function
+func NewIndexService(db interface{}) (index.Service, error) {
+	return index.NewService(db, requiredIndexes)
+}
@@ -1,6 +1,18 @@
 package service
 
 const (
-	userRelCollectionName       = "userRel"
-	numberOfItems         int64 = 10
+	userRelCollectionName             = "userRel"
+	outboxCollectionName              = "userRelOutbox"
+	restrictionCollectionName         = "userRelRestriction"
+	followRequestCollectionName       = "userRelRequest"
+	suggestionCollectionName          = "userRelSuggestion"
+	dismissalCollectionName           = "userRelSuggestionDismissal"
+	numberOfItems               int64 = 10
+	reconcileBatchSize          int64 = 100
+)
+
+// User restriction type
+const (
+	RestrictionTypeBlock = "block"
+	RestrictionTypeMute  = "mute"
 )
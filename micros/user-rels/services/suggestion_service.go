@@ -0,0 +1,282 @@
+package service
+
+import (
+	"fmt"
+
+	uuid "github.com/gofrs/uuid"
+	"github.com/red-gold/telar-core/config"
+	coreData "github.com/red-gold/telar-core/data"
+	repo "github.com/red-gold/telar-core/data"
+	"github.com/red-gold/telar-core/data/mongodb"
+	mongoRepo "github.com/red-gold/telar-core/data/mongodb"
+	"github.com/red-gold/telar-core/utils"
+	"github.com/red-gold/ts-serverless/micros/internal/inmemory"
+	dto "github.com/red-gold/ts-serverless/micros/user-rels/dto"
+	models "github.com/red-gold/ts-serverless/micros/user-rels/models"
+)
+
+// userCountSortModel sort by count then user id, the struct keeps the order of the keys
+type userCountSortModel struct {
+	Count  int `bson:"count"`
+	UserId int `bson:"_id"`
+}
+
+// userCountSort the most connected users first
+var userCountSort = userCountSortModel{Count: -1, UserId: 1}
+
+// SuggestionService handlers with injected dependencies
+type SuggestionServiceImpl struct {
+	SuggestionRepo repo.Repository
+}
+
+// NewSuggestionService initializes SuggestionService's dependencies and create new SuggestionService struct
+func NewSuggestionService(db interface{}) (SuggestionService, error) {
+
+	suggestionService := &SuggestionServiceImpl{}
+
+	switch *config.AppConfig.DBType {
+	case config.DB_MONGO:
+
+		mongodb := db.(mongodb.MongoDatabase)
+		suggestionService.SuggestionRepo = mongoRepo.NewDataRepositoryMongo(mongodb)
+
+	case config.DB_INMEMORY:
+
+		suggestionService.SuggestionRepo = inmemory.NewDataRepositoryInMemory(db.(*inmemory.Database))
+
+	}
+
+	return suggestionService, nil
+}
+
+// CountFriendsOfFriends count for each user how many of the followed users follow them.
+// The excluded users are not counted.
+func (s SuggestionServiceImpl) CountFriendsOfFriends(followingIds []uuid.UUID, excludeIds []uuid.UUID, limit int64) ([]models.UserCountModel, error) {
+	if len(followingIds) == 0 {
+		return []models.UserCountModel{}, nil
+	}
+
+	inFollowing := make(map[string]interface{})
+	inFollowing["$in"] = followingIds
+
+	filter := make(map[string]interface{})
+	filter["leftId"] = inFollowing
+	excludeUsers(filter, "rightId", excludeIds)
+
+	return s.countRelsByUser(filter, "$rightId", limit)
+}
+
+// CountSharedCircles count for each user how many relations put them in one of the circles.
+// The excluded users are not counted.
+func (s SuggestionServiceImpl) CountSharedCircles(circleIds []string, excludeIds []uuid.UUID, limit int64) ([]models.UserCountModel, error) {
+	if len(circleIds) == 0 {
+		return []models.UserCountModel{}, nil
+	}
+
+	inCircles := make(map[string]interface{})
+	inCircles["$in"] = circleIds
+
+	filter := make(map[string]interface{})
+	filter["circleIds"] = inCircles
+	excludeUsers(filter, "rightId", excludeIds)
+
+	return s.countRelsByUser(filter, "$rightId", limit)
+}
+
+// excludeUsers add the filter that excludes the users from the field
+func excludeUsers(filter map[string]interface{}, field string, userIds []uuid.UUID) {
+	if len(userIds) == 0 {
+		return
+	}
+	notIn := make(map[string]interface{})
+	notIn["$nin"] = userIds
+	filter[field] = notIn
+}
+
+// countRelsByUser count the user relations which match the filter per user in the field, the most connected users first
+func (s SuggestionServiceImpl) countRelsByUser(filter map[string]interface{}, userField string, limit int64) ([]models.UserCountModel, error) {
+	var pipeline []interface{}
+
+	matchOperator := make(map[string]interface{})
+	matchOperator["$match"] = filter
+
+	group := make(map[string]interface{})
+	group["_id"] = userField
+	group["count"] = map[string]interface{}{"$sum": 1}
+	groupOperator := make(map[string]interface{})
+	groupOperator["$group"] = group
+
+	sortOperator := make(map[string]interface{})
+	sortOperator["$sort"] = userCountSort
+
+	limitOperator := make(map[string]interface{})
+	limitOperator["$limit"] = limit
+
+	pipeline = append(pipeline, matchOperator, groupOperator, sortOperator, limitOperator)
+
+	result := <-s.SuggestionRepo.Aggregate(userRelCollectionName, pipeline)
+	defer result.Close()
+	if result.Error() != nil {
+		return nil, result.Error()
+	}
+
+	countList := []models.UserCountModel{}
+	for result.Next() {
+		var userCount models.UserCountModel
+		errDecode := result.Decode(&userCount)
+		if errDecode != nil {
+			return nil, fmt.Errorf("Error docoding on user count")
+		}
+		countList = append(countList, userCount)
+	}
+	return countList, nil
+}
+
+// FindUserSuggestion find the cached suggestions of the user, it is nil if the suggestions are not cached
+func (s SuggestionServiceImpl) FindUserSuggestion(ownerUserId uuid.UUID) (*dto.UserSuggestion, error) {
+	filter := struct {
+		OwnerUserId uuid.UUID `json:"ownerUserId" bson:"ownerUserId"`
+	}{
+		OwnerUserId: ownerUserId,
+	}
+
+	result := <-s.SuggestionRepo.FindOne(suggestionCollectionName, filter)
+	if result.Error() != nil {
+		if result.Error() == repo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, result.Error()
+	}
+	if result.NoResult() {
+		return nil, nil
+	}
+
+	var suggestionResult dto.UserSuggestion
+	errDecode := result.Decode(&suggestionResult)
+	if errDecode != nil {
+		return nil, fmt.Errorf("Error docoding on dto.UserSuggestion")
+	}
+	return &suggestionResult, nil
+}
+
+// SaveUserSuggestion replace the cached suggestions of the user
+func (s SuggestionServiceImpl) SaveUserSuggestion(ownerUserId uuid.UUID, suggestions []dto.SuggestedUser, createdDate int64) error {
+	filter := struct {
+		OwnerUserId uuid.UUID `json:"ownerUserId" bson:"ownerUserId"`
+	}{
+		OwnerUserId: ownerUserId,
+	}
+
+	data := make(map[string]interface{})
+	data["suggestions"] = suggestions
+	data["created_date"] = createdDate
+
+	insertData := make(map[string]interface{})
+	insertData["objectId"] = uuid.Must(uuid.NewV4())
+
+	updateOperator := make(map[string]interface{})
+	updateOperator["$set"] = data
+	updateOperator["$setOnInsert"] = insertData
+
+	options := &coreData.UpdateOptions{}
+	options.SetUpsert(true)
+	result := <-s.SuggestionRepo.Update(suggestionCollectionName, filter, updateOperator, options)
+	return result.Error
+}
+
+// RemoveSuggestedUser remove the user from the cached suggestions of the owner
+func (s SuggestionServiceImpl) RemoveSuggestedUser(ownerUserId uuid.UUID, userId uuid.UUID) error {
+	filter := struct {
+		OwnerUserId uuid.UUID `json:"ownerUserId" bson:"ownerUserId"`
+	}{
+		OwnerUserId: ownerUserId,
+	}
+
+	suggestedUser := make(map[string]interface{})
+	suggestedUser["userId"] = userId
+	data := make(map[string]interface{})
+	data["suggestions"] = suggestedUser
+
+	updateOperator := make(map[string]interface{})
+	updateOperator["$pull"] = data
+
+	result := <-s.SuggestionRepo.Update(suggestionCollectionName, filter, updateOperator, &coreData.UpdateOptions{})
+	return result.Error
+}
+
+// FindStaleSuggestionOwners find the users whose suggestions are cached before the date, the oldest first
+func (s SuggestionServiceImpl) FindStaleSuggestionOwners(createdBefore int64, limit int64) ([]uuid.UUID, error) {
+	sortMap := make(map[string]int)
+	sortMap["created_date"] = 1
+
+	before := make(map[string]interface{})
+	before["$lt"] = createdBefore
+
+	filter := make(map[string]interface{})
+	filter["created_date"] = before
+
+	result := <-s.SuggestionRepo.Find(suggestionCollectionName, filter, limit, 0, sortMap)
+	defer result.Close()
+	if result.Error() != nil {
+		return nil, result.Error()
+	}
+	var ownerIds []uuid.UUID
+	for result.Next() {
+		var suggestion dto.UserSuggestion
+		errDecode := result.Decode(&suggestion)
+		if errDecode != nil {
+			return nil, fmt.Errorf("Error docoding on dto.UserSuggestion")
+		}
+		ownerIds = append(ownerIds, suggestion.OwnerUserId)
+	}
+	return ownerIds, nil
+}
+
+// AddDismissal store that the owner does not want the user to be suggested.
+// Dismissing a user who is already dismissed keeps the existing dismissal.
+func (s SuggestionServiceImpl) AddDismissal(ownerUserId uuid.UUID, userId uuid.UUID) error {
+	filter := struct {
+		OwnerUserId uuid.UUID `json:"ownerUserId" bson:"ownerUserId"`
+		UserId      uuid.UUID `json:"userId" bson:"userId"`
+	}{
+		OwnerUserId: ownerUserId,
+		UserId:      userId,
+	}
+
+	insertData := make(map[string]interface{})
+	insertData["objectId"] = uuid.Must(uuid.NewV4())
+	insertData["created_date"] = utils.UTCNowUnix()
+
+	updateOperator := make(map[string]interface{})
+	updateOperator["$setOnInsert"] = insertData
+
+	options := &coreData.UpdateOptions{}
+	options.SetUpsert(true)
+	result := <-s.SuggestionRepo.Update(dismissalCollectionName, filter, updateOperator, options)
+	return result.Error
+}
+
+// GetDismissedIds get the users whom the owner dismissed from the suggestions
+func (s SuggestionServiceImpl) GetDismissedIds(ownerUserId uuid.UUID) ([]uuid.UUID, error) {
+	filter := struct {
+		OwnerUserId uuid.UUID `json:"ownerUserId" bson:"ownerUserId"`
+	}{
+		OwnerUserId: ownerUserId,
+	}
+
+	result := <-s.SuggestionRepo.Find(dismissalCollectionName, filter, 0, 0, nil)
+	defer result.Close()
+	if result.Error() != nil {
+		return nil, result.Error()
+	}
+	dismissedIds := []uuid.UUID{}
+	for result.Next() {
+		var dismissal dto.SuggestionDismissal
+		errDecode := result.Decode(&dismissal)
+		if errDecode != nil {
+			return nil, fmt.Errorf("Error docoding on dto.SuggestionDismissal")
+		}
+		dismissedIds = append(dismissedIds, dismissal.UserId)
+	}
+	return dismissedIds, nil
+}
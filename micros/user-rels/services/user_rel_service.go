@@ -1,7 +1,9 @@
 package service
 
 import (
+	"errors"
 	"fmt"
+	"regexp"
 
 	uuid "github.com/gofrs/uuid"
 	"github.com/red-gold/telar-core/config"
@@ -11,12 +13,21 @@ import (
 	mongoRepo "github.com/red-gold/telar-core/data/mongodb"
 	"github.com/red-gold/telar-core/pkg/log"
 	"github.com/red-gold/telar-core/utils"
+	"github.com/red-gold/ts-serverless/micros/internal/cursor"
+	"github.com/red-gold/ts-serverless/micros/internal/index"
+	"github.com/red-gold/ts-serverless/micros/internal/inmemory"
 	dto "github.com/red-gold/ts-serverless/micros/user-rels/dto"
+	models "github.com/red-gold/ts-serverless/micros/user-rels/models"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
+// ErrFollowIndexMissing is returned by FollowUser while the unique index of the relations is not created
+var ErrFollowIndexMissing = errors.New("the unique index of the user relations on left and right user is not created")
+
 // UserRelService handlers with injected dependencies
 type UserRelServiceImpl struct {
-	UserRelRepo repo.Repository
+	UserRelRepo  repo.Repository
+	IndexService index.Service
 }
 
 // NewUserRelService initializes UserRelService's dependencies and create new UserRelService struct
@@ -24,12 +35,22 @@ func NewUserRelService(db interface{}) (UserRelService, error) {
 
 	userRelService := &UserRelServiceImpl{}
 
+	indexService, err := NewIndexService(db)
+	if err != nil {
+		return nil, err
+	}
+	userRelService.IndexService = indexService
+
 	switch *config.AppConfig.DBType {
 	case config.DB_MONGO:
 
 		mongodb := db.(mongodb.MongoDatabase)
 		userRelService.UserRelRepo = mongoRepo.NewDataRepositoryMongo(mongodb)
 
+	case config.DB_INMEMORY:
+
+		userRelService.UserRelRepo = inmemory.NewDataRepositoryInMemory(db.(*inmemory.Database))
+
 	}
 
 	return userRelService, nil
@@ -55,13 +76,19 @@ func (s UserRelServiceImpl) SaveUserRel(userRel *dto.UserRel) error {
 	return result.Error
 }
 
-// FindOneUserRel get one userRel
+// FindOneUserRel get one userRel, nil is returned when no userRel matches the filter
 func (s UserRelServiceImpl) FindOneUserRel(filter interface{}) (*dto.UserRel, error) {
 
 	result := <-s.UserRelRepo.FindOne(userRelCollectionName, filter)
 	if result.Error() != nil {
+		if result.Error() == repo.ErrNoDocuments {
+			return nil, nil
+		}
 		return nil, result.Error()
 	}
+	if result.NoResult() {
+		return nil, nil
+	}
 
 	var userRelResult dto.UserRel
 	errDecode := result.Decode(&userRelResult)
@@ -116,6 +143,32 @@ func (s UserRelServiceImpl) FindRelsIncludeProfile(filter interface{}, limit int
 		pipeline = append(pipeline, limitOperator)
 	}
 
+	pipeline = append(pipeline, includeProfileStages()...)
+	log.Info("pipeline %v", pipeline)
+
+	result := <-s.UserRelRepo.Aggregate(userRelCollectionName, pipeline)
+
+	defer result.Close()
+	if result.Error() != nil {
+		return nil, result.Error()
+	}
+	var postList []dto.UserRel
+	for result.Next() {
+		var post dto.UserRel
+		errDecode := result.Decode(&post)
+		if errDecode != nil {
+			return nil, fmt.Errorf("Error docoding on dto.UserRel")
+		}
+		postList = append(postList, post)
+	}
+
+	return postList, nil
+}
+
+// includeProfileStages create the stages which replace the user meta of the relations with the user profiles
+func includeProfileStages() []interface{} {
+	var stages []interface{}
+
 	// Add left user pipeline
 	lookupLeftUser := make(map[string]map[string]string)
 	lookupLeftUser["$lookup"] = map[string]string{
@@ -127,7 +180,7 @@ func (s UserRelServiceImpl) FindRelsIncludeProfile(filter interface{}, limit int
 
 	unwindLeftUser := make(map[string]interface{})
 	unwindLeftUser["$unwind"] = "$leftUser"
-	pipeline = append(pipeline, lookupLeftUser, unwindLeftUser)
+	stages = append(stages, lookupLeftUser, unwindLeftUser)
 
 	// Add right user pipeline
 	lookupRightUser := make(map[string]map[string]string)
@@ -140,8 +193,7 @@ func (s UserRelServiceImpl) FindRelsIncludeProfile(filter interface{}, limit int
 
 	unwindRightUser := make(map[string]interface{})
 	unwindRightUser["$unwind"] = "$rightUser"
-	pipeline = append(pipeline, lookupRightUser, unwindRightUser)
-	log.Info("pipeline %v", pipeline)
+	stages = append(stages, lookupRightUser, unwindRightUser)
 
 	projectOperator := make(map[string]interface{})
 	project := make(map[string]interface{})
@@ -179,25 +231,74 @@ func (s UserRelServiceImpl) FindRelsIncludeProfile(filter interface{}, limit int
 
 	projectOperator["$project"] = project
 
-	pipeline = append(pipeline, projectOperator)
+	stages = append(stages, projectOperator)
 
-	result := <-s.UserRelRepo.Aggregate(userRelCollectionName, pipeline)
+	return stages
+}
+
+// FindRelsIncludeProfileByCursor get the user relations by filter in the page around the cursors including user profile entity
+func (s UserRelServiceImpl) FindRelsIncludeProfileByCursor(filter map[string]interface{}, after *cursor.Model, before *cursor.Model, limit int64) ([]dto.UserRel, error) {
+	return s.findRelsIncludeProfileByCursor(filter, nil, after, before, limit)
+}
+
+// findRelsIncludeProfileByCursor get the user relations by filter in the page around the cursors including user profile entity.
+// The stages run on the sorted relations before the limit, so they can drop relations from the page.
+func (s UserRelServiceImpl) findRelsIncludeProfileByCursor(filter map[string]interface{}, stages []interface{}, after *cursor.Model, before *cursor.Model, limit int64) ([]dto.UserRel, error) {
+	var pipeline []interface{}
+
+	sort := cursor.AddFilter(filter, "created_date", after, before)
+
+	matchOperator := make(map[string]interface{})
+	matchOperator["$match"] = filter
+
+	sortOperator := make(map[string]interface{})
+	sortOperator["$sort"] = sort
 
+	limitOperator := make(map[string]interface{})
+	limitOperator["$limit"] = limit
+
+	pipeline = append(pipeline, matchOperator, sortOperator)
+	pipeline = append(pipeline, stages...)
+	pipeline = append(pipeline, limitOperator)
+	pipeline = append(pipeline, includeProfileStages()...)
+
+	result := <-s.UserRelRepo.Aggregate(userRelCollectionName, pipeline)
 	defer result.Close()
 	if result.Error() != nil {
 		return nil, result.Error()
 	}
-	var postList []dto.UserRel
+	var userRelList []dto.UserRel
 	for result.Next() {
-		var post dto.UserRel
-		errDecode := result.Decode(&post)
+		var userRel dto.UserRel
+		errDecode := result.Decode(&userRel)
 		if errDecode != nil {
 			return nil, fmt.Errorf("Error docoding on dto.UserRel")
 		}
-		postList = append(postList, post)
+		userRelList = append(userRelList, userRel)
 	}
 
-	return postList, nil
+	// The page before the cursor is read in reverse order
+	if before != nil {
+		for i, j := 0, len(userRelList)-1; i < j; i, j = i+1, j-1 {
+			userRelList[i], userRelList[j] = userRelList[j], userRelList[i]
+		}
+	}
+
+	return userRelList, nil
+}
+
+// relSearchFilter create the filter that matches the name or the social name of the user meta with the search, case insensitive
+func relSearchFilter(metaField string, search string) []interface{} {
+	var conditions []interface{}
+	for _, field := range []string{"fullName", "socialName"} {
+		regex := make(map[string]interface{})
+		regex["$regex"] = regexp.QuoteMeta(search)
+		regex["$options"] = "i"
+		condition := make(map[string]interface{})
+		condition[metaField+"."+field] = regex
+		conditions = append(conditions, condition)
+	}
+	return conditions
 }
 
 // QueryUserRel get all userRels by query
@@ -334,8 +435,174 @@ func (s UserRelServiceImpl) GetFollowing(userId uuid.UUID) ([]dto.UserRel, error
 	return s.FindRelsIncludeProfile(filter, 0, 0, sortMap)
 }
 
-// FollowUser create relation between two users
-func (s UserRelServiceImpl) FollowUser(leftUser dto.UserRelMeta, rightUser dto.UserRelMeta, circleIds []string, tags []string) error {
+// GetFollowingIds get the users whom the user follows
+func (s UserRelServiceImpl) GetFollowingIds(userId uuid.UUID) ([]uuid.UUID, error) {
+	filter := struct {
+		LeftId uuid.UUID `json:"leftId" bson:"leftId"`
+	}{
+		LeftId: userId,
+	}
+
+	rels, err := s.FindUserRelList(filter, 0, 0, nil)
+	if err != nil {
+		return nil, err
+	}
+	followingIds := []uuid.UUID{}
+	for _, rel := range rels {
+		followingIds = append(followingIds, rel.RightId)
+	}
+	return followingIds, nil
+}
+
+// GetFollowersByCursor get the followers of the user in the page around the cursors, the search filters the followers by name
+func (s UserRelServiceImpl) GetFollowersByCursor(userId uuid.UUID, search string, after *cursor.Model, before *cursor.Model, limit int64) ([]dto.UserRel, error) {
+	if limit <= 0 {
+		limit = numberOfItems
+	}
+
+	filter := make(map[string]interface{})
+	filter["rightId"] = userId
+	if search != "" {
+		filter["$or"] = relSearchFilter("left", search)
+	}
+
+	return s.FindRelsIncludeProfileByCursor(filter, after, before, limit)
+}
+
+// GetFollowingByCursor get the users whom the user follows in the page around the cursors, the search filters the users by name
+func (s UserRelServiceImpl) GetFollowingByCursor(userId uuid.UUID, search string, after *cursor.Model, before *cursor.Model, limit int64) ([]dto.UserRel, error) {
+	if limit <= 0 {
+		limit = numberOfItems
+	}
+
+	filter := make(map[string]interface{})
+	filter["leftId"] = userId
+	if search != "" {
+		filter["$or"] = relSearchFilter("right", search)
+	}
+
+	return s.FindRelsIncludeProfileByCursor(filter, after, before, limit)
+}
+
+// GetMutualFollowersByCursor get the relations of the users who follow both the user and the other user
+// to the other user in the page around the cursors
+func (s UserRelServiceImpl) GetMutualFollowersByCursor(userId uuid.UUID, otherUserId uuid.UUID, after *cursor.Model, before *cursor.Model, limit int64) ([]dto.UserRel, error) {
+	if limit <= 0 {
+		limit = numberOfItems
+	}
+
+	filter := make(map[string]interface{})
+	filter["rightId"] = otherUserId
+
+	// The relations of each follower of the other user
+	lookupFollowerRels := make(map[string]map[string]string)
+	lookupFollowerRels["$lookup"] = map[string]string{
+		"localField":   "leftId",
+		"from":         userRelCollectionName,
+		"foreignField": "leftId",
+		"as":           "followerRels",
+	}
+
+	// Keep the followers who follow the user as well
+	mutualFilter := make(map[string]interface{})
+	mutualFilter["followerRels.rightId"] = userId
+	matchMutual := make(map[string]interface{})
+	matchMutual["$match"] = mutualFilter
+
+	return s.findRelsIncludeProfileByCursor(filter, []interface{}{lookupFollowerRels, matchMutual}, after, before, limit)
+}
+
+// FindFollowedIds get the users in the list whom the user follows
+func (s UserRelServiceImpl) FindFollowedIds(userId uuid.UUID, userIds []uuid.UUID) ([]uuid.UUID, error) {
+	if len(userIds) == 0 {
+		return []uuid.UUID{}, nil
+	}
+
+	include := make(map[string]interface{})
+	include["$in"] = userIds
+
+	filter := make(map[string]interface{})
+	filter["leftId"] = userId
+	filter["rightId"] = include
+
+	rels, err := s.FindUserRelList(filter, 0, 0, nil)
+	if err != nil {
+		return nil, err
+	}
+	followedIds := []uuid.UUID{}
+	for _, rel := range rels {
+		followedIds = append(followedIds, rel.RightId)
+	}
+	return followedIds, nil
+}
+
+// FindFollowerIds get the users in the list who follow the user
+func (s UserRelServiceImpl) FindFollowerIds(userId uuid.UUID, userIds []uuid.UUID) ([]uuid.UUID, error) {
+	if len(userIds) == 0 {
+		return []uuid.UUID{}, nil
+	}
+
+	include := make(map[string]interface{})
+	include["$in"] = userIds
+
+	filter := make(map[string]interface{})
+	filter["leftId"] = include
+	filter["rightId"] = userId
+
+	rels, err := s.FindUserRelList(filter, 0, 0, nil)
+	if err != nil {
+		return nil, err
+	}
+	followerIds := []uuid.UUID{}
+	for _, rel := range rels {
+		followerIds = append(followerIds, rel.LeftId)
+	}
+	return followerIds, nil
+}
+
+// GetCircleMembership Get the relations where other users put the user in their circles
+func (s UserRelServiceImpl) GetCircleMembership(userId uuid.UUID) ([]dto.UserRel, error) {
+	sortMap := make(map[string]int)
+	sortMap["created_date"] = -1
+
+	notEmpty := make(map[string]interface{})
+	notEmpty["$exists"] = true
+	notEmpty["$ne"] = []string{}
+
+	filter := make(map[string]interface{})
+	filter["rightId"] = userId
+	filter["circleIds"] = notEmpty
+
+	return s.FindUserRelList(filter, 0, 0, sortMap)
+}
+
+// FollowUser store the relation of the left user following the right user and report whether the relation is new.
+// The relation which already exists is kept as it is.
+func (s UserRelServiceImpl) FollowUser(leftUser dto.UserRelMeta, rightUser dto.UserRelMeta, circleIds []string, tags []string) (bool, error) {
+
+	// Without the unique index the relation which is stored meanwhile would be saved twice
+	indexed, err := uniqueFollowIndexGuard.Check(s.IndexService)
+	if err != nil {
+		return false, err
+	}
+	if !indexed {
+		return false, ErrFollowIndexMissing
+	}
+
+	filter := struct {
+		LeftId  uuid.UUID `json:"leftId" bson:"leftId"`
+		RightId uuid.UUID `json:"rightId" bson:"rightId"`
+	}{
+		LeftId:  leftUser.UserId,
+		RightId: rightUser.UserId,
+	}
+	currentRel, err := s.FindOneUserRel(filter)
+	if err != nil {
+		return false, err
+	}
+	if currentRel != nil {
+		return false, nil
+	}
 
 	newUserRel := &dto.UserRel{
 		Left:      leftUser,
@@ -346,8 +613,16 @@ func (s UserRelServiceImpl) FollowUser(leftUser dto.UserRelMeta, rightUser dto.U
 		CircleIds: circleIds,
 		Tags:      tags,
 	}
-	err := s.SaveUserRel(newUserRel)
-	return err
+	err = s.SaveUserRel(newUserRel)
+	if err == nil {
+		return true, nil
+	}
+
+	// The unique index on left and right user rejects the relation which is stored meanwhile
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	}
+	return false, err
 }
 
 // UpdateRelCircles update the user relation circle ids
@@ -399,3 +674,185 @@ func (s UserRelServiceImpl) UnfollowUser(leftId uuid.UUID, rightId uuid.UUID) er
 	}
 	return nil
 }
+
+// RemoveFollow delete relation between two users by left and right userId and report whether the relation existed.
+// The duplicated relations which are stored before the unique index are removed too.
+func (s UserRelServiceImpl) RemoveFollow(leftId uuid.UUID, rightId uuid.UUID) (bool, error) {
+
+	filter := struct {
+		LeftId  uuid.UUID `json:"leftId" bson:"leftId"`
+		RightId uuid.UUID `json:"rightId" bson:"rightId"`
+	}{
+		LeftId:  leftId,
+		RightId: rightId,
+	}
+	result := <-s.UserRelRepo.Delete(userRelCollectionName, filter, false)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	deletedCount, _ := result.Result.(int64)
+	return deletedCount > 0, nil
+}
+
+// relPairModel group the relations by the left and right user, the struct keeps the order of the keys
+type relPairModel struct {
+	LeftId  string `bson:"leftId"`
+	RightId string `bson:"rightId"`
+}
+
+// RemoveDuplicateRels remove the relations which repeat an older relation of the same left and right user and
+// return the number of the removed relations. Only the relations of the users are checked when the users are given.
+func (s UserRelServiceImpl) RemoveDuplicateRels(userIds []uuid.UUID) (int64, error) {
+	var pipeline []interface{}
+
+	if len(userIds) > 0 {
+		inUsers := make(map[string]interface{})
+		inUsers["$in"] = userIds
+		filter := make(map[string]interface{})
+		filter["$or"] = []interface{}{
+			map[string]interface{}{"leftId": inUsers},
+			map[string]interface{}{"rightId": inUsers},
+		}
+		matchOperator := make(map[string]interface{})
+		matchOperator["$match"] = filter
+		pipeline = append(pipeline, matchOperator)
+	}
+
+	// The oldest relation of each pair is the first one and it is kept
+	sortMap := make(map[string]int)
+	sortMap["created_date"] = 1
+	sortOperator := make(map[string]interface{})
+	sortOperator["$sort"] = sortMap
+
+	group := make(map[string]interface{})
+	group["_id"] = relPairModel{LeftId: "$leftId", RightId: "$rightId"}
+	group["relIds"] = map[string]interface{}{"$push": "$objectId"}
+	group["count"] = map[string]interface{}{"$sum": 1}
+	groupOperator := make(map[string]interface{})
+	groupOperator["$group"] = group
+
+	duplicateMatch := make(map[string]interface{})
+	duplicateMatch["$match"] = map[string]interface{}{"count": map[string]interface{}{"$gt": 1}}
+
+	pipeline = append(pipeline, sortOperator, groupOperator, duplicateMatch)
+
+	result := <-s.UserRelRepo.Aggregate(userRelCollectionName, pipeline)
+	defer result.Close()
+	if result.Error() != nil {
+		return 0, result.Error()
+	}
+
+	var duplicateIds []uuid.UUID
+	for result.Next() {
+		var pair struct {
+			RelIds []uuid.UUID `bson:"relIds"`
+		}
+		errDecode := result.Decode(&pair)
+		if errDecode != nil {
+			return 0, fmt.Errorf("Error docoding on duplicated userRel")
+		}
+		duplicateIds = append(duplicateIds, pair.RelIds[1:]...)
+	}
+	if len(duplicateIds) == 0 {
+		return 0, nil
+	}
+
+	inDuplicates := make(map[string]interface{})
+	inDuplicates["$in"] = duplicateIds
+	filter := make(map[string]interface{})
+	filter["objectId"] = inDuplicates
+
+	deleteResult := <-s.UserRelRepo.Delete(userRelCollectionName, filter, false)
+	if deleteResult.Error != nil {
+		return 0, deleteResult.Error
+	}
+	deletedCount, _ := deleteResult.Result.(int64)
+	return deletedCount, nil
+}
+
+// FindRelUserIds get the users who follow or are followed by a user
+func (s UserRelServiceImpl) FindRelUserIds() ([]uuid.UUID, error) {
+	sortMap := make(map[string]int)
+	sortMap["objectId"] = 1
+
+	found := make(map[uuid.UUID]bool)
+	userIds := []uuid.UUID{}
+	lastRelId := uuid.Nil
+	for {
+		filter := make(map[string]interface{})
+		if lastRelId != uuid.Nil {
+			afterFilter := make(map[string]interface{})
+			afterFilter["$gt"] = lastRelId
+			filter["objectId"] = afterFilter
+		}
+
+		rels, err := s.FindUserRelList(filter, reconcileBatchSize, 0, sortMap)
+		if err != nil {
+			return nil, err
+		}
+		for _, rel := range rels {
+			for _, userId := range []uuid.UUID{rel.LeftId, rel.RightId} {
+				if !found[userId] {
+					found[userId] = true
+					userIds = append(userIds, userId)
+				}
+			}
+		}
+
+		if int64(len(rels)) < reconcileBatchSize {
+			break
+		}
+		lastRelId = rels[len(rels)-1].ObjectId
+	}
+	return userIds, nil
+}
+
+// CountFollowing count the users whom each user follows
+func (s UserRelServiceImpl) CountFollowing(userIds []uuid.UUID) (map[uuid.UUID]int64, error) {
+	return s.countRelsByUser("leftId", userIds)
+}
+
+// CountFollowers count the followers of each user
+func (s UserRelServiceImpl) CountFollowers(userIds []uuid.UUID) (map[uuid.UUID]int64, error) {
+	return s.countRelsByUser("rightId", userIds)
+}
+
+// countRelsByUser count the relations of each user in the field
+func (s UserRelServiceImpl) countRelsByUser(userField string, userIds []uuid.UUID) (map[uuid.UUID]int64, error) {
+	counts := make(map[uuid.UUID]int64)
+	if len(userIds) == 0 {
+		return counts, nil
+	}
+
+	var pipeline []interface{}
+
+	inUsers := make(map[string]interface{})
+	inUsers["$in"] = userIds
+	filter := make(map[string]interface{})
+	filter[userField] = inUsers
+	matchOperator := make(map[string]interface{})
+	matchOperator["$match"] = filter
+
+	group := make(map[string]interface{})
+	group["_id"] = "$" + userField
+	group["count"] = map[string]interface{}{"$sum": 1}
+	groupOperator := make(map[string]interface{})
+	groupOperator["$group"] = group
+
+	pipeline = append(pipeline, matchOperator, groupOperator)
+
+	result := <-s.UserRelRepo.Aggregate(userRelCollectionName, pipeline)
+	defer result.Close()
+	if result.Error() != nil {
+		return nil, result.Error()
+	}
+	for result.Next() {
+		var userCount models.UserCountModel
+		errDecode := result.Decode(&userCount)
+		if errDecode != nil {
+			return nil, fmt.Errorf("Error docoding on models.UserCountModel")
+		}
+		counts[userCount.UserId] = userCount.Count
+	}
+	return counts, nil
+}
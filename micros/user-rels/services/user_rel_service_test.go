@@ -0,0 +1,170 @@
+package service
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	uuid "github.com/gofrs/uuid"
+	"github.com/red-gold/telar-core/config"
+	"github.com/red-gold/ts-serverless/micros/internal/inmemory"
+	"github.com/red-gold/ts-serverless/micros/user-rels/dto"
+)
+
+// testProfile the part of a user profile which the relations include
+type testProfile struct {
+	ObjectId   uuid.UUID `bson:"objectId"`
+	FullName   string    `bson:"fullName"`
+	SocialName string    `bson:"socialName"`
+}
+
+// newTestDatabase create an in-memory database with a profile for each user
+func newTestDatabase(t *testing.T, users ...dto.UserRelMeta) *inmemory.Database {
+	t.Helper()
+	dbType := config.DB_INMEMORY
+	config.AppConfig.DBType = &dbType
+	db := inmemory.NewDatabase()
+
+	profileRepo := inmemory.NewDataRepositoryInMemory(db)
+	for _, user := range users {
+		profile := testProfile{ObjectId: user.UserId, FullName: user.FullName, SocialName: user.SocialName}
+		if result := <-profileRepo.Save("userProfile", profile); result.Error != nil {
+			t.Fatalf("Save profile: %s", result.Error)
+		}
+	}
+	return db
+}
+
+// newTestUserRelService create a user relation service on an in-memory database with the indexes of the function
+// and a profile for each user
+func newTestUserRelService(t *testing.T, users ...dto.UserRelMeta) UserRelService {
+	t.Helper()
+	db := newTestDatabase(t, users...)
+	indexService, _ := NewIndexService(db)
+	if _, err := indexService.ReconcileIndexes(); err != nil {
+		t.Fatalf("ReconcileIndexes: %s", err)
+	}
+
+	userRelService, err := NewUserRelService(db)
+	if err != nil {
+		t.Fatalf("NewUserRelService: %s", err)
+	}
+	return userRelService
+}
+
+// newTestUser create the meta of a user
+func newTestUser(name string) dto.UserRelMeta {
+	return dto.UserRelMeta{UserId: uuid.Must(uuid.NewV4()), FullName: name, SocialName: name}
+}
+
+func TestFollowUserIsIdempotent(t *testing.T) {
+	alice, bob := newTestUser("alice"), newTestUser("bob")
+	userRelService := newTestUserRelService(t, alice, bob)
+
+	tests := []struct {
+		name    string
+		left    dto.UserRelMeta
+		right   dto.UserRelMeta
+		created bool
+	}{
+		{name: "follow", left: alice, right: bob, created: true},
+		{name: "follow again", left: alice, right: bob, created: false},
+		{name: "follow back", left: bob, right: alice, created: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			created, err := userRelService.FollowUser(test.left, test.right, []string{}, []string{})
+			if err != nil {
+				t.Fatalf("FollowUser: %s", err)
+			}
+			if created != test.created {
+				t.Errorf("got created %t, want %t", created, test.created)
+			}
+		})
+	}
+
+	followers, err := userRelService.GetFollowers(bob.UserId)
+	if err != nil {
+		t.Fatalf("GetFollowers: %s", err)
+	}
+	if len(followers) != 1 || followers[0].Left.FullName != "alice" {
+		t.Errorf("got followers %+v, want alice with the profile", followers)
+	}
+}
+
+func TestFollowUserConcurrently(t *testing.T) {
+	alice, bob := newTestUser("alice"), newTestUser("bob")
+	userRelService := newTestUserRelService(t, alice, bob)
+
+	const follows = 10
+	var wg sync.WaitGroup
+	results := make(chan bool, follows)
+	for i := 0; i < follows; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			created, err := userRelService.FollowUser(alice, bob, []string{}, []string{})
+			if err != nil {
+				t.Errorf("FollowUser: %s", err)
+				return
+			}
+			results <- created
+		}()
+	}
+	wg.Wait()
+	close(results)
+
+	createdCount := 0
+	for created := range results {
+		if created {
+			createdCount++
+		}
+	}
+	if createdCount != 1 {
+		t.Errorf("got %d created relations, want 1", createdCount)
+	}
+	rels, _ := userRelService.GetFollowing(alice.UserId)
+	if len(rels) != 1 {
+		t.Errorf("got %d relations, want 1", len(rels))
+	}
+}
+
+func TestFollowUserWithoutIndex(t *testing.T) {
+	alice, bob := newTestUser("alice"), newTestUser("bob")
+	userRelService, err := NewUserRelService(newTestDatabase(t, alice, bob))
+	if err != nil {
+		t.Fatalf("NewUserRelService: %s", err)
+	}
+
+	if _, err := userRelService.FollowUser(alice, bob, []string{}, []string{}); !errors.Is(err, ErrFollowIndexMissing) {
+		t.Errorf("got error %v, want ErrFollowIndexMissing", err)
+	}
+}
+
+func TestRemoveDuplicateRels(t *testing.T) {
+	alice, bob := newTestUser("alice"), newTestUser("bob")
+	userRelService, err := NewUserRelService(newTestDatabase(t, alice, bob))
+	if err != nil {
+		t.Fatalf("NewUserRelService: %s", err)
+	}
+
+	// The relations are saved without the unique index, as they were before it existed
+	for i := 0; i < 3; i++ {
+		rel := &dto.UserRel{LeftId: alice.UserId, Left: alice, RightId: bob.UserId, Right: bob, CreatedDate: int64(i + 1)}
+		if err := userRelService.SaveUserRel(rel); err != nil {
+			t.Fatalf("SaveUserRel: %s", err)
+		}
+	}
+
+	removed, err := userRelService.RemoveDuplicateRels(nil)
+	if err != nil {
+		t.Fatalf("RemoveDuplicateRels: %s", err)
+	}
+	if removed != 2 {
+		t.Errorf("got %d removed relations, want 2", removed)
+	}
+	rels, _ := userRelService.GetFollowing(alice.UserId)
+	if len(rels) != 1 || rels[0].CreatedDate != 1 {
+		t.Errorf("got relations %+v, want the oldest one", rels)
+	}
+}
@@ -0,0 +1,160 @@
+package service
+
+import (
+	"fmt"
+
+	uuid "github.com/gofrs/uuid"
+	"github.com/red-gold/telar-core/config"
+	coreData "github.com/red-gold/telar-core/data"
+	repo "github.com/red-gold/telar-core/data"
+	"github.com/red-gold/telar-core/data/mongodb"
+	mongoRepo "github.com/red-gold/telar-core/data/mongodb"
+	"github.com/red-gold/telar-core/utils"
+	"github.com/red-gold/ts-serverless/micros/internal/inmemory"
+	dto "github.com/red-gold/ts-serverless/micros/user-rels/dto"
+)
+
+// UserRestrictionService handlers with injected dependencies
+type UserRestrictionServiceImpl struct {
+	RestrictionRepo repo.Repository
+}
+
+// NewUserRestrictionService initializes UserRestrictionService's dependencies and create new UserRestrictionService struct
+func NewUserRestrictionService(db interface{}) (UserRestrictionService, error) {
+
+	restrictionService := &UserRestrictionServiceImpl{}
+
+	switch *config.AppConfig.DBType {
+	case config.DB_MONGO:
+
+		mongodb := db.(mongodb.MongoDatabase)
+		restrictionService.RestrictionRepo = mongoRepo.NewDataRepositoryMongo(mongodb)
+
+	case config.DB_INMEMORY:
+
+		restrictionService.RestrictionRepo = inmemory.NewDataRepositoryInMemory(db.(*inmemory.Database))
+
+	}
+
+	return restrictionService, nil
+}
+
+// FindRestrictionList get all user restrictions by filter
+func (s UserRestrictionServiceImpl) FindRestrictionList(filter interface{}, limit int64, skip int64, sort map[string]int) ([]dto.UserRestriction, error) {
+
+	result := <-s.RestrictionRepo.Find(restrictionCollectionName, filter, limit, skip, sort)
+	defer result.Close()
+	if result.Error() != nil {
+		return nil, result.Error()
+	}
+	var restrictionList []dto.UserRestriction
+	for result.Next() {
+		var restriction dto.UserRestriction
+		errDecode := result.Decode(&restriction)
+		if errDecode != nil {
+			return nil, fmt.Errorf("Error docoding on dto.UserRestriction")
+		}
+		restrictionList = append(restrictionList, restriction)
+	}
+
+	return restrictionList, nil
+}
+
+// AddRestriction put the restriction of the left user on the right user.
+// Adding a restriction which already exists keeps the existing one.
+func (s UserRestrictionServiceImpl) AddRestriction(leftId uuid.UUID, rightId uuid.UUID, restrictionType string) error {
+	filter := struct {
+		LeftId  uuid.UUID `json:"leftId" bson:"leftId"`
+		RightId uuid.UUID `json:"rightId" bson:"rightId"`
+		Type    string    `json:"type" bson:"type"`
+	}{
+		LeftId:  leftId,
+		RightId: rightId,
+		Type:    restrictionType,
+	}
+
+	insertData := make(map[string]interface{})
+	insertData["objectId"] = uuid.Must(uuid.NewV4())
+	insertData["created_date"] = utils.UTCNowUnix()
+
+	updateOperator := make(map[string]interface{})
+	updateOperator["$setOnInsert"] = insertData
+
+	options := &coreData.UpdateOptions{}
+	options.SetUpsert(true)
+	result := <-s.RestrictionRepo.Update(restrictionCollectionName, filter, updateOperator, options)
+	return result.Error
+}
+
+// RemoveRestriction remove the restriction of the left user on the right user
+func (s UserRestrictionServiceImpl) RemoveRestriction(leftId uuid.UUID, rightId uuid.UUID, restrictionType string) error {
+	filter := struct {
+		LeftId  uuid.UUID `json:"leftId" bson:"leftId"`
+		RightId uuid.UUID `json:"rightId" bson:"rightId"`
+		Type    string    `json:"type" bson:"type"`
+	}{
+		LeftId:  leftId,
+		RightId: rightId,
+		Type:    restrictionType,
+	}
+
+	result := <-s.RestrictionRepo.Delete(restrictionCollectionName, filter, true)
+	return result.Error
+}
+
+// GetRestrictionsByType get the restrictions of a type which the left user put on other users
+func (s UserRestrictionServiceImpl) GetRestrictionsByType(leftId uuid.UUID, restrictionType string) ([]dto.UserRestriction, error) {
+	sortMap := make(map[string]int)
+	sortMap["created_date"] = -1
+
+	filter := struct {
+		LeftId uuid.UUID `json:"leftId" bson:"leftId"`
+		Type   string    `json:"type" bson:"type"`
+	}{
+		LeftId: leftId,
+		Type:   restrictionType,
+	}
+	return s.FindRestrictionList(filter, 0, 0, sortMap)
+}
+
+// GetUserRestrictions get the restrictions which the user put on other users and the blocks which other users put on the user
+func (s UserRestrictionServiceImpl) GetUserRestrictions(userId uuid.UUID) ([]dto.UserRestriction, error) {
+	sortMap := make(map[string]int)
+	sortMap["created_date"] = -1
+
+	leftFilter := make(map[string]interface{})
+	leftFilter["leftId"] = userId
+
+	blockedByFilter := make(map[string]interface{})
+	blockedByFilter["rightId"] = userId
+	blockedByFilter["type"] = RestrictionTypeBlock
+
+	filter := make(map[string]interface{})
+	filter["$or"] = []interface{}{leftFilter, blockedByFilter}
+
+	return s.FindRestrictionList(filter, 0, 0, sortMap)
+}
+
+// IsBlocked check whether one of the users blocked the other one
+func (s UserRestrictionServiceImpl) IsBlocked(userId uuid.UUID, otherUserId uuid.UUID) (bool, error) {
+	blockFilter := make(map[string]interface{})
+	blockFilter["leftId"] = userId
+	blockFilter["rightId"] = otherUserId
+
+	blockedByFilter := make(map[string]interface{})
+	blockedByFilter["leftId"] = otherUserId
+	blockedByFilter["rightId"] = userId
+
+	filter := make(map[string]interface{})
+	filter["type"] = RestrictionTypeBlock
+	filter["$or"] = []interface{}{blockFilter, blockedByFilter}
+
+	sortMap := make(map[string]int)
+	sortMap["created_date"] = -1
+
+	restrictions, err := s.FindRestrictionList(filter, 1, 0, sortMap)
+	if err != nil {
+		return false, err
+	}
+	return len(restrictions) > 0, nil
+}
@@ -5,10 +5,20 @@ import (
 )
 
 type Message struct {
-	ObjectId    uuid.UUID `json:"objectId" bson:"objectId"`
-	OwnerUserId uuid.UUID `json:"ownerUserId" bson:"ownerUserId"`
-	RoomId      uuid.UUID `json:"roomId" bson:"roomId"`
-	Text        string    `json:"text" bson:"text"`
-	CreatedDate int64     `json:"createdDate" bson:"createdDate"`
-	UpdatedDate int64     `json:"updatedDate" bson:"updatedDate"`
+	ObjectId    uuid.UUID     `json:"objectId" bson:"objectId"`
+	OwnerUserId uuid.UUID     `json:"ownerUserId" bson:"ownerUserId"`
+	RoomId      uuid.UUID     `json:"roomId" bson:"roomId"`
+	Text        string        `json:"text" bson:"text"`
+	Edited      bool          `json:"edited" bson:"edited"`
+	EditHistory []MessageEdit `json:"editHistory" bson:"editHistory"` // The previous texts of the message
+	Deleted     bool          `json:"deleted" bson:"deleted"`         // The message is deleted for everyone and kept as a tombstone
+	DeletedDate int64         `json:"deletedDate" bson:"deletedDate"`
+	HiddenFor   []string      `json:"-" bson:"hiddenFor"` // ['userId1'] the users who deleted the message for themselves
+	CreatedDate int64         `json:"createdDate" bson:"createdDate"`
+	UpdatedDate int64         `json:"updatedDate" bson:"updatedDate"`
+}
+
+type MessageEdit struct {
+	Text       string `json:"text" bson:"text"`
+	EditedDate int64  `json:"editedDate" bson:"editedDate"`
 }
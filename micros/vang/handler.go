@@ -13,8 +13,10 @@ import (
 	"github.com/red-gold/telar-core/config"
 	"github.com/red-gold/telar-core/pkg/log"
 	micros "github.com/red-gold/ts-serverless/micros"
+	"github.com/red-gold/ts-serverless/micros/internal/index"
 	"github.com/red-gold/ts-serverless/micros/vang/database"
 	"github.com/red-gold/ts-serverless/micros/vang/router"
+	service "github.com/red-gold/ts-serverless/micros/vang/services"
 )
 
 // Cache state
@@ -53,9 +55,16 @@ func Handle(w http.ResponseWriter, r *http.Request) {
 			log.Error("Error startup: %s", startErr.Error())
 			w.WriteHeader(http.StatusInternalServerError)
 			w.Write([]byte(startErr.Error()))
+		} else {
+			go index.Reconcile(newIndexService)
 		}
 	}
 
 	adaptor.FiberApp(app)(w, r)
 
 }
+
+// newIndexService create the index service of the function for the reconciler
+func newIndexService() (index.Service, error) {
+	return service.NewIndexService(database.Db)
+}
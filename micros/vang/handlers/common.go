@@ -1,21 +1,28 @@
 package handlers
 
 import (
-	"bytes"
-	"encoding/hex"
+	"context"
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
 	"net/http"
 
-	"github.com/alexellis/hmac"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofrs/uuid"
-	coreConfig "github.com/red-gold/telar-core/config"
 	log "github.com/red-gold/telar-core/pkg/log"
-	"github.com/red-gold/telar-core/types"
-	"github.com/red-gold/telar-core/utils"
+	"github.com/red-gold/ts-serverless/micros/internal/rpc"
+	"github.com/red-gold/ts-serverless/micros/vang/dto"
 	models "github.com/red-gold/ts-serverless/micros/vang/models"
+	service "github.com/red-gold/ts-serverless/micros/vang/services"
+)
+
+// Action types dispatched to the room members
+const (
+	setActiveRoomActionType = "SET_ACTIVE_ROOM"
+	updateRoomActionType    = "UPDATE_ROOM"
+	removeRoomActionType    = "REMOVE_ROOM"
+	editMessageActionType   = "EDIT_MESSAGE"
+	deleteMessageActionType = "DELETE_MESSAGE"
+	hideMessageActionType   = "HIDE_MESSAGE"
 )
 
 type Action struct {
@@ -23,130 +30,108 @@ type Action struct {
 	Payload interface{} `json:"payload"`
 }
 
-type UserInfoInReq struct {
-	UserId      uuid.UUID `json:"userId"`
-	Username    string    `json:"username"`
-	Avatar      string    `json:"avatar"`
-	DisplayName string    `json:"displayName"`
-	SystemRole  string    `json:"systemRole"`
-}
+// Dispatch action
+func dispatchAction(ctx context.Context, action Action, roomId uuid.UUID) {
 
-// getHeadersFromUserInfoReq
-func getHeadersFromUserInfoReq(info *UserInfoInReq) map[string][]string {
-	userHeaders := make(map[string][]string)
-	userHeaders["uid"] = []string{info.UserId.String()}
-	userHeaders["email"] = []string{info.Username}
-	userHeaders["avatar"] = []string{info.Avatar}
-	userHeaders["displayName"] = []string{info.DisplayName}
-	userHeaders["role"] = []string{info.SystemRole}
+	actionURL := fmt.Sprintf("/actions/dispatch/%s", roomId.String())
 
-	return userHeaders
-}
+	actionBytes, marshalErr := json.Marshal(action)
+	if marshalErr != nil {
+		errorMessage := fmt.Sprintf("Marshal notification Error %s", marshalErr.Error())
+		fmt.Println(errorMessage)
+	}
+	_, actionErr := rpc.Call(ctx, http.MethodPost, actionURL, actionBytes)
 
-// getUserInfoReq
-func getUserInfoReqFromCurrentUser(currentUser types.UserContext) *UserInfoInReq {
-	userInfoInReq := &UserInfoInReq{
-		UserId:      currentUser.UserID,
-		Username:    currentUser.Username,
-		Avatar:      currentUser.Avatar,
-		DisplayName: currentUser.DisplayName,
-		SystemRole:  currentUser.SystemRole,
+	if actionErr != nil {
+		errorMessage := fmt.Sprintf("Cannot send action request! error: %s", actionErr.Error())
+		fmt.Println(errorMessage)
 	}
-	return userInfoInReq
 }
 
-// getUserInfoReq
-func getUserInfoReq(c *fiber.Ctx) *UserInfoInReq {
-	currentUser, ok := c.Locals("user").(types.UserContext)
-	if !ok {
-		return &UserInfoInReq{}
+// dispatchRoomAction dispatch the action to every member of the room
+func dispatchRoomAction(ctx context.Context, action Action, memberIds []string) {
+	for _, memberId := range memberIds {
+		memberUUID, err := uuid.FromString(memberId)
+		if err != nil {
+			log.Error("[dispatchRoomAction] member id %s is not valid", memberId)
+			continue
+		}
+		dispatchAction(ctx, action, memberUUID)
 	}
-	return getUserInfoReqFromCurrentUser(currentUser)
-
 }
 
-// functionCall send request to another function/microservice using HMAC validation
-func functionCall(method string, bytesReq []byte, url string, header map[string][]string) ([]byte, error) {
-	prettyURL := utils.GetPrettyURLf(url)
-	bodyReader := bytes.NewBuffer(bytesReq)
+// dispatchMessageChange refresh the last message of the room and dispatch the changed message to the room members
+func dispatchMessageChange(ctx context.Context, messageService service.MessageService, roomService service.RoomService, actionType string, message *dto.Message) error {
 
-	httpReq, httpErr := http.NewRequest(method, *coreConfig.AppConfig.InternalGateway+prettyURL, bodyReader)
-	if httpErr != nil {
-		return nil, httpErr
+	lastMessage, err := messageService.FindLastRoomMessage(message.RoomId)
+	if err != nil {
+		return err
 	}
-
-	digest := hmac.Sign(bytesReq, []byte(*coreConfig.AppConfig.PayloadSecret))
-	httpReq.Header.Set("Content-type", "application/json")
-	fmt.Printf("\ndigest: %s, header: %v \n", "sha1="+hex.EncodeToString(digest), types.HeaderHMACAuthenticate)
-	httpReq.Header.Add(types.HeaderHMACAuthenticate, "sha1="+hex.EncodeToString(digest))
-
-	if header != nil {
-		for k, v := range header {
-			httpReq.Header[k] = v
-		}
+	if err := roomService.SetLastMessage(message.RoomId, lastMessage); err != nil {
+		return err
 	}
 
-	c := http.Client{}
-	res, reqErr := c.Do(httpReq)
-	fmt.Printf("\nRes: %v\n", res)
-	if reqErr != nil {
-		return nil, fmt.Errorf("Error while sending admin check request!: %s", reqErr.Error())
-	}
-	if res.Body != nil {
-		defer res.Body.Close()
+	room, err := roomService.FindById(message.RoomId)
+	if err != nil {
+		return err
 	}
-
-	resData, readErr := ioutil.ReadAll(res.Body)
-	if resData == nil || readErr != nil {
-		return nil, fmt.Errorf("failed to read response from admin check request.")
+	if room == nil {
+		return nil
 	}
 
-	if res.StatusCode != http.StatusAccepted && res.StatusCode != http.StatusOK {
-		if res.StatusCode == http.StatusNotFound {
-			return nil, NotFoundHTTPStatusError
-		}
-		return nil, fmt.Errorf("failed to call %s api, invalid status: %s", prettyURL, res.Status)
+	messageAction := Action{
+		Type:    actionType,
+		Payload: fiber.Map{"roomId": room.ObjectId, "message": message, "lastMessage": room.LastMessage},
 	}
-
-	return resData, nil
+	go dispatchRoomAction(ctx, messageAction, room.Members)
+	return nil
 }
 
-// Dispatch action
-func dispatchAction(action Action, roomId uuid.UUID, userInfoInReq *UserInfoInReq) {
-
-	actionURL := fmt.Sprintf("/actions/dispatch/%s", roomId.String())
-
-	actionBytes, marshalErr := json.Marshal(action)
-	if marshalErr != nil {
-		errorMessage := fmt.Sprintf("Marshal notification Error %s", marshalErr.Error())
-		fmt.Println(errorMessage)
-	}
-	// Create user headers for http request
-	userHeaders := make(map[string][]string)
-	userHeaders["uid"] = []string{userInfoInReq.UserId.String()}
-	userHeaders["email"] = []string{userInfoInReq.Username}
-	userHeaders["avatar"] = []string{userInfoInReq.Avatar}
-	userHeaders["displayName"] = []string{userInfoInReq.DisplayName}
-	userHeaders["role"] = []string{userInfoInReq.SystemRole}
-
-	_, actionErr := functionCall(http.MethodPost, actionBytes, actionURL, userHeaders)
+// newRoomReadMaps create the read date, read count and read message id of the room members
+func newRoomReadMaps(memberIds []string) (map[string]int64, map[string]int64, map[string]string) {
+	readDateMap := make(map[string]int64)
+	readCountMap := make(map[string]int64)
+	readMessageIdMap := make(map[string]string)
+	for _, memberId := range memberIds {
+		readDateMap[memberId] = 0
+		readCountMap[memberId] = 0
+		readMessageIdMap[memberId] = ""
+	}
+	return readDateMap, readCountMap, readMessageIdMap
+}
 
-	if actionErr != nil {
-		errorMessage := fmt.Sprintf("Cannot send action request! error: %s", actionErr.Error())
-		fmt.Println(errorMessage)
+// mapRoomModel map room DTO to room model
+func mapRoomModel(room *dto.Room) models.RoomModel {
+	return models.RoomModel{
+		ObjectId:      room.ObjectId,
+		Members:       room.Members,
+		Type:          room.Type,
+		Title:         room.Title,
+		Avatar:        room.Avatar,
+		OwnerUserId:   room.OwnerUserId,
+		Admins:        room.Admins,
+		ReadDate:      room.ReadDate,
+		ReadCount:     room.ReadCount,
+		ReadMessageId: room.ReadMessageId,
+		DeactiveUsers: room.DeactiveUsers,
+		LastMessage:   room.LastMessage,
+		MemberCount:   room.MemberCount,
+		MessageCount:  room.MessageCount,
+		CreatedDate:   room.CreatedDate,
+		UpdatedDate:   room.UpdatedDate,
 	}
 }
 
 // getUserProfileByID Get user profile by user ID
-func getUserProfileByID(userID uuid.UUID) (*models.UserProfileModel, error) {
+func getUserProfileByID(ctx context.Context, userID uuid.UUID) (*models.UserProfileModel, error) {
 	profileURL := fmt.Sprintf("/profile/dto/id/%s", userID.String())
-	foundProfileData, err := functionCall(http.MethodGet, []byte(""), profileURL, nil)
+	foundProfileData, err := rpc.Call(ctx, http.MethodGet, profileURL, []byte(""))
 	if err != nil {
-		if err == NotFoundHTTPStatusError {
+		if rpc.IsNotFound(err) {
 			return nil, nil
 		}
-		log.Error("functionCall (%s) -  %s", profileURL, err.Error())
-		return nil, fmt.Errorf("getUserProfileByID/functionCall")
+		log.Error("rpc.Call (%s) -  %s", profileURL, err.Error())
+		return nil, fmt.Errorf("getUserProfileByID/rpc")
 	}
 	var foundProfile models.UserProfileModel
 	err = json.Unmarshal(foundProfileData, &foundProfile)
@@ -158,15 +143,15 @@ func getUserProfileByID(userID uuid.UUID) (*models.UserProfileModel, error) {
 }
 
 // getProfileBySocialName Get user profile by social name
-func getProfileBySocialName(socialName string) (*models.UserProfileModel, error) {
+func getProfileBySocialName(ctx context.Context, socialName string) (*models.UserProfileModel, error) {
 	profileURL := fmt.Sprintf("/profile/social/%s", socialName)
-	foundProfileData, err := functionCall(http.MethodGet, []byte(""), profileURL, nil)
+	foundProfileData, err := rpc.Call(ctx, http.MethodGet, profileURL, []byte(""))
 	if err != nil {
-		if err == NotFoundHTTPStatusError {
+		if rpc.IsNotFound(err) {
 			return nil, nil
 		}
-		log.Error("functionCall (%s) -  %s", profileURL, err.Error())
-		return nil, fmt.Errorf("getProfileBySocialName/functionCall")
+		log.Error("rpc.Call (%s) -  %s", profileURL, err.Error())
+		return nil, fmt.Errorf("getProfileBySocialName/rpc")
 	}
 	var foundProfile models.UserProfileModel
 	err = json.Unmarshal(foundProfileData, &foundProfile)
@@ -177,8 +162,39 @@ func getProfileBySocialName(socialName string) (*models.UserProfileModel, error)
 	return &foundProfile, nil
 }
 
+// getUserRestrictions Get the users which the current user blocked, muted or is blocked by
+func getUserRestrictions(ctx context.Context) (*models.UserRestrictionsModel, error) {
+	restrictionsURL := "/user-rels/restrictions"
+	restrictionsData, err := rpc.Call(ctx, http.MethodGet, restrictionsURL, []byte(""))
+	if err != nil {
+		log.Error("rpc.Call (%s) -  %s", restrictionsURL, err.Error())
+		return nil, fmt.Errorf("getUserRestrictions/rpc")
+	}
+	var restrictions models.UserRestrictionsModel
+	err = json.Unmarshal(restrictionsData, &restrictions)
+	if err != nil {
+		log.Error("Unmarshal restrictions -  %s", err.Error())
+		return nil, fmt.Errorf("getUserRestrictions/unmarshal")
+	}
+	return &restrictions, nil
+}
+
+// isBlockedPeer check whether the current user blocked the peer user or is blocked by the peer user
+func isBlockedPeer(ctx context.Context, peerUserId string) (bool, error) {
+	restrictions, err := getUserRestrictions(ctx)
+	if err != nil {
+		return false, err
+	}
+	for _, blockedUserId := range restrictions.BlockedUserIds {
+		if blockedUserId.String() == peerUserId {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
 // getProfilesByUserIds Get user profiles by user IDs
-func getProfilesByUserIds(model models.GetProfilesModel, userInfoInReq *UserInfoInReq) ([]models.UserProfileModel, error) {
+func getProfilesByUserIds(ctx context.Context, model models.GetProfilesModel) ([]models.UserProfileModel, error) {
 	profileURL := "/profile/dto/ids"
 	body, marshalErr := json.Marshal(model)
 	if marshalErr != nil {
@@ -187,21 +203,13 @@ func getProfilesByUserIds(model models.GetProfilesModel, userInfoInReq *UserInfo
 		return nil, marshalErr
 	}
 
-	// Create user headers for http request
-	userHeaders := make(map[string][]string)
-	userHeaders["uid"] = []string{userInfoInReq.UserId.String()}
-	userHeaders["email"] = []string{userInfoInReq.Username}
-	userHeaders["avatar"] = []string{userInfoInReq.Avatar}
-	userHeaders["displayName"] = []string{userInfoInReq.DisplayName}
-	userHeaders["role"] = []string{userInfoInReq.SystemRole}
-
-	foundProfilesData, err := functionCall(http.MethodPost, body, profileURL, nil)
+	foundProfilesData, err := rpc.Call(ctx, http.MethodPost, profileURL, body)
 	if err != nil {
-		if err == NotFoundHTTPStatusError {
+		if rpc.IsNotFound(err) {
 			return nil, nil
 		}
-		log.Error("functionCall (%s) -  %s", profileURL, err.Error())
-		return nil, fmt.Errorf("getProfilesByUserIds/functionCall")
+		log.Error("rpc.Call (%s) -  %s", profileURL, err.Error())
+		return nil, fmt.Errorf("getProfilesByUserIds/rpc")
 	}
 	var foundProfile []models.UserProfileModel
 	err = json.Unmarshal(foundProfilesData, &foundProfile)
@@ -213,7 +221,7 @@ func getProfilesByUserIds(model models.GetProfilesModel, userInfoInReq *UserInfo
 }
 
 // dispatchProfileByUserIds Dispatch profile by user Ids
-func dispatchProfileByUserIds(model models.DispatchProfilesModel, userInfoInReq *UserInfoInReq) error {
+func dispatchProfileByUserIds(ctx context.Context, model models.DispatchProfilesModel) error {
 	profileURL := "/profile/dispatch"
 	body, marshalErr := json.Marshal(model)
 	if marshalErr != nil {
@@ -221,21 +229,13 @@ func dispatchProfileByUserIds(model models.DispatchProfilesModel, userInfoInReq
 		fmt.Println(errorMessage)
 	}
 
-	// Create user headers for http request
-	userHeaders := make(map[string][]string)
-	userHeaders["uid"] = []string{userInfoInReq.UserId.String()}
-	userHeaders["email"] = []string{userInfoInReq.Username}
-	userHeaders["avatar"] = []string{userInfoInReq.Avatar}
-	userHeaders["displayName"] = []string{userInfoInReq.DisplayName}
-	userHeaders["role"] = []string{userInfoInReq.SystemRole}
-
-	_, err := functionCall(http.MethodPost, body, profileURL, userHeaders)
+	_, err := rpc.Call(ctx, http.MethodPost, profileURL, body)
 	if err != nil {
-		if err == NotFoundHTTPStatusError {
+		if rpc.IsNotFound(err) {
 			return nil
 		}
-		log.Error("functionCall (%s) -  %s", profileURL, err.Error())
-		return fmt.Errorf("dispatchProfileByUserIds/functionCall")
+		log.Error("rpc.Call (%s) -  %s", profileURL, err.Error())
+		return fmt.Errorf("dispatchProfileByUserIds/rpc")
 	}
 	return nil
 }
@@ -9,11 +9,18 @@ import (
 	log "github.com/red-gold/telar-core/pkg/log"
 	"github.com/red-gold/telar-core/types"
 	"github.com/red-gold/telar-core/utils"
+	"github.com/red-gold/ts-serverless/micros/internal/rpc"
 	"github.com/red-gold/ts-serverless/micros/vang/database"
 	service "github.com/red-gold/ts-serverless/micros/vang/services"
 )
 
-// DeleteMessageHandle handle delete a Message
+// Delete scopes of a message
+const (
+	deleteScopeMe       = "me"
+	deleteScopeEveryone = "everyone"
+)
+
+// DeleteMessageHandle handle delete a message for the current user or a tombstone for everyone
 func DeleteMessageHandle(c *fiber.Ctx) error {
 
 	// params from /message/id/:messageId
@@ -31,6 +38,11 @@ func DeleteMessageHandle(c *fiber.Ctx) error {
 		return c.Status(http.StatusBadRequest).JSON(utils.Error("messageIdIsNotValid", "Message id is not valid!"))
 	}
 
+	scope := c.Query("scope", deleteScopeEveryone)
+	if scope != deleteScopeEveryone && scope != deleteScopeMe {
+		return c.Status(http.StatusBadRequest).JSON(utils.Error("deleteScopeIsNotValid", "Delete scope should be me or everyone!"))
+	}
+
 	// Create service
 	messageService, serviceErr := service.NewMessageService(database.Db)
 	if serviceErr != nil {
@@ -39,6 +51,12 @@ func DeleteMessageHandle(c *fiber.Ctx) error {
 
 	}
 
+	roomService, serviceErr := service.NewRoomService(database.Db)
+	if serviceErr != nil {
+		log.Error("NewRoomService %s", serviceErr.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/roomService", "Error happened while creating roomService!"))
+	}
+
 	currentUser, ok := c.Locals(types.UserCtxName).(types.UserContext)
 	if !ok {
 		log.Error("[DeleteMessageHandle] Can not get current user")
@@ -46,11 +64,59 @@ func DeleteMessageHandle(c *fiber.Ctx) error {
 			"Can not get current user"))
 	}
 
-	if err := messageService.DeleteMessageByOwner(currentUser.UserID, messageUUID); err != nil {
+	if scope == deleteScopeMe {
+		return hideMessageForUser(c, messageService, roomService, messageUUID, currentUser)
+	}
+
+	deletedMessage, err := messageService.DeleteMessageForEveryone(currentUser.UserID, messageUUID)
+	if err != nil {
 		errorMessage := fmt.Sprintf("Delete Message Error %s", err.Error())
 		log.Error(errorMessage)
 		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/deleteMessage", "Error happened while removing message!"))
 	}
+	if deletedMessage == nil {
+		return c.Status(http.StatusNotFound).JSON(utils.Error("messageNotFound", "Message not found!"))
+	}
+
+	if err := dispatchMessageChange(rpc.Context(c), messageService, roomService, deleteMessageActionType, deletedMessage); err != nil {
+		log.Error("[DeleteMessageHandle.dispatchMessageChange] %s", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/updateRoom", "Error happened while updating room!"))
+	}
+
+	return c.SendStatus(http.StatusOK)
+}
+
+// hideMessageForUser delete the message for the current user only and dispatch it to the current user
+func hideMessageForUser(c *fiber.Ctx, messageService service.MessageService, roomService service.RoomService, messageId uuid.UUID, currentUser types.UserContext) error {
+
+	message, err := messageService.FindById(messageId)
+	if err != nil {
+		log.Error("[hideMessageForUser.FindById] %s", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/findMessage", "Error happened while finding message!"))
+	}
+	if message == nil {
+		return c.Status(http.StatusNotFound).JSON(utils.Error("messageNotFound", "Message not found!"))
+	}
+
+	room, err := roomService.FindById(message.RoomId)
+	if err != nil {
+		log.Error("[hideMessageForUser.roomService.FindById] %s", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/findRoom", "Error happened while finding room!"))
+	}
+	if room == nil || !isRoomMember(room, currentUser.UserID) {
+		return c.Status(http.StatusNotFound).JSON(utils.Error("messageNotFound", "Message not found!"))
+	}
+
+	if err := messageService.HideMessageForUser(messageId, currentUser.UserID); err != nil {
+		log.Error("[hideMessageForUser.HideMessageForUser] %s", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/deleteMessage", "Error happened while removing message!"))
+	}
+
+	hideAction := Action{
+		Type:    hideMessageActionType,
+		Payload: fiber.Map{"roomId": room.ObjectId, "messageId": messageId},
+	}
+	go dispatchAction(rpc.Context(c), hideAction, currentUser.UserID)
 
 	return c.SendStatus(http.StatusOK)
 }
@@ -0,0 +1,488 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofrs/uuid"
+	log "github.com/red-gold/telar-core/pkg/log"
+	"github.com/red-gold/telar-core/types"
+	"github.com/red-gold/telar-core/utils"
+	"github.com/red-gold/ts-serverless/micros/internal/httperr"
+	"github.com/red-gold/ts-serverless/micros/internal/rpc"
+	"github.com/red-gold/ts-serverless/micros/vang/database"
+	"github.com/red-gold/ts-serverless/micros/vang/dto"
+	models "github.com/red-gold/ts-serverless/micros/vang/models"
+	service "github.com/red-gold/ts-serverless/micros/vang/services"
+)
+
+// CreateGroupRoomHandle handle create a multiple room
+func CreateGroupRoomHandle(c *fiber.Ctx) error {
+
+	// Create the model object
+	model := new(models.CreateGroupRoomModel)
+	if err := c.BodyParser(model); err != nil {
+		errorMessage := fmt.Sprintf("Parse CreateGroupRoomModel Error %s", err.Error())
+		log.Error(errorMessage)
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/parseModel", "Error happened while parsing model!"))
+	}
+
+	if model.Title == "" {
+		return c.Status(http.StatusBadRequest).JSON(utils.Error("titleIsRequired", "Room title is required!"))
+	}
+
+	currentUser, ok := c.Locals(types.UserCtxName).(types.UserContext)
+	if !ok {
+		log.Error("[CreateGroupRoomHandle] Can not get current user")
+		return c.Status(http.StatusBadRequest).JSON(utils.Error("invalidCurrentUser",
+			"Can not get current user"))
+	}
+
+	roomMemberIds := []string{currentUser.UserID.String()}
+	roomMemberIds = appendNewMembers(roomMemberIds, model.Members)
+	if len(roomMemberIds) < 2 {
+		return c.Status(http.StatusBadRequest).JSON(utils.Error("membersAreRequired", "Room members are required!"))
+	}
+
+	// Create service
+	roomService, serviceErr := service.NewRoomService(database.Db)
+	if serviceErr != nil {
+		log.Error("NewRoomService %s", serviceErr.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/roomService", "Error happened while creating roomService!"))
+	}
+
+	readDateMap, readCountMap, readMessageIdMap := newRoomReadMaps(roomMemberIds)
+	newRoom := dto.Room{
+		ObjectId:      uuid.Must(uuid.NewV4()),
+		Members:       roomMemberIds,
+		Type:          service.RoomTypeMultiple,
+		Title:         model.Title,
+		Avatar:        model.Avatar,
+		OwnerUserId:   currentUser.UserID,
+		Admins:        []string{currentUser.UserID.String()},
+		ReadDate:      readDateMap,
+		ReadCount:     readCountMap,
+		ReadMessageId: readMessageIdMap,
+		DeactiveUsers: []string{},
+		LastMessage:   make(map[string]interface{}),
+		MemberCount:   int64(len(roomMemberIds)),
+		MessageCount:  0,
+		CreatedDate:   utils.UTCNowUnix(),
+		UpdatedDate:   utils.UTCNowUnix(),
+	}
+	if err := roomService.SaveRoom(&newRoom); err != nil {
+		errorMessage := fmt.Sprintf("Vang save room %s", err.Error())
+		log.Error(errorMessage)
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/saveRoom", "Error happened while saving room!"))
+	}
+
+	ctx := rpc.Context(c)
+	roomModel := mapRoomModel(&newRoom)
+
+	participantsProfile, err := getRoomMembersProfile(ctx, roomMemberIds)
+	if err != nil {
+		log.Error("[CreateGroupRoomHandle] Error while getting participants profile %s", err.Error())
+	}
+
+	activeRoomAction := Action{
+		Type: setActiveRoomActionType,
+		Payload: &SetActiveRoomPayload{
+			Room:  roomModel,
+			Users: participantsProfile,
+		},
+	}
+	if model.ResponseActionType != "" {
+		activeRoomAction.Type = model.ResponseActionType
+	}
+	go func() {
+		dispatchAction(ctx, activeRoomAction, currentUser.UserID)
+		dispatchRoomAction(ctx, Action{Type: updateRoomActionType, Payload: roomModel}, roomMemberIds[1:])
+	}()
+
+	return c.JSON(roomModel)
+}
+
+// UpdateGroupRoomHandle handle rename a multiple room and change its avatar
+func UpdateGroupRoomHandle(c *fiber.Ctx) error {
+
+	// Create the model object
+	model := new(models.UpdateRoomProfileModel)
+	if err := c.BodyParser(model); err != nil {
+		errorMessage := fmt.Sprintf("Parse UpdateRoomProfileModel Error %s", err.Error())
+		log.Error(errorMessage)
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/parseModel", "Error happened while parsing model!"))
+	}
+
+	if model.Title == "" {
+		return c.Status(http.StatusBadRequest).JSON(utils.Error("titleIsRequired", "Room title is required!"))
+	}
+
+	currentUser, ok := c.Locals(types.UserCtxName).(types.UserContext)
+	if !ok {
+		log.Error("[UpdateGroupRoomHandle] Can not get current user")
+		return c.Status(http.StatusBadRequest).JSON(utils.Error("invalidCurrentUser",
+			"Can not get current user"))
+	}
+
+	// Create service
+	roomService, serviceErr := service.NewRoomService(database.Db)
+	if serviceErr != nil {
+		log.Error("NewRoomService %s", serviceErr.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/roomService", "Error happened while creating roomService!"))
+	}
+
+	room, err := getGroupRoom(c, roomService)
+	if err != nil {
+		return httperr.Respond(c, err)
+	}
+
+	if !isRoomAdmin(room, currentUser.UserID) {
+		return c.Status(http.StatusForbidden).JSON(utils.Error("notRoomAdmin", "Only room admins can update the room!"))
+	}
+
+	if err := roomService.UpdateRoomProfile(room.ObjectId, model.Title, model.Avatar); err != nil {
+		log.Error("[UpdateGroupRoomHandle.UpdateRoomProfile] %s", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/updateRoom", "Error happened while updating room!"))
+	}
+
+	return dispatchUpdatedRoom(c, roomService, room.ObjectId)
+}
+
+// AddRoomMembersHandle handle add members to a multiple room
+func AddRoomMembersHandle(c *fiber.Ctx) error {
+
+	// Create the model object
+	model := new(models.RoomMembersModel)
+	if err := c.BodyParser(model); err != nil {
+		errorMessage := fmt.Sprintf("Parse RoomMembersModel Error %s", err.Error())
+		log.Error(errorMessage)
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/parseModel", "Error happened while parsing model!"))
+	}
+
+	currentUser, ok := c.Locals(types.UserCtxName).(types.UserContext)
+	if !ok {
+		log.Error("[AddRoomMembersHandle] Can not get current user")
+		return c.Status(http.StatusBadRequest).JSON(utils.Error("invalidCurrentUser",
+			"Can not get current user"))
+	}
+
+	// Create service
+	roomService, serviceErr := service.NewRoomService(database.Db)
+	if serviceErr != nil {
+		log.Error("NewRoomService %s", serviceErr.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/roomService", "Error happened while creating roomService!"))
+	}
+
+	room, err := getGroupRoom(c, roomService)
+	if err != nil {
+		return httperr.Respond(c, err)
+	}
+
+	if !isRoomAdmin(room, currentUser.UserID) {
+		return c.Status(http.StatusForbidden).JSON(utils.Error("notRoomAdmin", "Only room admins can add members!"))
+	}
+
+	newMemberIds := appendNewMembers(append([]string{}, room.Members...), model.Members)[len(room.Members):]
+	if len(newMemberIds) == 0 {
+		return c.JSON(mapRoomModel(room))
+	}
+
+	added, err := roomService.AddRoomMembers(room.ObjectId, newMemberIds)
+	if err != nil {
+		log.Error("[AddRoomMembersHandle.AddRoomMembers] %s", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/updateRoom", "Error happened while updating room!"))
+	}
+	if !added {
+		return c.Status(http.StatusConflict).JSON(utils.Error("roomMembersChanged", "Room members were changed by another request!"))
+	}
+
+	return dispatchUpdatedRoom(c, roomService, room.ObjectId)
+}
+
+// RemoveRoomMemberHandle handle remove a member from a multiple room
+func RemoveRoomMemberHandle(c *fiber.Ctx) error {
+
+	userId := c.Params("userId")
+	memberUUID, uuidErr := uuid.FromString(userId)
+	if uuidErr != nil {
+		errorMessage := fmt.Sprintf("Parse user UUID Error %s", uuidErr.Error())
+		log.Error(errorMessage)
+		return c.Status(http.StatusBadRequest).JSON(utils.Error("invalidUserId", "Invalid userId!"))
+	}
+
+	currentUser, ok := c.Locals(types.UserCtxName).(types.UserContext)
+	if !ok {
+		log.Error("[RemoveRoomMemberHandle] Can not get current user")
+		return c.Status(http.StatusBadRequest).JSON(utils.Error("invalidCurrentUser",
+			"Can not get current user"))
+	}
+
+	// Create service
+	roomService, serviceErr := service.NewRoomService(database.Db)
+	if serviceErr != nil {
+		log.Error("NewRoomService %s", serviceErr.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/roomService", "Error happened while creating roomService!"))
+	}
+
+	room, err := getGroupRoom(c, roomService)
+	if err != nil {
+		return httperr.Respond(c, err)
+	}
+
+	if !isRoomAdmin(room, currentUser.UserID) {
+		return c.Status(http.StatusForbidden).JSON(utils.Error("notRoomAdmin", "Only room admins can remove members!"))
+	}
+
+	// The owner leaves the room by itself and only the owner can remove an admin
+	if memberUUID == room.OwnerUserId {
+		return c.Status(http.StatusForbidden).JSON(utils.Error("cannotRemoveOwner", "Room owner can not be removed!"))
+	}
+	if isRoomAdmin(room, memberUUID) && currentUser.UserID != room.OwnerUserId {
+		return c.Status(http.StatusForbidden).JSON(utils.Error("notRoomOwner", "Only room owner can remove an admin!"))
+	}
+
+	return removeRoomMember(c, roomService, room, memberUUID, currentUser)
+}
+
+// LeaveRoomHandle handle leave a multiple room
+func LeaveRoomHandle(c *fiber.Ctx) error {
+
+	currentUser, ok := c.Locals(types.UserCtxName).(types.UserContext)
+	if !ok {
+		log.Error("[LeaveRoomHandle] Can not get current user")
+		return c.Status(http.StatusBadRequest).JSON(utils.Error("invalidCurrentUser",
+			"Can not get current user"))
+	}
+
+	// Create service
+	roomService, serviceErr := service.NewRoomService(database.Db)
+	if serviceErr != nil {
+		log.Error("NewRoomService %s", serviceErr.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/roomService", "Error happened while creating roomService!"))
+	}
+
+	room, err := getGroupRoom(c, roomService)
+	if err != nil {
+		return httperr.Respond(c, err)
+	}
+
+	if !isRoomMember(room, currentUser.UserID) {
+		return c.Status(http.StatusForbidden).JSON(utils.Error("notRoomMember", "User is not a member of the room!"))
+	}
+
+	// The last member removes the room
+	if len(room.Members) == 1 {
+		filter := struct {
+			ObjectId uuid.UUID `json:"objectId" bson:"objectId"`
+		}{
+			ObjectId: room.ObjectId,
+		}
+		if err := roomService.DeleteRoom(filter); err != nil {
+			log.Error("[LeaveRoomHandle.DeleteRoom] %s", err.Error())
+			return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/deleteRoom", "Error happened while removing room!"))
+		}
+		return c.SendStatus(http.StatusOK)
+	}
+
+	// The owner hands the room to an admin, or to the oldest member if there is no other admin
+	if currentUser.UserID == room.OwnerUserId {
+		newOwnerId := nextRoomOwner(room)
+		if err := roomService.TransferRoomOwner(room.ObjectId, room.OwnerUserId, newOwnerId); err != nil {
+			log.Error("[LeaveRoomHandle.TransferRoomOwner] %s", err.Error())
+			return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/updateRoom", "Error happened while updating room!"))
+		}
+	}
+
+	return removeRoomMember(c, roomService, room, currentUser.UserID, currentUser)
+}
+
+// SetRoomAdminHandle handle add a member to the admins of a multiple room or remove the member from the admins
+func SetRoomAdminHandle(c *fiber.Ctx) error {
+
+	// Create the model object
+	model := new(models.RoomAdminModel)
+	if err := c.BodyParser(model); err != nil {
+		errorMessage := fmt.Sprintf("Parse RoomAdminModel Error %s", err.Error())
+		log.Error(errorMessage)
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/parseModel", "Error happened while parsing model!"))
+	}
+
+	currentUser, ok := c.Locals(types.UserCtxName).(types.UserContext)
+	if !ok {
+		log.Error("[SetRoomAdminHandle] Can not get current user")
+		return c.Status(http.StatusBadRequest).JSON(utils.Error("invalidCurrentUser",
+			"Can not get current user"))
+	}
+
+	// Create service
+	roomService, serviceErr := service.NewRoomService(database.Db)
+	if serviceErr != nil {
+		log.Error("NewRoomService %s", serviceErr.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/roomService", "Error happened while creating roomService!"))
+	}
+
+	room, err := getGroupRoom(c, roomService)
+	if err != nil {
+		return httperr.Respond(c, err)
+	}
+
+	if currentUser.UserID != room.OwnerUserId {
+		return c.Status(http.StatusForbidden).JSON(utils.Error("notRoomOwner", "Only room owner can change the admins!"))
+	}
+	if model.UserId == room.OwnerUserId {
+		return c.Status(http.StatusBadRequest).JSON(utils.Error("cannotChangeOwner", "Room owner is always an admin!"))
+	}
+	if !isRoomMember(room, model.UserId) {
+		return c.Status(http.StatusBadRequest).JSON(utils.Error("notRoomMember", "User is not a member of the room!"))
+	}
+
+	if err := roomService.SetRoomAdmin(room.ObjectId, model.UserId.String(), model.Admin); err != nil {
+		log.Error("[SetRoomAdminHandle.SetRoomAdmin] %s", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/updateRoom", "Error happened while updating room!"))
+	}
+
+	return dispatchUpdatedRoom(c, roomService, room.ObjectId)
+}
+
+// getGroupRoom find the multiple room of the roomId param, the error is not found if the room is not a group room
+func getGroupRoom(c *fiber.Ctx, roomService service.RoomService) (*dto.Room, error) {
+
+	roomUUID, uuidErr := uuid.FromString(c.Params("roomId"))
+	if uuidErr != nil {
+		errorMessage := fmt.Sprintf("Parse room UUID Error %s", uuidErr.Error())
+		log.Error(errorMessage)
+		return nil, httperr.New(http.StatusBadRequest, "invalidRoomId", "Invalid roomId!")
+	}
+
+	room, err := roomService.FindById(roomUUID)
+	if err != nil {
+		log.Error("[getGroupRoom.FindById] %s", err.Error())
+		return nil, httperr.New(http.StatusInternalServerError, "internal/findRoom", "Error happened while finding room!")
+	}
+	if room == nil || room.Type != service.RoomTypeMultiple {
+		return nil, httperr.New(http.StatusNotFound, "roomNotFound", "Room not found!")
+	}
+	return room, nil
+}
+
+// removeRoomMember remove the member and dispatch the room to the members
+func removeRoomMember(c *fiber.Ctx, roomService service.RoomService, room *dto.Room, memberId uuid.UUID, currentUser types.UserContext) error {
+
+	removed, err := roomService.RemoveRoomMember(room.ObjectId, memberId.String())
+	if err != nil {
+		log.Error("[removeRoomMember.RemoveRoomMember] %s", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/updateRoom", "Error happened while updating room!"))
+	}
+	if !removed {
+		return c.Status(http.StatusNotFound).JSON(utils.Error("notRoomMember", "User is not a member of the room!"))
+	}
+
+	removeAction := Action{
+		Type:    removeRoomActionType,
+		Payload: fiber.Map{"roomId": room.ObjectId},
+	}
+	go dispatchAction(rpc.Context(c), removeAction, memberId)
+
+	return dispatchUpdatedRoom(c, roomService, room.ObjectId)
+}
+
+// dispatchUpdatedRoom read the room and dispatch it to every member
+func dispatchUpdatedRoom(c *fiber.Ctx, roomService service.RoomService, roomId uuid.UUID) error {
+
+	room, err := roomService.FindById(roomId)
+	if err != nil {
+		log.Error("[dispatchUpdatedRoom.FindById] %s", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/findRoom", "Error happened while finding room!"))
+	}
+	if room == nil {
+		return c.Status(http.StatusNotFound).JSON(utils.Error("roomNotFound", "Room not found!"))
+	}
+
+	roomModel := mapRoomModel(room)
+	updateAction := Action{
+		Type:    updateRoomActionType,
+		Payload: roomModel,
+	}
+	go dispatchRoomAction(rpc.Context(c), updateAction, room.Members)
+
+	return c.JSON(roomModel)
+}
+
+// getRoomMembersProfile get the profile of the room members mapped by user id
+func getRoomMembersProfile(ctx context.Context, memberIds []string) (map[string]interface{}, error) {
+	mappedParticipants := make(map[string]interface{})
+
+	profiles, err := getProfilesByUserIds(ctx, models.GetProfilesModel{UserIds: memberIds})
+	if err != nil {
+		return mappedParticipants, err
+	}
+
+	for _, profile := range profiles {
+		mappedUser := make(map[string]interface{})
+		mappedUser["userId"] = profile.ObjectId
+		mappedUser["fullName"] = profile.FullName
+		mappedUser["socialName"] = profile.SocialName
+		mappedUser["avatar"] = profile.Avatar
+		mappedUser["banner"] = profile.Banner
+		mappedUser["tagLine"] = profile.TagLine
+		mappedUser["lastSeen"] = profile.LastSeen
+		mappedUser["createdDate"] = profile.CreatedDate
+		mappedParticipants[profile.ObjectId.String()] = mappedUser
+	}
+	return mappedParticipants, nil
+}
+
+// appendNewMembers append the user ids which are not in the members
+func appendNewMembers(memberIds []string, userIds []uuid.UUID) []string {
+	encountered := make(map[string]bool)
+	for _, memberId := range memberIds {
+		encountered[memberId] = true
+	}
+	for _, userId := range userIds {
+		if userId == uuid.Nil || encountered[userId.String()] {
+			continue
+		}
+		encountered[userId.String()] = true
+		memberIds = append(memberIds, userId.String())
+	}
+	return memberIds
+}
+
+// nextRoomOwner find the member who owns the room after the owner leaves
+func nextRoomOwner(room *dto.Room) uuid.UUID {
+	ownerId := room.OwnerUserId.String()
+	candidates := append(append([]string{}, room.Admins...), room.Members...)
+	for _, memberId := range candidates {
+		memberUUID, err := uuid.FromString(memberId)
+		if err != nil || memberId == ownerId || !isRoomMember(room, memberUUID) {
+			continue
+		}
+		return memberUUID
+	}
+	return uuid.Nil
+}
+
+// isRoomMember check whether the user is a member of the room
+func isRoomMember(room *dto.Room, userId uuid.UUID) bool {
+	for _, memberId := range room.Members {
+		if memberId == userId.String() {
+			return true
+		}
+	}
+	return false
+}
+
+// isRoomAdmin check whether the user is the owner or an admin of the room
+func isRoomAdmin(room *dto.Room, userId uuid.UUID) bool {
+	if room.OwnerUserId == userId {
+		return true
+	}
+	for _, adminId := range room.Admins {
+		if adminId == userId.String() {
+			return true
+		}
+	}
+	return false
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 
@@ -9,6 +10,7 @@ import (
 	log "github.com/red-gold/telar-core/pkg/log"
 	"github.com/red-gold/telar-core/types"
 	"github.com/red-gold/telar-core/utils"
+	"github.com/red-gold/ts-serverless/micros/internal/rpc"
 	"github.com/red-gold/ts-serverless/micros/vang/database"
 	"github.com/red-gold/ts-serverless/micros/vang/dto"
 	models "github.com/red-gold/ts-serverless/micros/vang/models"
@@ -39,20 +41,31 @@ func ActivePeerRoom(c *fiber.Ctx) error {
 	}
 
 	// Get the participants profile
-	participantsProfile, roomMemberIds, err := getParticipantsProfile(&currentUser, model)
+	participantsProfile, roomMemberIds, err := getParticipantsProfile(rpc.Context(c), &currentUser, model)
 	if err != nil {
 		log.Error("[ActivePeerRoom] Error while getting participants profile %s", err.Error())
 		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/getParticipantsProfile",
 			"Error while getting participants profile"))
 	}
 
+	// A room is not opened between users while one of them blocks the other
+	blocked, err := isBlockedPeer(rpc.Context(c), roomMemberIds[1])
+	if err != nil {
+		log.Error("[ActivePeerRoom.isBlockedPeer] %s", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/getUserRestrictions",
+			"Error while getting user restrictions"))
+	}
+	if blocked {
+		return c.Status(http.StatusForbidden).JSON(utils.Error("userBlocked", "You can not message this user!"))
+	}
+
 	// Create service
 	roomService, serviceErr := service.NewRoomService(database.Db)
 	if serviceErr != nil {
 		log.Error("NewRoomService %s", serviceErr.Error())
 		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/roomService", "Error happened while creating roomService!"))
 	}
-	room, findRoomErr := roomService.FindOneRoomByMembers(roomMemberIds, 0)
+	room, findRoomErr := roomService.FindOneRoomByMembers(roomMemberIds, service.RoomTypePeer)
 	if findRoomErr != nil {
 		errorMessage := fmt.Sprintf("Vang find room %s", findRoomErr.Error())
 		log.Error(errorMessage)
@@ -60,23 +73,13 @@ func ActivePeerRoom(c *fiber.Ctx) error {
 	}
 
 	if room == nil {
-		readDateMap := make(map[string]int64)
-		readDateMap[roomMemberIds[0]] = 0
-		readDateMap[roomMemberIds[1]] = 0
-
-		readCountMap := make(map[string]int64)
-		readCountMap[roomMemberIds[0]] = 0
-		readCountMap[roomMemberIds[1]] = 0
-
-		readMessageIdMap := make(map[string]string)
-		readMessageIdMap[roomMemberIds[0]] = ""
-		readMessageIdMap[roomMemberIds[1]] = ""
+		readDateMap, readCountMap, readMessageIdMap := newRoomReadMaps(roomMemberIds)
 
 		lastMessageMap := make(map[string]interface{})
 		newRoom := dto.Room{
 			ObjectId:      uuid.Must(uuid.NewV4()),
 			Members:       roomMemberIds,
-			Type:          0,
+			Type:          service.RoomTypePeer,
 			ReadDate:      readDateMap,
 			ReadCount:     readCountMap,
 			ReadMessageId: readMessageIdMap,
@@ -96,20 +99,7 @@ func ActivePeerRoom(c *fiber.Ctx) error {
 		room = &newRoom
 	}
 
-	roomModel := models.RoomModel{
-		ObjectId:      room.ObjectId,
-		Members:       room.Members,
-		Type:          room.Type,
-		ReadDate:      room.ReadDate,
-		ReadCount:     room.ReadCount,
-		ReadMessageId: room.ReadMessageId,
-		DeactiveUsers: room.DeactiveUsers,
-		LastMessage:   room.LastMessage,
-		MemberCount:   room.MemberCount,
-		MessageCount:  room.MessageCount,
-		CreatedDate:   room.CreatedDate,
-		UpdatedDate:   room.UpdatedDate,
-	}
+	roomModel := mapRoomModel(room)
 
 	actionRoomPayload := &SetActiveRoomPayload{
 		Room:  roomModel,
@@ -117,19 +107,19 @@ func ActivePeerRoom(c *fiber.Ctx) error {
 	}
 
 	activeRoomAction := Action{
-		Type:    "SET_ACTIVE_ROOM",
+		Type:    setActiveRoomActionType,
 		Payload: actionRoomPayload,
 	}
 
 	if model.ResponseActionType != "" {
 		activeRoomAction.Type = model.ResponseActionType
 	}
-	go dispatchAction(activeRoomAction, currentUser.UserID, getUserInfoReqFromCurrentUser(currentUser))
+	go dispatchAction(rpc.Context(c), activeRoomAction, currentUser.UserID)
 	return c.JSON(roomModel)
 }
 
 // getParticipantsProfile
-func getParticipantsProfile(currentUser *types.UserContext, model *models.ActivePeerRoomModel) (map[string]interface{}, []string, error) {
+func getParticipantsProfile(ctx context.Context, currentUser *types.UserContext, model *models.ActivePeerRoomModel) (map[string]interface{}, []string, error) {
 
 	mappedParticipants := make(map[string]interface{})
 
@@ -144,13 +134,13 @@ func getParticipantsProfile(currentUser *types.UserContext, model *models.Active
 
 	var err error
 	if model.SocialName != "" {
-		receptionist, err = getProfileBySocialName(model.SocialName)
+		receptionist, err = getProfileBySocialName(ctx, model.SocialName)
 		if err != nil {
 			return nil, nil, fmt.Errorf("Get user profile by social name %s", err.Error())
 		}
 
 	} else {
-		receptionist, err = getUserProfileByID(model.PeerUserId)
+		receptionist, err = getUserProfileByID(ctx, model.PeerUserId)
 		if err != nil {
 			return nil, nil, fmt.Errorf("Get user profile by ID %s", err.Error())
 		}
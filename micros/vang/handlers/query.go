@@ -9,11 +9,32 @@ import (
 	"github.com/red-gold/telar-core/pkg/log"
 	"github.com/red-gold/telar-core/types"
 	utils "github.com/red-gold/telar-core/utils"
+	"github.com/red-gold/ts-serverless/micros/internal/cursor"
+	"github.com/red-gold/ts-serverless/micros/internal/rpc"
 	"github.com/red-gold/ts-serverless/micros/vang/database"
+	dto "github.com/red-gold/ts-serverless/micros/vang/dto"
 	models "github.com/red-gold/ts-serverless/micros/vang/models"
 	service "github.com/red-gold/ts-serverless/micros/vang/services"
 )
 
+// newMessagePage create the page of messages with the cursors of the next and previous pages
+func newMessagePage(vangList []dto.Message, limit int64, readBefore bool) models.MessagePageModel {
+	page := models.MessagePageModel{
+		Messages: []dto.Message{},
+	}
+	if len(vangList) == 0 {
+		return page
+	}
+	page.Messages = vangList
+
+	positions := make([]cursor.Model, 0, len(vangList))
+	for _, item := range vangList {
+		positions = append(positions, cursor.Model{CreatedDate: item.CreatedDate, ObjectId: item.ObjectId})
+	}
+	page.NextCursor, page.PrevCursor = cursor.PageCursors(positions, limit, readBefore)
+	return page
+}
+
 // QueryMessagesHandle handle query on vang
 func QueryMessagesHandle(c *fiber.Ctx) error {
 
@@ -52,13 +73,38 @@ func QueryMessagesHandle(c *fiber.Ctx) error {
 
 	}
 
-	vangList, err := vangService.GetMessageByRoomId(&model.RoomId, "createdDate", model.Page, model.Lte, model.Gte)
+	// Numbered pages are kept for the clients which do not use cursors
+	if model.Page > 0 || (model.Limit == 0 && model.After == "" && model.Before == "") {
+		vangList, err := vangService.GetMessageByRoomId(&model.RoomId, currentUser.UserID, "createdDate", model.Page, model.Lte, model.Gte)
+		if err != nil {
+			log.Error("[QueryMessagesHandle.vangService.GetMessageByRoomId] %s", err.Error())
+			return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/getMessages", "Error happened while reading messages!"))
+		}
+
+		return c.JSON(vangList)
+	}
+
+	if model.Limit < 0 || model.Limit > cursor.MaxPageLimit {
+		return c.Status(http.StatusBadRequest).JSON(utils.Error("limitIsNotValid", fmt.Sprintf("Limit should be between 1 and %d!", cursor.MaxPageLimit)))
+	}
+
+	after, before, err := cursor.ReadPage(model.After, model.Before)
+	if err != nil {
+		log.Error("[QueryMessagesHandle.readPageCursors] %s", err.Error())
+		return c.Status(http.StatusBadRequest).JSON(utils.Error("cursorIsNotValid", "Cursor is not valid!"))
+	}
+
+	limit := model.Limit
+	if limit == 0 {
+		limit = cursor.DefaultPageLimit
+	}
+	vangList, err := vangService.GetMessageByRoomIdByCursor(&model.RoomId, currentUser.UserID, after, before, limit)
 	if err != nil {
-		log.Error("[QueryMessagesHandle.vangService.GetMessageByRoomId] %s", err.Error())
+		log.Error("[QueryMessagesHandle.vangService.GetMessageByRoomIdByCursor] %s ", err.Error())
 		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/getMessages", "Error happened while reading messages!"))
 	}
 
-	return c.JSON(vangList)
+	return c.JSON(newMessagePage(vangList, limit, before != nil))
 }
 
 // GetActiveRoomHandle handle get an active room
@@ -117,7 +163,7 @@ func GetUserRooms(c *fiber.Ctx) error {
 		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/roomService", "Error happened while creating roomService!"))
 	}
 
-	rooms, findRoomErr := roomService.GetRoomsByUserId(model.UserId.String(), 0)
+	rooms, findRoomErr := roomService.GetRoomsByUserId(model.UserId.String(), []int8{service.RoomTypePeer, service.RoomTypeMultiple})
 	if findRoomErr != nil {
 		log.Error("[GetUserRooms.roomService.GetRoomsByUserId] %s", findRoomErr.Error())
 		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/findRoom", "Error happened while finding room!"))
@@ -144,6 +190,10 @@ func GetUserRooms(c *fiber.Ctx) error {
 		mappedRoom["objectId"] = roomId
 		mappedRoom["members"] = v.Members
 		mappedRoom["type"] = v.Type
+		mappedRoom["title"] = v.Title
+		mappedRoom["avatar"] = v.Avatar
+		mappedRoom["ownerUserId"] = v.OwnerUserId
+		mappedRoom["admins"] = v.Admins
 		mappedRoom["readDate"] = v.ReadDate
 		mappedRoom["readCount"] = v.ReadCount
 		mappedRoom["readMessageId"] = v.ReadMessageId
@@ -158,7 +208,7 @@ func GetUserRooms(c *fiber.Ctx) error {
 		resRooms.RoomIds = append(resRooms.RoomIds, roomId)
 
 		// Merge members into a single array
-		for _, v := range v.Members[:2] {
+		for _, v := range v.Members {
 			if encountered[v] != true {
 				encountered[v] = true
 				allMembers = append(allMembers, v)
@@ -178,14 +228,7 @@ func GetUserRooms(c *fiber.Ctx) error {
 			"Can not get current user"))
 	}
 	log.Info("[GetUserRooms] Current USER %v ", currentUser)
-	userInfoInReq := &UserInfoInReq{
-		UserId:      currentUser.UserID,
-		Username:    currentUser.Username,
-		Avatar:      currentUser.Avatar,
-		DisplayName: currentUser.DisplayName,
-		SystemRole:  currentUser.SystemRole,
-	}
-	go dispatchProfileByUserIds(dispatchProfileModel, userInfoInReq)
+	go dispatchProfileByUserIds(rpc.Context(c), dispatchProfileModel)
 
 	return c.JSON(resRooms)
 }
@@ -9,13 +9,13 @@ import (
 	"github.com/red-gold/telar-core/pkg/log"
 	"github.com/red-gold/telar-core/types"
 	"github.com/red-gold/telar-core/utils"
+	"github.com/red-gold/ts-serverless/micros/internal/rpc"
 	"github.com/red-gold/ts-serverless/micros/vang/database"
-	domain "github.com/red-gold/ts-serverless/micros/vang/dto"
 	models "github.com/red-gold/ts-serverless/micros/vang/models"
 	service "github.com/red-gold/ts-serverless/micros/vang/services"
 )
 
-// UpdateMessageHandle handle create a new vang
+// UpdateMessageHandle handle edit the text of a message and keep the previous text in the edit history
 func UpdateMessageHandle(c *fiber.Ctx) error {
 
 	// Create the model object
@@ -26,6 +26,10 @@ func UpdateMessageHandle(c *fiber.Ctx) error {
 		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/parseModel", "Error happened while parsing model!"))
 	}
 
+	if model.Text == "" {
+		return c.Status(http.StatusBadRequest).JSON(utils.Error("textIsRequired", "Message text is required!"))
+	}
+
 	// Create service
 	messageService, serviceErr := service.NewMessageService(database.Db)
 	if serviceErr != nil {
@@ -33,6 +37,12 @@ func UpdateMessageHandle(c *fiber.Ctx) error {
 		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/messageService", "Error happened while creating messageService!"))
 	}
 
+	roomService, serviceErr := service.NewRoomService(database.Db)
+	if serviceErr != nil {
+		log.Error("NewRoomService %s", serviceErr.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/roomService", "Error happened while creating roomService!"))
+	}
+
 	currentUser, ok := c.Locals(types.UserCtxName).(types.UserContext)
 	if !ok {
 		log.Error("[UpdateMessageHandle] Can not get current user")
@@ -40,21 +50,22 @@ func UpdateMessageHandle(c *fiber.Ctx) error {
 			"Can not get current user"))
 	}
 
-	updatedMessage := &domain.Message{
-		ObjectId:    model.ObjectId,
-		OwnerUserId: currentUser.UserID,
-		RoomId:      model.RoomId,
-		Text:        model.Text,
-		CreatedDate: model.CreatedDate,
-		UpdatedDate: model.UpdatedDate,
-	}
-
-	if err := messageService.UpdateMessageById(updatedMessage); err != nil {
+	updatedMessage, err := messageService.EditMessage(currentUser.UserID, model.ObjectId, model.Text)
+	if err != nil {
 		errorMessage := fmt.Sprintf("Update Message Error %s", err.Error())
 		log.Error(errorMessage)
 		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/updateMessage", "Error happened while updating message!"))
 	}
-	return c.SendStatus(http.StatusOK)
+	if updatedMessage == nil {
+		return c.Status(http.StatusNotFound).JSON(utils.Error("messageNotFound", "Message not found!"))
+	}
+
+	if err := dispatchMessageChange(rpc.Context(c), messageService, roomService, editMessageActionType, updatedMessage); err != nil {
+		log.Error("[UpdateMessageHandle.dispatchMessageChange] %s", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/updateRoom", "Error happened while updating room!"))
+	}
+
+	return c.JSON(updatedMessage)
 }
 
 // UpdateMessageHandle handle create a new vang
@@ -82,7 +93,7 @@ func UpdateReadMessageHandle(c *fiber.Ctx) error {
 			"Can not get current user"))
 	}
 
-	if err := roomService.UpdateMemberRead(model.RoomId, currentUser.UserID, model.Amount, model.MessageCreatedDate); err != nil {
+	if err := roomService.UpdateMemberRead(model.RoomId, currentUser.UserID, model.Amount, model.MessageCreatedDate, model.MessageId); err != nil {
 		errorMessage := fmt.Sprintf("Update Message Error %s", err.Error())
 		log.Error(errorMessage)
 		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/updateMessage", "Error happened while updating message!"))
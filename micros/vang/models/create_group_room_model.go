@@ -0,0 +1,10 @@
+package models
+
+import uuid "github.com/gofrs/uuid"
+
+type CreateGroupRoomModel struct {
+	Title              string      `json:"title" bson:"title"`
+	Avatar             string      `json:"avatar" bson:"avatar"`
+	Members            []uuid.UUID `json:"members" bson:"members"`
+	ResponseActionType string      `json:"responseActionType" bson:"responseActionType"`
+}
@@ -0,0 +1,9 @@
+package models
+
+import dto "github.com/red-gold/ts-serverless/micros/vang/dto"
+
+type MessagePageModel struct {
+	Messages   []dto.Message `json:"messages"`
+	NextCursor string        `json:"nextCursor"`
+	PrevCursor string        `json:"prevCursor"`
+}
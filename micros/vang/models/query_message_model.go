@@ -10,4 +10,7 @@ type QueryMessageModel struct {
 	Page      int64     `json:"page" bson:"page"`
 	Lte       int64     `json:"lte" bson:"lte"`
 	Gte       int64     `json:"gte" bson:"gte"`
+	Limit     int64     `json:"limit" bson:"limit"`
+	After     string    `json:"after" bson:"after"`
+	Before    string    `json:"before" bson:"before"`
 }
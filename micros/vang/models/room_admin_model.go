@@ -0,0 +1,8 @@
+package models
+
+import uuid "github.com/gofrs/uuid"
+
+type RoomAdminModel struct {
+	UserId uuid.UUID `json:"userId" bson:"userId"`
+	Admin  bool      `json:"admin" bson:"admin"`
+}
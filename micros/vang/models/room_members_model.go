@@ -0,0 +1,7 @@
+package models
+
+import uuid "github.com/gofrs/uuid"
+
+type RoomMembersModel struct {
+	Members []uuid.UUID `json:"members" bson:"members"`
+}
@@ -8,6 +8,10 @@ type RoomModel struct {
 	ObjectId      uuid.UUID              `json:"objectId" bson:"objectId"`
 	Members       []string               `json:"members" bson:"members"`
 	Type          int8                   `json:"type" bson:"type"`                   // {0: peer, 1: multiple}
+	Title         string                 `json:"title" bson:"title"`                 // Title of a multiple room
+	Avatar        string                 `json:"avatar" bson:"avatar"`               // Avatar of a multiple room
+	OwnerUserId   uuid.UUID              `json:"ownerUserId" bson:"ownerUserId"`     // Owner of a multiple room
+	Admins        []string               `json:"admins" bson:"admins"`               // ['userId1'] admins of a multiple room
 	ReadDate      map[string]int64       `json:"readDate" bson:"readDate"`           // {'userId1': last_seen_date_time, 'userId2': last_seen_date_time}
 	ReadCount     map[string]int64       `json:"readCount" bson:"readCount"`         // {'userId1': read_count, 'userId2': read_count}
 	ReadMessageId map[string]string      `json:"readMessageId" bson:"readMessageId"` // {'userId1': 'message_id_234', 'userId2': 'message_id_2323'}
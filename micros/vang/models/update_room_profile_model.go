@@ -0,0 +1,6 @@
+package models
+
+type UpdateRoomProfileModel struct {
+	Title  string `json:"title" bson:"title"`
+	Avatar string `json:"avatar" bson:"avatar"`
+}
@@ -0,0 +1,10 @@
+package models
+
+import uuid "github.com/gofrs/uuid"
+
+// UserRestrictionsModel the users which are hidden from a user.
+// Blocked users are blocked by the user or have blocked the user, muted users are muted by the user.
+type UserRestrictionsModel struct {
+	BlockedUserIds []uuid.UUID `json:"blockedUserIds"`
+	MutedUserIds   []uuid.UUID `json:"mutedUserIds"`
+}
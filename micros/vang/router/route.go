@@ -11,7 +11,10 @@ import (
 	"github.com/red-gold/telar-core/middleware/authcookie"
 	"github.com/red-gold/telar-core/middleware/authhmac"
 	"github.com/red-gold/telar-core/types"
+	"github.com/red-gold/ts-serverless/micros/internal/index"
+	"github.com/red-gold/ts-serverless/micros/vang/database"
 	"github.com/red-gold/ts-serverless/micros/vang/handlers"
+	service "github.com/red-gold/ts-serverless/micros/vang/services"
 )
 
 // SetupRoutes func
@@ -53,14 +56,27 @@ func SetupRoutes(app *fiber.App) {
 	hmacCookieHandlers := []func(*fiber.Ctx) error{authHMACMiddleware(true), authCookieMiddleware(true)}
 
 	// Routers
+	app.Post("/index", authHMACMiddleware(false), index.InitHandle(newIndexService))
+	app.Get("/index", authHMACMiddleware(false), index.MissingHandle(newIndexService))
 	app.Post("/messages", append(hmacCookieHandlers, handlers.SaveMessages)...)
 	app.Post("/message/query", append(hmacCookieHandlers, handlers.QueryMessagesHandle)...)
 	app.Put("/message", append(hmacCookieHandlers, handlers.UpdateMessageHandle)...)
 	app.Put("/room/deactive/:roomId", authCookieMiddleware(false), handlers.DeactiveUserRoomHandle)
 	app.Delete("/message/:messageId", append(hmacCookieHandlers, handlers.DeleteMessageHandle)...)
 	app.Post("/room/active", append(hmacCookieHandlers, handlers.ActivePeerRoom)...)
+	app.Post("/room/group", append(hmacCookieHandlers, handlers.CreateGroupRoomHandle)...)
+	app.Put("/room/group/:roomId", append(hmacCookieHandlers, handlers.UpdateGroupRoomHandle)...)
+	app.Post("/room/group/:roomId/members", append(hmacCookieHandlers, handlers.AddRoomMembersHandle)...)
+	app.Delete("/room/group/:roomId/members/:userId", append(hmacCookieHandlers, handlers.RemoveRoomMemberHandle)...)
+	app.Put("/room/group/:roomId/admins", append(hmacCookieHandlers, handlers.SetRoomAdminHandle)...)
+	app.Post("/room/group/:roomId/leave", append(hmacCookieHandlers, handlers.LeaveRoomHandle)...)
 
 	app.Get("/active-room/:roomId", append(hmacCookieHandlers, handlers.GetActiveRoomHandle)...)
 	app.Post("/rooms", authHMACMiddleware(false), handlers.GetUserRooms)
 	app.Put("/read", authHMACMiddleware(false), handlers.UpdateReadMessageHandle)
 }
+
+// newIndexService create the index service of the function
+func newIndexService() (index.Service, error) {
+	return service.NewIndexService(database.Db)
+}
@@ -3,6 +3,7 @@ package service
 import (
 	uuid "github.com/gofrs/uuid"
 	coreData "github.com/red-gold/telar-core/data"
+	"github.com/red-gold/ts-serverless/micros/internal/cursor"
 	dto "github.com/red-gold/ts-serverless/micros/vang/dto"
 )
 
@@ -11,6 +12,7 @@ type MessageService interface {
 	SaveManyMessages(messages []dto.Message) error
 	FindOneMessage(filter interface{}) (*dto.Message, error)
 	FindMessageList(filter interface{}, limit int64, skip int64, sort map[string]int) ([]dto.Message, error)
+	FindMessageListByCursor(filter map[string]interface{}, after *cursor.Model, before *cursor.Model, limit int64) ([]dto.Message, error)
 	FindById(objectId uuid.UUID) (*dto.Message, error)
 	FindByOwnerUserId(ownerUserId uuid.UUID) ([]dto.Message, error)
 	UpdateMessage(filter interface{}, data interface{}, opts ...*coreData.UpdateOptions) error
@@ -19,6 +21,11 @@ type MessageService interface {
 	DeleteMessageByOwner(ownerUserId uuid.UUID, vangId uuid.UUID) error
 	DeleteManyMessage(filter interface{}) error
 	CreateMessageIndex(indexes map[string]interface{}) error
-	GetMessageByRoomId(roomId *uuid.UUID, sortBy string, page int64, lteDate int64, gteDate int64) ([]dto.Message, error)
+	GetMessageByRoomId(roomId *uuid.UUID, viewerId uuid.UUID, sortBy string, page int64, lteDate int64, gteDate int64) ([]dto.Message, error)
+	GetMessageByRoomIdByCursor(roomId *uuid.UUID, viewerId uuid.UUID, after *cursor.Model, before *cursor.Model, limit int64) ([]dto.Message, error)
+	EditMessage(ownerUserId uuid.UUID, messageId uuid.UUID, text string) (*dto.Message, error)
+	DeleteMessageForEveryone(ownerUserId uuid.UUID, messageId uuid.UUID) (*dto.Message, error)
+	HideMessageForUser(messageId uuid.UUID, userId uuid.UUID) error
+	FindLastRoomMessage(roomId uuid.UUID) (*dto.Message, error)
 	DeleteMessageByRoomId(ownerUserId uuid.UUID, roomId uuid.UUID) error
 }
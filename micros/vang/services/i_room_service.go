@@ -21,10 +21,16 @@ type RoomService interface {
 	GetPeerRoom(roomId uuid.UUID, members []string, DeactivePeerId uuid.UUID) (*dto.Room, error)
 	DeleteRoomByRoomId(ownerUserId uuid.UUID, roomId uuid.UUID) error
 	FindOneRoomByMembers(userIds []string, roomType int8) (*dto.Room, error)
-	GetRoomsByUserId(userId string, roomType int8) ([]dto.Room, error)
+	GetRoomsByUserId(userId string, roomTypes []int8) ([]dto.Room, error)
 	UpdateMessageMeta(roomId uuid.UUID, amount, createdDate int64, text, ownerId string) error
-	UpdateMemberRead(roomId uuid.UUID, userId uuid.UUID, amount, messageCreatedDate int64) error
+	UpdateMemberRead(roomId uuid.UUID, userId uuid.UUID, amount, messageCreatedDate int64, messageId uuid.UUID) error
 	DeactiveUserRoom(roomId uuid.UUID, userId uuid.UUID) error
 	ActiveAllPeerRoom(roomId uuid.UUID, members []string, deactivePeerId uuid.UUID) error
 	GetActiveRoom(roomId uuid.UUID, members []string) (*dto.Room, error)
+	AddRoomMembers(roomId uuid.UUID, memberIds []string) (bool, error)
+	RemoveRoomMember(roomId uuid.UUID, memberId string) (bool, error)
+	SetRoomAdmin(roomId uuid.UUID, memberId string, admin bool) error
+	TransferRoomOwner(roomId uuid.UUID, ownerUserId uuid.UUID, newOwnerId uuid.UUID) error
+	UpdateRoomProfile(roomId uuid.UUID, title string, avatar string) error
+	SetLastMessage(roomId uuid.UUID, message *dto.Message) error
 }
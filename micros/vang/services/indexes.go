@@ -0,0 +1,16 @@
+package service
+
+import (
+	"github.com/red-gold/ts-serverless/micros/internal/index"
+)
+
+// requiredIndexes are the indexes which the vang function needs
+var requiredIndexes = []index.Model{
+	index.New(vangMessageCollectionName, false, "roomId", 1, "createdDate", 1),
+	index.New(vangRoomCollectionName, false, "members", 1),
+}
+
+// NewIndexService create the index service on the required indexes of the function
+func NewIndexService(db interface{}) (index.Service, error) {
+	return index.NewService(db, requiredIndexes)
+}
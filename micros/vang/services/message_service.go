@@ -10,6 +10,8 @@ import (
 	"github.com/red-gold/telar-core/data/mongodb"
 	mongoRepo "github.com/red-gold/telar-core/data/mongodb"
 	"github.com/red-gold/telar-core/utils"
+	"github.com/red-gold/ts-serverless/micros/internal/cursor"
+	"github.com/red-gold/ts-serverless/micros/internal/inmemory"
 	dto "github.com/red-gold/ts-serverless/micros/vang/dto"
 )
 
@@ -29,6 +31,10 @@ func NewMessageService(db interface{}) (MessageService, error) {
 		mongodb := db.(mongodb.MongoDatabase)
 		messageService.MessageRepo = mongoRepo.NewDataRepositoryMongo(mongodb)
 
+	case config.DB_INMEMORY:
+
+		messageService.MessageRepo = inmemory.NewDataRepositoryInMemory(db.(*inmemory.Database))
+
 	}
 
 	return messageService, nil
@@ -216,14 +222,56 @@ func (s MessageServiceImpl) CreateMessageIndex(indexes map[string]interface{}) e
 	return result
 }
 
+// FindMessageListByCursor get the messages by filter in the page around the cursors
+func (s MessageServiceImpl) FindMessageListByCursor(filter map[string]interface{}, after *cursor.Model, before *cursor.Model, limit int64) ([]dto.Message, error) {
+	var pipeline []interface{}
+
+	sort := cursor.AddFilter(filter, "createdDate", after, before)
+
+	matchOperator := make(map[string]interface{})
+	matchOperator["$match"] = filter
+
+	sortOperator := make(map[string]interface{})
+	sortOperator["$sort"] = sort
+
+	limitOperator := make(map[string]interface{})
+	limitOperator["$limit"] = limit
+
+	pipeline = append(pipeline, matchOperator, sortOperator, limitOperator)
+
+	result := <-s.MessageRepo.Aggregate(vangMessageCollectionName, pipeline)
+	defer result.Close()
+	if result.Error() != nil {
+		return nil, result.Error()
+	}
+	var messageList []dto.Message
+	for result.Next() {
+		var message dto.Message
+		errDecode := result.Decode(&message)
+		if errDecode != nil {
+			return nil, fmt.Errorf("Error docoding on dto.Message")
+		}
+		messageList = append(messageList, message)
+	}
+
+	// The page before the cursor is read in reverse order
+	if before != nil {
+		for i, j := 0, len(messageList)-1; i < j; i, j = i+1, j-1 {
+			messageList[i], messageList[j] = messageList[j], messageList[i]
+		}
+	}
+
+	return messageList, nil
+}
+
 // GetMessageByRoomId get all message by room ID
-func (s MessageServiceImpl) GetMessageByRoomId(roomId *uuid.UUID, sortBy string, page int64, lteDate int64, gteDate int64) ([]dto.Message, error) {
+func (s MessageServiceImpl) GetMessageByRoomId(roomId *uuid.UUID, viewerId uuid.UUID, sortBy string, page int64, lteDate int64, gteDate int64) ([]dto.Message, error) {
 	sortMap := make(map[string]int)
 	sortMap[sortBy] = -1
 	skip := numberOfItems * (page - 1)
 	limit := numberOfItems
 
-	filter := make(map[string]interface{})
+	filter := visibleMessageFilter(viewerId)
 
 	if roomId != nil {
 		filter["roomId"] = *roomId
@@ -246,6 +294,21 @@ func (s MessageServiceImpl) GetMessageByRoomId(roomId *uuid.UUID, sortBy string,
 	return result, err
 }
 
+// GetMessageByRoomIdByCursor get the messages of the room in the page around the cursors
+func (s MessageServiceImpl) GetMessageByRoomIdByCursor(roomId *uuid.UUID, viewerId uuid.UUID, after *cursor.Model, before *cursor.Model, limit int64) ([]dto.Message, error) {
+	if limit <= 0 {
+		limit = numberOfItems
+	}
+
+	filter := visibleMessageFilter(viewerId)
+
+	if roomId != nil {
+		filter["roomId"] = *roomId
+	}
+
+	return s.FindMessageListByCursor(filter, after, before, limit)
+}
+
 // DeleteMessageByRoomId delete message by room id
 func (s MessageServiceImpl) DeleteMessageByRoomId(ownerUserId uuid.UUID, roomId uuid.UUID) error {
 
@@ -262,3 +325,163 @@ func (s MessageServiceImpl) DeleteMessageByRoomId(ownerUserId uuid.UUID, roomId
 	}
 	return nil
 }
+
+// visibleMessageFilter create the filter of the messages which are not deleted by the viewer for themselves
+func visibleMessageFilter(viewerId uuid.UUID) map[string]interface{} {
+	notHidden := make(map[string]interface{})
+	notHidden["$ne"] = viewerId.String()
+
+	filter := make(map[string]interface{})
+	filter["hiddenFor"] = notHidden
+	return filter
+}
+
+// updateOneMessage update a message and return the number of modified messages
+func (s MessageServiceImpl) updateOneMessage(filter interface{}, data interface{}) (int64, error) {
+
+	result := <-s.MessageRepo.Update(vangMessageCollectionName, filter, data)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	modifiedCount, _ := result.Result.(int64)
+	return modifiedCount, nil
+}
+
+// EditMessage change the text of the owner message and keep the previous text in the edit history.
+// It returns nil if the message is not found, deleted or changed by another request.
+func (s MessageServiceImpl) EditMessage(ownerUserId uuid.UUID, messageId uuid.UUID, text string) (*dto.Message, error) {
+
+	message, err := s.FindById(messageId)
+	if err != nil {
+		return nil, err
+	}
+	if message == nil || message.OwnerUserId != ownerUserId || message.Deleted {
+		return nil, nil
+	}
+
+	now := utils.UTCNowUnix()
+	previousText := dto.MessageEdit{
+		Text:       message.Text,
+		EditedDate: now,
+	}
+
+	push := make(map[string]interface{})
+	push["editHistory"] = previousText
+
+	setData := make(map[string]interface{})
+	setData["text"] = text
+	setData["edited"] = true
+	setData["updatedDate"] = now
+
+	data := make(map[string]interface{})
+	data["$set"] = setData
+	data["$push"] = push
+
+	// The text in the filter keeps a concurrent edit from being lost in the history
+	notDeleted := make(map[string]interface{})
+	notDeleted["$ne"] = true
+
+	filter := make(map[string]interface{})
+	filter["objectId"] = messageId
+	filter["ownerUserId"] = ownerUserId
+	filter["text"] = message.Text
+	filter["deleted"] = notDeleted
+
+	modifiedCount, err := s.updateOneMessage(filter, data)
+	if err != nil {
+		return nil, err
+	}
+	if modifiedCount == 0 {
+		return nil, nil
+	}
+
+	message.EditHistory = append(message.EditHistory, previousText)
+	message.Text = text
+	message.Edited = true
+	message.UpdatedDate = now
+	return message, nil
+}
+
+// DeleteMessageForEveryone replace the owner message with a tombstone, the text and the edit history are removed.
+// It returns nil if the message is not found or already deleted.
+func (s MessageServiceImpl) DeleteMessageForEveryone(ownerUserId uuid.UUID, messageId uuid.UUID) (*dto.Message, error) {
+
+	message, err := s.FindById(messageId)
+	if err != nil {
+		return nil, err
+	}
+	if message == nil || message.OwnerUserId != ownerUserId || message.Deleted {
+		return nil, nil
+	}
+
+	now := utils.UTCNowUnix()
+	setData := make(map[string]interface{})
+	setData["text"] = ""
+	setData["editHistory"] = []dto.MessageEdit{}
+	setData["deleted"] = true
+	setData["deletedDate"] = now
+	setData["updatedDate"] = now
+
+	data := make(map[string]interface{})
+	data["$set"] = setData
+
+	notDeleted := make(map[string]interface{})
+	notDeleted["$ne"] = true
+
+	filter := make(map[string]interface{})
+	filter["objectId"] = messageId
+	filter["ownerUserId"] = ownerUserId
+	filter["deleted"] = notDeleted
+
+	modifiedCount, err := s.updateOneMessage(filter, data)
+	if err != nil {
+		return nil, err
+	}
+	if modifiedCount == 0 {
+		return nil, nil
+	}
+
+	message.Text = ""
+	message.EditHistory = []dto.MessageEdit{}
+	message.Deleted = true
+	message.DeletedDate = now
+	message.UpdatedDate = now
+	return message, nil
+}
+
+// HideMessageForUser delete the message for the user only
+func (s MessageServiceImpl) HideMessageForUser(messageId uuid.UUID, userId uuid.UUID) error {
+
+	addToSet := make(map[string]interface{})
+	addToSet["hiddenFor"] = userId.String()
+
+	data := make(map[string]interface{})
+	data["$addToSet"] = addToSet
+
+	filter := make(map[string]interface{})
+	filter["objectId"] = messageId
+
+	return s.UpdateMessage(filter, data)
+}
+
+// FindLastRoomMessage find the last message of the room which is not deleted for everyone
+func (s MessageServiceImpl) FindLastRoomMessage(roomId uuid.UUID) (*dto.Message, error) {
+	sortMap := make(map[string]int)
+	sortMap["createdDate"] = -1
+
+	notDeleted := make(map[string]interface{})
+	notDeleted["$ne"] = true
+
+	filter := make(map[string]interface{})
+	filter["roomId"] = roomId
+	filter["deleted"] = notDeleted
+
+	messageList, err := s.FindMessageList(filter, 1, 0, sortMap)
+	if err != nil {
+		return nil, err
+	}
+	if len(messageList) == 0 {
+		return nil, nil
+	}
+	return &messageList[0], nil
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/red-gold/telar-core/data/mongodb"
 	mongoRepo "github.com/red-gold/telar-core/data/mongodb"
 	"github.com/red-gold/telar-core/utils"
+	"github.com/red-gold/ts-serverless/micros/internal/inmemory"
 	dto "github.com/red-gold/ts-serverless/micros/vang/dto"
 )
 
@@ -29,6 +30,10 @@ func NewRoomService(db interface{}) (RoomService, error) {
 		mongodb := db.(mongodb.MongoDatabase)
 		roomService.RoomRepo = mongoRepo.NewDataRepositoryMongo(mongodb)
 
+	case config.DB_INMEMORY:
+
+		roomService.RoomRepo = inmemory.NewDataRepositoryInMemory(db.(*inmemory.Database))
+
 	}
 
 	return roomService, nil
@@ -240,7 +245,7 @@ func (s RoomServiceImpl) FindOneRoomByMembers(userIds []string, roomType int8) (
 }
 
 // GetRoomsByUserId Get rooms by user ID
-func (s RoomServiceImpl) GetRoomsByUserId(userId string, roomType int8) ([]dto.Room, error) {
+func (s RoomServiceImpl) GetRoomsByUserId(userId string, roomTypes []int8) ([]dto.Room, error) {
 	sortMap := make(map[string]int)
 	sortMap["updatedDate"] = -1
 
@@ -250,9 +255,12 @@ func (s RoomServiceImpl) GetRoomsByUserId(userId string, roomType int8) ([]dto.R
 	nin := make(map[string]interface{})
 	nin["$nin"] = []string{userId}
 
+	inTypes := make(map[string]interface{})
+	inTypes["$in"] = roomTypes
+
 	filter := make(map[string]interface{})
 	filter["members"] = include
-	filter["type"] = roomType
+	filter["type"] = inTypes
 	filter["deactiveUsers"] = nin
 
 	return s.FindRoomList(filter, 0, 0, sortMap)
@@ -283,11 +291,7 @@ func (s RoomServiceImpl) UpdateMessageMeta(roomId uuid.UUID, amount, createdDate
 	data["$inc"] = increase
 
 	setData := make(map[string]interface{})
-	lastMessage := make(map[string]interface{})
-	lastMessage["text"] = text
-	lastMessage["ownerId"] = ownerId
-	lastMessage["createdDate"] = createdDate
-	setData["lastMessage"] = lastMessage
+	setData["lastMessage"] = newLastMessage(text, ownerId, createdDate)
 	data["$set"] = setData
 
 	filter := make(map[string]interface{})
@@ -299,7 +303,7 @@ func (s RoomServiceImpl) UpdateMessageMeta(roomId uuid.UUID, amount, createdDate
 }
 
 // UpdateMemberRead Increase member read count and read member date to now
-func (s RoomServiceImpl) UpdateMemberRead(roomId uuid.UUID, userId uuid.UUID, amount, messageCreatedDate int64) error {
+func (s RoomServiceImpl) UpdateMemberRead(roomId uuid.UUID, userId uuid.UUID, amount, messageCreatedDate int64, messageId uuid.UUID) error {
 
 	readCountField := fmt.Sprintf("readCount.%s", userId.String())
 	readDateField := fmt.Sprintf("readDate.%s", userId.String())
@@ -308,6 +312,9 @@ func (s RoomServiceImpl) UpdateMemberRead(roomId uuid.UUID, userId uuid.UUID, am
 	data := make(map[string]interface{})
 	setData[readDateField] = messageCreatedDate
 	setData[readCountField] = amount
+	if messageId != uuid.Nil {
+		setData[fmt.Sprintf("readMessageId.%s", userId.String())] = messageId.String()
+	}
 	data["$set"] = setData
 	filter := make(map[string]interface{})
 	filter["objectId"] = roomId
@@ -389,3 +396,176 @@ func (s RoomServiceImpl) GetActiveRoom(roomId uuid.UUID, members []string) (*dto
 
 	return s.FindOneRoom(filter)
 }
+
+// updateOneRoom update a room and return the number of modified rooms
+func (s RoomServiceImpl) updateOneRoom(filter interface{}, data interface{}) (int64, error) {
+
+	result := <-s.RoomRepo.Update(vangRoomCollectionName, filter, data)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	modifiedCount, _ := result.Result.(int64)
+	return modifiedCount, nil
+}
+
+// AddRoomMembers add the members to a multiple room with their read state.
+// It returns false if one of the members is already in the room.
+func (s RoomServiceImpl) AddRoomMembers(roomId uuid.UUID, memberIds []string) (bool, error) {
+
+	each := make(map[string]interface{})
+	each["$each"] = memberIds
+	push := make(map[string]interface{})
+	push["members"] = each
+
+	setData := make(map[string]interface{})
+	for _, memberId := range memberIds {
+		setData[fmt.Sprintf("readDate.%s", memberId)] = 0
+		setData[fmt.Sprintf("readCount.%s", memberId)] = 0
+		setData[fmt.Sprintf("readMessageId.%s", memberId)] = ""
+	}
+	setData["updatedDate"] = utils.UTCNowUnix()
+
+	increase := make(map[string]interface{})
+	increase["memberCount"] = len(memberIds)
+
+	data := make(map[string]interface{})
+	data["$push"] = push
+	data["$set"] = setData
+	data["$inc"] = increase
+
+	// filters
+	nin := make(map[string]interface{})
+	nin["$nin"] = memberIds
+
+	filter := make(map[string]interface{})
+	filter["objectId"] = roomId
+	filter["type"] = RoomTypeMultiple
+	filter["members"] = nin
+
+	modifiedCount, err := s.updateOneRoom(filter, data)
+	return modifiedCount > 0, err
+}
+
+// RemoveRoomMember remove a member and the read state of the member from a multiple room.
+// It returns false if the user is not a member of the room.
+func (s RoomServiceImpl) RemoveRoomMember(roomId uuid.UUID, memberId string) (bool, error) {
+
+	pull := make(map[string]interface{})
+	pull["members"] = memberId
+	pull["admins"] = memberId
+	pull["deactiveUsers"] = memberId
+
+	unsetData := make(map[string]interface{})
+	unsetData[fmt.Sprintf("readDate.%s", memberId)] = ""
+	unsetData[fmt.Sprintf("readCount.%s", memberId)] = ""
+	unsetData[fmt.Sprintf("readMessageId.%s", memberId)] = ""
+
+	setData := make(map[string]interface{})
+	setData["updatedDate"] = utils.UTCNowUnix()
+
+	increase := make(map[string]interface{})
+	increase["memberCount"] = -1
+
+	data := make(map[string]interface{})
+	data["$pull"] = pull
+	data["$unset"] = unsetData
+	data["$set"] = setData
+	data["$inc"] = increase
+
+	filter := make(map[string]interface{})
+	filter["objectId"] = roomId
+	filter["type"] = RoomTypeMultiple
+	filter["members"] = memberId
+
+	modifiedCount, err := s.updateOneRoom(filter, data)
+	return modifiedCount > 0, err
+}
+
+// SetRoomAdmin add a member to the admins of a multiple room or remove the member from the admins
+func (s RoomServiceImpl) SetRoomAdmin(roomId uuid.UUID, memberId string, admin bool) error {
+
+	admins := make(map[string]interface{})
+	admins["admins"] = memberId
+
+	data := make(map[string]interface{})
+	if admin {
+		data["$addToSet"] = admins
+	} else {
+		data["$pull"] = admins
+	}
+
+	filter := make(map[string]interface{})
+	filter["objectId"] = roomId
+	filter["type"] = RoomTypeMultiple
+	filter["members"] = memberId
+
+	return s.UpdateRoom(filter, data)
+}
+
+// TransferRoomOwner set a member as the owner of a multiple room, the new owner is an admin too
+func (s RoomServiceImpl) TransferRoomOwner(roomId uuid.UUID, ownerUserId uuid.UUID, newOwnerId uuid.UUID) error {
+
+	setData := make(map[string]interface{})
+	setData["ownerUserId"] = newOwnerId
+
+	admins := make(map[string]interface{})
+	admins["admins"] = newOwnerId.String()
+
+	data := make(map[string]interface{})
+	data["$set"] = setData
+	data["$addToSet"] = admins
+
+	filter := make(map[string]interface{})
+	filter["objectId"] = roomId
+	filter["type"] = RoomTypeMultiple
+	filter["ownerUserId"] = ownerUserId
+
+	return s.UpdateRoom(filter, data)
+}
+
+// UpdateRoomProfile update title and avatar of a multiple room
+func (s RoomServiceImpl) UpdateRoomProfile(roomId uuid.UUID, title string, avatar string) error {
+
+	setData := make(map[string]interface{})
+	setData["title"] = title
+	setData["avatar"] = avatar
+	setData["updatedDate"] = utils.UTCNowUnix()
+
+	data := make(map[string]interface{})
+	data["$set"] = setData
+
+	filter := make(map[string]interface{})
+	filter["objectId"] = roomId
+	filter["type"] = RoomTypeMultiple
+
+	return s.UpdateRoom(filter, data)
+}
+
+// newLastMessage create the last message of a room
+func newLastMessage(text, ownerId string, createdDate int64) map[string]interface{} {
+	lastMessage := make(map[string]interface{})
+	lastMessage["text"] = text
+	lastMessage["ownerId"] = ownerId
+	lastMessage["createdDate"] = createdDate
+	return lastMessage
+}
+
+// SetLastMessage set the last message of the room, an empty last message is set if the message is nil
+func (s RoomServiceImpl) SetLastMessage(roomId uuid.UUID, message *dto.Message) error {
+
+	lastMessage := make(map[string]interface{})
+	if message != nil {
+		lastMessage = newLastMessage(message.Text, message.OwnerUserId.String(), message.CreatedDate)
+	}
+
+	setData := make(map[string]interface{})
+	setData["lastMessage"] = lastMessage
+
+	data := make(map[string]interface{})
+	data["$set"] = setData
+
+	filter := make(map[string]interface{})
+	filter["objectId"] = roomId
+
+	return s.UpdateRoom(filter, data)
+}
@@ -5,3 +5,9 @@ const (
 	vangRoomCollectionName          = "vangRooms"
 	numberOfItems             int64 = 10
 )
+
+// Room types
+const (
+	RoomTypePeer     int8 = 0
+	RoomTypeMultiple int8 = 1
+)
@@ -10,6 +10,8 @@ type Vote struct {
 	OwnerDisplayName string    `json:"ownerDisplayName" bson:"ownerDisplayName"`
 	OwnerAvatar      string    `json:"ownerAvatar" bson:"ownerAvatar"`
 	PostId           uuid.UUID `json:"postId" bson:"postId"`
+	PostOwnerUserId  uuid.UUID `json:"postOwnerUserId" bson:"postOwnerUserId"`
 	TypeId           int       `json:"type" bson:"type"`
 	CreatedDate      int64     `json:"created_date" bson:"created_date"`
+	PostDeleted      bool      `json:"postDeleted" bson:"postDeleted"`
 }
@@ -13,8 +13,11 @@ import (
 	"github.com/red-gold/telar-core/config"
 	"github.com/red-gold/telar-core/pkg/log"
 	micros "github.com/red-gold/ts-serverless/micros"
+	"github.com/red-gold/ts-serverless/micros/internal/index"
+	"github.com/red-gold/ts-serverless/micros/internal/outbox"
 	"github.com/red-gold/ts-serverless/micros/votes/database"
 	"github.com/red-gold/ts-serverless/micros/votes/router"
+	service "github.com/red-gold/ts-serverless/micros/votes/services"
 )
 
 // Cache state
@@ -52,9 +55,44 @@ func Handle(w http.ResponseWriter, r *http.Request) {
 			log.Error("Error startup: %s", startErr.Error())
 			w.WriteHeader(http.StatusInternalServerError)
 			w.Write([]byte(startErr.Error()))
+		} else {
+			go reconcileIndexes()
+			go outbox.RunDispatcher(ctx, newOutboxService, outbox.DispatchInterval)
 		}
 	}
 
 	adaptor.FiberApp(app)(w, r)
 
 }
+
+// reconcileIndexes remove the duplicate votes and create the indexes which the function needs and are missing in the database.
+// Votes are refused until the unique index of the votes is created.
+func reconcileIndexes() {
+	voteService, err := service.NewVoteService(database.Db)
+	if err != nil {
+		log.Error("NewVoteService %s", err.Error())
+		return
+	}
+	removed, err := voteService.RemoveDuplicateVotes()
+	if err != nil {
+		log.Error("Error remove duplicate votes, votes are refused until the unique vote index is created: %s", err.Error())
+		return
+	}
+	if removed > 0 {
+		log.Info("%d duplicate votes are removed", removed)
+	}
+
+	if err := index.Reconcile(newIndexService); err != nil {
+		log.Error("Votes are refused until the unique vote index is created")
+	}
+}
+
+// newIndexService create the index service of the function for the reconciler
+func newIndexService() (index.Service, error) {
+	return service.NewIndexService(database.Db)
+}
+
+// newOutboxService create the outbox service of the function for the dispatcher
+func newOutboxService() (outbox.Service, error) {
+	return service.NewOutboxService(database.Db)
+}
@@ -1,19 +1,17 @@
 package handlers
 
 import (
-	"bytes"
-	"encoding/hex"
+	"context"
+	"encoding/json"
 	"fmt"
-	"io/ioutil"
 	"net/http"
 
-	"github.com/alexellis/hmac"
 	"github.com/gofiber/fiber/v2"
 	uuid "github.com/gofrs/uuid"
-	coreConfig "github.com/red-gold/telar-core/config"
 	"github.com/red-gold/telar-core/pkg/log"
 	"github.com/red-gold/telar-core/types"
-	"github.com/red-gold/telar-core/utils"
+	"github.com/red-gold/ts-serverless/micros/internal/rpc"
+	models "github.com/red-gold/ts-serverless/micros/votes/models"
 )
 
 type ResultAsync struct {
@@ -70,57 +68,44 @@ func getHeaderInfoReq(c *fiber.Ctx) map[string][]string {
 	return getHeadersFromUserInfoReq(getUserInfoReq(c))
 }
 
-// functionCall send request to another function/microservice using HMAC validation
-func functionCall(method string, bytesReq []byte, url string, header map[string][]string) ([]byte, error) {
-	prettyURL := utils.GetPrettyURLf(url)
-	bodyReader := bytes.NewBuffer(bytesReq)
-
-	fullURL := *coreConfig.AppConfig.InternalGateway + prettyURL
-	httpReq, httpErr := http.NewRequest(method, fullURL, bodyReader)
-	if httpErr != nil {
-		return nil, httpErr
-	}
-
-	digest := hmac.Sign(bytesReq, []byte(*coreConfig.AppConfig.PayloadSecret))
-	httpReq.Header.Set("Content-type", "application/json")
-	log.Info("\ndigest: %s, header: %v \n", "sha1="+hex.EncodeToString(digest), types.HeaderHMACAuthenticate)
-	httpReq.Header.Add(types.HeaderHMACAuthenticate, "sha1="+hex.EncodeToString(digest))
-
-	if header != nil {
-		for k, v := range header {
-			httpReq.Header[k] = v
-		}
+// getBlockedUserIds Get the users which the current user blocked or is blocked by.
+// If the restrictions are not available, the request fails, the votes of the blocked users are never shown.
+func getBlockedUserIds(c *fiber.Ctx) ([]uuid.UUID, error) {
+	if getUserInfoReq(c).UserId == uuid.Nil {
+		return nil, nil
 	}
-
-	c := http.Client{}
-	res, reqErr := c.Do(httpReq)
-	if reqErr != nil {
-		return nil, fmt.Errorf("Error while sending request [%s] - %s", fullURL, reqErr.Error())
+	restrictionsURL := "/user-rels/restrictions"
+	restrictionsData, err := rpc.Call(rpc.Context(c), http.MethodGet, restrictionsURL, []byte(""))
+	if err != nil {
+		log.Error("rpc.Call (%s) -  %s", restrictionsURL, err.Error())
+		return nil, fmt.Errorf("getBlockedUserIds/rpc")
 	}
-	if res.Body != nil {
-		defer res.Body.Close()
-	}
-
-	resData, readErr := ioutil.ReadAll(res.Body)
-	if resData == nil || readErr != nil {
-		return nil, fmt.Errorf("failed to read response from [%s].", fullURL)
+	var restrictions models.UserRestrictionsModel
+	if err := json.Unmarshal(restrictionsData, &restrictions); err != nil {
+		log.Error("Unmarshal restrictions -  %s", err.Error())
+		return nil, fmt.Errorf("getBlockedUserIds/unmarshal")
 	}
+	return restrictions.BlockedUserIds, nil
+}
 
-	if res.StatusCode != http.StatusAccepted && res.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to call api, invalid status: %s", res.Status)
+// isBlockedUser check whether the user is in the blocked users
+func isBlockedUser(blockedUserIds []uuid.UUID, userId uuid.UUID) bool {
+	for _, blockedUserId := range blockedUserIds {
+		if blockedUserId == userId {
+			return true
+		}
 	}
-
-	return resData, nil
+	return false
 }
 
 // readPostAsync Read post async
-func readPostAsync(postId uuid.UUID, infoReq *UserInfoInReq) <-chan ResultAsync {
+func readPostAsync(ctx context.Context, postId uuid.UUID) <-chan ResultAsync {
 	r := make(chan ResultAsync)
 	go func() {
 		defer close(r)
 		postURL := fmt.Sprintf("/posts/%s", postId.String())
 
-		post, err := functionCall(http.MethodGet, []byte(""), postURL, getHeadersFromUserInfoReq(infoReq))
+		post, err := rpc.Call(ctx, http.MethodGet, postURL, []byte(""))
 		if err != nil {
 			r <- ResultAsync{Error: err}
 			return
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 
@@ -10,6 +11,8 @@ import (
 	"github.com/red-gold/telar-core/pkg/log"
 	"github.com/red-gold/telar-core/types"
 	"github.com/red-gold/telar-core/utils"
+	"github.com/red-gold/ts-serverless/micros/internal/outbox"
+	"github.com/red-gold/ts-serverless/micros/internal/rpc"
 	"github.com/red-gold/ts-serverless/micros/votes/database"
 	domain "github.com/red-gold/ts-serverless/micros/votes/dto"
 	models "github.com/red-gold/ts-serverless/micros/votes/models"
@@ -42,6 +45,12 @@ func CreateVoteHandle(c *fiber.Ctx) error {
 		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/voteService", "Error happened while creating voteService!"))
 	}
 
+	outboxService, serviceErr := service.NewOutboxService(database.Db)
+	if serviceErr != nil {
+		log.Error("NewOutboxService %s", serviceErr.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/outboxService", "Error happened while creating outboxService!"))
+	}
+
 	currentUser, ok := c.Locals(types.UserCtxName).(types.UserContext)
 	if !ok {
 		log.Error("[CreateVoteHandle] Can not get current user")
@@ -49,28 +58,9 @@ func CreateVoteHandle(c *fiber.Ctx) error {
 			"Can not get current user"))
 	}
 
-	newVote := &domain.Vote{
-		OwnerUserId:      currentUser.UserID,
-		PostId:           model.PostId,
-		OwnerDisplayName: currentUser.DisplayName,
-		OwnerAvatar:      currentUser.Avatar,
-		TypeId:           model.TypeId,
-	}
-	userInfoReq := getUserInfoReq(c)
-
-	saveVoteChannel := voteService.SaveVote(newVote)
-	readPostChannel := readPostAsync(model.PostId, userInfoReq)
-
-	saveVoteResult, postResult := <-saveVoteChannel, <-readPostChannel
-	if saveVoteResult.Error != nil {
-		errorMessage := fmt.Sprintf("Save Vote Error %s", saveVoteResult.Error.Error())
-		log.Error(errorMessage)
-		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/saveVote", "Error happened while saving Vote!"))
-	}
-
-	if postResult.Error != nil {
-		messageError := fmt.Sprintf("Cannot get the post! error: %s", postResult.Error.Error())
-		fmt.Println(messageError)
+	typeId, ok := models.NormalizeReaction(model.TypeId)
+	if !ok {
+		return c.Status(http.StatusBadRequest).JSON(utils.Error("reactionTypeIsNotValid", "Reaction type is not valid!"))
 	}
 
 	// Create user headers for http request
@@ -81,69 +71,161 @@ func CreateVoteHandle(c *fiber.Ctx) error {
 	userHeaders["displayName"] = []string{currentUser.DisplayName}
 	userHeaders["role"] = []string{currentUser.SystemRole}
 
-	go func() {
-		fullURL := "/posts/score"
-		payload, err := json.Marshal(fiber.Map{
-			"postId": model.PostId,
-			"count":  1,
-		})
-		if err != nil {
-			messageError := fmt.Sprintf("Can not parse score payload: %s", err.Error())
-			log.Error(messageError)
+	// The owner of the post is kept on the vote for the interactions between the users
+	var post *PostModelNotification
+	postResult := <-readPostAsync(rpc.Context(c), model.PostId)
+	if postResult.Error != nil {
+		messageError := fmt.Sprintf("Cannot get the post! error: %s", postResult.Error.Error())
+		fmt.Println(messageError)
+	} else {
+		var readPost PostModelNotification
+		if marshalErr := json.Unmarshal(postResult.Result, &readPost); marshalErr != nil {
+			messageError := fmt.Sprintf("Cannot unmarshal the post! error: %s", marshalErr.Error())
+			fmt.Println(messageError)
+		} else {
+			post = &readPost
 		}
+	}
 
-		_, functionErr := functionCall(http.MethodPut, payload, fullURL, userHeaders)
+	newVote := &domain.Vote{
+		OwnerUserId:      currentUser.UserID,
+		PostId:           model.PostId,
+		OwnerDisplayName: currentUser.DisplayName,
+		OwnerAvatar:      currentUser.Avatar,
+		TypeId:           typeId,
+	}
+	if post != nil {
+		newVote.PostOwnerUserId = post.OwnerUserId
+	}
 
-		if functionErr != nil {
-			messageError := fmt.Sprintf("Cannot save vote on post! error: %s", functionErr.Error())
-			log.Error(messageError)
-		}
+	// A user has one reaction on a post, voting again switches the reaction
+	storedVote, created, err := voteService.UpsertVote(newVote)
+	if errors.Is(err, service.ErrVoteIndexMissing) {
+		log.Error("[CreateVoteHandle.UpsertVote] %s", err.Error())
+		return c.Status(http.StatusServiceUnavailable).JSON(utils.Error("voteIndexMissing", "Votes are not accepted until the vote index is created!"))
+	}
+	if err != nil {
+		errorMessage := fmt.Sprintf("Save Vote Error %s", err.Error())
+		log.Error(errorMessage)
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/saveVote", "Error happened while saving Vote!"))
+	}
+	if !created {
+		return switchVoteReaction(c, voteService, outboxService, storedVote, typeId, userHeaders)
+	}
 
-	}()
+	// Create request to increase score on post
+	fullURL := "/posts/score"
+	payload, err := json.Marshal(fiber.Map{
+		"postId":   model.PostId,
+		"count":    1,
+		"reaction": models.ReactionName(typeId),
+	})
+	if err != nil {
+		messageError := fmt.Sprintf("Can not parse score payload: %s", err.Error())
+		log.Error(messageError)
+	}
+	events := []outbox.Event{outbox.NewEvent(http.MethodPut, fullURL, payload, userHeaders)}
 
 	// Create notification request
-	go func(currentUser types.UserContext) {
-
-		var post PostModelNotification
-		marshalErr := json.Unmarshal(postResult.Result, &post)
-		if marshalErr != nil {
-			messageError := fmt.Sprintf("Cannot unmarshal the post! error: %s", marshalErr.Error())
-			fmt.Println(messageError)
-		}
-
-		if post.OwnerUserId == currentUser.UserID {
-			// Should not send notification if the owner of the vote is same as owner of post
-			return
-		}
-
+	// Should not send notification if the owner of the vote is same as owner of post
+	if post != nil && post.OwnerUserId != currentUser.UserID {
 		URL := fmt.Sprintf("/posts/%s", post.URLKey)
 		notificationModel := &models.NotificationModel{
 			OwnerUserId:          currentUser.UserID,
 			OwnerDisplayName:     currentUser.DisplayName,
 			OwnerAvatar:          currentUser.Avatar,
 			Title:                currentUser.DisplayName,
-			Description:          fmt.Sprintf("%s like your post.", currentUser.DisplayName),
+			Description:          reactionDescription(currentUser.DisplayName, typeId),
 			URL:                  URL,
 			NotifyRecieverUserId: post.OwnerUserId,
 			TargetId:             model.PostId,
 			IsSeen:               false,
-			Type:                 "like",
+			Type:                 models.ReactionName(typeId),
 		}
 		notificationBytes, marshalErr := json.Marshal(notificationModel)
 		if marshalErr != nil {
 			fmt.Printf("Cannot marshal notification! error: %s", marshalErr.Error())
-
 		}
+		events = append(events, outbox.NewEvent(http.MethodPost, "/notifications", notificationBytes, userHeaders))
+	}
 
-		notificationURL := "/notifications"
-		_, notificationIndexErr := functionCall(http.MethodPost, notificationBytes, notificationURL, userHeaders)
-		if notificationIndexErr != nil {
-			fmt.Printf("\nCannot save notification on follow user! error: %s\n", notificationIndexErr.Error())
+	// The vote is removed if its side effects can not be stored
+	rollbackVote := func() error {
+		filter := struct {
+			ObjectId uuid.UUID `json:"objectId" bson:"objectId"`
+		}{
+			ObjectId: newVote.ObjectId,
 		}
+		return voteService.DeleteVote(filter)
+	}
+	if err := outbox.SaveEvents(outboxService, events, rollbackVote); err != nil {
+		log.Error("[CreateVoteHandle.saveOutboxEvents] %s", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/saveVote", "Error happened while saving Vote!"))
+	}
 
-	}(currentUser)
+	go outbox.Dispatch(outboxService, events)
 
 	return c.JSON(fiber.Map{
 		"objectId": newVote.ObjectId.String(),
+		"created":  true,
 	})
 }
+
+// switchVoteReaction change the reaction of the current vote and move the post reaction count
+func switchVoteReaction(c *fiber.Ctx, voteService service.VoteService, outboxService outbox.Service,
+	currentVote *domain.Vote, typeId int, userHeaders map[string][]string) error {
+
+	previousTypeId, _ := models.NormalizeReaction(currentVote.TypeId)
+	if previousTypeId == typeId {
+		return c.JSON(fiber.Map{
+			"objectId": currentVote.ObjectId.String(),
+			"created":  false,
+		})
+	}
+
+	switched, err := voteService.SwitchVoteType(currentVote.ObjectId, previousTypeId, typeId)
+	if err != nil {
+		log.Error("[switchVoteReaction.SwitchVoteType] %s", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/updateVote", "Error happened while update Vote!"))
+	}
+	if !switched {
+		return c.Status(http.StatusConflict).JSON(utils.Error("voteChanged", "Vote was changed by another request!"))
+	}
+
+	payload, err := json.Marshal(fiber.Map{
+		"postId":           currentVote.PostId,
+		"count":            0,
+		"reaction":         models.ReactionName(typeId),
+		"previousReaction": models.ReactionName(previousTypeId),
+	})
+	if err != nil {
+		messageError := fmt.Sprintf("Can not parse score payload: %s", err.Error())
+		log.Error(messageError)
+	}
+	events := []outbox.Event{outbox.NewEvent(http.MethodPut, "/posts/score", payload, userHeaders)}
+
+	// The reaction is switched back if the post counts can not be moved
+	rollbackVote := func() error {
+		_, err := voteService.SwitchVoteType(currentVote.ObjectId, typeId, previousTypeId)
+		return err
+	}
+	if err := outbox.SaveEvents(outboxService, events, rollbackVote); err != nil {
+		log.Error("[switchVoteReaction.saveOutboxEvents] %s", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/updateVote", "Error happened while update Vote!"))
+	}
+
+	go outbox.Dispatch(outboxService, events)
+
+	return c.JSON(fiber.Map{
+		"objectId": currentVote.ObjectId.String(),
+		"created":  false,
+	})
+}
+
+// reactionDescription create the notification description of a reaction
+func reactionDescription(displayName string, typeId int) string {
+	if typeId == models.ReactionLike {
+		return fmt.Sprintf("%s like your post.", displayName)
+	}
+	return fmt.Sprintf("%s reacted to your post with %s.", displayName, models.ReactionName(typeId))
+}
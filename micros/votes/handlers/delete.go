@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -10,7 +11,10 @@ import (
 	"github.com/red-gold/telar-core/pkg/log"
 	"github.com/red-gold/telar-core/types"
 	"github.com/red-gold/telar-core/utils"
+	"github.com/red-gold/ts-serverless/micros/internal/rpc"
 	"github.com/red-gold/ts-serverless/micros/votes/database"
+	domain "github.com/red-gold/ts-serverless/micros/votes/dto"
+	models "github.com/red-gold/ts-serverless/micros/votes/models"
 	service "github.com/red-gold/ts-serverless/micros/votes/services"
 )
 
@@ -48,6 +52,15 @@ func DeleteVoteHandle(c *fiber.Ctx) error {
 			"Can not get current user"))
 	}
 
+	foundVote, err := voteService.FindById(voteUUID)
+	if err != nil {
+		log.Error("[DeleteVoteHandle.FindById] %s", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/findVote", "Error happened while find Vote!"))
+	}
+	if foundVote == nil || foundVote.OwnerUserId != currentUser.UserID {
+		return c.Status(http.StatusNotFound).JSON(utils.Error("voteNotFound", "Vote not found!"))
+	}
+
 	if err := voteService.DeleteVoteByOwner(currentUser.UserID, voteUUID); err != nil {
 		errorMessage := fmt.Sprintf("Delete Vote Error %s", err.Error())
 		log.Error(errorMessage)
@@ -55,9 +68,35 @@ func DeleteVoteHandle(c *fiber.Ctx) error {
 
 	}
 
+	if err := decreasePostScore(rpc.Context(c), foundVote); err != nil {
+		log.Error("[DeleteVoteHandle.decreasePostScore] %s", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/postDecreaseScore", "Error happened while delete Vote!"))
+	}
+
 	return c.SendStatus(http.StatusOK)
 }
 
+// decreasePostScore remove the reaction of a deleted vote from the post
+func decreasePostScore(ctx context.Context, vote *domain.Vote) error {
+
+	fullURL := "/posts/score"
+	payload, err := json.Marshal(fiber.Map{
+		"postId":   vote.PostId,
+		"count":    -1,
+		"reaction": models.ReactionName(vote.TypeId),
+	})
+	if err != nil {
+		messageError := fmt.Sprintf("Can not parse score payload: %s", err.Error())
+		log.Error(messageError)
+	}
+
+	_, functionErr := rpc.Call(ctx, http.MethodPut, fullURL, payload)
+	if functionErr != nil {
+		return fmt.Errorf("%s - %s", fullURL, functionErr.Error())
+	}
+	return nil
+}
+
 // DeleteVoteByPostIdHandle handle delete a Vote but postId
 func DeleteVoteByPostIdHandle(c *fiber.Ctx) error {
 
@@ -91,34 +130,104 @@ func DeleteVoteByPostIdHandle(c *fiber.Ctx) error {
 			"Can not get current user"))
 	}
 
+	foundVote, err := voteService.FindByPostAndOwner(PostUUID, currentUser.UserID)
+	if err != nil {
+		log.Error("[DeleteVoteByPostIdHandle.FindByPostAndOwner] %s", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/findVote", "Error happened while find Vote!"))
+	}
+
+	// There is no reaction to remove from the post
+	if foundVote == nil {
+		return c.SendStatus(http.StatusOK)
+	}
+
 	if err := voteService.DeleteVotesByPostId(currentUser.UserID, PostUUID); err != nil {
 		errorMessage := fmt.Sprintf("Delete Vote Error %s", err.Error())
 		log.Error(errorMessage)
 		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/deleteVote", "Error happened while delete Vote!"))
 	}
 
-	// Create user headers for http request
-	userHeaders := make(map[string][]string)
-	userHeaders["uid"] = []string{currentUser.UserID.String()}
-	userHeaders["email"] = []string{currentUser.Username}
-	userHeaders["avatar"] = []string{currentUser.Avatar}
-	userHeaders["displayName"] = []string{currentUser.DisplayName}
-	userHeaders["role"] = []string{currentUser.SystemRole}
+	if err := decreasePostScore(rpc.Context(c), foundVote); err != nil {
+		log.Error("[DeleteVoteByPostIdHandle.decreasePostScore] %s", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/postDecreaseScore", "Error happened while delete Vote!"))
+	}
 
-	fullURL := "/posts/score"
-	payload, err := json.Marshal(fiber.Map{
-		"postId": postId,
-		"count":  -1,
-	})
-	if err != nil {
-		messageError := fmt.Sprintf("Can not parse score payload: %s", err.Error())
-		log.Error(messageError)
+	return c.SendStatus(http.StatusOK)
+}
+
+// SetPostDeletedHandle handle hide the votes of a deleted post or show them when the post is restored
+func SetPostDeletedHandle(c *fiber.Ctx) error {
+
+	// params from /votes/post/:postId/deleted
+	postId := c.Params("postId")
+	if postId == "" {
+		errorMessage := fmt.Sprintf("Post Id is required!")
+		log.Error(errorMessage)
+		return c.Status(http.StatusBadRequest).JSON(utils.Error("postIdRequired", errorMessage))
 	}
 
-	_, functionErr := functionCall(http.MethodPut, payload, fullURL, userHeaders)
-	if functionErr != nil {
-		log.Error("[DeleteVoteByPostIdHandle.functionCall] %s - %s", fullURL, functionErr.Error())
-		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/postDecreaseScore", "Error happened while delete Vote!"))
+	postUUID, uuidErr := uuid.FromString(postId)
+	if uuidErr != nil {
+		errorMessage := fmt.Sprintf("UUID Error %s", uuidErr.Error())
+		log.Error(errorMessage)
+		return c.Status(http.StatusBadRequest).JSON(utils.Error("postIdIsNotValid", "Post id is not valid!"))
+	}
+
+	// Create the model object
+	model := new(models.PostDeletedModel)
+	if err := c.BodyParser(model); err != nil {
+		errorMessage := fmt.Sprintf("Parse PostDeletedModel Error %s", err.Error())
+		log.Error(errorMessage)
+		return c.Status(http.StatusBadRequest).JSON(utils.Error("parseModel", "Can not parse the model!"))
+	}
+
+	if model.Version <= 0 {
+		return c.Status(http.StatusBadRequest).JSON(utils.Error("versionIsRequired", "The version of the change is required!"))
+	}
+
+	// Create service
+	voteService, serviceErr := service.NewVoteService(database.Db)
+	if serviceErr != nil {
+		log.Error("NewVoteService %s", serviceErr.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/voteService", "Error happened while creating voteService!"))
+	}
+
+	if err := voteService.SetPostDeleted(postUUID, model.Deleted, model.Version); err != nil {
+		log.Error("[SetPostDeletedHandle.voteService.SetPostDeleted] %s", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/updateVote", "Error happened while updating votes!"))
+	}
+
+	return c.SendStatus(http.StatusOK)
+}
+
+// PurgePostVotesHandle handle remove all the votes of a post which is purged
+func PurgePostVotesHandle(c *fiber.Ctx) error {
+
+	// params from /votes/post/:postId/deleted
+	postId := c.Params("postId")
+	if postId == "" {
+		errorMessage := fmt.Sprintf("Post Id is required!")
+		log.Error(errorMessage)
+		return c.Status(http.StatusBadRequest).JSON(utils.Error("postIdRequired", errorMessage))
+	}
+
+	postUUID, uuidErr := uuid.FromString(postId)
+	if uuidErr != nil {
+		errorMessage := fmt.Sprintf("UUID Error %s", uuidErr.Error())
+		log.Error(errorMessage)
+		return c.Status(http.StatusBadRequest).JSON(utils.Error("postIdIsNotValid", "Post id is not valid!"))
+	}
+
+	// Create service
+	voteService, serviceErr := service.NewVoteService(database.Db)
+	if serviceErr != nil {
+		log.Error("NewVoteService %s", serviceErr.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/voteService", "Error happened while creating voteService!"))
+	}
+
+	if err := voteService.PurgeVotesByPostId(postUUID); err != nil {
+		log.Error("[PurgePostVotesHandle.voteService.PurgeVotesByPostId] %s", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/purgeVote", "Error happened while purging votes!"))
 	}
 
 	return c.SendStatus(http.StatusOK)
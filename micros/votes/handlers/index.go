@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/red-gold/telar-core/pkg/log"
+	"github.com/red-gold/telar-core/utils"
+	"github.com/red-gold/ts-serverless/micros/votes/database"
+	service "github.com/red-gold/ts-serverless/micros/votes/services"
+)
+
+// InitVoteIndexHandle handle remove the duplicate votes and create the missing indexes of votes
+func InitVoteIndexHandle(c *fiber.Ctx) error {
+
+	// Create service
+	voteService, serviceErr := service.NewVoteService(database.Db)
+	if serviceErr != nil {
+		log.Error("NewVoteService %s", serviceErr.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/voteService", "Error happened while creating voteService!"))
+	}
+
+	// The unique index of the votes can not be created while duplicates exist
+	removed, err := voteService.RemoveDuplicateVotes()
+	if err != nil {
+		log.Error("[InitVoteIndexHandle.RemoveDuplicateVotes] %s", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/removeDuplicateVotes", "Error happened while removing duplicate votes!"))
+	}
+
+	indexService, serviceErr := service.NewIndexService(database.Db)
+	if serviceErr != nil {
+		log.Error("NewIndexService %s", serviceErr.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/indexService", "Error happened while creating indexService!"))
+	}
+
+	created, err := indexService.ReconcileIndexes()
+	if err != nil {
+		log.Error("[InitVoteIndexHandle.ReconcileIndexes] %s", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/createVoteIndex", "Error happened while creating vote index!"))
+	}
+
+	return c.JSON(fiber.Map{
+		"removed": removed,
+		"created": created,
+	})
+}
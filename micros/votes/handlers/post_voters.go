@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/red-gold/telar-core/pkg/log"
+	"github.com/red-gold/telar-core/utils"
+	"github.com/red-gold/ts-serverless/micros/votes/database"
+	models "github.com/red-gold/ts-serverless/micros/votes/models"
+	service "github.com/red-gold/ts-serverless/micros/votes/services"
+)
+
+// GetPostVotersHandle handle get the voters of each post.
+// The posts function reconciles the score, votes and reactions of the posts with the voters.
+func GetPostVotersHandle(c *fiber.Ctx) error {
+
+	// Create the model object
+	model := new(models.PostIdsModel)
+	if err := c.BodyParser(model); err != nil {
+		errorMessage := fmt.Sprintf("Parse PostIdsModel Error %s", err.Error())
+		log.Error(errorMessage)
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/parseModel", "Error happened while parsing model!"))
+	}
+
+	if len(model.PostIds) == 0 {
+		return c.Status(http.StatusBadRequest).JSON(utils.Error("postIdsRequired", "Post ids are required!"))
+	}
+
+	// Create service
+	voteService, serviceErr := service.NewVoteService(database.Db)
+	if serviceErr != nil {
+		log.Error("NewVoteService %s", serviceErr.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/voteService", "Error happened while creating voteService!"))
+	}
+
+	postVoters, err := voteService.FindVotersByPost(model.PostIds)
+	if err != nil {
+		log.Error("[GetPostVotersHandle.voteService.FindVotersByPost] %s", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/findPostVoters", "Error happened while reading post voters!"))
+	}
+
+	return c.JSON(postVoters)
+}
@@ -9,7 +9,9 @@ import (
 	"github.com/red-gold/telar-core/pkg/log"
 	"github.com/red-gold/telar-core/pkg/parser"
 	utils "github.com/red-gold/telar-core/utils"
+	"github.com/red-gold/ts-serverless/micros/internal/cursor"
 	"github.com/red-gold/ts-serverless/micros/votes/database"
+	dto "github.com/red-gold/ts-serverless/micros/votes/dto"
 	models "github.com/red-gold/ts-serverless/micros/votes/models"
 	service "github.com/red-gold/ts-serverless/micros/votes/services"
 )
@@ -17,6 +19,28 @@ import (
 type VoteQueryModel struct {
 	Page   int64     `query:"page"`
 	PostId uuid.UUID `query:"postId"`
+	Type   string    `query:"type"`
+	Limit  int64     `query:"limit"`
+	After  string    `query:"after"`
+	Before string    `query:"before"`
+}
+
+// newVotePage create the page of votes with the cursors of the next and previous pages
+func newVotePage(voteList []dto.Vote, limit int64, readBefore bool) models.VotePageModel {
+	page := models.VotePageModel{
+		Votes: []dto.Vote{},
+	}
+	if len(voteList) == 0 {
+		return page
+	}
+	page.Votes = voteList
+
+	positions := make([]cursor.Model, 0, len(voteList))
+	for _, item := range voteList {
+		positions = append(positions, cursor.Model{CreatedDate: item.CreatedDate, ObjectId: item.ObjectId})
+	}
+	page.NextCursor, page.PrevCursor = cursor.PageCursors(positions, limit, readBefore)
+	return page
 }
 
 // GetVotesByPostIdHandle handle query on vote
@@ -42,13 +66,57 @@ func GetVotesByPostIdHandle(c *fiber.Ctx) error {
 		return c.Status(http.StatusBadRequest).JSON(utils.Error("postIdRequired", errorMessage))
 	}
 
-	voteList, err := voteService.GetVoteByPostId(&query.PostId, "created_date", query.Page)
+	var typeId *int
+	if query.Type != "" {
+		reactionType, ok := models.ReactionTypeByName(query.Type)
+		if !ok {
+			return c.Status(http.StatusBadRequest).JSON(utils.Error("reactionTypeIsNotValid", "Reaction type is not valid!"))
+		}
+		typeId = &reactionType
+	}
+
+	// Numbered pages are kept for the clients which do not use cursors
+	if query.Page > 0 || (query.Limit == 0 && query.After == "" && query.Before == "") {
+		blockedUserIds, err := getBlockedUserIds(c)
+		if err != nil {
+			log.Error("[GetVotesByPostIdHandle.getBlockedUserIds] %s", err.Error())
+			return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/getUserRestrictions", "Error while getting user restrictions"))
+		}
+		voteList, err := voteService.GetVoteByPostId(&query.PostId, typeId, "created_date", query.Page, blockedUserIds)
+		if err != nil {
+			log.Error("[GetVotesByPostIdHandle.voteService.GetVoteByPostId] %s ", err.Error())
+			return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/getVoteByPostId", "Error happened while query vote!"))
+		}
+
+		return c.JSON(voteList)
+	}
+
+	if query.Limit < 0 || query.Limit > cursor.MaxPageLimit {
+		return c.Status(http.StatusBadRequest).JSON(utils.Error("limitIsNotValid", fmt.Sprintf("Limit should be between 1 and %d!", cursor.MaxPageLimit)))
+	}
+
+	after, before, err := cursor.ReadPage(query.After, query.Before)
 	if err != nil {
-		log.Error("[GetVotesByPostIdHandle.voteService.GetVoteByPostId] %s ", err.Error())
+		log.Error("[GetVotesByPostIdHandle.readPageCursors] %s", err.Error())
+		return c.Status(http.StatusBadRequest).JSON(utils.Error("cursorIsNotValid", "Cursor is not valid!"))
+	}
+
+	limit := query.Limit
+	if limit == 0 {
+		limit = cursor.DefaultPageLimit
+	}
+	blockedUserIds, err := getBlockedUserIds(c)
+	if err != nil {
+		log.Error("[GetVotesByPostIdHandle.getBlockedUserIds] %s", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/getUserRestrictions", "Error while getting user restrictions"))
+	}
+	voteList, err := voteService.GetVoteByPostIdByCursor(&query.PostId, typeId, after, before, limit, blockedUserIds)
+	if err != nil {
+		log.Error("[GetVotesByPostIdHandle.voteService.GetVoteByPostIdByCursor] %s ", err.Error())
 		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/getVoteByPostId", "Error happened while query vote!"))
 	}
 
-	return c.JSON(voteList)
+	return c.JSON(newVotePage(voteList, limit, before != nil))
 }
 
 // GetVoteHandle handle get a vote
@@ -81,6 +149,19 @@ func GetVoteHandle(c *fiber.Ctx) error {
 		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/findVote", "Error happened while find Vote!"))
 	}
 
+	// The votes of the blocked users are reported as not found
+	if foundVote == nil {
+		return c.Status(http.StatusNotFound).JSON(utils.Error("voteNotFound", "Vote not found!"))
+	}
+	blockedUserIds, err := getBlockedUserIds(c)
+	if err != nil {
+		log.Error("[GetVoteHandle.getBlockedUserIds] %s", err.Error())
+		return c.Status(http.StatusInternalServerError).JSON(utils.Error("internal/getUserRestrictions", "Error while getting user restrictions"))
+	}
+	if isBlockedUser(blockedUserIds, foundVote.OwnerUserId) {
+		return c.Status(http.StatusNotFound).JSON(utils.Error("voteNotFound", "Vote not found!"))
+	}
+
 	voteModel := models.VoteModel{
 		ObjectId:         foundVote.ObjectId,
 		OwnerUserId:      foundVote.OwnerUserId,
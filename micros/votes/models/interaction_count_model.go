@@ -0,0 +1,9 @@
+package models
+
+import uuid "github.com/gofrs/uuid"
+
+// InteractionCountModel the number of the interactions between the user and another user
+type InteractionCountModel struct {
+	UserId uuid.UUID `json:"userId" bson:"_id"`
+	Count  int64     `json:"count" bson:"count"`
+}
@@ -0,0 +1,8 @@
+package models
+
+// PostDeletedModel the change of the deleted state of a post which is cascaded to its comments and votes.
+// The version is the date of the change, the receivers ignore a change which is older than the one they applied.
+type PostDeletedModel struct {
+	Deleted bool  `json:"deleted"`
+	Version int64 `json:"version"`
+}
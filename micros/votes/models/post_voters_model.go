@@ -0,0 +1,21 @@
+package models
+
+import uuid "github.com/gofrs/uuid"
+
+// PostIdsModel the posts which the voters are requested for
+type PostIdsModel struct {
+	PostIds []uuid.UUID `json:"postIds"`
+}
+
+// VoterModel the owner and the reaction of a vote
+type VoterModel struct {
+	OwnerUserId uuid.UUID `json:"ownerUserId" bson:"ownerUserId"`
+	OwnerAvatar string    `json:"ownerAvatar" bson:"ownerAvatar"`
+	TypeId      int       `json:"type" bson:"type"`
+}
+
+// PostVotersModel the voters of a post
+type PostVotersModel struct {
+	PostId uuid.UUID    `json:"postId" bson:"_id"`
+	Voters []VoterModel `json:"voters" bson:"voters"`
+}
@@ -0,0 +1,43 @@
+package models
+
+// Reaction types of a vote
+const (
+	ReactionLike  = 1
+	ReactionLove  = 2
+	ReactionLaugh = 3
+	ReactionSad   = 4
+	ReactionAngry = 5
+)
+
+var reactionNames = map[int]string{
+	ReactionLike:  "like",
+	ReactionLove:  "love",
+	ReactionLaugh: "laugh",
+	ReactionSad:   "sad",
+	ReactionAngry: "angry",
+}
+
+// NormalizeReaction return the reaction type of a vote type, the votes without a type are likes
+func NormalizeReaction(typeId int) (int, bool) {
+	if typeId == 0 {
+		return ReactionLike, true
+	}
+	_, ok := reactionNames[typeId]
+	return typeId, ok
+}
+
+// ReactionName return the name of a reaction type
+func ReactionName(typeId int) string {
+	typeId, _ = NormalizeReaction(typeId)
+	return reactionNames[typeId]
+}
+
+// ReactionTypeByName return the reaction type of a reaction name
+func ReactionTypeByName(name string) (int, bool) {
+	for typeId, reactionName := range reactionNames {
+		if reactionName == name {
+			return typeId, true
+		}
+	}
+	return 0, false
+}
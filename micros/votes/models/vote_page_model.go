@@ -0,0 +1,9 @@
+package models
+
+import dto "github.com/red-gold/ts-serverless/micros/votes/dto"
+
+type VotePageModel struct {
+	Votes      []dto.Vote `json:"votes"`
+	NextCursor string     `json:"nextCursor"`
+	PrevCursor string     `json:"prevCursor"`
+}
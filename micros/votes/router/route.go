@@ -11,7 +11,10 @@ import (
 	"github.com/red-gold/telar-core/middleware/authcookie"
 	"github.com/red-gold/telar-core/middleware/authhmac"
 	"github.com/red-gold/telar-core/types"
+	"github.com/red-gold/ts-serverless/micros/internal/index"
+	"github.com/red-gold/ts-serverless/micros/votes/database"
 	"github.com/red-gold/ts-serverless/micros/votes/handlers"
+	service "github.com/red-gold/ts-serverless/micros/votes/services"
 )
 
 // SetupRoutes func
@@ -57,6 +60,19 @@ func SetupRoutes(app *fiber.App) {
 	app.Put("/", append(hmacCookieHandlers, handlers.UpdateVoteHandle)...)
 	app.Delete("/id/:voteId", append(hmacCookieHandlers, handlers.DeleteVoteHandle)...)
 	app.Delete("/post/:postId", append(hmacCookieHandlers, handlers.DeleteVoteByPostIdHandle)...)
+	app.Put("/post/:postId/deleted", authHMACMiddleware(false), handlers.SetPostDeletedHandle)
+	app.Delete("/post/:postId/deleted", authHMACMiddleware(false), handlers.PurgePostVotesHandle)
+	app.Get("/interactions/:userId", authHMACMiddleware(false), handlers.GetInteractionsHandle)
+	app.Post("/post/voters", authHMACMiddleware(false), handlers.GetPostVotersHandle)
+	app.Post("/index", authHMACMiddleware(false), handlers.InitVoteIndexHandle)
+	app.Get("/index", authHMACMiddleware(false), index.MissingHandle(newIndexService))
+	app.Post("/outbox/dispatch", authHMACMiddleware(false), handlers.DispatchOutboxHandle)
+	app.Get("/outbox/status", authHMACMiddleware(false), handlers.GetOutboxStatusHandle)
 	app.Get("/", append(hmacCookieHandlers, handlers.GetVotesByPostIdHandle)...)
 	app.Get("/:voteId", append(hmacCookieHandlers, handlers.GetVoteHandle)...)
 }
+
+// newIndexService create the index service of the function
+func newIndexService() (index.Service, error) {
+	return service.NewIndexService(database.Db)
+}
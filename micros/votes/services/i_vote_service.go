@@ -3,13 +3,16 @@ package service
 import (
 	uuid "github.com/gofrs/uuid"
 	coreData "github.com/red-gold/telar-core/data"
+	"github.com/red-gold/ts-serverless/micros/internal/cursor"
 	dto "github.com/red-gold/ts-serverless/micros/votes/dto"
+	models "github.com/red-gold/ts-serverless/micros/votes/models"
 )
 
 type VoteService interface {
 	SaveVote(vote *dto.Vote) <-chan SaveResultAsync
 	FindOneVote(filter interface{}) (*dto.Vote, error)
 	FindVoteList(filter interface{}, limit int64, skip int64, sort map[string]int) ([]dto.Vote, error)
+	FindVoteListByCursor(filter map[string]interface{}, after *cursor.Model, before *cursor.Model, limit int64) ([]dto.Vote, error)
 	FindById(objectId uuid.UUID) (*dto.Vote, error)
 	FindByOwnerUserId(ownerUserId uuid.UUID) ([]dto.Vote, error)
 	UpdateVote(filter interface{}, data interface{}, opts ...*coreData.UpdateOptions) error
@@ -18,6 +21,15 @@ type VoteService interface {
 	DeleteVoteByOwner(ownerUserId uuid.UUID, voteId uuid.UUID) error
 	DeleteManyVotes(filter interface{}) error
 	CreateVoteIndex(indexes map[string]interface{}) error
-	GetVoteByPostId(postId *uuid.UUID, sortBy string, page int64) ([]dto.Vote, error)
+	UpsertVote(vote *dto.Vote) (*dto.Vote, bool, error)
+	FindByPostAndOwner(postId uuid.UUID, ownerUserId uuid.UUID) (*dto.Vote, error)
+	SwitchVoteType(voteId uuid.UUID, previousTypeId int, typeId int) (bool, error)
+	GetVoteByPostId(postId *uuid.UUID, typeId *int, sortBy string, page int64, hiddenUserIds []uuid.UUID) ([]dto.Vote, error)
+	GetVoteByPostIdByCursor(postId *uuid.UUID, typeId *int, after *cursor.Model, before *cursor.Model, limit int64, hiddenUserIds []uuid.UUID) ([]dto.Vote, error)
 	DeleteVotesByPostId(ownerUserId uuid.UUID, postId uuid.UUID) error
+	SetPostDeleted(postId uuid.UUID, deleted bool, version int64) error
+	PurgeVotesByPostId(postId uuid.UUID) error
+	CountInteractions(userId uuid.UUID, since int64) ([]models.InteractionCountModel, error)
+	RemoveDuplicateVotes() (int64, error)
+	FindVotersByPost(postIds []uuid.UUID) ([]models.PostVotersModel, error)
 }
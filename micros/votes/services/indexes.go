@@ -0,0 +1,24 @@
+package service
+
+import (
+	"github.com/red-gold/ts-serverless/micros/internal/index"
+)
+
+// uniqueVoteIndex keeps one vote of a user on a post, UpsertVote relies on it
+var uniqueVoteIndex = index.New(voteCollectionName, true, "postId", 1, "ownerUserId", 1)
+
+// uniqueVoteIndexGuard refuses the votes while the unique vote index is not created
+var uniqueVoteIndexGuard = index.NewGuard(uniqueVoteIndex)
+
+// requiredIndexes are the indexes which the votes function needs
+var requiredIndexes = []index.Model{
+	uniqueVoteIndex,
+	index.New(voteCollectionName, false, "postOwnerUserId", 1, "created_date", -1),
+	index.New(voteCollectionName, false, "ownerUserId", 1, "created_date", -1),
+	index.New(outboxCollectionName, false, "status", 1, "nextAttemptDate", 1),
+}
+
+// NewIndexService create the index service on the required indexes of the function
+func NewIndexService(db interface{}) (index.Service, error) {
+	return index.NewService(db, requiredIndexes)
+}
@@ -0,0 +1,91 @@
+package service
+
+import (
+	"fmt"
+
+	uuid "github.com/gofrs/uuid"
+	models "github.com/red-gold/ts-serverless/micros/votes/models"
+)
+
+// CountInteractions count the votes of other users on the posts of the user and the votes
+// of the user on the posts of other users since the date, per other user
+func (s VoteServiceImpl) CountInteractions(userId uuid.UUID, since int64) ([]models.InteractionCountModel, error) {
+	sinceFilter := make(map[string]interface{})
+	sinceFilter["$gte"] = since
+
+	// The votes of other users on the posts of the user
+	notOwner := make(map[string]interface{})
+	notOwner["$ne"] = userId
+	receivedFilter := notPostDeletedFilter()
+	receivedFilter["postOwnerUserId"] = userId
+	receivedFilter["ownerUserId"] = notOwner
+	receivedFilter["created_date"] = sinceFilter
+	received, err := s.countVotesByUser(receivedFilter, "$ownerUserId")
+	if err != nil {
+		return nil, err
+	}
+
+	// The votes of the user on the posts of other users, the votes without the post owner are skipped
+	otherPostOwner := make(map[string]interface{})
+	otherPostOwner["$nin"] = []interface{}{userId, uuid.Nil, nil}
+	sentFilter := notPostDeletedFilter()
+	sentFilter["ownerUserId"] = userId
+	sentFilter["postOwnerUserId"] = otherPostOwner
+	sentFilter["created_date"] = sinceFilter
+	sent, err := s.countVotesByUser(sentFilter, "$postOwnerUserId")
+	if err != nil {
+		return nil, err
+	}
+
+	return mergeInteractionCounts(received, sent), nil
+}
+
+// countVotesByUser count the votes which match the filter per user in the field
+func (s VoteServiceImpl) countVotesByUser(filter map[string]interface{}, userField string) ([]models.InteractionCountModel, error) {
+	var pipeline []interface{}
+
+	matchOperator := make(map[string]interface{})
+	matchOperator["$match"] = filter
+
+	group := make(map[string]interface{})
+	group["_id"] = userField
+	group["count"] = map[string]interface{}{"$sum": 1}
+	groupOperator := make(map[string]interface{})
+	groupOperator["$group"] = group
+
+	pipeline = append(pipeline, matchOperator, groupOperator)
+
+	result := <-s.VoteRepo.Aggregate(voteCollectionName, pipeline)
+	defer result.Close()
+	if result.Error() != nil {
+		return nil, result.Error()
+	}
+
+	var countList []models.InteractionCountModel
+	for result.Next() {
+		var interactionCount models.InteractionCountModel
+		errDecode := result.Decode(&interactionCount)
+		if errDecode != nil {
+			return nil, fmt.Errorf("Error docoding on interaction count")
+		}
+		countList = append(countList, interactionCount)
+	}
+	return countList, nil
+}
+
+// mergeInteractionCounts add up the counts of the same users
+func mergeInteractionCounts(countLists ...[]models.InteractionCountModel) []models.InteractionCountModel {
+	merged := []models.InteractionCountModel{}
+	indexes := make(map[uuid.UUID]int)
+	for _, countList := range countLists {
+		for _, interactionCount := range countList {
+			if index, ok := indexes[interactionCount.UserId]; ok {
+				merged[index].Count += interactionCount.Count
+				continue
+			}
+			indexes[interactionCount.UserId] = len(merged)
+			merged = append(merged, interactionCount)
+		}
+	}
+	return merged
+}
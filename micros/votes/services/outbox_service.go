@@ -0,0 +1,10 @@
+package service
+
+import (
+	"github.com/red-gold/ts-serverless/micros/internal/outbox"
+)
+
+// NewOutboxService create the outbox service on the outbox collection of the function
+func NewOutboxService(db interface{}) (outbox.Service, error) {
+	return outbox.NewService(db, outboxCollectionName)
+}
@@ -0,0 +1,50 @@
+package service
+
+import (
+	"fmt"
+
+	uuid "github.com/gofrs/uuid"
+	models "github.com/red-gold/ts-serverless/micros/votes/models"
+)
+
+// FindVotersByPost get the owner, avatar and reaction of the votes for each post
+func (s VoteServiceImpl) FindVotersByPost(postIds []uuid.UUID) ([]models.PostVotersModel, error) {
+	var pipeline []interface{}
+
+	inFilter := make(map[string]interface{})
+	inFilter["$in"] = postIds
+
+	match := make(map[string]interface{})
+	match["postId"] = inFilter
+	matchOperator := make(map[string]interface{})
+	matchOperator["$match"] = match
+
+	voter := make(map[string]interface{})
+	voter["ownerUserId"] = "$ownerUserId"
+	voter["ownerAvatar"] = "$ownerAvatar"
+	voter["type"] = "$type"
+	group := make(map[string]interface{})
+	group["_id"] = "$postId"
+	group["voters"] = map[string]interface{}{"$push": voter}
+	groupOperator := make(map[string]interface{})
+	groupOperator["$group"] = group
+
+	pipeline = append(pipeline, matchOperator, groupOperator)
+
+	result := <-s.VoteRepo.Aggregate(voteCollectionName, pipeline)
+	defer result.Close()
+	if result.Error() != nil {
+		return nil, result.Error()
+	}
+
+	postVotersList := []models.PostVotersModel{}
+	for result.Next() {
+		var postVoters models.PostVotersModel
+		errDecode := result.Decode(&postVoters)
+		if errDecode != nil {
+			return nil, fmt.Errorf("Error docoding on post voters")
+		}
+		postVotersList = append(postVotersList, postVoters)
+	}
+	return postVotersList, nil
+}
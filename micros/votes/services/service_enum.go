@@ -1,6 +1,7 @@
 package service
 
 const (
-	voteCollectionName       = "vote"
-	numberOfItems      int64 = 10
+	voteCollectionName         = "vote"
+	outboxCollectionName       = "voteOutbox"
+	numberOfItems        int64 = 10
 )
@@ -1,6 +1,7 @@
 package service
 
 import (
+	"errors"
 	"fmt"
 
 	uuid "github.com/gofrs/uuid"
@@ -10,12 +11,21 @@ import (
 	"github.com/red-gold/telar-core/data/mongodb"
 	mongoRepo "github.com/red-gold/telar-core/data/mongodb"
 	"github.com/red-gold/telar-core/utils"
+	"github.com/red-gold/ts-serverless/micros/internal/cursor"
+	"github.com/red-gold/ts-serverless/micros/internal/index"
+	"github.com/red-gold/ts-serverless/micros/internal/inmemory"
 	dto "github.com/red-gold/ts-serverless/micros/votes/dto"
+	models "github.com/red-gold/ts-serverless/micros/votes/models"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
+// ErrVoteIndexMissing is returned by UpsertVote while the unique index of the votes is not created
+var ErrVoteIndexMissing = errors.New("the unique index of the votes on post and owner is not created")
+
 // VoteService handlers with injected dependencies
 type VoteServiceImpl struct {
-	VoteRepo repo.Repository
+	VoteRepo     repo.Repository
+	IndexService index.Service
 }
 
 // NewVoteService initializes VoteService's dependencies and create new VoteService struct
@@ -23,12 +33,22 @@ func NewVoteService(db interface{}) (VoteService, error) {
 
 	voteService := &VoteServiceImpl{}
 
+	indexService, err := NewIndexService(db)
+	if err != nil {
+		return nil, err
+	}
+	voteService.IndexService = indexService
+
 	switch *config.AppConfig.DBType {
 	case config.DB_MONGO:
 
 		mongodb := db.(mongodb.MongoDatabase)
 		voteService.VoteRepo = mongoRepo.NewDataRepositoryMongo(mongodb)
 
+	case config.DB_INMEMORY:
+
+		voteService.VoteRepo = inmemory.NewDataRepositoryInMemory(db.(*inmemory.Database))
+
 	}
 
 	return voteService, nil
@@ -62,6 +82,9 @@ func (s VoteServiceImpl) FindOneVote(filter interface{}) (*dto.Vote, error) {
 
 	result := <-s.VoteRepo.FindOne(voteCollectionName, filter)
 	if result.Error() != nil {
+		if result.Error() == repo.ErrNoDocuments {
+			return nil, nil
+		}
 		return nil, result.Error()
 	}
 
@@ -191,24 +214,231 @@ func (s VoteServiceImpl) CreateVoteIndex(indexes map[string]interface{}) error {
 	return result
 }
 
+// UpsertVote save the vote if the owner has no vote on the post.
+// It returns the stored vote of the owner and whether the vote is new.
+func (s VoteServiceImpl) UpsertVote(vote *dto.Vote) (*dto.Vote, bool, error) {
+
+	// Without the unique index the second vote of the owner would be saved
+	indexed, err := uniqueVoteIndexGuard.Check(s.IndexService)
+	if err != nil {
+		return nil, false, err
+	}
+	if !indexed {
+		return nil, false, ErrVoteIndexMissing
+	}
+
+	saveResult := <-s.SaveVote(vote)
+	if saveResult.Error == nil {
+		return vote, true, nil
+	}
+
+	// The unique index on post and owner rejects the second vote
+	if !mongo.IsDuplicateKeyError(saveResult.Error) {
+		return nil, false, saveResult.Error
+	}
+	currentVote, err := s.FindByPostAndOwner(vote.PostId, vote.OwnerUserId)
+	if err != nil {
+		return nil, false, err
+	}
+	if currentVote == nil {
+		return nil, false, fmt.Errorf("vote of owner %s on post %s is not found", vote.OwnerUserId, vote.PostId)
+	}
+	return currentVote, false, nil
+}
+
+// FindVoteListByCursor get the votes by filter in the page around the cursors
+func (s VoteServiceImpl) FindVoteListByCursor(filter map[string]interface{}, after *cursor.Model, before *cursor.Model, limit int64) ([]dto.Vote, error) {
+	var pipeline []interface{}
+
+	sort := cursor.AddFilter(filter, "created_date", after, before)
+
+	matchOperator := make(map[string]interface{})
+	matchOperator["$match"] = filter
+
+	sortOperator := make(map[string]interface{})
+	sortOperator["$sort"] = sort
+
+	limitOperator := make(map[string]interface{})
+	limitOperator["$limit"] = limit
+
+	pipeline = append(pipeline, matchOperator, sortOperator, limitOperator)
+
+	result := <-s.VoteRepo.Aggregate(voteCollectionName, pipeline)
+	defer result.Close()
+	if result.Error() != nil {
+		return nil, result.Error()
+	}
+	var voteList []dto.Vote
+	for result.Next() {
+		var vote dto.Vote
+		errDecode := result.Decode(&vote)
+		if errDecode != nil {
+			return nil, fmt.Errorf("Error docoding on dto.Vote")
+		}
+		voteList = append(voteList, vote)
+	}
+
+	// The page before the cursor is read in reverse order
+	if before != nil {
+		for i, j := 0, len(voteList)-1; i < j; i, j = i+1, j-1 {
+			voteList[i], voteList[j] = voteList[j], voteList[i]
+		}
+	}
+
+	return voteList, nil
+}
+
+// FindByPostAndOwner find the vote of a user on a post
+func (s VoteServiceImpl) FindByPostAndOwner(postId uuid.UUID, ownerUserId uuid.UUID) (*dto.Vote, error) {
+
+	filter := struct {
+		PostId      uuid.UUID `json:"postId" bson:"postId"`
+		OwnerUserId uuid.UUID `json:"ownerUserId" bson:"ownerUserId"`
+	}{
+		PostId:      postId,
+		OwnerUserId: ownerUserId,
+	}
+	return s.FindOneVote(filter)
+}
+
+// SwitchVoteType change the reaction type of a vote only if the vote still has the previous type.
+// It returns false if the vote was changed by another request in between.
+func (s VoteServiceImpl) SwitchVoteType(voteId uuid.UUID, previousTypeId int, typeId int) (bool, error) {
+
+	filter := make(map[string]interface{})
+	filter["objectId"] = voteId
+	filter["type"] = typeFilter(previousTypeId)
+
+	data := struct {
+		TypeId int `json:"type" bson:"type"`
+	}{
+		TypeId: typeId,
+	}
+	updateOperator := coreData.UpdateOperator{
+		Set: data,
+	}
+
+	result := <-s.VoteRepo.Update(voteCollectionName, filter, updateOperator)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	modifiedCount, _ := result.Result.(int64)
+	return modifiedCount > 0, nil
+}
+
+// typeFilter create the filter of a reaction type, the votes without a type are likes
+func typeFilter(typeId int) interface{} {
+	if typeId != models.ReactionLike {
+		return typeId
+	}
+	inTypes := make(map[string]interface{})
+	inTypes["$in"] = []interface{}{nil, 0, models.ReactionLike}
+	return inTypes
+}
+
 // GetVoteByPostId get all votes by postId
-func (s VoteServiceImpl) GetVoteByPostId(postId *uuid.UUID, sortBy string, page int64) ([]dto.Vote, error) {
+func (s VoteServiceImpl) GetVoteByPostId(postId *uuid.UUID, typeId *int, sortBy string, page int64, hiddenUserIds []uuid.UUID) ([]dto.Vote, error) {
 	sortMap := make(map[string]int)
 	sortMap[sortBy] = -1
 	skip := numberOfItems * (page - 1)
 	limit := numberOfItems
 
-	filter := make(map[string]interface{})
+	filter := notPostDeletedFilter()
 
 	if postId != nil {
 		filter["postId"] = *postId
 	}
 
+	if typeId != nil {
+		filter["type"] = typeFilter(*typeId)
+	}
+	excludeOwners(filter, hiddenUserIds)
+
 	result, err := s.FindVoteList(filter, limit, skip, sortMap)
 
 	return result, err
 }
 
+// GetVoteByPostIdByCursor get the votes of a post in the page around the cursors
+func (s VoteServiceImpl) GetVoteByPostIdByCursor(postId *uuid.UUID, typeId *int, after *cursor.Model, before *cursor.Model, limit int64, hiddenUserIds []uuid.UUID) ([]dto.Vote, error) {
+	if limit <= 0 {
+		limit = numberOfItems
+	}
+
+	filter := notPostDeletedFilter()
+
+	if postId != nil {
+		filter["postId"] = *postId
+	}
+
+	if typeId != nil {
+		filter["type"] = typeFilter(*typeId)
+	}
+	excludeOwners(filter, hiddenUserIds)
+
+	return s.FindVoteListByCursor(filter, after, before, limit)
+}
+
+// excludeOwners add the filter that excludes the votes of the users
+func excludeOwners(filter map[string]interface{}, userIds []uuid.UUID) {
+	if len(userIds) == 0 {
+		return
+	}
+	notInFilter := make(map[string]interface{})
+	notInFilter["$nin"] = userIds
+	filter["ownerUserId"] = notInFilter
+}
+
+// notPostDeletedFilter create the filter of the votes which their post is not deleted
+func notPostDeletedFilter() map[string]interface{} {
+	notDeleted := make(map[string]interface{})
+	notDeleted["$ne"] = true
+
+	filter := make(map[string]interface{})
+	filter["postDeleted"] = notDeleted
+	return filter
+}
+
+// SetPostDeleted hide the votes of the post when the post is deleted and show them when it is restored
+func (s VoteServiceImpl) SetPostDeleted(postId uuid.UUID, deleted bool, version int64) error {
+
+	olderVersion := make(map[string]interface{})
+	olderVersion["$lt"] = version
+	noVersion := make(map[string]interface{})
+	noVersion["$exists"] = false
+
+	filter := make(map[string]interface{})
+	filter["postId"] = postId
+	filter["$or"] = []interface{}{
+		map[string]interface{}{"postDeletedVersion": olderVersion},
+		map[string]interface{}{"postDeletedVersion": noVersion},
+	}
+
+	data := struct {
+		PostDeleted        bool  `json:"postDeleted" bson:"postDeleted"`
+		PostDeletedVersion int64 `json:"postDeletedVersion" bson:"postDeletedVersion"`
+	}{
+		PostDeleted:        deleted,
+		PostDeletedVersion: version,
+	}
+	updateOperator := coreData.UpdateOperator{
+		Set: data,
+	}
+	result := <-s.VoteRepo.UpdateMany(voteCollectionName, filter, updateOperator)
+	return result.Error
+}
+
+// PurgeVotesByPostId remove all the votes of the post
+func (s VoteServiceImpl) PurgeVotesByPostId(postId uuid.UUID) error {
+
+	filter := struct {
+		PostId uuid.UUID `json:"postId" bson:"postId"`
+	}{
+		PostId: postId,
+	}
+	return s.DeleteManyVotes(filter)
+}
+
 // DeleteVotesByPostId delete votes by postId
 func (s VoteServiceImpl) DeleteVotesByPostId(ownerUserId uuid.UUID, postId uuid.UUID) error {
 
@@ -225,3 +455,66 @@ func (s VoteServiceImpl) DeleteVotesByPostId(ownerUserId uuid.UUID, postId uuid.
 	}
 	return nil
 }
+
+// votePairModel group the votes by the post and owner, the struct keeps the order of the keys
+type votePairModel struct {
+	PostId      string `bson:"postId"`
+	OwnerUserId string `bson:"ownerUserId"`
+}
+
+// RemoveDuplicateVotes remove the votes which repeat an older vote of the same owner on the same post and
+// return the number of the removed votes. The unique index of the votes can not be created while duplicates exist.
+func (s VoteServiceImpl) RemoveDuplicateVotes() (int64, error) {
+	var pipeline []interface{}
+
+	// The oldest vote of each pair is the first one and it is kept
+	sortMap := make(map[string]int)
+	sortMap["created_date"] = 1
+	sortOperator := make(map[string]interface{})
+	sortOperator["$sort"] = sortMap
+
+	group := make(map[string]interface{})
+	group["_id"] = votePairModel{PostId: "$postId", OwnerUserId: "$ownerUserId"}
+	group["voteIds"] = map[string]interface{}{"$push": "$objectId"}
+	group["count"] = map[string]interface{}{"$sum": 1}
+	groupOperator := make(map[string]interface{})
+	groupOperator["$group"] = group
+
+	duplicateMatch := make(map[string]interface{})
+	duplicateMatch["$match"] = map[string]interface{}{"count": map[string]interface{}{"$gt": 1}}
+
+	pipeline = append(pipeline, sortOperator, groupOperator, duplicateMatch)
+
+	result := <-s.VoteRepo.Aggregate(voteCollectionName, pipeline)
+	defer result.Close()
+	if result.Error() != nil {
+		return 0, result.Error()
+	}
+
+	var duplicateIds []uuid.UUID
+	for result.Next() {
+		var pair struct {
+			VoteIds []uuid.UUID `bson:"voteIds"`
+		}
+		errDecode := result.Decode(&pair)
+		if errDecode != nil {
+			return 0, fmt.Errorf("Error docoding on duplicated vote")
+		}
+		duplicateIds = append(duplicateIds, pair.VoteIds[1:]...)
+	}
+	if len(duplicateIds) == 0 {
+		return 0, nil
+	}
+
+	inDuplicates := make(map[string]interface{})
+	inDuplicates["$in"] = duplicateIds
+	filter := make(map[string]interface{})
+	filter["objectId"] = inDuplicates
+
+	deleteResult := <-s.VoteRepo.Delete(voteCollectionName, filter, false)
+	if deleteResult.Error != nil {
+		return 0, deleteResult.Error
+	}
+	deletedCount, _ := deleteResult.Result.(int64)
+	return deletedCount, nil
+}
@@ -0,0 +1,233 @@
+package service
+
+import (
+	"testing"
+
+	uuid "github.com/gofrs/uuid"
+	"github.com/red-gold/telar-core/config"
+	"github.com/red-gold/ts-serverless/micros/internal/inmemory"
+	"github.com/red-gold/ts-serverless/micros/votes/dto"
+	models "github.com/red-gold/ts-serverless/micros/votes/models"
+)
+
+// newTestVoteService create a vote service on an in-memory database with the required indexes
+func newTestVoteService(t *testing.T) VoteService {
+	t.Helper()
+	dbType := config.DB_INMEMORY
+	config.AppConfig.DBType = &dbType
+	db := inmemory.NewDatabase()
+	indexService, _ := NewIndexService(db)
+	if _, err := indexService.ReconcileIndexes(); err != nil {
+		t.Fatalf("ReconcileIndexes: %s", err)
+	}
+	voteService, err := NewVoteService(db)
+	if err != nil {
+		t.Fatalf("NewVoteService: %s", err)
+	}
+	return voteService
+}
+
+func TestUpsertVote(t *testing.T) {
+	voteService := newTestVoteService(t)
+	ownerUserId := uuid.Must(uuid.NewV4())
+	postId := uuid.Must(uuid.NewV4())
+
+	tests := []struct {
+		name    string
+		ownerId uuid.UUID
+		typeId  int
+		created bool
+	}{
+		{name: "first vote", ownerId: ownerUserId, typeId: models.ReactionLike, created: true},
+		{name: "second vote", ownerId: ownerUserId, typeId: models.ReactionLike, created: false},
+		{name: "vote of another user", ownerId: uuid.Must(uuid.NewV4()), typeId: models.ReactionLike, created: true},
+	}
+	var firstVoteId uuid.UUID
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			vote := &dto.Vote{
+				ObjectId:    uuid.Must(uuid.NewV4()),
+				OwnerUserId: test.ownerId,
+				PostId:      postId,
+				TypeId:      test.typeId,
+			}
+			stored, created, err := voteService.UpsertVote(vote)
+			if err != nil {
+				t.Fatalf("UpsertVote: %s", err)
+			}
+			if created != test.created {
+				t.Errorf("got created %t, want %t", created, test.created)
+			}
+			if firstVoteId == uuid.Nil {
+				firstVoteId = stored.ObjectId
+			} else if !created && stored.ObjectId != firstVoteId {
+				t.Errorf("got vote %s, want the stored vote %s", stored.ObjectId, firstVoteId)
+			}
+		})
+	}
+
+	votes, err := voteService.GetVoteByPostId(&postId, nil, "created_date", 1, nil)
+	if err != nil {
+		t.Fatalf("GetVoteByPostId: %s", err)
+	}
+	if len(votes) != 2 {
+		t.Errorf("got %d votes on the post, want 2", len(votes))
+	}
+}
+
+func TestRemoveDuplicateVotes(t *testing.T) {
+	dbType := config.DB_INMEMORY
+	config.AppConfig.DBType = &dbType
+	db := inmemory.NewDatabase()
+	voteService, err := NewVoteService(db)
+	if err != nil {
+		t.Fatalf("NewVoteService: %s", err)
+	}
+
+	// The votes are saved before the unique index is created
+	ownerUserId := uuid.Must(uuid.NewV4())
+	postId := uuid.Must(uuid.NewV4())
+	firstVote := &dto.Vote{ObjectId: uuid.Must(uuid.NewV4()), OwnerUserId: ownerUserId, PostId: postId, CreatedDate: 1}
+	votes := []*dto.Vote{
+		{ObjectId: uuid.Must(uuid.NewV4()), OwnerUserId: ownerUserId, PostId: postId, CreatedDate: 3},
+		firstVote,
+		{ObjectId: uuid.Must(uuid.NewV4()), OwnerUserId: ownerUserId, PostId: postId, CreatedDate: 2},
+		{ObjectId: uuid.Must(uuid.NewV4()), OwnerUserId: uuid.Must(uuid.NewV4()), PostId: postId, CreatedDate: 4},
+	}
+	for _, vote := range votes {
+		if result := <-voteService.SaveVote(vote); result.Error != nil {
+			t.Fatalf("SaveVote: %s", result.Error)
+		}
+	}
+
+	newVote := &dto.Vote{ObjectId: uuid.Must(uuid.NewV4()), OwnerUserId: ownerUserId, PostId: postId}
+	if _, _, err := voteService.UpsertVote(newVote); err != ErrVoteIndexMissing {
+		t.Fatalf("got UpsertVote error %v without the unique index, want %v", err, ErrVoteIndexMissing)
+	}
+
+	removed, err := voteService.RemoveDuplicateVotes()
+	if err != nil {
+		t.Fatalf("RemoveDuplicateVotes: %s", err)
+	}
+	if removed != 2 {
+		t.Errorf("got %d removed votes, want 2", removed)
+	}
+
+	indexService, _ := NewIndexService(db)
+	if _, err := indexService.ReconcileIndexes(); err != nil {
+		t.Fatalf("ReconcileIndexes: %s", err)
+	}
+
+	stored, created, err := voteService.UpsertVote(newVote)
+	if err != nil {
+		t.Fatalf("UpsertVote: %s", err)
+	}
+	if created {
+		t.Error("got a new vote, want the oldest vote of the owner")
+	}
+	if stored.ObjectId != firstVote.ObjectId {
+		t.Errorf("got vote %s, want the oldest vote %s", stored.ObjectId, firstVote.ObjectId)
+	}
+}
+
+func TestSwitchVoteType(t *testing.T) {
+	voteService := newTestVoteService(t)
+	vote := &dto.Vote{
+		ObjectId:    uuid.Must(uuid.NewV4()),
+		OwnerUserId: uuid.Must(uuid.NewV4()),
+		PostId:      uuid.Must(uuid.NewV4()),
+	}
+	if _, _, err := voteService.UpsertVote(vote); err != nil {
+		t.Fatalf("UpsertVote: %s", err)
+	}
+
+	tests := []struct {
+		name         string
+		previousType int
+		typeId       int
+		switched     bool
+	}{
+		{name: "a vote without type is a like", previousType: models.ReactionLike, typeId: models.ReactionLove, switched: true},
+		{name: "the previous type is changed", previousType: models.ReactionLike, typeId: models.ReactionLove, switched: false},
+		{name: "back to like", previousType: models.ReactionLove, typeId: models.ReactionLike, switched: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			switched, err := voteService.SwitchVoteType(vote.ObjectId, test.previousType, test.typeId)
+			if err != nil {
+				t.Fatalf("SwitchVoteType: %s", err)
+			}
+			if switched != test.switched {
+				t.Errorf("got switched %t, want %t", switched, test.switched)
+			}
+		})
+	}
+}
+
+func TestSetPostDeletedIgnoresOlderVersions(t *testing.T) {
+	voteService := newTestVoteService(t)
+	postId := uuid.Must(uuid.NewV4())
+	vote := &dto.Vote{
+		ObjectId:    uuid.Must(uuid.NewV4()),
+		OwnerUserId: uuid.Must(uuid.NewV4()),
+		PostId:      postId,
+		TypeId:      models.ReactionLike,
+	}
+	if _, _, err := voteService.UpsertVote(vote); err != nil {
+		t.Fatalf("UpsertVote: %s", err)
+	}
+
+	// The restore is delivered before the delete which it follows
+	tests := []struct {
+		name    string
+		deleted bool
+		version int64
+		want    int
+	}{
+		{name: "restore", deleted: false, version: 20, want: 1},
+		{name: "older delete", deleted: true, version: 10, want: 1},
+		{name: "newer delete", deleted: true, version: 30, want: 0},
+	}
+	for _, test := range tests {
+		if err := voteService.SetPostDeleted(postId, test.deleted, test.version); err != nil {
+			t.Fatalf("SetPostDeleted: %s", err)
+		}
+		votes, err := voteService.GetVoteByPostId(&postId, nil, "created_date", 1, nil)
+		if err != nil {
+			t.Fatalf("GetVoteByPostId: %s", err)
+		}
+		if len(votes) != test.want {
+			t.Errorf("%s: got %d votes, want %d", test.name, len(votes), test.want)
+		}
+	}
+}
+
+func TestFindVotersByPost(t *testing.T) {
+	voteService := newTestVoteService(t)
+	postId := uuid.Must(uuid.NewV4())
+	voters := []*dto.Vote{
+		{OwnerUserId: uuid.Must(uuid.NewV4()), OwnerAvatar: "first", PostId: postId, TypeId: models.ReactionLike},
+		{OwnerUserId: uuid.Must(uuid.NewV4()), OwnerAvatar: "second", PostId: postId, TypeId: models.ReactionLike},
+		{OwnerUserId: uuid.Must(uuid.NewV4()), PostId: uuid.Must(uuid.NewV4()), TypeId: models.ReactionLike},
+	}
+	for _, vote := range voters {
+		if result := <-voteService.SaveVote(vote); result.Error != nil {
+			t.Fatalf("SaveVote: %s", result.Error)
+		}
+	}
+
+	postVoters, err := voteService.FindVotersByPost([]uuid.UUID{postId})
+	if err != nil {
+		t.Fatalf("FindVotersByPost: %s", err)
+	}
+	if len(postVoters) != 1 || postVoters[0].PostId != postId {
+		t.Fatalf("got voters of %d posts, want the voters of the post", len(postVoters))
+	}
+	avatars := make(map[uuid.UUID]string)
+	for _, voter := range postVoters[0].Voters {
+		avatars[voter.OwnerUserId] = voter.OwnerAvatar
+	}
+	if len(avatars) != 2 || avatars[voters[0].OwnerUserId] != "first" || avatars[voters[1].OwnerUserId] != "second" {
+		t.Errorf("got voters %v, want the two voters of the post", avatars)
+	}
+}